@@ -1,10 +1,86 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"net/http"
 
+	"github.com/aaryansinhaa/patient-management-system/internals/blobstore"
 	"github.com/aaryansinhaa/patient-management-system/internals/config"
 	"github.com/aaryansinhaa/patient-management-system/internals/database"
+	"github.com/aaryansinhaa/patient-management-system/internals/events"
+	attachment_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/attachment"
+	auth_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/auth"
+	billing_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/billing"
+	clinic_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/clinic"
+	consent_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/consent"
+	document_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/document"
+	emergency_access_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/emergency_access"
+	encounter_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/encounter"
+	fhir_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/fhir"
+	hl7_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/hl7"
+	jwks_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/jwks"
+	lab_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/lab"
+	notification_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/notification"
+	patient_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/patient"
+	patient_csv_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/patient_csv"
+	patient_merge_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/patient_merge"
+	queue_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/queue"
+	webhook_handler "github.com/aaryansinhaa/patient-management-system/internals/handlers/webhook"
+	"github.com/aaryansinhaa/patient-management-system/internals/hl7"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/notification"
+	allergy_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/allergy"
+	attachment_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/attachment"
+	audit_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/audit"
+	catalogue_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/catalogue"
+	chronic_condition_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/chronic_condition"
+	clinic_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/clinic"
+	consent_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/consent"
+	data_key_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/data_key"
+	diagnosis_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/diagnosis"
+	domain_event_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/domain_event"
+	emergency_access_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/emergency_access"
+	encounter_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/encounter"
+	family_history_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/family_history"
+	hl7_dead_letter_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/hl7_dead_letter"
+	invoice_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/invoice"
+	lab_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/lab"
+	login_attempt_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/login_attempt"
+	mfa_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/mfa"
+	notification_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/notification"
+	password_reset_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/password_reset"
+	patient_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient"
+	patient_contact_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient_contact"
+	patient_identifier_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient_identifier"
+	patient_merge_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient_merge"
+	prescription_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/prescription"
+	queue_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/queue"
+	user_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/user"
+	vitals_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/vitals"
+	webhook_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/webhook"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	attachment_service "github.com/aaryansinhaa/patient-management-system/internals/service/attachment"
+	auth_service "github.com/aaryansinhaa/patient-management-system/internals/service/auth"
+	billing_service "github.com/aaryansinhaa/patient-management-system/internals/service/billing"
+	clinic_service "github.com/aaryansinhaa/patient-management-system/internals/service/clinic"
+	consent_service "github.com/aaryansinhaa/patient-management-system/internals/service/consent"
+	document_service "github.com/aaryansinhaa/patient-management-system/internals/service/document"
+	emergency_access_service "github.com/aaryansinhaa/patient-management-system/internals/service/emergency_access"
+	encounter_service "github.com/aaryansinhaa/patient-management-system/internals/service/encounter"
+	fhir_service "github.com/aaryansinhaa/patient-management-system/internals/service/fhir"
+	hl7_service "github.com/aaryansinhaa/patient-management-system/internals/service/hl7"
+	lab_service "github.com/aaryansinhaa/patient-management-system/internals/service/lab"
+	notification_service "github.com/aaryansinhaa/patient-management-system/internals/service/notification"
+	patient_service "github.com/aaryansinhaa/patient-management-system/internals/service/patient"
+	patient_csv_service "github.com/aaryansinhaa/patient-management-system/internals/service/patient_csv"
+	patient_merge_service "github.com/aaryansinhaa/patient-management-system/internals/service/patient_merge"
+	queue_service "github.com/aaryansinhaa/patient-management-system/internals/service/queue"
+	webhook_service "github.com/aaryansinhaa/patient-management-system/internals/service/webhook"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
 )
 
 func main() {
@@ -19,6 +95,187 @@ func main() {
 		return
 	}
 	defer connection.Connection.Close()
-	fmt.Printf("Database connection established successfully. %v", connection.Connection.Stats().OpenConnections)
+	fmt.Printf("Database connection established successfully. %v\n", connection.Connection.Stats().OpenConnections)
 
+	jwtManager, err := utils.LoadJWTManager(config.AuthConfig.JWT)
+	if err != nil {
+		fmt.Printf("Failed to load JWT keys: %v\n", err)
+		return
+	}
+	passwordPolicy, err := auth_service.LoadPasswordPolicy(config.AuthConfig.Password)
+	if err != nil {
+		fmt.Printf("Failed to load password policy: %v\n", err)
+		return
+	}
+	keyring, err := utils.LoadKeyring(config.Encryption, data_key_repo.NewDataKeyStorage(connection.Connection))
+	if err != nil {
+		fmt.Printf("Failed to load encryption keys: %v\n", err)
+		return
+	}
+
+	// Every authenticated request runs on its clinic's pool, where row-level
+	// security hides other clinics' records. The shared pool serves logins.
+	pools := database.NewTenantPools(&config.DatabaseConfig, config.Tenancy)
+	defer pools.Close()
+
+	hl7ClinicID := model.DefaultClinicID
+	if config.HL7Config.ClinicID != "" {
+		hl7ClinicID, err = uuid.Parse(config.HL7Config.ClinicID)
+		if err != nil {
+			fmt.Printf("Invalid HL7 clinic id: %v\n", err)
+			return
+		}
+	}
+	hl7DB, err := pools.Pool(hl7ClinicID)
+	if err != nil {
+		fmt.Printf("Failed to open the HL7 clinic pool: %v\n", err)
+		return
+	}
+	hl7Service := hl7_service.NewHL7Service(patient_repo.NewPatientStorage(hl7DB, keyring),
+		patient_identifier_repo.NewPatientIdentifierStorage(hl7DB),
+		hl7_dead_letter_repo.NewHL7DeadLetterStorage(hl7DB, keyring), config.HL7Config)
+
+	// The outbox is drained on the shared pool, for every clinic at once.
+	providers, err := notification.NewProviders(config.Notifications)
+	if err != nil {
+		fmt.Printf("Failed to set up notification providers: %v\n", err)
+		return
+	}
+	dispatcher := notification.NewDispatcher(notification_repo.NewNotificationStorage(connection.Connection, keyring),
+		consent_repo.NewConsentStorage(connection.Connection), providers, config.Notifications)
+	go dispatcher.Run(context.Background())
+
+	// Domain events are delivered from the shared pool too.
+	bus := events.NewBus()
+	bus.Subscribe(model.EventUserDeactivated,
+		events.RevokePasswordResets(password_reset_repo.NewPasswordResetStorage(connection.Connection)))
+	webhookDeliveries := webhook_repo.NewWebhookStorage(connection.Connection, keyring)
+	bus.SubscribeAll(events.EnqueueWebhooks(webhookDeliveries))
+	eventDispatcher := events.NewDispatcher(domain_event_repo.NewDomainEventStorage(connection.Connection), bus,
+		config.Events)
+	go eventDispatcher.Run(context.Background())
+	go events.NewWebhookDispatcher(webhookDeliveries, nil, config.Events.Webhooks).Run(context.Background())
+
+	// Attachment contents are addressed by hash, so every clinic shares the store.
+	attachmentStore, err := blobstore.NewStore(config.Attachments)
+	if err != nil {
+		fmt.Printf("Failed to set up the attachment store: %v\n", err)
+		return
+	}
+
+	auth := middleware.NewAuth(jwtManager)
+	newMux := func(db *sql.DB, hl7Service service.HL7Service) *http.ServeMux {
+		return routes(db, config, auth, jwtManager, passwordPolicy, keyring, hl7Service, attachmentStore)
+	}
+	tenants := middleware.NewTenants(jwtManager, newMux(connection.Connection, nil),
+		func(clinicID uuid.UUID) (http.Handler, error) {
+			db, err := pools.Pool(clinicID)
+			if err != nil {
+				return nil, err
+			}
+			if clinicID != hl7ClinicID {
+				return newMux(db, nil), nil
+			}
+			return newMux(db, hl7Service), nil
+		})
+
+	if config.HL7Config.ListenAddress != "" {
+		mllpServer := &hl7.Server{
+			Addr:        config.HL7Config.ListenAddress,
+			Handler:     hl7Service.HandleMessage,
+			IdleTimeout: config.HL7Config.IdleTimeout,
+		}
+		go func() {
+			if err := mllpServer.ListenAndServe(); err != nil {
+				fmt.Printf("HL7 listener stopped: %v\n", err)
+			}
+		}()
+		fmt.Printf("HL7 MLLP listener on %s\n", config.HL7Config.ListenAddress)
+	}
+
+	if err := http.ListenAndServe(config.HTTPServerConfig.Host, tenants); err != nil {
+		fmt.Printf("HTTP server stopped: %v\n", err)
+	}
+}
+
+// routes builds every service on db and registers their handlers. HL7 ingest
+// always runs on the pool of its configured clinic, so it is built once and
+// only that clinic's admins get its dead letters; hl7Service is nil for every
+// other clinic. The attachment store is shared.
+func routes(db *sql.DB, config *config.Config, auth *middleware.Auth, jwtManager *utils.JWTManager,
+	passwordPolicy *auth_service.PasswordPolicy, keyring *utils.Keyring, hl7Service service.HL7Service,
+	attachmentStore blobstore.Store) *http.ServeMux {
+	userStorage := user_repo.NewUserStorage(db)
+	patientStorage := patient_repo.NewPatientStorage(db, keyring)
+	diagnosisStorage := diagnosis_repo.NewDiagnosisStorage(db, keyring)
+	auditStorage := audit_repo.NewAuditStorage(db)
+	encounterStorage := encounter_repo.NewEncounterStorage(db)
+	prescriptionStorage := prescription_repo.NewPrescriptionStorage(db)
+	vitalsStorage := vitals_repo.NewVitalsStorage(db)
+	invoiceStorage := invoice_repo.NewInvoiceStorage(db)
+	chronicConditionStorage := chronic_condition_repo.NewChronicConditionStorage(db)
+	clinicStorage := clinic_repo.NewClinicStorage(db)
+	consentStorage := consent_repo.NewConsentStorage(db)
+	patientContactStorage := patient_contact_repo.NewPatientContactStorage(db, keyring)
+	notificationStorage := notification_repo.NewNotificationStorage(db, keyring)
+
+	authService := auth_service.NewAuthService(userStorage, userStorage,
+		login_attempt_repo.NewLoginAttemptStorage(db), auditStorage, mfa_repo.NewMFAStorage(db),
+		password_reset_repo.NewPasswordResetStorage(db), clinicStorage, passwordPolicy, jwtManager, config.AuthConfig)
+	encounterService := encounter_service.NewEncounterService(encounterStorage, patientStorage,
+		userStorage, diagnosisStorage, vitalsStorage, prescriptionStorage, consentStorage)
+	queueService := queue_service.NewQueueService(queue_repo.NewQueueStorage(db), encounterStorage, patientStorage,
+		config.QueueConfig)
+	billingService := billing_service.NewBillingService(catalogue_repo.NewCatalogueStorage(db),
+		invoiceStorage, encounterStorage, patientStorage, prescriptionStorage, config.BillingConfig)
+	documentService := document_service.NewDocumentService(encounterStorage, patientStorage, userStorage,
+		diagnosisStorage, vitalsStorage, prescriptionStorage, invoiceStorage, config.ClinicConfig)
+	fhirService := fhir_service.NewFHIRService(patientStorage, userStorage, diagnosisStorage,
+		chronicConditionStorage, encounterStorage, consentStorage, config.FHIRConfig)
+	patientService := patient_service.NewPatientService(patientStorage, diagnosisStorage,
+		allergy_repo.NewAllergyStorage(db), chronicConditionStorage, family_history_repo.NewFamilyHistoryStorage(db),
+		patientContactStorage)
+	patientCSVService := patient_csv_service.NewPatientCSVService(patientStorage, config.PatientImport)
+	patientMergeService := patient_merge_service.NewPatientMergeService(patientStorage,
+		patient_merge_repo.NewPatientMergeStorage(db, keyring), config.PatientMerge)
+	clinicService := clinic_service.NewClinicService(clinicStorage)
+	consentService := consent_service.NewConsentService(consentStorage, patientStorage)
+	emergencyAccessService := emergency_access_service.NewEmergencyAccessService(
+		emergency_access_repo.NewEmergencyAccessStorage(db), patientStorage, encounterStorage, config.EmergencyAccess)
+
+	notifier := notification.NewNotifier(notificationStorage, patientStorage, patientContactStorage, consentStorage,
+		config.ClinicConfig)
+	notificationService := notification_service.NewNotificationService(notifier, notificationStorage, patientStorage,
+		userStorage, config.Notifications)
+	webhookService := webhook_service.NewWebhookService(webhook_repo.NewWebhookStorage(db, keyring))
+	labService := lab_service.NewLabService(lab_repo.NewLabStorage(db, keyring), patientStorage, diagnosisStorage,
+		consentStorage, notifier)
+	attachmentService := attachment_service.NewAttachmentService(attachment_repo.NewAttachmentStorage(db, keyring),
+		patientStorage, diagnosisStorage, attachmentStore, config.Attachments)
+
+	// Doctors only see the records of patients they treat, unless they break the glass.
+	auth = auth.WithPatientAuthorizer(emergencyAccessService)
+
+	mux := http.NewServeMux()
+	jwks_handler.NewJWKSHandler(jwtManager).RegisterRoutes(mux)
+	auth_handler.NewAuthHandler(authService).RegisterRoutes(mux, auth)
+	encounter_handler.NewEncounterHandler(encounterService).RegisterRoutes(mux, auth)
+	queue_handler.NewQueueHandler(queueService).RegisterRoutes(mux, auth)
+	billing_handler.NewBillingHandler(billingService).RegisterRoutes(mux, auth)
+	document_handler.NewDocumentHandler(documentService).RegisterRoutes(mux, auth)
+	fhir_handler.NewFHIRHandler(fhirService).RegisterRoutes(mux, auth)
+	if hl7Service != nil {
+		hl7_handler.NewHL7Handler(hl7Service).RegisterRoutes(mux, auth)
+	}
+	patient_handler.NewPatientHandler(patientService).RegisterRoutes(mux, auth)
+	patient_csv_handler.NewPatientCSVHandler(patientCSVService).RegisterRoutes(mux, auth)
+	patient_merge_handler.NewPatientMergeHandler(patientMergeService).RegisterRoutes(mux, auth)
+	clinic_handler.NewClinicHandler(clinicService).RegisterRoutes(mux, auth)
+	consent_handler.NewConsentHandler(consentService).RegisterRoutes(mux, auth)
+	emergency_access_handler.NewEmergencyAccessHandler(emergencyAccessService).RegisterRoutes(mux, auth)
+	notification_handler.NewNotificationHandler(notificationService).RegisterRoutes(mux, auth)
+	webhook_handler.NewWebhookHandler(webhookService).RegisterRoutes(mux, auth)
+	lab_handler.NewLabHandler(labService).RegisterRoutes(mux, auth)
+	attachment_handler.NewAttachmentHandler(attachmentService).RegisterRoutes(mux, auth)
+	return mux
 }
@@ -0,0 +1,120 @@
+// Command patientcsv bulk imports patients from, or exports them to, a CSV
+// file using the application's configuration and database.
+//
+//	patientcsv import patients.csv
+//	patientcsv -clinic <clinic id> export patients.csv
+//
+// A file name of "-" reads from standard input or writes to standard output.
+// Patients are imported into, and exported from, the default clinic unless
+// -clinic names another.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/database"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	data_key_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/data_key"
+	patient_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	patient_csv_service "github.com/aaryansinhaa/patient-management-system/internals/service/patient_csv"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+func main() {
+	clinic := flag.String("clinic", model.DefaultClinicID.String(), "id of the clinic to import into or export from")
+	flag.Parse()
+	args := flag.Args()
+	if len(args) != 2 || (args[0] != "import" && args[0] != "export") {
+		fmt.Fprintln(os.Stderr, "usage: patientcsv [-clinic <id>] import|export <file>")
+		os.Exit(2)
+	}
+	command, path := args[0], args[1]
+	clinicID, err := uuid.Parse(*clinic)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid clinic id: %v\n", err)
+		os.Exit(2)
+	}
+
+	config := config.MustLoadConfig()
+	// Connecting runs the migrations and serves the data keys; the clinic's
+	// pool then only sees, and only inserts into, that clinic.
+	connection, err := database.LoadPSqlDb(&config.DatabaseConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the database: %v\n", err)
+		os.Exit(1)
+	}
+	defer connection.Connection.Close()
+	keyring, err := utils.LoadKeyring(config.Encryption, data_key_repo.NewDataKeyStorage(connection.Connection))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load encryption keys: %v\n", err)
+		os.Exit(1)
+	}
+	pools := database.NewTenantPools(&config.DatabaseConfig, config.Tenancy)
+	db, err := pools.Pool(clinicID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the database: %v\n", err)
+		os.Exit(1)
+	}
+	defer pools.Close()
+
+	patientCSVService := patient_csv_service.NewPatientCSVService(patient_repo.NewPatientStorage(db, keyring), config.PatientImport)
+	if command == "import" {
+		err = importPatients(patientCSVService, path)
+	} else {
+		err = exportPatients(patientCSVService, path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", command, err)
+		pools.Close()
+		connection.Connection.Close()
+		os.Exit(1)
+	}
+}
+
+func importPatients(service service.PatientCSVService, path string) error {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	report, err := service.ImportPatients(in)
+	if err != nil {
+		return err
+	}
+	for _, rowErr := range report.Errors {
+		if rowErr.PhoneNumber != "" {
+			fmt.Fprintf(os.Stderr, "line %d (%s): %s\n", rowErr.Row, rowErr.PhoneNumber, rowErr.Error)
+		} else {
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", rowErr.Row, rowErr.Error)
+		}
+	}
+	fmt.Printf("%d rows: %d imported, %d duplicates, %d failed\n",
+		report.Rows, report.Imported, report.Duplicates, report.Failed)
+	return nil
+}
+
+func exportPatients(service service.PatientCSVService, path string) error {
+	if path == "-" {
+		return service.ExportPatients(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := service.ExportPatients(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,129 @@
+// Command rotatekeys rotates the data key that encrypts sensitive patient
+// data and re-encrypts every clinic's patients, patient contacts, diagnoses,
+// merge snapshots, HL7 dead letters, notifications, webhook secrets, lab
+// results and attachment details with the new key.
+// Rows are rewritten in small batches with a pause in between, so it can run
+// alongside the application.
+//
+//	rotatekeys            create a new data key, then re-encrypt
+//	rotatekeys -resume    re-encrypt with the current data key only
+//
+// The first run after upgrading also encrypts the rows stored in plaintext
+// before encryption existed. Running instances keep using the previous data
+// key until they refresh it, so rows they write in that window are caught by
+// running again with -resume. Changing the active master key in the
+// configuration and rotating wraps every data key with the new master key, so
+// the old one can then be removed from the key file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/database"
+	attachment_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/attachment"
+	clinic_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/clinic"
+	data_key_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/data_key"
+	diagnosis_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/diagnosis"
+	hl7_dead_letter_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/hl7_dead_letter"
+	lab_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/lab"
+	notification_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/notification"
+	patient_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient"
+	patient_contact_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient_contact"
+	patient_merge_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient_merge"
+	webhook_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/webhook"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+func main() {
+	resume := flag.Bool("resume", false, "re-encrypt with the current data key without creating a new one")
+	flag.Parse()
+
+	config := config.MustLoadConfig()
+	connection, err := database.LoadPSqlDb(&config.DatabaseConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to the database: %v\n", err)
+		os.Exit(1)
+	}
+	defer connection.Connection.Close()
+	keyring, err := utils.LoadKeyring(config.Encryption, data_key_repo.NewDataKeyStorage(connection.Connection))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load encryption keys: %v\n", err)
+		os.Exit(1)
+	}
+	pools := database.NewTenantPools(&config.DatabaseConfig, config.Tenancy)
+	defer pools.Close()
+
+	if err := rotate(keyring, clinic_repo.NewClinicStorage(connection.Connection), pools, config.Encryption, *resume); err != nil {
+		fmt.Fprintf(os.Stderr, "rotation failed: %v\n", err)
+		pools.Close()
+		connection.Connection.Close()
+		os.Exit(1)
+	}
+}
+
+func rotate(keyring *utils.Keyring, clinics *clinic_repo.ClinicStorage, pools *database.TenantPools,
+	cfg config.EncryptionConfig, resume bool) error {
+	key, err := keyring.ActiveKey()
+	if !resume {
+		key, err = keyring.Rotate()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Re-encrypting with data key %d\n", key.ID)
+
+	// Row-level security hides every clinic's rows from the shared pool, so
+	// each clinic is re-encrypted through its own.
+	all, err := clinics.GetAllClinics()
+	if err != nil {
+		return err
+	}
+	for _, clinic := range all {
+		db, err := pools.Pool(clinic.ID)
+		if err != nil {
+			return err
+		}
+		for _, table := range []struct {
+			name  string
+			batch func(limit int) (int, error)
+		}{
+			{"patients", patient_repo.NewPatientStorage(db, keyring).ReencryptPatients},
+			{"contacts", patient_contact_repo.NewPatientContactStorage(db, keyring).ReencryptContacts},
+			{"diagnoses", diagnosis_repo.NewDiagnosisStorage(db, keyring).ReencryptDiagnoses},
+			{"merge snapshots", patient_merge_repo.NewPatientMergeStorage(db, keyring).ReencryptMerges},
+			{"HL7 dead letters", hl7_dead_letter_repo.NewHL7DeadLetterStorage(db, keyring).ReencryptDeadLetters},
+			{"notifications", notification_repo.NewNotificationStorage(db, keyring).ReencryptNotifications},
+			{"webhook subscriptions", webhook_repo.NewWebhookStorage(db, keyring).ReencryptSubscriptions},
+			{"lab results", lab_repo.NewLabStorage(db, keyring).ReencryptLabResults},
+			{"attachments", attachment_repo.NewAttachmentStorage(db, keyring).ReencryptAttachments},
+		} {
+			count, err := reencrypt(table.batch, cfg)
+			if err != nil {
+				return fmt.Errorf("clinic %s: %s: %w", clinic.Code, table.name, err)
+			}
+			fmt.Printf("%s: %d %s re-encrypted\n", clinic.Code, count, table.name)
+		}
+	}
+	return nil
+}
+
+// reencrypt runs batch until there is nothing left to rewrite and returns the
+// number of rows rewritten.
+func reencrypt(batch func(limit int) (int, error), cfg config.EncryptionConfig) (int, error) {
+	total := 0
+	for {
+		count, err := batch(cfg.RotationBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += count
+		if count < cfg.RotationBatchSize {
+			return total, nil
+		}
+		time.Sleep(cfg.RotationPause)
+	}
+}
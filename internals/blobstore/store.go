@@ -0,0 +1,37 @@
+// Package blobstore keeps the contents of uploaded files. Files are stored
+// under a key chosen by the caller; metadata about them lives in Postgres.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store reads and writes blobs. Putting a key that exists replaces it.
+type Store interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStore builds the store the configuration names.
+func NewStore(cfg config.AttachmentConfig) (Store, error) {
+	switch cfg.Store {
+	case "", "local":
+		return NewLocalStore(cfg.LocalPath)
+	case "s3":
+		if cfg.S3.Bucket == "" {
+			return nil, fmt.Errorf("s3 attachment store needs a bucket")
+		}
+		return NewS3Store(cfg.S3, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown attachment store %q", cfg.Store)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -20,11 +21,267 @@ type DatabaseConfig struct {
 	Password string `yaml:"password"`
 }
 
+// LoginThrottleConfig controls how failed logins are slowed down and locked out.
+// Every failure doubles the wait before the next attempt, starting at BaseBackoff
+// and capped at MaxBackoff; MaxFailures failures within FailureWindow lock the
+// username or client IP for LockoutDuration.
+type LoginThrottleConfig struct {
+	MaxFailures     int           `yaml:"max_failures" env-default:"5"`
+	BaseBackoff     time.Duration `yaml:"base_backoff" env-default:"1s"`
+	MaxBackoff      time.Duration `yaml:"max_backoff" env-default:"5m"`
+	LockoutDuration time.Duration `yaml:"lockout_duration" env-default:"15m"`
+	FailureWindow   time.Duration `yaml:"failure_window" env-default:"1h"`
+}
+
+// MFAConfig controls TOTP two-factor authentication. Skew is the number of
+// 30 second periods either side of now in which a code is still accepted.
+type MFAConfig struct {
+	Issuer            string        `yaml:"issuer" env-default:"Patient Management System"`
+	Skew              int           `yaml:"skew" env-default:"1"`
+	ChallengeDuration time.Duration `yaml:"challenge_duration" env-default:"5m"`
+	RecoveryCodes     int           `yaml:"recovery_codes" env-default:"10"`
+}
+
+// PasswordConfig is the password policy. BreachedListPath points to a local
+// file of known-breached passwords, one per line, either in plain text or as
+// SHA-1 hex digests (optionally followed by ":count" as in the HIBP dumps).
+// HistoryDepth is how many of the most recent passwords may not be reused.
+type PasswordConfig struct {
+	MinLength        int           `yaml:"min_length" env-default:"12"`
+	MaxLength        int           `yaml:"max_length" env-default:"72"`
+	BreachedListPath string        `yaml:"breached_list_path"`
+	HistoryDepth     int           `yaml:"history_depth" env-default:"5"`
+	BcryptCost       int           `yaml:"bcrypt_cost" env-default:"12"`
+	ResetTokenTTL    time.Duration `yaml:"reset_token_ttl" env-default:"1h"`
+}
+
+// JWTKeyConfig describes one signing key. Algorithm is RS256 or EdDSA. Keys
+// other than the active one only verify tokens; once RetiredAt (RFC 3339) is
+// set they keep verifying for the grace period after it and are then dropped.
+type JWTKeyConfig struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+	PublicKeyPath  string `yaml:"public_key_path"`
+	RetiredAt      string `yaml:"retired_at"`
+}
+
+// JWTConfig configures token signing. Issuer and Audience are stamped on every
+// token and required on parse, so tokens from another deployment are rejected.
+type JWTConfig struct {
+	Issuer        string         `yaml:"issuer"`
+	Audience      string         `yaml:"audience"`
+	ActiveKeyID   string         `yaml:"active_key_id"`
+	Keys          []JWTKeyConfig `yaml:"keys"`
+	GracePeriod   time.Duration  `yaml:"grace_period" env-default:"24h"`
+	TokenDuration time.Duration  `yaml:"token_duration" env-default:"15m"`
+}
+
+type AuthConfig struct {
+	JWT           JWTConfig           `yaml:"jwt"`
+	LoginThrottle LoginThrottleConfig `yaml:"login_throttle"`
+	MFA           MFAConfig           `yaml:"mfa"`
+	Password      PasswordConfig      `yaml:"password"`
+}
+
+// QueueConfig tunes the waiting-room queue. Wait estimates use the doctor's
+// average consultation over HistoryWindow, or DefaultConsultation when there
+// is no history yet. Patients of ElderlyAge or older get elderly priority.
+type QueueConfig struct {
+	DefaultConsultation time.Duration `yaml:"default_consultation" env-default:"15m"`
+	HistoryWindow       time.Duration `yaml:"history_window" env-default:"720h"`
+	ElderlyAge          int           `yaml:"elderly_age" env-default:"65"`
+}
+
+// BillingConfig sets the currency invoices are issued in. Amounts are stored
+// in its minor unit. DefaultTaxRate, in basis points, applies to catalogue
+// items without a tax rate of their own.
+type BillingConfig struct {
+	Currency       string `yaml:"currency" env-default:"INR"`
+	DefaultTaxRate int    `yaml:"default_tax_rate" env-default:"0"`
+}
+
+// ClinicConfig is printed as the letterhead of generated documents.
+type ClinicConfig struct {
+	Name    string `yaml:"name" env-default:"Clinic"`
+	Address string `yaml:"address"`
+	Phone   string `yaml:"phone"`
+	Email   string `yaml:"email"`
+}
+
+// FHIRConfig sets how resources are exposed to other systems. BaseURL is the
+// public URL of the /fhir endpoints; IdentifierSystem namespaces the patient
+// and practitioner identifiers this system issues.
+type FHIRConfig struct {
+	BaseURL          string `yaml:"base_url" env-default:"http://localhost:8080/fhir"`
+	IdentifierSystem string `yaml:"identifier_system" env-default:"urn:patient-management-system:id"`
+}
+
+// HL7Config configures the MLLP listener for inbound HL7 v2 ADT messages. The
+// listener is disabled when ListenAddress is empty. Application and Facility
+// identify this system in acknowledgements. Patients are registered at
+// ClinicID, or the default clinic when it is empty.
+type HL7Config struct {
+	ListenAddress string        `yaml:"listen_address"`
+	ClinicID      string        `yaml:"clinic_id"`
+	IdleTimeout   time.Duration `yaml:"idle_timeout" env-default:"5m"`
+	Application   string        `yaml:"application" env-default:"PMS"`
+	Facility      string        `yaml:"facility"`
+}
+
+// PatientImportConfig tunes CSV patient imports. Valid rows are inserted
+// BatchSize at a time.
+type PatientImportConfig struct {
+	BatchSize int `yaml:"batch_size" env-default:"500"`
+}
+
+// PatientMergeConfig tunes the duplicate finder. Pairs scoring at least
+// DuplicateThreshold, between 0 and 1, are reported, best first and at most
+// MaxCandidates of them.
+type PatientMergeConfig struct {
+	DuplicateThreshold float64 `yaml:"duplicate_threshold" env-default:"0.75"`
+	MaxCandidates      int     `yaml:"max_candidates" env-default:"100"`
+}
+
+// TenancyConfig sizes the connection pool each clinic gets. Pools are opened
+// the first time a clinic is used, so the totals grow with active clinics.
+type TenancyConfig struct {
+	MaxOpenConnections int `yaml:"max_open_connections" env-default:"10"`
+	MaxIdleConnections int `yaml:"max_idle_connections" env-default:"2"`
+}
+
+// EncryptionConfig configures field-level encryption of sensitive patient
+// data. KeyFile holds the master keys, one "id:base64 key" per line; new data
+// keys are wrapped with ActiveKeyID. Blind indexes are keyed from the master
+// key BlindIndexKeyID, which must stay in the file for lookups to keep
+// working. Running instances pick up a rotated data key within
+// ActiveKeyRefresh. Rotation re-encrypts RotationBatchSize rows at a time,
+// pausing RotationPause between batches.
+type EncryptionConfig struct {
+	KeyFile           string        `yaml:"key_file"`
+	ActiveKeyID       string        `yaml:"active_key_id"`
+	BlindIndexKeyID   string        `yaml:"blind_index_key_id"`
+	ActiveKeyRefresh  time.Duration `yaml:"active_key_refresh" env-default:"1m"`
+	RotationBatchSize int           `yaml:"rotation_batch_size" env-default:"200"`
+	RotationPause     time.Duration `yaml:"rotation_pause" env-default:"100ms"`
+}
+
+// EmergencyAccessConfig limits break-the-glass access: it lasts Duration and
+// the doctor must give a reason of at least MinReasonLength characters.
+type EmergencyAccessConfig struct {
+	Duration        time.Duration `yaml:"duration" env-default:"1h"`
+	MinReasonLength int           `yaml:"min_reason_length" env-default:"20"`
+}
+
+// SMSConfig configures the SMS gateway. Messages are posted as JSON to URL
+// with APIKey as a bearer token.
+type SMSConfig struct {
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+	From   string `yaml:"from"`
+}
+
+// EmailConfig configures the SMTP server emails are sent through. STARTTLS is
+// used whenever the server offers it.
+type EmailConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// NotificationConfig configures how messages to patients are sent. A channel
+// without a configured provider cannot be used; with FilePath set every
+// channel is written to that file instead, for development and tests. The
+// outbox is polled every PollInterval for up to BatchSize due messages. A
+// failed send is retried after RetryBackoff, doubling up to MaxRetryBackoff,
+// until MaxAttempts have failed. Appointment reminders go out ReminderLead
+// before the appointment.
+type NotificationConfig struct {
+	SMS             SMSConfig     `yaml:"sms"`
+	Email           EmailConfig   `yaml:"email"`
+	FilePath        string        `yaml:"file_path"`
+	PollInterval    time.Duration `yaml:"poll_interval" env-default:"5s"`
+	BatchSize       int           `yaml:"batch_size" env-default:"20"`
+	SendTimeout     time.Duration `yaml:"send_timeout" env-default:"30s"`
+	MaxAttempts     int           `yaml:"max_attempts" env-default:"5"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff" env-default:"30s"`
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff" env-default:"1h"`
+	ReminderLead    time.Duration `yaml:"reminder_lead" env-default:"24h"`
+}
+
+// WebhookConfig configures delivery to webhook subscriptions. Due deliveries
+// are polled every PollInterval, up to BatchSize at a time, each request
+// given Timeout. A refused delivery is retried after RetryBackoff, doubling
+// up to MaxRetryBackoff, until MaxAttempts have failed.
+type WebhookConfig struct {
+	PollInterval    time.Duration `yaml:"poll_interval" env-default:"5s"`
+	BatchSize       int           `yaml:"batch_size" env-default:"20"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxAttempts     int           `yaml:"max_attempts" env-default:"8"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff" env-default:"30s"`
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff" env-default:"6h"`
+}
+
+// EventConfig configures delivery of domain events from the outbox to
+// in-process subscribers, one of which queues webhook deliveries. The outbox
+// is polled every PollInterval for up to BatchSize events, each given
+// DeliveryTimeout to reach every subscriber. A failed delivery is retried
+// after RetryBackoff, doubling up to MaxRetryBackoff, until MaxAttempts have
+// failed.
+type EventConfig struct {
+	Webhooks        WebhookConfig `yaml:"webhooks"`
+	PollInterval    time.Duration `yaml:"poll_interval" env-default:"2s"`
+	BatchSize       int           `yaml:"batch_size" env-default:"50"`
+	DeliveryTimeout time.Duration `yaml:"delivery_timeout" env-default:"10s"`
+	MaxAttempts     int           `yaml:"max_attempts" env-default:"10"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff" env-default:"10s"`
+	MaxRetryBackoff time.Duration `yaml:"max_retry_backoff" env-default:"1h"`
+}
+
+// S3Config points the attachment store at an S3-compatible bucket. Objects
+// are addressed path-style, as Endpoint/Bucket/key, which AWS and
+// self-hosted stores such as MinIO both accept.
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint" env-default:"https://s3.amazonaws.com"`
+	Region          string `yaml:"region" env-default:"us-east-1"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+// AttachmentConfig configures where uploaded documents and images are kept.
+// Store is "local", keeping files under LocalPath, or "s3". Uploads are
+// spooled to TempDir, the system default when empty, while they are hashed
+// and checked, and may be at most MaxSize bytes.
+type AttachmentConfig struct {
+	Store     string   `yaml:"store" env-default:"local"`
+	LocalPath string   `yaml:"local_path" env-default:"data/attachments"`
+	S3        S3Config `yaml:"s3"`
+	TempDir   string   `yaml:"temp_dir"`
+	MaxSize   int64    `yaml:"max_size" env-default:"26214400"`
+}
+
 type Config struct {
-	Env              string           `yaml:"env"`
-	Description      string           `yaml:"description"`
-	HTTPServerConfig HTTPServerConfig `yaml:"http_server"`
-	DatabaseConfig   DatabaseConfig   `yaml:"database"`
+	Env              string                `yaml:"env"`
+	Description      string                `yaml:"description"`
+	HTTPServerConfig HTTPServerConfig      `yaml:"http_server"`
+	DatabaseConfig   DatabaseConfig        `yaml:"database"`
+	AuthConfig       AuthConfig            `yaml:"auth"`
+	QueueConfig      QueueConfig           `yaml:"queue"`
+	BillingConfig    BillingConfig         `yaml:"billing"`
+	ClinicConfig     ClinicConfig          `yaml:"clinic"`
+	FHIRConfig       FHIRConfig            `yaml:"fhir"`
+	HL7Config        HL7Config             `yaml:"hl7"`
+	PatientImport    PatientImportConfig   `yaml:"patient_import"`
+	PatientMerge     PatientMergeConfig    `yaml:"patient_merge"`
+	Tenancy          TenancyConfig         `yaml:"tenancy"`
+	Encryption       EncryptionConfig      `yaml:"encryption"`
+	EmergencyAccess  EmergencyAccessConfig `yaml:"emergency_access"`
+	Notifications    NotificationConfig    `yaml:"notifications"`
+	Events           EventConfig           `yaml:"events"`
+	Attachments      AttachmentConfig      `yaml:"attachments"`
 }
 
 func MustLoadConfig() *Config {
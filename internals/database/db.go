@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
 	_ "github.com/lib/pq" // PostgreSQL driver
 )
 
@@ -13,11 +14,13 @@ type DatabaseConnection struct {
 	Connection *sql.DB
 }
 
-func LoadPSqlDb(config *config.DatabaseConfig) (*DatabaseConnection, error) {
-	connStr := "host=" + config.Host + " port=" + strconv.Itoa(config.Port) +
+func connectionString(config *config.DatabaseConfig) string {
+	return "host=" + config.Host + " port=" + strconv.Itoa(config.Port) +
 		" dbname=" + config.DbName + " user=" + config.User + " password=" + config.Password + " sslmode=disable"
+}
 
-	db, err := sql.Open("postgres", connStr)
+func LoadPSqlDb(config *config.DatabaseConfig) (*DatabaseConnection, error) {
+	db, err := sql.Open("postgres", connectionString(config))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
@@ -42,7 +45,7 @@ func LoadPSqlDb(config *config.DatabaseConfig) (*DatabaseConnection, error) {
 	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS users (
 		id UUID PRIMARY KEY,
 		name TEXT NOT NULL,
-		role TEXT NOT NULL CHECK (role IN ('doctor', 'receptionist')),
+		role TEXT NOT NULL CHECK (role IN ('admin', 'doctor', 'receptionist')),
 		username TEXT UNIQUE NOT NULL,
 		password TEXT NOT NULL,
 		phone_number TEXT UNIQUE NOT NULL,
@@ -53,8 +56,15 @@ func LoadPSqlDb(config *config.DatabaseConfig) (*DatabaseConnection, error) {
 		return nil, fmt.Errorf("failed to create users table: %w", err)
 	}
 
-	// Create patient table
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS patient (
+	// Allow the admin role on databases created before it existed
+	_, err = db.Exec(`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+	ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'doctor', 'receptionist'));`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update users role constraint: %w", err)
+	}
+
+	// Create patients table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS patients (
 		id UUID PRIMARY KEY,
 		name TEXT NOT NULL,
 		age INT NOT NULL CHECK (age >= 0),
@@ -64,19 +74,865 @@ func LoadPSqlDb(config *config.DatabaseConfig) (*DatabaseConnection, error) {
 		updated_at TIMESTAMPTZ DEFAULT NOW()
 	);`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create patient table: %w", err)
+		return nil, fmt.Errorf("failed to create patients table: %w", err)
 	}
 
-	// Create diagnosis table
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS diagnosis (
+	// Create diagnoses table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS diagnoses (
 		id SERIAL PRIMARY KEY,
-		patient_id UUID NOT NULL REFERENCES patient(id) ON DELETE CASCADE,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
 		doctor_id UUID NOT NULL REFERENCES users(id) ON DELETE SET NULL,
 		description TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diagnoses table: %w", err)
+	}
+
+	// Create login attempts table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS login_attempts (
+		scope TEXT NOT NULL CHECK (scope IN ('username', 'ip')),
+		key TEXT NOT NULL,
+		failures INT NOT NULL DEFAULT 0,
+		last_failure_at TIMESTAMPTZ,
+		locked_until TIMESTAMPTZ,
+		PRIMARY KEY (scope, key)
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login attempts table: %w", err)
+	}
+
+	// Create audit log table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+		action TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		client_ip TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log table: %w", err)
+	}
+
+	// Add two-factor columns to users
+	_, err = db.Exec(`ALTER TABLE users
+		ADD COLUMN IF NOT EXISTS totp_secret TEXT,
+		ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+		ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add two-factor columns: %w", err)
+	}
+
+	// Create recovery codes table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS recovery_codes (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recovery codes table: %w", err)
+	}
+
+	// Create per-role two-factor policy table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS mfa_role_policy (
+		role TEXT PRIMARY KEY,
+		required BOOLEAN NOT NULL DEFAULT FALSE,
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mfa role policy table: %w", err)
+	}
+
+	// Create password history table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS password_history (
+		id BIGSERIAL PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password history table: %w", err)
+	}
+
+	// Create password reset tokens table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		token_hash TEXT PRIMARY KEY,
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_at TIMESTAMPTZ,
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password reset tokens table: %w", err)
+	}
+
+	// Create allergies table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS allergies (
+		id SERIAL PRIMARY KEY,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		substance TEXT NOT NULL,
+		reaction TEXT NOT NULL DEFAULT '',
+		severity TEXT NOT NULL CHECK (severity IN ('mild', 'moderate', 'severe', 'life_threatening')),
+		recorded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create allergies table: %w", err)
+	}
+
+	// Create chronic conditions table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS chronic_conditions (
+		id SERIAL PRIMARY KEY,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'inactive', 'resolved')),
+		onset_date DATE,
+		notes TEXT NOT NULL DEFAULT '',
+		recorded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chronic conditions table: %w", err)
+	}
+
+	// Create family history table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS family_history (
+		id SERIAL PRIMARY KEY,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		relation TEXT NOT NULL,
+		condition TEXT NOT NULL,
+		notes TEXT NOT NULL DEFAULT '',
+		recorded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create family history table: %w", err)
+	}
+
+	// Create encounters table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS encounters (
+		id UUID PRIMARY KEY,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		doctor_id UUID NOT NULL REFERENCES users(id),
+		chief_complaint TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'checked_in'
+			CHECK (status IN ('checked_in', 'in_consultation', 'discharged', 'cancelled')),
+		checked_in_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		consultation_started_at TIMESTAMPTZ,
+		discharged_at TIMESTAMPTZ,
+		discharge_notes TEXT NOT NULL DEFAULT '',
+		created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS encounters_doctor_status_idx ON encounters (doctor_id, status, checked_in_at);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encounters table: %w", err)
+	}
+
+	// Attach diagnoses to encounters
+	_, err = db.Exec(`ALTER TABLE diagnoses
+		ADD COLUMN IF NOT EXISTS encounter_id UUID REFERENCES encounters(id) ON DELETE SET NULL;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add encounter to diagnoses: %w", err)
+	}
+
+	// Create vitals table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS vitals (
+		id SERIAL PRIMARY KEY,
+		encounter_id UUID NOT NULL REFERENCES encounters(id) ON DELETE CASCADE,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		recorded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		temperature_c NUMERIC(4, 1),
+		pulse_bpm INT,
+		systolic_bp INT,
+		diastolic_bp INT,
+		respiratory_rate INT,
+		oxygen_saturation INT CHECK (oxygen_saturation BETWEEN 0 AND 100),
+		weight_kg NUMERIC(5, 2),
+		height_cm NUMERIC(5, 1),
+		recorded_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vitals table: %w", err)
+	}
+
+	// Create prescriptions table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS prescriptions (
+		id SERIAL PRIMARY KEY,
+		encounter_id UUID NOT NULL REFERENCES encounters(id) ON DELETE CASCADE,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		doctor_id UUID NOT NULL REFERENCES users(id),
+		medication TEXT NOT NULL,
+		dosage TEXT NOT NULL,
+		frequency TEXT NOT NULL,
+		duration_days INT NOT NULL DEFAULT 0 CHECK (duration_days >= 0),
+		instructions TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prescriptions table: %w", err)
+	}
+
+	// Create queue entries table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS queue_entries (
+		id BIGSERIAL PRIMARY KEY,
+		encounter_id UUID NOT NULL REFERENCES encounters(id) ON DELETE CASCADE,
+		doctor_id UUID NOT NULL REFERENCES users(id),
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		token_number INT NOT NULL,
+		priority TEXT NOT NULL DEFAULT 'normal' CHECK (priority IN ('emergency', 'elderly', 'normal')),
+		status TEXT NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'called', 'done', 'left')),
+		queue_date DATE NOT NULL DEFAULT CURRENT_DATE,
+		checked_in_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		called_at TIMESTAMPTZ,
+		finished_at TIMESTAMPTZ,
+		UNIQUE (doctor_id, queue_date, token_number)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS queue_entries_active_encounter_idx
+		ON queue_entries (encounter_id) WHERE status IN ('waiting', 'called');`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue entries table: %w", err)
+	}
+
+	// Create tax rates table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS tax_rates (
+		id SERIAL PRIMARY KEY,
+		name TEXT UNIQUE NOT NULL,
+		rate_bp INT NOT NULL CHECK (rate_bp >= 0)
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tax rates table: %w", err)
+	}
+
+	// Create service catalogue table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS catalogue_items (
+		id SERIAL PRIMARY KEY,
+		code TEXT UNIQUE NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL CHECK (kind IN ('service', 'medication')),
+		unit_price BIGINT NOT NULL CHECK (unit_price >= 0),
+		tax_rate_id INT REFERENCES tax_rates(id),
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalogue items table: %w", err)
+	}
+
+	// Link prescriptions to catalogue medications
+	_, err = db.Exec(`ALTER TABLE prescriptions
+		ADD COLUMN IF NOT EXISTS catalogue_item_id INT REFERENCES catalogue_items(id);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add catalogue item to prescriptions: %w", err)
+	}
+
+	// Create encounter charges table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS encounter_charges (
+		id SERIAL PRIMARY KEY,
+		encounter_id UUID NOT NULL REFERENCES encounters(id) ON DELETE CASCADE,
+		catalogue_item_id INT NOT NULL REFERENCES catalogue_items(id),
+		quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+		added_by UUID REFERENCES users(id) ON DELETE SET NULL,
 		created_at TIMESTAMPTZ DEFAULT NOW()
 	);`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create diagnosis table: %w", err)
+		return nil, fmt.Errorf("failed to create encounter charges table: %w", err)
+	}
+
+	// Create invoices, invoice lines, payments and refunds tables
+	_, err = db.Exec(`CREATE SEQUENCE IF NOT EXISTS invoice_number_seq;
+	CREATE TABLE IF NOT EXISTS invoices (
+		id UUID PRIMARY KEY,
+		invoice_number TEXT UNIQUE NOT NULL,
+		patient_id UUID NOT NULL REFERENCES patients(id),
+		encounter_id UUID UNIQUE REFERENCES encounters(id),
+		status TEXT NOT NULL CHECK (status IN ('issued', 'partially_paid', 'paid')),
+		currency TEXT NOT NULL,
+		subtotal BIGINT NOT NULL,
+		tax_total BIGINT NOT NULL,
+		total BIGINT NOT NULL,
+		amount_paid BIGINT NOT NULL DEFAULT 0,
+		issued_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS invoice_lines (
+		id SERIAL PRIMARY KEY,
+		invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+		catalogue_item_id INT REFERENCES catalogue_items(id),
+		description TEXT NOT NULL,
+		quantity INT NOT NULL CHECK (quantity > 0),
+		unit_price BIGINT NOT NULL,
+		tax_rate_bp INT NOT NULL,
+		tax_amount BIGINT NOT NULL,
+		line_total BIGINT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS payments (
+		id SERIAL PRIMARY KEY,
+		invoice_id UUID NOT NULL REFERENCES invoices(id),
+		amount BIGINT NOT NULL CHECK (amount > 0),
+		refunded BIGINT NOT NULL DEFAULT 0,
+		method TEXT NOT NULL,
+		reference TEXT NOT NULL DEFAULT '',
+		received_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CHECK (refunded >= 0 AND refunded <= amount)
+	);
+	CREATE TABLE IF NOT EXISTS refunds (
+		id SERIAL PRIMARY KEY,
+		payment_id INT NOT NULL REFERENCES payments(id),
+		invoice_id UUID NOT NULL REFERENCES invoices(id),
+		amount BIGINT NOT NULL CHECK (amount > 0),
+		reason TEXT NOT NULL DEFAULT '',
+		refunded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create billing tables: %w", err)
+	}
+
+	// Create patient identifiers table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS patient_identifiers (
+		system TEXT NOT NULL,
+		value TEXT NOT NULL,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (system, value)
+	);
+	CREATE INDEX IF NOT EXISTS patient_identifiers_patient_idx ON patient_identifiers (patient_id);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patient identifiers table: %w", err)
+	}
+
+	// Create HL7 dead letter table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS hl7_dead_letters (
+		id BIGSERIAL PRIMARY KEY,
+		remote_addr TEXT NOT NULL DEFAULT '',
+		message TEXT NOT NULL,
+		error TEXT NOT NULL,
+		received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		resolved_at TIMESTAMPTZ
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hl7 dead letters table: %w", err)
+	}
+
+	// Create patient merge tables. survivor_id is deliberately not a foreign
+	// key so the history survives the survivor itself being merged later.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS patient_merges (
+		id BIGSERIAL PRIMARY KEY,
+		survivor_id UUID NOT NULL,
+		merged_patient_id UUID NOT NULL,
+		merged_name TEXT NOT NULL,
+		merged_age INT NOT NULL,
+		merged_gender gender_type NOT NULL,
+		merged_phone_number TEXT NOT NULL,
+		merged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		undone_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		undone_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS patient_merges_survivor_idx ON patient_merges (survivor_id);
+	CREATE INDEX IF NOT EXISTS patient_merges_merged_idx ON patient_merges (merged_patient_id);
+	CREATE TABLE IF NOT EXISTS patient_merge_rows (
+		merge_id BIGINT NOT NULL REFERENCES patient_merges(id) ON DELETE CASCADE,
+		table_name TEXT NOT NULL,
+		row_key TEXT NOT NULL,
+		PRIMARY KEY (merge_id, table_name, row_key)
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patient merge tables: %w", err)
+	}
+
+	// Create patient contacts table. Phone numbers are no longer unique per
+	// patient since families often share one; contacts are how a patient is
+	// looked up instead.
+	_, err = db.Exec(`ALTER TABLE patients DROP CONSTRAINT IF EXISTS patients_phone_number_key;
+	CREATE TABLE IF NOT EXISTS patient_contacts (
+		id SERIAL PRIMARY KEY,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		name TEXT NOT NULL DEFAULT '',
+		relation TEXT NOT NULL CHECK (relation IN ('self', 'parent', 'guardian', 'spouse', 'child', 'sibling', 'caregiver', 'other')),
+		phone_number TEXT NOT NULL DEFAULT '',
+		email TEXT NOT NULL DEFAULT '',
+		address TEXT NOT NULL DEFAULT '',
+		is_emergency BOOLEAN NOT NULL DEFAULT FALSE,
+		is_guardian BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS patient_contacts_patient_idx ON patient_contacts (patient_id);
+	CREATE INDEX IF NOT EXISTS patient_contacts_phone_idx ON patient_contacts (phone_number) WHERE phone_number <> '';
+	CREATE INDEX IF NOT EXISTS patient_contacts_email_idx ON patient_contacts (LOWER(email)) WHERE email <> '';`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patient contacts table: %w", err)
+	}
+
+	// Create clinics and scope patients, diagnoses and users to them. Records
+	// that predate clinics move to the default clinic, once. Row-level
+	// security keeps each clinic's connections to their own rows; it is
+	// forced so it also applies to the table owner, but superusers bypass it,
+	// so the application must not connect as one.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS clinics (
+		id UUID PRIMARY KEY,
+		name TEXT NOT NULL,
+		code TEXT UNIQUE NOT NULL,
+		address TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	INSERT INTO clinics (id, name, code) VALUES ('` + model.DefaultClinicID.String() + `', 'Main clinic', 'main')
+		ON CONFLICT DO NOTHING;
+	CREATE TABLE IF NOT EXISTS user_clinics (
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		clinic_id UUID NOT NULL REFERENCES clinics(id) ON DELETE CASCADE,
+		is_default BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (user_id, clinic_id)
+	);
+	CREATE INDEX IF NOT EXISTS user_clinics_clinic_idx ON user_clinics (clinic_id);
+
+	CREATE OR REPLACE FUNCTION current_clinic_id() RETURNS UUID LANGUAGE sql STABLE AS
+		$fn$ SELECT NULLIF(current_setting('app.clinic_id', true), '')::uuid $fn$;
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+		               WHERE table_name = 'patients' AND column_name = 'clinic_id') THEN
+			INSERT INTO user_clinics (user_id, clinic_id, is_default)
+				SELECT id, '` + model.DefaultClinicID.String() + `', TRUE FROM users;
+			ALTER TABLE patients ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE patients SET clinic_id = '` + model.DefaultClinicID.String() + `';
+			ALTER TABLE diagnoses ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE diagnoses SET clinic_id = '` + model.DefaultClinicID.String() + `';
+		END IF;
+	END
+	$$;
+	ALTER TABLE patients ALTER COLUMN clinic_id SET NOT NULL, ALTER COLUMN clinic_id SET DEFAULT current_clinic_id();
+	ALTER TABLE diagnoses ALTER COLUMN clinic_id SET NOT NULL, ALTER COLUMN clinic_id SET DEFAULT current_clinic_id();
+	CREATE INDEX IF NOT EXISTS patients_clinic_idx ON patients (clinic_id);
+	CREATE INDEX IF NOT EXISTS diagnoses_clinic_idx ON diagnoses (clinic_id);
+
+	ALTER TABLE patients ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE patients FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS clinic_isolation ON patients;
+	CREATE POLICY clinic_isolation ON patients
+		USING (clinic_id = current_clinic_id()) WITH CHECK (clinic_id = current_clinic_id());
+
+	ALTER TABLE diagnoses ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE diagnoses FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS clinic_isolation ON diagnoses;
+	CREATE POLICY clinic_isolation ON diagnoses
+		USING (clinic_id = current_clinic_id()) WITH CHECK (clinic_id = current_clinic_id());
+
+	-- Users log in before a clinic is chosen, so connections without a clinic
+	-- see every user; clinic connections only see the clinic's staff.
+	ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE users FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS clinic_members ON users;
+	CREATE POLICY clinic_members ON users
+		USING (current_clinic_id() IS NULL OR EXISTS (
+			SELECT 1 FROM user_clinics uc WHERE uc.user_id = users.id AND uc.clinic_id = current_clinic_id()))
+		WITH CHECK (true);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clinic tables: %w", err)
+	}
+
+	// Create data keys and the columns of encrypted values. Rows written before
+	// encryption keep a NULL data_key_id and stay plaintext until the key
+	// rotation command re-encrypts them. The phone number index only serves
+	// those plaintext rows now; encrypted ones are found by their blind index.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS data_keys (
+		id SERIAL PRIMARY KEY,
+		master_key_id TEXT NOT NULL,
+		wrapped_key BYTEA NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS data_keys_active_idx ON data_keys (active) WHERE active;
+	ALTER TABLE patients ADD COLUMN IF NOT EXISTS data_key_id INT REFERENCES data_keys(id),
+		ADD COLUMN IF NOT EXISTS phone_number_index TEXT;
+	ALTER TABLE diagnoses ADD COLUMN IF NOT EXISTS data_key_id INT REFERENCES data_keys(id);
+	ALTER TABLE patient_merges ADD COLUMN IF NOT EXISTS merged_data_key_id INT REFERENCES data_keys(id);
+	DROP INDEX IF EXISTS patients_phone_number_idx;
+	CREATE INDEX IF NOT EXISTS patients_plaintext_phone_number_idx ON patients (phone_number) WHERE data_key_id IS NULL;
+	CREATE INDEX IF NOT EXISTS patients_phone_number_index_idx ON patients (phone_number_index);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data keys table: %w", err)
+	}
+
+	// Create consent tables. Each clinic publishes its own consent texts. A
+	// patient may hold several grants for a scope over time; the latest one
+	// not withdrawn is the patient's current consent.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS consent_texts (
+		id SERIAL PRIMARY KEY,
+		clinic_id UUID NOT NULL DEFAULT current_clinic_id() REFERENCES clinics(id),
+		scope TEXT NOT NULL CHECK (scope IN ('treatment', 'data_sharing', 'sms_reminders')),
+		version INT NOT NULL,
+		text TEXT NOT NULL,
+		created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (clinic_id, scope, version)
+	);
+
+	CREATE TABLE IF NOT EXISTS patient_consents (
+		id BIGSERIAL PRIMARY KEY,
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		consent_text_id INT NOT NULL REFERENCES consent_texts(id),
+		granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		withdrawn_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		withdrawn_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS patient_consents_patient_idx ON patient_consents (patient_id, granted_at);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consent tables: %w", err)
+	}
+
+	// Create emergency access tables. A grant is never deleted, so the review
+	// queue and the log of what was looked at outlive its expiry.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS emergency_accesses (
+		id BIGSERIAL PRIMARY KEY,
+		clinic_id UUID NOT NULL DEFAULT current_clinic_id() REFERENCES clinics(id),
+		doctor_id UUID NOT NULL REFERENCES users(id),
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		reason TEXT NOT NULL,
+		granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		reviewed_at TIMESTAMPTZ,
+		review_note TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS emergency_accesses_doctor_patient_idx ON emergency_accesses (doctor_id, patient_id, expires_at);
+	CREATE INDEX IF NOT EXISTS emergency_accesses_pending_idx ON emergency_accesses (clinic_id, granted_at) WHERE reviewed_at IS NULL;
+
+	CREATE TABLE IF NOT EXISTS emergency_access_log (
+		id BIGSERIAL PRIMARY KEY,
+		access_id BIGINT NOT NULL REFERENCES emergency_accesses(id) ON DELETE CASCADE,
+		action TEXT NOT NULL,
+		accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS emergency_access_log_access_idx ON emergency_access_log (access_id, accessed_at);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create emergency access tables: %w", err)
+	}
+
+	// Create the notification outbox. Messages are sent by a background
+	// dispatcher on the shared pool, so the table's row-level security lets
+	// the shared pool see every clinic's messages; the recipient and message
+	// are encrypted like other patient data.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS notifications (
+		id BIGSERIAL PRIMARY KEY,
+		clinic_id UUID NOT NULL DEFAULT current_clinic_id() REFERENCES clinics(id),
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		channel TEXT NOT NULL CHECK (channel IN ('sms', 'email')),
+		template TEXT NOT NULL,
+		recipient TEXT NOT NULL,
+		subject TEXT NOT NULL DEFAULT '',
+		body TEXT NOT NULL,
+		data_key_id INT REFERENCES data_keys(id),
+		status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		sent_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS notifications_due_idx ON notifications (next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS notifications_patient_idx ON notifications (patient_id, created_at);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifications table: %w", err)
+	}
+
+	// Add deactivation columns to users
+	_, err = db.Exec(`ALTER TABLE users
+		ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS deactivated_by UUID REFERENCES users(id);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add deactivation columns: %w", err)
+	}
+
+	// Create the domain event outbox. Events are written in the transaction
+	// of the change they describe, belong to the clinic of the connection
+	// that wrote them, and are delivered by a background dispatcher on the
+	// shared pool. Payloads hold ids only, so nothing in the table needs
+	// encrypting.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS domain_events (
+		id BIGSERIAL PRIMARY KEY,
+		clinic_id UUID DEFAULT current_clinic_id() REFERENCES clinics(id),
+		type TEXT NOT NULL,
+		aggregate_id TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_error TEXT NOT NULL DEFAULT '',
+		delivered_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS domain_events_due_idx ON domain_events (next_attempt_at) WHERE status = 'pending';`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create domain events table: %w", err)
+	}
+
+	// Create webhook tables. Subscriptions belong to a clinic and receive its
+	// events only; the secret signing their requests is encrypted. Each event
+	// gets one delivery per matching subscription, retried on its own, and
+	// every attempt is logged.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id BIGSERIAL PRIMARY KEY,
+		clinic_id UUID NOT NULL DEFAULT current_clinic_id() REFERENCES clinics(id),
+		url TEXT NOT NULL,
+		event_types TEXT[] NOT NULL,
+		secret TEXT NOT NULL,
+		data_key_id INT REFERENCES data_keys(id),
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS webhook_subscriptions_clinic_idx ON webhook_subscriptions (clinic_id) WHERE active;
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		event_id BIGINT NOT NULL REFERENCES domain_events(id) ON DELETE CASCADE,
+		status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_error TEXT NOT NULL DEFAULT '',
+		last_response_status INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		delivered_at TIMESTAMPTZ,
+		UNIQUE (subscription_id, event_id)
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS webhook_attempts (
+		id BIGSERIAL PRIMARY KEY,
+		delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+		attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		response_status INT NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		duration_ms INT NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_idx ON webhook_attempts (delivery_id, attempted_at);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook tables: %w", err)
+	}
+
+	// Create lab tables. The test catalogue is shared by every clinic like the
+	// service catalogue; orders belong to a clinic. A range with no gender
+	// applies to every gender. Result values and comments are encrypted.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS lab_tests (
+		id SERIAL PRIMARY KEY,
+		code TEXT UNIQUE NOT NULL,
+		name TEXT NOT NULL,
+		unit TEXT NOT NULL DEFAULT '',
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMPTZ DEFAULT NOW(),
+		updated_at TIMESTAMPTZ DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS lab_reference_ranges (
+		id SERIAL PRIMARY KEY,
+		test_id INT NOT NULL REFERENCES lab_tests(id) ON DELETE CASCADE,
+		gender gender_type,
+		min_age INT NOT NULL DEFAULT 0 CHECK (min_age >= 0),
+		max_age INT CHECK (max_age >= min_age),
+		low DOUBLE PRECISION,
+		high DOUBLE PRECISION,
+		CHECK (low IS NOT NULL OR high IS NOT NULL),
+		CHECK (low <= high)
+	);
+	CREATE INDEX IF NOT EXISTS lab_reference_ranges_test_idx ON lab_reference_ranges (test_id);
+
+	CREATE TABLE IF NOT EXISTS lab_orders (
+		id BIGSERIAL PRIMARY KEY,
+		clinic_id UUID NOT NULL DEFAULT current_clinic_id() REFERENCES clinics(id),
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		diagnosis_id INT REFERENCES diagnoses(id) ON DELETE SET NULL,
+		test_id INT NOT NULL REFERENCES lab_tests(id),
+		ordered_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		notes TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'ordered' CHECK (status IN ('ordered', 'resulted', 'cancelled')),
+		ordered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		cancelled_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS lab_orders_pending_idx ON lab_orders (clinic_id, ordered_at) WHERE status = 'ordered';
+	CREATE INDEX IF NOT EXISTS lab_orders_patient_idx ON lab_orders (patient_id, ordered_at);
+
+	CREATE TABLE IF NOT EXISTS lab_results (
+		order_id BIGINT PRIMARY KEY REFERENCES lab_orders(id) ON DELETE CASCADE,
+		value TEXT NOT NULL,
+		unit TEXT NOT NULL DEFAULT '',
+		reference_low DOUBLE PRECISION,
+		reference_high DOUBLE PRECISION,
+		flag TEXT NOT NULL CHECK (flag IN ('normal', 'low', 'high', 'no_range')),
+		comment TEXT NOT NULL DEFAULT '',
+		data_key_id INT REFERENCES data_keys(id),
+		recorded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lab tables: %w", err)
+	}
+
+	// Create attachments table. Contents live in the blob store, keyed by
+	// their SHA-256 so identical files are stored once. Filenames and
+	// descriptions are encrypted. Deleted attachments are kept.
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS attachments (
+		id BIGSERIAL PRIMARY KEY,
+		clinic_id UUID NOT NULL DEFAULT current_clinic_id() REFERENCES clinics(id),
+		patient_id UUID NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+		diagnosis_id INT REFERENCES diagnoses(id) ON DELETE SET NULL,
+		filename TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		mime_type TEXT NOT NULL,
+		size BIGINT NOT NULL CHECK (size > 0),
+		sha256 TEXT NOT NULL,
+		storage_key TEXT NOT NULL,
+		data_key_id INT REFERENCES data_keys(id),
+		uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		uploaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		deleted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+		deleted_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS attachments_patient_idx ON attachments (patient_id, uploaded_at);
+	CREATE INDEX IF NOT EXISTS attachments_sha256_idx ON attachments (patient_id, sha256) WHERE deleted_at IS NULL;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachments table: %w", err)
+	}
+
+	// Scope every other patient-owned table to a clinic like patients, once
+	// filling in existing rows from the patient or record they belong to.
+	// Row-level security on patients is lifted for the table owner while
+	// they are read. Queue tokens and patient identifiers become unique per
+	// clinic, since each clinic only sees its own. The notification
+	// dispatcher checks consent on the shared pool, so the consent tables and
+	// the outbox let a connection without a clinic see every clinic's rows;
+	// only logins, which read none of them, are served there.
+	_, err = db.Exec(`DO $$
+	DECLARE
+		t TEXT;
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+		               WHERE table_name = 'allergies' AND column_name = 'clinic_id') THEN
+			ALTER TABLE patients NO FORCE ROW LEVEL SECURITY;
+			FOREACH t IN ARRAY ARRAY['allergies', 'chronic_conditions', 'family_history', 'encounters', 'vitals',
+				'prescriptions', 'queue_entries', 'invoices', 'patient_identifiers', 'patient_contacts',
+				'patient_consents'] LOOP
+				EXECUTE format('ALTER TABLE %I ADD COLUMN clinic_id UUID REFERENCES clinics(id)', t);
+				EXECUTE format('UPDATE %I t SET clinic_id = p.clinic_id FROM patients p WHERE p.id = t.patient_id', t);
+			END LOOP;
+
+			ALTER TABLE encounter_charges ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE encounter_charges c SET clinic_id = e.clinic_id FROM encounters e WHERE e.id = c.encounter_id;
+			FOREACH t IN ARRAY ARRAY['invoice_lines', 'payments', 'refunds'] LOOP
+				EXECUTE format('ALTER TABLE %I ADD COLUMN clinic_id UUID REFERENCES clinics(id)', t);
+				EXECUTE format('UPDATE %I t SET clinic_id = i.clinic_id FROM invoices i WHERE i.id = t.invoice_id', t);
+			END LOOP;
+			ALTER TABLE emergency_access_log ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE emergency_access_log l SET clinic_id = a.clinic_id FROM emergency_accesses a WHERE a.id = l.access_id;
+			ALTER TABLE lab_results ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE lab_results r SET clinic_id = o.clinic_id FROM lab_orders o WHERE o.id = r.order_id;
+
+			-- The survivor may itself have been merged away since.
+			ALTER TABLE patient_merges ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE patient_merges m SET clinic_id = COALESCE(
+				(SELECT p.clinic_id FROM patients p WHERE p.id = m.survivor_id), '` + model.DefaultClinicID.String() + `');
+			ALTER TABLE patient_merge_rows ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE patient_merge_rows r SET clinic_id = m.clinic_id FROM patient_merges m WHERE m.id = r.merge_id;
+			ALTER TABLE patients FORCE ROW LEVEL SECURITY;
+
+			ALTER TABLE queue_entries DROP CONSTRAINT IF EXISTS queue_entries_doctor_id_queue_date_token_number_key;
+			ALTER TABLE queue_entries ADD UNIQUE (clinic_id, doctor_id, queue_date, token_number);
+			ALTER TABLE patient_identifiers DROP CONSTRAINT IF EXISTS patient_identifiers_pkey;
+			ALTER TABLE patient_identifiers ADD PRIMARY KEY (clinic_id, system, value);
+		END IF;
+
+		FOREACH t IN ARRAY ARRAY['allergies', 'chronic_conditions', 'family_history', 'encounters', 'vitals',
+			'prescriptions', 'queue_entries', 'encounter_charges', 'invoices', 'invoice_lines', 'payments', 'refunds',
+			'patient_identifiers', 'patient_merges', 'patient_merge_rows', 'patient_contacts', 'patient_consents',
+			'emergency_accesses', 'emergency_access_log', 'lab_orders', 'lab_results', 'attachments',
+			'consent_texts', 'notifications'] LOOP
+			EXECUTE format('ALTER TABLE %I ALTER COLUMN clinic_id SET NOT NULL, ALTER COLUMN clinic_id SET DEFAULT current_clinic_id()', t);
+			EXECUTE format('CREATE INDEX IF NOT EXISTS %I ON %I (clinic_id)', t || '_clinic_idx', t);
+			EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+			EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+			EXECUTE format('DROP POLICY IF EXISTS clinic_isolation ON %I', t);
+			IF t IN ('consent_texts', 'patient_consents', 'notifications') THEN
+				EXECUTE format('CREATE POLICY clinic_isolation ON %I 
+					USING (current_clinic_id() IS NULL OR clinic_id = current_clinic_id())
+					WITH CHECK (current_clinic_id() IS NULL OR clinic_id = current_clinic_id())', t);
+			ELSE
+				EXECUTE format('CREATE POLICY clinic_isolation ON %I
+					USING (clinic_id = current_clinic_id()) WITH CHECK (clinic_id = current_clinic_id())', t);
+			END IF;
+		END LOOP;
+	END
+	$$;`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scope patient records to clinics: %w", err)
+	}
+
+	// Encrypt patient contacts like patients. Contacts written before keep a
+	// NULL data_key_id and stay plaintext, and findable by the old indexes,
+	// until the key rotation command re-encrypts them; encrypted ones are
+	// found by their blind indexes.
+	_, err = db.Exec(`ALTER TABLE patient_contacts ADD COLUMN IF NOT EXISTS data_key_id INT REFERENCES data_keys(id),
+		ADD COLUMN IF NOT EXISTS phone_number_index TEXT,
+		ADD COLUMN IF NOT EXISTS email_index TEXT;
+	DROP INDEX IF EXISTS patient_contacts_phone_idx;
+	DROP INDEX IF EXISTS patient_contacts_email_idx;
+	CREATE INDEX IF NOT EXISTS patient_contacts_plaintext_phone_idx ON patient_contacts (phone_number)
+		WHERE data_key_id IS NULL AND phone_number <> '';
+	CREATE INDEX IF NOT EXISTS patient_contacts_plaintext_email_idx ON patient_contacts (LOWER(email))
+		WHERE data_key_id IS NULL AND email <> '';
+	CREATE INDEX IF NOT EXISTS patient_contacts_phone_number_index_idx ON patient_contacts (phone_number_index);
+	CREATE INDEX IF NOT EXISTS patient_contacts_email_index_idx ON patient_contacts (email_index);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt patient contacts: %w", err)
+	}
+
+	// Scope HL7 dead letters to the clinic that ingested them; they hold raw
+	// PID segments. Dead letters from before were all ingested by the HL7
+	// clinic, which is the default clinic unless configured otherwise.
+	_, err = db.Exec(`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+		               WHERE table_name = 'hl7_dead_letters' AND column_name = 'clinic_id') THEN
+			ALTER TABLE hl7_dead_letters ADD COLUMN clinic_id UUID REFERENCES clinics(id);
+			UPDATE hl7_dead_letters SET clinic_id = '` + model.DefaultClinicID.String() + `';
+		END IF;
+	END
+	$$;
+	ALTER TABLE hl7_dead_letters ALTER COLUMN clinic_id SET NOT NULL,
+		ALTER COLUMN clinic_id SET DEFAULT current_clinic_id();
+	CREATE INDEX IF NOT EXISTS hl7_dead_letters_clinic_idx ON hl7_dead_letters (clinic_id);
+	ALTER TABLE hl7_dead_letters ENABLE ROW LEVEL SECURITY;
+	ALTER TABLE hl7_dead_letters FORCE ROW LEVEL SECURITY;
+	DROP POLICY IF EXISTS clinic_isolation ON hl7_dead_letters;
+	CREATE POLICY clinic_isolation ON hl7_dead_letters
+		USING (clinic_id = current_clinic_id()) WITH CHECK (clinic_id = current_clinic_id());`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scope hl7 dead letters to clinics: %w", err)
+	}
+
+	// Encrypt HL7 dead letter messages. Messages stored before stay plaintext
+	// until the key rotation command re-encrypts them.
+	_, err = db.Exec(`ALTER TABLE hl7_dead_letters ADD COLUMN IF NOT EXISTS data_key_id INT REFERENCES data_keys(id);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt hl7 dead letters: %w", err)
 	}
 
 	return &DatabaseConnection{Connection: db}, nil
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/google/uuid"
+)
+
+// TenantPools hands out one connection pool per clinic. Every connection in a
+// clinic's pool has app.clinic_id set when it is opened, which is what the
+// row-level security policies and current_clinic_id() read, so a pool can
+// never see another clinic's rows whatever query runs on it.
+type TenantPools struct {
+	config  *config.DatabaseConfig
+	tenancy config.TenancyConfig
+
+	mu    sync.Mutex
+	pools map[uuid.UUID]*sql.DB
+}
+
+func NewTenantPools(config *config.DatabaseConfig, tenancy config.TenancyConfig) *TenantPools {
+	return &TenantPools{
+		config:  config,
+		tenancy: tenancy,
+		pools:   make(map[uuid.UUID]*sql.DB),
+	}
+}
+
+// Pool returns the pool for clinicID, opening it on first use.
+func (t *TenantPools) Pool(clinicID uuid.UUID) (*sql.DB, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if db, ok := t.pools[clinicID]; ok {
+		return db, nil
+	}
+
+	// lib/pq sends settings it does not know itself to the server as run-time
+	// parameters of the session.
+	db, err := sql.Open("postgres", connectionString(t.config)+" app.clinic_id="+clinicID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pool for clinic %s: %w", clinicID, err)
+	}
+	db.SetMaxOpenConns(t.tenancy.MaxOpenConnections)
+	db.SetMaxIdleConns(t.tenancy.MaxIdleConnections)
+	t.pools[clinicID] = db
+	return db, nil
+}
+
+// Close closes every pool opened so far.
+func (t *TenantPools) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var firstErr error
+	for clinicID, db := range t.pools {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(t.pools, clinicID)
+	}
+	return firstErr
+}
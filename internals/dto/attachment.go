@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+// AttachmentUpload describes a file being uploaded. Its content is streamed
+// separately.
+type AttachmentUpload struct {
+	Filename    string
+	DiagnosisID *int
+	Description string
+}
+
+// AttachmentResponse describes an attachment. Duplicate is set when an
+// upload matched a file the patient already had, which is returned instead.
+type AttachmentResponse struct {
+	ID          int64      `json:"id"`
+	PatientID   uuid.UUID  `json:"patient_id"`
+	DiagnosisID *int       `json:"diagnosis_id,omitempty"`
+	Filename    string     `json:"filename"`
+	Description string     `json:"description,omitempty"`
+	MimeType    string     `json:"mime_type"`
+	Size        int64      `json:"size"`
+	SHA256      string     `json:"sha256"`
+	UploadedBy  uuid.UUID  `json:"uploaded_by"`
+	UploadedAt  time.Time  `json:"uploaded_at"`
+	DeletedBy   *uuid.UUID `json:"deleted_by,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Duplicate   bool       `json:"duplicate,omitempty"`
+}
+
+func ToAttachmentResponse(attachment model.Attachment) AttachmentResponse {
+	var deletedBy *uuid.UUID
+	if attachment.DeletedBy.Valid {
+		deletedBy = &attachment.DeletedBy.UUID
+	}
+	return AttachmentResponse{
+		ID:          attachment.ID,
+		PatientID:   attachment.PatientID,
+		DiagnosisID: attachment.DiagnosisID,
+		Filename:    attachment.Filename,
+		Description: attachment.Description,
+		MimeType:    attachment.MimeType,
+		Size:        attachment.Size,
+		SHA256:      attachment.SHA256,
+		UploadedBy:  attachment.UploadedBy,
+		UploadedAt:  attachment.UploadedAt,
+		DeletedBy:   deletedBy,
+		DeletedAt:   attachment.DeletedAt,
+	}
+}
+
+func ToAttachmentResponses(attachments []model.Attachment) []AttachmentResponse {
+	responses := make([]AttachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		responses = append(responses, ToAttachmentResponse(attachment))
+	}
+	return responses
+}
@@ -0,0 +1,260 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+// All amounts are integers in the minor unit of the invoice currency, and
+// rates are in basis points (1800 is 18%).
+
+type TaxRateRequest struct {
+	Name string `json:"name"`
+	Rate int    `json:"rate_bp"`
+}
+
+type TaxRateResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Rate int    `json:"rate_bp"`
+}
+
+type CatalogueItemRequest struct {
+	Code      string      `json:"code"`
+	Name      string      `json:"name"`
+	Kind      string      `json:"kind"`
+	UnitPrice model.Money `json:"unit_price"`
+	TaxRateID *int        `json:"tax_rate_id,omitempty"`
+	Active    *bool       `json:"active,omitempty"`
+}
+
+type CatalogueItemResponse struct {
+	ID        int         `json:"id"`
+	Code      string      `json:"code"`
+	Name      string      `json:"name"`
+	Kind      string      `json:"kind"`
+	UnitPrice model.Money `json:"unit_price"`
+	TaxRateID *int        `json:"tax_rate_id,omitempty"`
+	Active    bool        `json:"active"`
+}
+
+// EncounterChargeRequest bills a catalogue service rendered during an encounter.
+type EncounterChargeRequest struct {
+	CatalogueItemID int `json:"catalogue_item_id"`
+	Quantity        int `json:"quantity"`
+}
+
+type EncounterChargeResponse struct {
+	ID              int       `json:"id"`
+	EncounterID     uuid.UUID `json:"encounter_id"`
+	CatalogueItemID int       `json:"catalogue_item_id"`
+	Quantity        int       `json:"quantity"`
+	AddedBy         uuid.UUID `json:"added_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type InvoiceLineResponse struct {
+	CatalogueItemID *int        `json:"catalogue_item_id,omitempty"`
+	Description     string      `json:"description"`
+	Quantity        int         `json:"quantity"`
+	UnitPrice       model.Money `json:"unit_price"`
+	TaxRate         int         `json:"tax_rate_bp"`
+	TaxAmount       model.Money `json:"tax_amount"`
+	LineTotal       model.Money `json:"line_total"`
+}
+
+type InvoiceResponse struct {
+	ID            uuid.UUID             `json:"id"`
+	InvoiceNumber string                `json:"invoice_number"`
+	PatientID     uuid.UUID             `json:"patient_id"`
+	EncounterID   *uuid.UUID            `json:"encounter_id,omitempty"`
+	Status        string                `json:"status"`
+	Currency      string                `json:"currency"`
+	Subtotal      model.Money           `json:"subtotal"`
+	TaxTotal      model.Money           `json:"tax_total"`
+	Total         model.Money           `json:"total"`
+	AmountPaid    model.Money           `json:"amount_paid"`
+	Outstanding   model.Money           `json:"outstanding"`
+	IssuedBy      uuid.UUID             `json:"issued_by"`
+	IssuedAt      time.Time             `json:"issued_at"`
+	Lines         []InvoiceLineResponse `json:"lines,omitempty"`
+	Payments      []PaymentResponse     `json:"payments,omitempty"`
+	Refunds       []RefundResponse      `json:"refunds,omitempty"`
+}
+
+type PaymentRequest struct {
+	Amount    model.Money `json:"amount"`
+	Method    string      `json:"method"`
+	Reference string      `json:"reference"`
+}
+
+type PaymentResponse struct {
+	ID         int         `json:"id"`
+	InvoiceID  uuid.UUID   `json:"invoice_id"`
+	Amount     model.Money `json:"amount"`
+	Refunded   model.Money `json:"refunded"`
+	Method     string      `json:"method"`
+	Reference  string      `json:"reference"`
+	ReceivedBy uuid.UUID   `json:"received_by"`
+	ReceivedAt time.Time   `json:"received_at"`
+}
+
+type RefundRequest struct {
+	Amount model.Money `json:"amount"`
+	Reason string      `json:"reason"`
+}
+
+type RefundResponse struct {
+	ID         int         `json:"id"`
+	PaymentID  int         `json:"payment_id"`
+	InvoiceID  uuid.UUID   `json:"invoice_id"`
+	Amount     model.Money `json:"amount"`
+	Reason     string      `json:"reason"`
+	RefundedBy uuid.UUID   `json:"refunded_by"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// PatientBalanceResponse lists the patient's unpaid invoices, oldest first.
+type PatientBalanceResponse struct {
+	PatientID   uuid.UUID         `json:"patient_id"`
+	Currency    string            `json:"currency"`
+	Outstanding model.Money       `json:"outstanding"`
+	Invoices    []InvoiceResponse `json:"invoices"`
+}
+
+func (r TaxRateRequest) ToModel() model.TaxRate {
+	return model.TaxRate{
+		Name: r.Name,
+		Rate: model.BasisPoints(r.Rate),
+	}
+}
+
+func ToTaxRateResponse(rate model.TaxRate) TaxRateResponse {
+	return TaxRateResponse{
+		ID:   rate.ID,
+		Name: rate.Name,
+		Rate: int(rate.Rate),
+	}
+}
+
+func ToTaxRateResponses(rates []model.TaxRate) []TaxRateResponse {
+	responses := make([]TaxRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		responses = append(responses, ToTaxRateResponse(rate))
+	}
+	return responses
+}
+
+// ToModel builds the catalogue item; items are active unless Active is false.
+func (r CatalogueItemRequest) ToModel() model.CatalogueItem {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return model.CatalogueItem{
+		Code:      r.Code,
+		Name:      r.Name,
+		Kind:      r.Kind,
+		UnitPrice: r.UnitPrice,
+		TaxRateID: r.TaxRateID,
+		Active:    active,
+	}
+}
+
+func ToCatalogueItemResponse(item model.CatalogueItem) CatalogueItemResponse {
+	return CatalogueItemResponse{
+		ID:        item.ID,
+		Code:      item.Code,
+		Name:      item.Name,
+		Kind:      item.Kind,
+		UnitPrice: item.UnitPrice,
+		TaxRateID: item.TaxRateID,
+		Active:    item.Active,
+	}
+}
+
+func ToCatalogueItemResponses(items []model.CatalogueItem) []CatalogueItemResponse {
+	responses := make([]CatalogueItemResponse, 0, len(items))
+	for _, item := range items {
+		responses = append(responses, ToCatalogueItemResponse(item))
+	}
+	return responses
+}
+
+func ToEncounterChargeResponse(charge model.EncounterCharge) EncounterChargeResponse {
+	return EncounterChargeResponse{
+		ID:              charge.ID,
+		EncounterID:     charge.EncounterID,
+		CatalogueItemID: charge.CatalogueItemID,
+		Quantity:        charge.Quantity,
+		AddedBy:         charge.AddedBy,
+		CreatedAt:       charge.CreatedAt,
+	}
+}
+
+func ToInvoiceResponse(invoice model.Invoice) InvoiceResponse {
+	response := InvoiceResponse{
+		ID:            invoice.ID,
+		InvoiceNumber: invoice.InvoiceNumber,
+		PatientID:     invoice.PatientID,
+		Status:        invoice.Status,
+		Currency:      invoice.Currency,
+		Subtotal:      invoice.Subtotal,
+		TaxTotal:      invoice.TaxTotal,
+		Total:         invoice.Total,
+		AmountPaid:    invoice.AmountPaid,
+		Outstanding:   invoice.Outstanding(),
+		IssuedBy:      invoice.IssuedBy,
+		IssuedAt:      invoice.IssuedAt,
+	}
+	if invoice.EncounterID.Valid {
+		response.EncounterID = &invoice.EncounterID.UUID
+	}
+	for _, line := range invoice.Lines {
+		response.Lines = append(response.Lines, InvoiceLineResponse{
+			CatalogueItemID: line.CatalogueItemID,
+			Description:     line.Description,
+			Quantity:        line.Quantity,
+			UnitPrice:       line.UnitPrice,
+			TaxRate:         int(line.TaxRate),
+			TaxAmount:       line.TaxAmount,
+			LineTotal:       line.LineTotal,
+		})
+	}
+	return response
+}
+
+func ToInvoiceResponses(invoices []model.Invoice) []InvoiceResponse {
+	responses := make([]InvoiceResponse, 0, len(invoices))
+	for _, invoice := range invoices {
+		responses = append(responses, ToInvoiceResponse(invoice))
+	}
+	return responses
+}
+
+func ToPaymentResponse(payment model.Payment) PaymentResponse {
+	return PaymentResponse{
+		ID:         payment.ID,
+		InvoiceID:  payment.InvoiceID,
+		Amount:     payment.Amount,
+		Refunded:   payment.Refunded,
+		Method:     payment.Method,
+		Reference:  payment.Reference,
+		ReceivedBy: payment.ReceivedBy,
+		ReceivedAt: payment.ReceivedAt,
+	}
+}
+
+func ToRefundResponse(refund model.Refund) RefundResponse {
+	return RefundResponse{
+		ID:         refund.ID,
+		PaymentID:  refund.PaymentID,
+		InvoiceID:  refund.InvoiceID,
+		Amount:     refund.Amount,
+		Reason:     refund.Reason,
+		RefundedBy: refund.RefundedBy,
+		CreatedAt:  refund.CreatedAt,
+	}
+}
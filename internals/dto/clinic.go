@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type CreateClinicRequest struct {
+	Name    string `json:"name"`
+	Code    string `json:"code"`
+	Address string `json:"address"`
+}
+
+type AddClinicMemberRequest struct {
+	UserID    uuid.UUID `json:"user_id"`
+	IsDefault bool      `json:"default"`
+}
+
+// SwitchClinicRequest asks for an access token for another clinic the user
+// works at.
+type SwitchClinicRequest struct {
+	ClinicID uuid.UUID `json:"clinic_id"`
+}
+
+type ClinicResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Code      string    `json:"code"`
+	Address   string    `json:"address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ClinicMembershipResponse struct {
+	Clinic    ClinicResponse `json:"clinic"`
+	IsDefault bool           `json:"default"`
+}
+
+func (r CreateClinicRequest) ToModel() model.Clinic {
+	return model.Clinic{
+		Name:    r.Name,
+		Code:    r.Code,
+		Address: r.Address,
+	}
+}
+
+func ToClinicResponse(clinic model.Clinic) ClinicResponse {
+	return ClinicResponse{
+		ID:        clinic.ID,
+		Name:      clinic.Name,
+		Code:      clinic.Code,
+		Address:   clinic.Address,
+		CreatedAt: clinic.CreatedAt,
+	}
+}
+
+func ToClinicResponses(clinics []model.Clinic) []ClinicResponse {
+	responses := make([]ClinicResponse, 0, len(clinics))
+	for _, clinic := range clinics {
+		responses = append(responses, ToClinicResponse(clinic))
+	}
+	return responses
+}
+
+func ToClinicMembershipResponses(memberships []model.ClinicMembership) []ClinicMembershipResponse {
+	responses := make([]ClinicMembershipResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		responses = append(responses, ClinicMembershipResponse{
+			Clinic:    ToClinicResponse(membership.Clinic),
+			IsDefault: membership.IsDefault,
+		})
+	}
+	return responses
+}
@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type ConsentTextRequest struct {
+	Scope string `json:"scope"`
+	Text  string `json:"text"`
+}
+
+// GrantConsentRequest records consent to a version of the scope's text; a
+// zero version means the latest one.
+type GrantConsentRequest struct {
+	Scope   string `json:"scope"`
+	Version int    `json:"version"`
+}
+
+type ConsentTextResponse struct {
+	ID        int       `json:"id"`
+	Scope     string    `json:"scope"`
+	Version   int       `json:"version"`
+	Text      string    `json:"text"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PatientConsentResponse struct {
+	ID          int64      `json:"id"`
+	PatientID   uuid.UUID  `json:"patient_id"`
+	Scope       string     `json:"scope"`
+	Version     int        `json:"version"`
+	GrantedBy   uuid.UUID  `json:"granted_by"`
+	GrantedAt   time.Time  `json:"granted_at"`
+	WithdrawnBy *uuid.UUID `json:"withdrawn_by,omitempty"`
+	WithdrawnAt *time.Time `json:"withdrawn_at,omitempty"`
+}
+
+// ConsentStatusResponse is whether the patient currently consents to a
+// scope. LatestVersion lets staff see a consent given to older wording.
+type ConsentStatusResponse struct {
+	Scope         string                  `json:"scope"`
+	Granted       bool                    `json:"granted"`
+	Consent       *PatientConsentResponse `json:"consent,omitempty"`
+	LatestVersion int                     `json:"latest_version,omitempty"`
+}
+
+type PatientConsentsResponse struct {
+	PatientID uuid.UUID                `json:"patient_id"`
+	Status    []ConsentStatusResponse  `json:"status"`
+	History   []PatientConsentResponse `json:"history"`
+}
+
+func ToConsentTextResponse(text model.ConsentText) ConsentTextResponse {
+	return ConsentTextResponse{
+		ID:        text.ID,
+		Scope:     text.Scope,
+		Version:   text.Version,
+		Text:      text.Text,
+		CreatedBy: text.CreatedBy,
+		CreatedAt: text.CreatedAt,
+	}
+}
+
+func ToConsentTextResponses(texts []model.ConsentText) []ConsentTextResponse {
+	responses := make([]ConsentTextResponse, 0, len(texts))
+	for _, text := range texts {
+		responses = append(responses, ToConsentTextResponse(text))
+	}
+	return responses
+}
+
+func ToPatientConsentResponse(consent model.PatientConsent) PatientConsentResponse {
+	var withdrawnBy *uuid.UUID
+	if consent.WithdrawnBy.Valid {
+		withdrawnBy = &consent.WithdrawnBy.UUID
+	}
+	return PatientConsentResponse{
+		ID:          consent.ID,
+		PatientID:   consent.PatientID,
+		Scope:       consent.Scope,
+		Version:     consent.TextVersion,
+		GrantedBy:   consent.GrantedBy,
+		GrantedAt:   consent.GrantedAt,
+		WithdrawnBy: withdrawnBy,
+		WithdrawnAt: consent.WithdrawnAt,
+	}
+}
+
+func ToPatientConsentResponses(consents []model.PatientConsent) []PatientConsentResponse {
+	responses := make([]PatientConsentResponse, 0, len(consents))
+	for _, consent := range consents {
+		responses = append(responses, ToPatientConsentResponse(consent))
+	}
+	return responses
+}
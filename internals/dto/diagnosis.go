@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type DiagnosisRequest struct {
+	PatientID   uuid.UUID `json:"patient_id"`
+	DoctorID    uuid.UUID `json:"doctor_id"`
+	Description string    `json:"description"`
+}
+
+type DiagnosisResponse struct {
+	ID          int        `json:"id"`
+	PatientID   uuid.UUID  `json:"patient_id"`
+	DoctorID    uuid.UUID  `json:"doctor_id"`
+	EncounterID *uuid.UUID `json:"encounter_id,omitempty"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (r DiagnosisRequest) ToModel() model.Diagnosis {
+	return model.Diagnosis{
+		PatientID:   r.PatientID,
+		DoctorID:    r.DoctorID,
+		Description: r.Description,
+	}
+}
+
+func ToDiagnosisResponse(diagnosis model.Diagnosis) DiagnosisResponse {
+	var encounterID *uuid.UUID
+	if diagnosis.EncounterID.Valid {
+		encounterID = &diagnosis.EncounterID.UUID
+	}
+	return DiagnosisResponse{
+		EncounterID: encounterID,
+		ID:          diagnosis.ID,
+		PatientID:   diagnosis.PatientID,
+		DoctorID:    diagnosis.DoctorID,
+		Description: diagnosis.Description,
+		CreatedAt:   diagnosis.CreatedAt,
+	}
+}
+
+func ToDiagnosisResponses(diagnoses []model.Diagnosis) []DiagnosisResponse {
+	responses := make([]DiagnosisResponse, 0, len(diagnoses))
+	for _, diagnosis := range diagnoses {
+		responses = append(responses, ToDiagnosisResponse(diagnosis))
+	}
+	return responses
+}
@@ -0,0 +1,8 @@
+package dto
+
+// DocumentFile is a generated document ready to be downloaded.
+type DocumentFile struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
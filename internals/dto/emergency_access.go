@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type BreakGlassRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ReviewEmergencyAccessRequest struct {
+	Note string `json:"note"`
+}
+
+type EmergencyAccessResponse struct {
+	ID         int64      `json:"id"`
+	DoctorID   uuid.UUID  `json:"doctor_id"`
+	PatientID  uuid.UUID  `json:"patient_id"`
+	Reason     string     `json:"reason"`
+	GrantedAt  time.Time  `json:"granted_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Active     bool       `json:"active"`
+	ReviewedBy *uuid.UUID `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote string     `json:"review_note,omitempty"`
+}
+
+type EmergencyAccessLogResponse struct {
+	Action     string    `json:"action"`
+	AccessedAt time.Time `json:"accessed_at"`
+}
+
+// EmergencyAccessDetailResponse is an access with every request made under it.
+type EmergencyAccessDetailResponse struct {
+	EmergencyAccessResponse
+	Log []EmergencyAccessLogResponse `json:"log"`
+}
+
+func ToEmergencyAccessResponse(access model.EmergencyAccess, now time.Time) EmergencyAccessResponse {
+	var reviewedBy *uuid.UUID
+	if access.ReviewedBy.Valid {
+		reviewedBy = &access.ReviewedBy.UUID
+	}
+	return EmergencyAccessResponse{
+		ID:         access.ID,
+		DoctorID:   access.DoctorID,
+		PatientID:  access.PatientID,
+		Reason:     access.Reason,
+		GrantedAt:  access.GrantedAt,
+		ExpiresAt:  access.ExpiresAt,
+		Active:     access.IsActive(now),
+		ReviewedBy: reviewedBy,
+		ReviewedAt: access.ReviewedAt,
+		ReviewNote: access.ReviewNote,
+	}
+}
+
+func ToEmergencyAccessResponses(accesses []model.EmergencyAccess, now time.Time) []EmergencyAccessResponse {
+	responses := make([]EmergencyAccessResponse, 0, len(accesses))
+	for _, access := range accesses {
+		responses = append(responses, ToEmergencyAccessResponse(access, now))
+	}
+	return responses
+}
+
+func ToEmergencyAccessDetailResponse(access model.EmergencyAccess, log []model.EmergencyAccessLog, now time.Time) EmergencyAccessDetailResponse {
+	entries := make([]EmergencyAccessLogResponse, 0, len(log))
+	for _, entry := range log {
+		entries = append(entries, EmergencyAccessLogResponse{Action: entry.Action, AccessedAt: entry.AccessedAt})
+	}
+	return EmergencyAccessDetailResponse{
+		EmergencyAccessResponse: ToEmergencyAccessResponse(access, now),
+		Log:                     entries,
+	}
+}
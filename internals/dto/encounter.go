@@ -0,0 +1,185 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type OpenEncounterRequest struct {
+	PatientID      uuid.UUID `json:"patient_id"`
+	DoctorID       uuid.UUID `json:"doctor_id"`
+	ChiefComplaint string    `json:"chief_complaint"`
+}
+
+type CloseEncounterRequest struct {
+	DischargeNotes string `json:"discharge_notes"`
+}
+
+type EncounterDiagnosisRequest struct {
+	Description string `json:"description"`
+}
+
+type EncounterResponse struct {
+	ID                    uuid.UUID  `json:"id"`
+	PatientID             uuid.UUID  `json:"patient_id"`
+	DoctorID              uuid.UUID  `json:"doctor_id"`
+	ChiefComplaint        string     `json:"chief_complaint"`
+	Status                string     `json:"status"`
+	CheckedInAt           time.Time  `json:"checked_in_at"`
+	ConsultationStartedAt *time.Time `json:"consultation_started_at,omitempty"`
+	DischargedAt          *time.Time `json:"discharged_at,omitempty"`
+	DischargeNotes        string     `json:"discharge_notes,omitempty"`
+}
+
+// EncounterDetailResponse is an encounter with everything recorded during it.
+type EncounterDetailResponse struct {
+	Encounter     EncounterResponse      `json:"encounter"`
+	Diagnoses     []DiagnosisResponse    `json:"diagnoses"`
+	Vitals        []VitalsResponse       `json:"vitals"`
+	Prescriptions []PrescriptionResponse `json:"prescriptions"`
+}
+
+type VitalsRequest struct {
+	TemperatureC     *float64 `json:"temperature_c,omitempty"`
+	PulseBPM         *int     `json:"pulse_bpm,omitempty"`
+	SystolicBP       *int     `json:"systolic_bp,omitempty"`
+	DiastolicBP      *int     `json:"diastolic_bp,omitempty"`
+	RespiratoryRate  *int     `json:"respiratory_rate,omitempty"`
+	OxygenSaturation *int     `json:"oxygen_saturation,omitempty"`
+	WeightKg         *float64 `json:"weight_kg,omitempty"`
+	HeightCm         *float64 `json:"height_cm,omitempty"`
+}
+
+type VitalsResponse struct {
+	ID          int       `json:"id"`
+	EncounterID uuid.UUID `json:"encounter_id"`
+	RecordedBy  uuid.UUID `json:"recorded_by"`
+	VitalsRequest
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+type PrescriptionRequest struct {
+	CatalogueItemID *int   `json:"catalogue_item_id,omitempty"`
+	Medication      string `json:"medication"`
+	Dosage          string `json:"dosage"`
+	Frequency       string `json:"frequency"`
+	DurationDays    int    `json:"duration_days"`
+	Instructions    string `json:"instructions"`
+}
+
+type PrescriptionResponse struct {
+	ID          int       `json:"id"`
+	EncounterID uuid.UUID `json:"encounter_id"`
+	PatientID   uuid.UUID `json:"patient_id"`
+	DoctorID    uuid.UUID `json:"doctor_id"`
+	PrescriptionRequest
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ToEncounterResponse(encounter model.Encounter) EncounterResponse {
+	return EncounterResponse{
+		ID:                    encounter.ID,
+		PatientID:             encounter.PatientID,
+		DoctorID:              encounter.DoctorID,
+		ChiefComplaint:        encounter.ChiefComplaint,
+		Status:                encounter.Status,
+		CheckedInAt:           encounter.CheckedInAt,
+		ConsultationStartedAt: encounter.ConsultationStartedAt,
+		DischargedAt:          encounter.DischargedAt,
+		DischargeNotes:        encounter.DischargeNotes,
+	}
+}
+
+func ToEncounterResponses(encounters []model.Encounter) []EncounterResponse {
+	responses := make([]EncounterResponse, 0, len(encounters))
+	for _, encounter := range encounters {
+		responses = append(responses, ToEncounterResponse(encounter))
+	}
+	return responses
+}
+
+func (r VitalsRequest) ToModel(encounter model.Encounter, recordedBy uuid.UUID) model.Vitals {
+	return model.Vitals{
+		EncounterID:      encounter.ID,
+		PatientID:        encounter.PatientID,
+		RecordedBy:       recordedBy,
+		TemperatureC:     r.TemperatureC,
+		PulseBPM:         r.PulseBPM,
+		SystolicBP:       r.SystolicBP,
+		DiastolicBP:      r.DiastolicBP,
+		RespiratoryRate:  r.RespiratoryRate,
+		OxygenSaturation: r.OxygenSaturation,
+		WeightKg:         r.WeightKg,
+		HeightCm:         r.HeightCm,
+	}
+}
+
+func ToVitalsResponse(vitals model.Vitals) VitalsResponse {
+	return VitalsResponse{
+		ID:          vitals.ID,
+		EncounterID: vitals.EncounterID,
+		RecordedBy:  vitals.RecordedBy,
+		VitalsRequest: VitalsRequest{
+			TemperatureC:     vitals.TemperatureC,
+			PulseBPM:         vitals.PulseBPM,
+			SystolicBP:       vitals.SystolicBP,
+			DiastolicBP:      vitals.DiastolicBP,
+			RespiratoryRate:  vitals.RespiratoryRate,
+			OxygenSaturation: vitals.OxygenSaturation,
+			WeightKg:         vitals.WeightKg,
+			HeightCm:         vitals.HeightCm,
+		},
+		RecordedAt: vitals.RecordedAt,
+	}
+}
+
+func (r PrescriptionRequest) ToModel(encounter model.Encounter, doctorID uuid.UUID) model.Prescription {
+	return model.Prescription{
+		EncounterID:     encounter.ID,
+		PatientID:       encounter.PatientID,
+		DoctorID:        doctorID,
+		CatalogueItemID: r.CatalogueItemID,
+		Medication:      r.Medication,
+		Dosage:          r.Dosage,
+		Frequency:       r.Frequency,
+		DurationDays:    r.DurationDays,
+		Instructions:    r.Instructions,
+	}
+}
+
+func ToPrescriptionResponse(prescription model.Prescription) PrescriptionResponse {
+	return PrescriptionResponse{
+		ID:          prescription.ID,
+		EncounterID: prescription.EncounterID,
+		PatientID:   prescription.PatientID,
+		DoctorID:    prescription.DoctorID,
+		PrescriptionRequest: PrescriptionRequest{
+			CatalogueItemID: prescription.CatalogueItemID,
+			Medication:      prescription.Medication,
+			Dosage:          prescription.Dosage,
+			Frequency:       prescription.Frequency,
+			DurationDays:    prescription.DurationDays,
+			Instructions:    prescription.Instructions,
+		},
+		CreatedAt: prescription.CreatedAt,
+	}
+}
+
+func ToEncounterDetailResponse(encounter model.Encounter, diagnoses []model.Diagnosis, vitals []model.Vitals,
+	prescriptions []model.Prescription) EncounterDetailResponse {
+	detail := EncounterDetailResponse{
+		Encounter:     ToEncounterResponse(encounter),
+		Diagnoses:     ToDiagnosisResponses(diagnoses),
+		Vitals:        make([]VitalsResponse, 0, len(vitals)),
+		Prescriptions: make([]PrescriptionResponse, 0, len(prescriptions)),
+	}
+	for _, v := range vitals {
+		detail.Vitals = append(detail.Vitals, ToVitalsResponse(v))
+	}
+	for _, prescription := range prescriptions {
+		detail.Prescriptions = append(detail.Prescriptions, ToPrescriptionResponse(prescription))
+	}
+	return detail
+}
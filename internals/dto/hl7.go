@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+type HL7DeadLetterResponse struct {
+	ID         int64     `json:"id"`
+	RemoteAddr string    `json:"remote_addr"`
+	Message    string    `json:"message"`
+	Error      string    `json:"error"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+func ToHL7DeadLetterResponses(letters []model.HL7DeadLetter) []HL7DeadLetterResponse {
+	responses := make([]HL7DeadLetterResponse, 0, len(letters))
+	for _, letter := range letters {
+		responses = append(responses, HL7DeadLetterResponse{
+			ID:         letter.ID,
+			RemoteAddr: letter.RemoteAddr,
+			Message:    letter.Message,
+			Error:      letter.Error,
+			ReceivedAt: letter.ReceivedAt,
+		})
+	}
+	return responses
+}
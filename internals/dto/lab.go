@@ -0,0 +1,152 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+// LabReferenceRangeRequest is a normal range for patients of a gender, any
+// when empty, within an age band in years. A nil max_age has no upper limit.
+type LabReferenceRangeRequest struct {
+	Gender string   `json:"gender"`
+	MinAge int      `json:"min_age"`
+	MaxAge *int     `json:"max_age"`
+	Low    *float64 `json:"low"`
+	High   *float64 `json:"high"`
+}
+
+// LabTestRequest creates or replaces a test in the catalogue. Active defaults
+// to true.
+type LabTestRequest struct {
+	Code   string                     `json:"code"`
+	Name   string                     `json:"name"`
+	Unit   string                     `json:"unit"`
+	Active *bool                      `json:"active"`
+	Ranges []LabReferenceRangeRequest `json:"reference_ranges"`
+}
+
+// LabOrderRequest orders a catalogue test, optionally for one of the
+// patient's diagnoses.
+type LabOrderRequest struct {
+	TestCode    string `json:"test_code"`
+	DiagnosisID *int   `json:"diagnosis_id"`
+	Notes       string `json:"notes"`
+}
+
+type LabResultRequest struct {
+	Value   *float64 `json:"value"`
+	Comment string   `json:"comment"`
+}
+
+type LabReferenceRangeResponse struct {
+	Gender string   `json:"gender,omitempty"`
+	MinAge int      `json:"min_age"`
+	MaxAge *int     `json:"max_age,omitempty"`
+	Low    *float64 `json:"low,omitempty"`
+	High   *float64 `json:"high,omitempty"`
+}
+
+type LabTestResponse struct {
+	ID     int                         `json:"id"`
+	Code   string                      `json:"code"`
+	Name   string                      `json:"name"`
+	Unit   string                      `json:"unit"`
+	Active bool                        `json:"active"`
+	Ranges []LabReferenceRangeResponse `json:"reference_ranges"`
+}
+
+type LabResultResponse struct {
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit"`
+	ReferenceLow  *float64  `json:"reference_low,omitempty"`
+	ReferenceHigh *float64  `json:"reference_high,omitempty"`
+	Flag          string    `json:"flag"`
+	Abnormal      bool      `json:"abnormal"`
+	Comment       string    `json:"comment,omitempty"`
+	RecordedBy    uuid.UUID `json:"recorded_by"`
+	RecordedAt    time.Time `json:"recorded_at"`
+}
+
+type LabOrderResponse struct {
+	ID          int64              `json:"id"`
+	PatientID   uuid.UUID          `json:"patient_id"`
+	DiagnosisID *int               `json:"diagnosis_id,omitempty"`
+	TestCode    string             `json:"test_code"`
+	TestName    string             `json:"test_name"`
+	Unit        string             `json:"unit"`
+	OrderedBy   uuid.UUID          `json:"ordered_by"`
+	Notes       string             `json:"notes,omitempty"`
+	Status      string             `json:"status"`
+	OrderedAt   time.Time          `json:"ordered_at"`
+	CancelledAt *time.Time         `json:"cancelled_at,omitempty"`
+	Result      *LabResultResponse `json:"result,omitempty"`
+}
+
+func ToLabTestResponse(test model.LabTest) LabTestResponse {
+	ranges := make([]LabReferenceRangeResponse, 0, len(test.Ranges))
+	for _, r := range test.Ranges {
+		ranges = append(ranges, LabReferenceRangeResponse{
+			Gender: r.Gender,
+			MinAge: r.MinAge,
+			MaxAge: r.MaxAge,
+			Low:    r.Low,
+			High:   r.High,
+		})
+	}
+	return LabTestResponse{
+		ID:     test.ID,
+		Code:   test.Code,
+		Name:   test.Name,
+		Unit:   test.Unit,
+		Active: test.Active,
+		Ranges: ranges,
+	}
+}
+
+func ToLabTestResponses(tests []model.LabTest) []LabTestResponse {
+	responses := make([]LabTestResponse, 0, len(tests))
+	for _, test := range tests {
+		responses = append(responses, ToLabTestResponse(test))
+	}
+	return responses
+}
+
+func ToLabOrderResponse(order model.LabOrder) LabOrderResponse {
+	response := LabOrderResponse{
+		ID:          order.ID,
+		PatientID:   order.PatientID,
+		DiagnosisID: order.DiagnosisID,
+		TestCode:    order.TestCode,
+		TestName:    order.TestName,
+		Unit:        order.Unit,
+		OrderedBy:   order.OrderedBy,
+		Notes:       order.Notes,
+		Status:      order.Status,
+		OrderedAt:   order.OrderedAt,
+		CancelledAt: order.CancelledAt,
+	}
+	if result := order.Result; result != nil {
+		response.Result = &LabResultResponse{
+			Value:         result.Value,
+			Unit:          result.Unit,
+			ReferenceLow:  result.ReferenceLow,
+			ReferenceHigh: result.ReferenceHigh,
+			Flag:          result.Flag,
+			Abnormal:      result.Abnormal(),
+			Comment:       result.Comment,
+			RecordedBy:    result.RecordedBy,
+			RecordedAt:    result.RecordedAt,
+		}
+	}
+	return response
+}
+
+func ToLabOrderResponses(orders []model.LabOrder) []LabOrderResponse {
+	responses := make([]LabOrderResponse, 0, len(orders))
+	for _, order := range orders {
+		responses = append(responses, ToLabOrderResponse(order))
+	}
+	return responses
+}
@@ -0,0 +1,159 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type AllergyRequest struct {
+	Substance string `json:"substance"`
+	Reaction  string `json:"reaction"`
+	Severity  string `json:"severity"`
+}
+
+type AllergyResponse struct {
+	ID         int       `json:"id"`
+	PatientID  uuid.UUID `json:"patient_id"`
+	Substance  string    `json:"substance"`
+	Reaction   string    `json:"reaction"`
+	Severity   string    `json:"severity"`
+	RecordedBy uuid.UUID `json:"recorded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ChronicConditionRequest struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	OnsetDate *time.Time `json:"onset_date,omitempty"`
+	Notes     string     `json:"notes"`
+}
+
+type ChronicConditionResponse struct {
+	ID         int        `json:"id"`
+	PatientID  uuid.UUID  `json:"patient_id"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	OnsetDate  *time.Time `json:"onset_date,omitempty"`
+	Notes      string     `json:"notes"`
+	RecordedBy uuid.UUID  `json:"recorded_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type FamilyHistoryRequest struct {
+	Relation  string `json:"relation"`
+	Condition string `json:"condition"`
+	Notes     string `json:"notes"`
+}
+
+type FamilyHistoryResponse struct {
+	ID         int       `json:"id"`
+	PatientID  uuid.UUID `json:"patient_id"`
+	Relation   string    `json:"relation"`
+	Condition  string    `json:"condition"`
+	Notes      string    `json:"notes"`
+	RecordedBy uuid.UUID `json:"recorded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PatientSummaryResponse is what a doctor sees when opening a patient.
+type PatientSummaryResponse struct {
+	Patient           PatientResponse            `json:"patient"`
+	Allergies         []AllergyResponse          `json:"allergies"`
+	ChronicConditions []ChronicConditionResponse `json:"chronic_conditions"`
+	FamilyHistory     []FamilyHistoryResponse    `json:"family_history"`
+	RecentDiagnoses   []DiagnosisResponse        `json:"recent_diagnoses"`
+	Contacts          []PatientContactResponse   `json:"contacts"`
+}
+
+func (r AllergyRequest) ToModel(patientID, recordedBy uuid.UUID) model.Allergy {
+	return model.Allergy{
+		PatientID:  patientID,
+		Substance:  r.Substance,
+		Reaction:   r.Reaction,
+		Severity:   r.Severity,
+		RecordedBy: recordedBy,
+	}
+}
+
+func (r ChronicConditionRequest) ToModel(patientID, recordedBy uuid.UUID) model.ChronicCondition {
+	return model.ChronicCondition{
+		PatientID:  patientID,
+		Name:       r.Name,
+		Status:     r.Status,
+		OnsetDate:  r.OnsetDate,
+		Notes:      r.Notes,
+		RecordedBy: recordedBy,
+	}
+}
+
+func (r FamilyHistoryRequest) ToModel(patientID, recordedBy uuid.UUID) model.FamilyHistory {
+	return model.FamilyHistory{
+		PatientID:  patientID,
+		Relation:   r.Relation,
+		Condition:  r.Condition,
+		Notes:      r.Notes,
+		RecordedBy: recordedBy,
+	}
+}
+
+func ToAllergyResponse(allergy model.Allergy) AllergyResponse {
+	return AllergyResponse{
+		ID:         allergy.ID,
+		PatientID:  allergy.PatientID,
+		Substance:  allergy.Substance,
+		Reaction:   allergy.Reaction,
+		Severity:   allergy.Severity,
+		RecordedBy: allergy.RecordedBy,
+		CreatedAt:  allergy.CreatedAt,
+	}
+}
+
+func ToChronicConditionResponse(condition model.ChronicCondition) ChronicConditionResponse {
+	return ChronicConditionResponse{
+		ID:         condition.ID,
+		PatientID:  condition.PatientID,
+		Name:       condition.Name,
+		Status:     condition.Status,
+		OnsetDate:  condition.OnsetDate,
+		Notes:      condition.Notes,
+		RecordedBy: condition.RecordedBy,
+		CreatedAt:  condition.CreatedAt,
+	}
+}
+
+func ToFamilyHistoryResponse(entry model.FamilyHistory) FamilyHistoryResponse {
+	return FamilyHistoryResponse{
+		ID:         entry.ID,
+		PatientID:  entry.PatientID,
+		Relation:   entry.Relation,
+		Condition:  entry.Condition,
+		Notes:      entry.Notes,
+		RecordedBy: entry.RecordedBy,
+		CreatedAt:  entry.CreatedAt,
+	}
+}
+
+// ToPatientSummaryResponse maps a patient with its medical history loaded,
+// together with their recent diagnoses.
+func ToPatientSummaryResponse(patient model.Patient, recentDiagnoses []model.Diagnosis) PatientSummaryResponse {
+	summary := PatientSummaryResponse{
+		Patient:           ToPatientResponse(patient),
+		Allergies:         make([]AllergyResponse, 0, len(patient.Allergies)),
+		ChronicConditions: make([]ChronicConditionResponse, 0, len(patient.ChronicConditions)),
+		FamilyHistory:     make([]FamilyHistoryResponse, 0, len(patient.FamilyHistory)),
+		RecentDiagnoses:   ToDiagnosisResponses(recentDiagnoses),
+		Contacts:          ToPatientContactResponses(patient.Contacts),
+	}
+	for _, allergy := range patient.Allergies {
+		summary.Allergies = append(summary.Allergies, ToAllergyResponse(allergy))
+	}
+	for _, condition := range patient.ChronicConditions {
+		summary.ChronicConditions = append(summary.ChronicConditions, ToChronicConditionResponse(condition))
+	}
+	for _, entry := range patient.FamilyHistory {
+		summary.FamilyHistory = append(summary.FamilyHistory, ToFamilyHistoryResponse(entry))
+	}
+	return summary
+}
@@ -0,0 +1,27 @@
+package dto
+
+// MFAVerifyRequest completes a two-step login with either a TOTP code or a recovery code.
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+	ClientIP     string `json:"-"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAPolicyRequest struct {
+	Role     string `json:"role"`
+	Required bool   `json:"required"`
+}
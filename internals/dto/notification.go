@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+// AppointmentReminderRequest schedules a reminder of an appointment. The
+// channel defaults to SMS and the doctor is optional.
+type AppointmentReminderRequest struct {
+	Channel       string    `json:"channel"`
+	AppointmentAt time.Time `json:"appointment_at"`
+	DoctorID      uuid.UUID `json:"doctor_id"`
+}
+
+type ResultsReadyRequest struct {
+	Channel  string `json:"channel"`
+	TestName string `json:"test_name"`
+}
+
+type NotificationResponse struct {
+	ID            int64      `json:"id"`
+	PatientID     uuid.UUID  `json:"patient_id"`
+	Channel       string     `json:"channel"`
+	Template      string     `json:"template"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject,omitempty"`
+	Body          string     `json:"body"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+func ToNotificationResponse(notification model.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:            notification.ID,
+		PatientID:     notification.PatientID,
+		Channel:       notification.Channel,
+		Template:      notification.Template,
+		Recipient:     notification.Recipient,
+		Subject:       notification.Subject,
+		Body:          notification.Body,
+		Status:        notification.Status,
+		Attempts:      notification.Attempts,
+		NextAttemptAt: notification.NextAttemptAt,
+		LastError:     notification.LastError,
+		CreatedAt:     notification.CreatedAt,
+		SentAt:        notification.SentAt,
+	}
+}
+
+func ToNotificationResponses(notifications []model.Notification) []NotificationResponse {
+	responses := make([]NotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		responses = append(responses, ToNotificationResponse(notification))
+	}
+	return responses
+}
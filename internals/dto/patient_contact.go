@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type PatientContactRequest struct {
+	Name        string `json:"name"`
+	Relation    string `json:"relation"`
+	PhoneNumber string `json:"phone_number"`
+	Email       string `json:"email"`
+	Address     string `json:"address"`
+	IsEmergency bool   `json:"emergency_contact"`
+	IsGuardian  bool   `json:"guardian"`
+}
+
+type PatientContactResponse struct {
+	ID          int       `json:"id"`
+	PatientID   uuid.UUID `json:"patient_id"`
+	Name        string    `json:"name"`
+	Relation    string    `json:"relation"`
+	PhoneNumber string    `json:"phone_number,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	Address     string    `json:"address,omitempty"`
+	IsEmergency bool      `json:"emergency_contact"`
+	IsGuardian  bool      `json:"guardian"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (r PatientContactRequest) ToModel(patientID uuid.UUID) model.PatientContact {
+	return model.PatientContact{
+		PatientID:   patientID,
+		Name:        r.Name,
+		Relation:    r.Relation,
+		PhoneNumber: r.PhoneNumber,
+		Email:       r.Email,
+		Address:     r.Address,
+		IsEmergency: r.IsEmergency,
+		IsGuardian:  r.IsGuardian,
+	}
+}
+
+func ToPatientContactResponse(contact model.PatientContact) PatientContactResponse {
+	return PatientContactResponse{
+		ID:          contact.ID,
+		PatientID:   contact.PatientID,
+		Name:        contact.Name,
+		Relation:    contact.Relation,
+		PhoneNumber: contact.PhoneNumber,
+		Email:       contact.Email,
+		Address:     contact.Address,
+		IsEmergency: contact.IsEmergency,
+		IsGuardian:  contact.IsGuardian,
+		CreatedAt:   contact.CreatedAt,
+	}
+}
+
+func ToPatientContactResponses(contacts []model.PatientContact) []PatientContactResponse {
+	responses := make([]PatientContactResponse, 0, len(contacts))
+	for _, contact := range contacts {
+		responses = append(responses, ToPatientContactResponse(contact))
+	}
+	return responses
+}
@@ -0,0 +1,17 @@
+package dto
+
+// PatientImportRowError explains why one CSV row was not imported. Row is the
+// line number in the file, the header being line 1.
+type PatientImportRowError struct {
+	Row         int    `json:"row"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	Error       string `json:"error"`
+}
+
+type PatientImportReport struct {
+	Rows       int                     `json:"rows"`
+	Imported   int                     `json:"imported"`
+	Duplicates int                     `json:"duplicates"`
+	Failed     int                     `json:"failed"`
+	Errors     []PatientImportRowError `json:"errors"`
+}
@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type MergePatientsRequest struct {
+	MergedPatientID uuid.UUID `json:"merged_patient_id"`
+}
+
+type PatientMergeResponse struct {
+	ID            int64           `json:"id"`
+	SurvivorID    uuid.UUID       `json:"survivor_id"`
+	MergedPatient PatientResponse `json:"merged_patient"`
+	MergedBy      uuid.UUID       `json:"merged_by"`
+	MergedAt      time.Time       `json:"merged_at"`
+	UndoneBy      *uuid.UUID      `json:"undone_by,omitempty"`
+	UndoneAt      *time.Time      `json:"undone_at,omitempty"`
+}
+
+type DuplicateCandidateResponse struct {
+	Patient   PatientResponse `json:"patient"`
+	Duplicate PatientResponse `json:"duplicate"`
+	Score     float64         `json:"score"`
+	Reasons   []string        `json:"reasons"`
+}
+
+func ToPatientMergeResponse(merge model.PatientMerge) PatientMergeResponse {
+	var undoneBy *uuid.UUID
+	if merge.UndoneBy.Valid {
+		undoneBy = &merge.UndoneBy.UUID
+	}
+	return PatientMergeResponse{
+		ID:            merge.ID,
+		SurvivorID:    merge.SurvivorID,
+		MergedPatient: ToPatientResponse(merge.Merged),
+		MergedBy:      merge.MergedBy,
+		MergedAt:      merge.MergedAt,
+		UndoneBy:      undoneBy,
+		UndoneAt:      merge.UndoneAt,
+	}
+}
+
+func ToPatientMergeResponses(merges []model.PatientMerge) []PatientMergeResponse {
+	responses := make([]PatientMergeResponse, 0, len(merges))
+	for _, merge := range merges {
+		responses = append(responses, ToPatientMergeResponse(merge))
+	}
+	return responses
+}
+
+func ToDuplicateCandidateResponses(candidates []model.DuplicateCandidate) []DuplicateCandidateResponse {
+	responses := make([]DuplicateCandidateResponse, 0, len(candidates))
+	for _, candidate := range candidates {
+		responses = append(responses, DuplicateCandidateResponse{
+			Patient:   ToPatientResponse(candidate.Patient),
+			Duplicate: ToPatientResponse(candidate.Duplicate),
+			Score:     candidate.Score,
+			Reasons:   candidate.Reasons,
+		})
+	}
+	return responses
+}
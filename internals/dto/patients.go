@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type PatientRequest struct {
+	Name        string `json:"name"`
+	Age         int    `json:"age"`
+	Gender      string `json:"gender"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+type PatientResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Age         int       `json:"age"`
+	Gender      string    `json:"gender"`
+	PhoneNumber string    `json:"phone_number"`
+}
+
+func (r PatientRequest) ToModel(id uuid.UUID) model.Patient {
+	return model.Patient{
+		ID:          id,
+		Name:        r.Name,
+		Age:         r.Age,
+		Gender:      r.Gender,
+		PhoneNumber: r.PhoneNumber,
+	}
+}
+
+func ToPatientResponse(patient model.Patient) PatientResponse {
+	return PatientResponse{
+		ID:          patient.ID,
+		Name:        patient.Name,
+		Age:         patient.Age,
+		Gender:      patient.Gender,
+		PhoneNumber: patient.PhoneNumber,
+	}
+}
+
+func ToPatientResponses(patients []model.Patient) []PatientResponse {
+	responses := make([]PatientResponse, 0, len(patients))
+	for _, patient := range patients {
+		responses = append(responses, ToPatientResponse(patient))
+	}
+	return responses
+}
@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+// EnqueueRequest puts a checked-in encounter in its doctor's queue. Priority
+// defaults to elderly or normal based on the patient's age.
+type EnqueueRequest struct {
+	EncounterID uuid.UUID `json:"encounter_id"`
+	Priority    string    `json:"priority,omitempty"`
+}
+
+type QueueEntryResponse struct {
+	ID                   int64      `json:"id"`
+	EncounterID          uuid.UUID  `json:"encounter_id"`
+	DoctorID             uuid.UUID  `json:"doctor_id"`
+	PatientID            uuid.UUID  `json:"patient_id"`
+	TokenNumber          int        `json:"token_number"`
+	Priority             string     `json:"priority"`
+	Status               string     `json:"status"`
+	CheckedInAt          time.Time  `json:"checked_in_at"`
+	CalledAt             *time.Time `json:"called_at,omitempty"`
+	EstimatedWaitSeconds int        `json:"estimated_wait_seconds"`
+}
+
+// QueueResponse is a snapshot of one doctor's queue, in calling order.
+type QueueResponse struct {
+	DoctorID                   uuid.UUID            `json:"doctor_id"`
+	AverageConsultationSeconds int                  `json:"average_consultation_seconds"`
+	Entries                    []QueueEntryResponse `json:"entries"`
+}
+
+func ToQueueEntryResponse(entry model.QueueEntry, estimatedWait time.Duration) QueueEntryResponse {
+	return QueueEntryResponse{
+		ID:                   entry.ID,
+		EncounterID:          entry.EncounterID,
+		DoctorID:             entry.DoctorID,
+		PatientID:            entry.PatientID,
+		TokenNumber:          entry.TokenNumber,
+		Priority:             entry.Priority,
+		Status:               entry.Status,
+		CheckedInAt:          entry.CheckedInAt,
+		CalledAt:             entry.CalledAt,
+		EstimatedWaitSeconds: int(estimatedWait.Seconds()),
+	}
+}
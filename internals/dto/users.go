@@ -0,0 +1,108 @@
+package dto
+
+// Package dto contains the request and response shapes exchanged with API
+// clients, together with the functions that map them to and from the model.
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type RegisterUserRequest struct {
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// ClientIP is filled in by the HTTP layer and used for login throttling.
+	ClientIP string `json:"-"`
+}
+
+type UpdateUserRequest struct {
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	Username    string `json:"username"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+type UserResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Role        string    `json:"role"`
+	Username    string    `json:"username"`
+	PhoneNumber string    `json:"phone_number"`
+}
+
+// LoginResponse carries either an access token or, when the user has two-factor
+// authentication enabled, an MFAToken to exchange for one via MFAVerifyRequest.
+// Clinic is the clinic the access token works in. With MFAEnrollmentRequired
+// the token only works for enrolling a second factor.
+type LoginResponse struct {
+	User                  *UserResponse   `json:"user,omitempty"`
+	Token                 string          `json:"token,omitempty"`
+	Clinic                *ClinicResponse `json:"clinic,omitempty"`
+	MFARequired           bool            `json:"mfa_required,omitempty"`
+	MFAToken              string          `json:"mfa_token,omitempty"`
+	MFAEnrollmentRequired bool            `json:"mfa_enrollment_required,omitempty"`
+}
+
+// ToModel maps a registration request to a user. The password is deliberately
+// left out; it is hashed and stored separately by the auth service.
+func (r RegisterUserRequest) ToModel() model.User {
+	return model.User{
+		Name:        r.Name,
+		Role:        r.Role,
+		Username:    r.Username,
+		PhoneNumber: r.PhoneNumber,
+	}
+}
+
+func (r UpdateUserRequest) ToModel(id uuid.UUID) model.User {
+	return model.User{
+		ID:          id,
+		Name:        r.Name,
+		Role:        r.Role,
+		Username:    r.Username,
+		PhoneNumber: r.PhoneNumber,
+	}
+}
+
+func ToUserResponse(user model.User) UserResponse {
+	return UserResponse{
+		ID:          user.ID,
+		Name:        user.Name,
+		Role:        user.Role,
+		Username:    user.Username,
+		PhoneNumber: user.PhoneNumber,
+	}
+}
+
+func ToUserResponses(users []model.User) []UserResponse {
+	responses := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, ToUserResponse(user))
+	}
+	return responses
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordResetTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,118 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+// WebhookSubscriptionRequest creates or replaces a subscription. A secret is
+// generated when none is given on creation; on update an empty secret keeps
+// the current one. Active defaults to true.
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookSubscriptionResponse only carries the secret when it was generated,
+// so it is shown once.
+type WebhookSubscriptionResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret,omitempty"`
+	Active     bool      `json:"active"`
+	CreatedBy  uuid.UUID `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID                 int64      `json:"id"`
+	SubscriptionID     int64      `json:"subscription_id"`
+	EventID            int64      `json:"event_id"`
+	EventType          string     `json:"event_type"`
+	Status             string     `json:"status"`
+	Attempts           int        `json:"attempts"`
+	NextAttemptAt      time.Time  `json:"next_attempt_at"`
+	LastError          string     `json:"last_error,omitempty"`
+	LastResponseStatus int        `json:"last_response_status,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	DeliveredAt        *time.Time `json:"delivered_at,omitempty"`
+}
+
+type WebhookAttemptResponse struct {
+	AttemptedAt    time.Time `json:"attempted_at"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	DurationMS     int64     `json:"duration_ms"`
+}
+
+// WebhookDeliveryDetailResponse is a delivery with every attempt made at it.
+type WebhookDeliveryDetailResponse struct {
+	WebhookDeliveryResponse
+	AttemptLog []WebhookAttemptResponse `json:"attempt_log"`
+}
+
+func ToWebhookSubscriptionResponse(subscription model.WebhookSubscription) WebhookSubscriptionResponse {
+	return WebhookSubscriptionResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		Active:     subscription.Active,
+		CreatedBy:  subscription.CreatedBy,
+		CreatedAt:  subscription.CreatedAt,
+		UpdatedAt:  subscription.UpdatedAt,
+	}
+}
+
+func ToWebhookSubscriptionResponses(subscriptions []model.WebhookSubscription) []WebhookSubscriptionResponse {
+	responses := make([]WebhookSubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		responses = append(responses, ToWebhookSubscriptionResponse(subscription))
+	}
+	return responses
+}
+
+func ToWebhookDeliveryResponse(delivery model.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:                 delivery.ID,
+		SubscriptionID:     delivery.SubscriptionID,
+		EventID:            delivery.EventID,
+		EventType:          delivery.EventType,
+		Status:             delivery.Status,
+		Attempts:           delivery.Attempts,
+		NextAttemptAt:      delivery.NextAttemptAt,
+		LastError:          delivery.LastError,
+		LastResponseStatus: delivery.LastResponseStatus,
+		CreatedAt:          delivery.CreatedAt,
+		DeliveredAt:        delivery.DeliveredAt,
+	}
+}
+
+func ToWebhookDeliveryResponses(deliveries []model.WebhookDelivery) []WebhookDeliveryResponse {
+	responses := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		responses = append(responses, ToWebhookDeliveryResponse(delivery))
+	}
+	return responses
+}
+
+func ToWebhookDeliveryDetailResponse(delivery model.WebhookDelivery, attempts []model.WebhookAttempt) WebhookDeliveryDetailResponse {
+	log := make([]WebhookAttemptResponse, 0, len(attempts))
+	for _, attempt := range attempts {
+		log = append(log, WebhookAttemptResponse{
+			AttemptedAt:    attempt.AttemptedAt,
+			ResponseStatus: attempt.ResponseStatus,
+			Error:          attempt.Error,
+			DurationMS:     attempt.Duration.Milliseconds(),
+		})
+	}
+	return WebhookDeliveryDetailResponse{
+		WebhookDeliveryResponse: ToWebhookDeliveryResponse(delivery),
+		AttemptLog:              log,
+	}
+}
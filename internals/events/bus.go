@@ -0,0 +1,60 @@
+// Package events delivers the domain events in the Postgres outbox to their
+// subscribers: handlers inside this process and, through a queue of their
+// own, signed outgoing webhooks. Delivery is at least once, so every
+// subscriber must cope with seeing an event again; the event id identifies
+// repeats.
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+// Handler reacts to an event. A returned error has the event delivered again
+// later, to every subscriber.
+type Handler func(ctx context.Context, event model.DomainEvent) error
+
+// Bus hands events to the handlers subscribed to their type.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	all      []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// SubscribeAll subscribes handler to every event type.
+func (b *Bus) SubscribeAll(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, handler)
+}
+
+// Publish calls every handler subscribed to the event, even after one has
+// failed, and returns their errors joined.
+func (b *Bus) Publish(ctx context.Context, event model.DomainEvent) error {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[event.Type])+len(b.all))
+	handlers = append(handlers, b.handlers[event.Type]...)
+	handlers = append(handlers, b.all...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
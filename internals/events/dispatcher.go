@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+)
+
+// Dispatcher publishes the events in the outbox on a Bus, oldest first. An
+// event is only marked delivered once every subscriber has handled it; when
+// one fails, the event is published to all of them again later. Webhooks are
+// only queued here, and are sent by the WebhookDispatcher.
+type Dispatcher struct {
+	outbox          repositories.DomainEventRepository
+	bus             *Bus
+	pollInterval    time.Duration
+	batchSize       int
+	deliveryTimeout time.Duration
+	maxAttempts     int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+}
+
+func NewDispatcher(outbox repositories.DomainEventRepository, bus *Bus, cfg config.EventConfig) *Dispatcher {
+	return &Dispatcher{
+		outbox:          outbox,
+		bus:             bus,
+		pollInterval:    cfg.PollInterval,
+		batchSize:       cfg.BatchSize,
+		deliveryTimeout: cfg.DeliveryTimeout,
+		maxAttempts:     cfg.MaxAttempts,
+		retryBackoff:    cfg.RetryBackoff,
+		maxRetryBackoff: cfg.MaxRetryBackoff,
+	}
+}
+
+// Run delivers due events until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		// Keep going while batches come back full, then wait for the next poll.
+		for {
+			claimed, err := d.DispatchDue(ctx)
+			if err != nil {
+				log.Printf("events: %v", err)
+				break
+			}
+			if claimed < d.batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue delivers one batch of due events and returns how many it took
+// from the outbox.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// A claimed event is only retried by another dispatcher once every event
+	// in the batch could have timed out.
+	lease := time.Duration(d.batchSize) * d.deliveryTimeout
+	due, err := d.outbox.ClaimDueEvents(d.batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		d.deliver(ctx, event)
+	}
+	return len(due), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event model.DomainEvent) {
+	deliverCtx, cancel := context.WithTimeout(ctx, d.deliveryTimeout)
+	err := d.bus.Publish(deliverCtx, event)
+	cancel()
+	switch {
+	case err == nil:
+		d.record(d.outbox.MarkDelivered(event.ID))
+	case event.Attempts >= d.maxAttempts:
+		d.record(d.outbox.MarkFailed(event.ID, err.Error()))
+	default:
+		nextAttemptAt := time.Now().Add(backoff(d.retryBackoff, d.maxRetryBackoff, event.Attempts))
+		d.record(d.outbox.MarkRetry(event.ID, nextAttemptAt, err.Error()))
+	}
+}
+
+// backoff is how long to wait after the given number of failed attempts:
+// base after the first, doubling after each further one up to limit.
+func backoff(base, limit time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+func (d *Dispatcher) record(err error) {
+	if err != nil {
+		log.Printf("events: %v", err)
+	}
+}
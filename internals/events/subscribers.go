@@ -0,0 +1,22 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+)
+
+// RevokePasswordResets deletes the outstanding password reset tokens of a
+// deactivated user. They cannot be redeemed while the user is deactivated,
+// and must not become usable again should the user be reactivated.
+func RevokePasswordResets(resets repositories.PasswordResetRepository) Handler {
+	return func(ctx context.Context, event model.DomainEvent) error {
+		var payload model.UserDeactivatedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return err
+		}
+		return resets.DeletePasswordResetTokensByUser(payload.UserID.String())
+	}
+}
@@ -0,0 +1,203 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+)
+
+// Headers sent with every webhook request. The signature covers the
+// timestamp and the body, so receivers can reject stale or replayed
+// requests. A replayed or retried delivery keeps its event and delivery ids.
+const (
+	EventIDHeader    = "X-Event-Id"
+	EventTypeHeader  = "X-Event-Type"
+	DeliveryIDHeader = "X-Webhook-Delivery"
+	TimestampHeader  = "X-Webhook-Timestamp"
+	SignatureHeader  = "X-Webhook-Signature"
+)
+
+// maxErrorBody bounds how much of a receiver's error response is kept.
+const maxErrorBody = 512
+
+// WebhookPayload is the JSON body posted for an event.
+type WebhookPayload struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// Sign is the value of the signature header: the hex HMAC-SHA256, keyed with
+// secret, of the timestamp, a dot and the body, prefixed with "sha256=".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// EnqueueWebhooks is the subscriber that queues a delivery of every event to
+// each webhook subscription wanting it. Queueing is idempotent, so an event
+// published again is not delivered twice.
+func EnqueueWebhooks(deliveries repositories.WebhookRepository) Handler {
+	return func(ctx context.Context, event model.DomainEvent) error {
+		_, err := deliveries.EnqueueDeliveries(event)
+		return err
+	}
+}
+
+// WebhookDispatcher sends queued webhook deliveries as they fall due. Each
+// delivery is retried on its own, so one failing receiver holds up no other.
+// Delivery is at least once: a request the receiver accepted but that could
+// not be recorded as delivered is sent again.
+type WebhookDispatcher struct {
+	deliveries      repositories.WebhookRepository
+	client          *http.Client
+	pollInterval    time.Duration
+	batchSize       int
+	timeout         time.Duration
+	maxAttempts     int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+}
+
+// NewWebhookDispatcher sends requests with client, http.DefaultClient when nil.
+func NewWebhookDispatcher(deliveries repositories.WebhookRepository, client *http.Client,
+	cfg config.WebhookConfig) *WebhookDispatcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookDispatcher{
+		deliveries:      deliveries,
+		client:          client,
+		pollInterval:    cfg.PollInterval,
+		batchSize:       cfg.BatchSize,
+		timeout:         cfg.Timeout,
+		maxAttempts:     cfg.MaxAttempts,
+		retryBackoff:    cfg.RetryBackoff,
+		maxRetryBackoff: cfg.MaxRetryBackoff,
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		// Keep going while batches come back full, then wait for the next poll.
+		for {
+			claimed, err := d.DispatchDue(ctx)
+			if err != nil {
+				log.Printf("webhooks: %v", err)
+				break
+			}
+			if claimed < d.batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due deliveries and returns how many it took
+// from the queue.
+func (d *WebhookDispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// A claimed delivery is only retried by another dispatcher once every
+	// delivery in the batch could have timed out.
+	lease := time.Duration(d.batchSize) * d.timeout
+	due, err := d.deliveries.ClaimDueDeliveries(d.batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, pending := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		d.send(ctx, pending)
+	}
+	return len(due), nil
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, pending model.PendingWebhookDelivery) {
+	started := time.Now()
+	status, err := d.post(ctx, pending)
+	attempt := model.WebhookAttempt{
+		DeliveryID:     pending.Delivery.ID,
+		ResponseStatus: status,
+		Duration:       time.Since(started),
+	}
+	switch {
+	case err == nil:
+		err = d.deliveries.FinishAttempt(attempt, model.WebhookDeliveryDelivered, time.Now())
+	case pending.Delivery.Attempts >= d.maxAttempts:
+		attempt.Error = err.Error()
+		err = d.deliveries.FinishAttempt(attempt, model.WebhookDeliveryFailed, time.Now())
+	default:
+		attempt.Error = err.Error()
+		nextAttemptAt := time.Now().Add(backoff(d.retryBackoff, d.maxRetryBackoff, pending.Delivery.Attempts))
+		err = d.deliveries.FinishAttempt(attempt, model.WebhookDeliveryPending, nextAttemptAt)
+	}
+	if err != nil {
+		log.Printf("webhooks: %v", err)
+	}
+}
+
+// post sends the delivery and returns the response status, 0 if there was
+// no response. Any status other than 2xx is an error.
+func (d *WebhookDispatcher) post(ctx context.Context, pending model.PendingWebhookDelivery) (int, error) {
+	event := pending.Event
+	body, err := json.Marshal(WebhookPayload{
+		ID:          event.ID,
+		Type:        event.Type,
+		AggregateID: event.AggregateID,
+		OccurredAt:  event.OccurredAt,
+		Data:        event.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, pending.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventIDHeader, strconv.FormatInt(event.ID, 10))
+	request.Header.Set(EventTypeHeader, event.Type)
+	request.Header.Set(DeliveryIDHeader, strconv.FormatInt(pending.Delivery.ID, 10))
+	request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(SignatureHeader, Sign(pending.Subscription.Secret, timestamp, body))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach webhook: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return response.StatusCode, fmt.Errorf("webhook returned %s: %s", response.Status, bytes.TrimSpace(body))
+	}
+	return response.StatusCode, nil
+}
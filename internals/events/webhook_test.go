@@ -0,0 +1,327 @@
+package events
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+)
+
+const testSecret = "whsec_test"
+
+// fakeDeliveries is an in-memory delivery queue. Deliveries fall due by the
+// fake's clock, which the tests move forward instead of waiting out backoffs.
+type fakeDeliveries struct {
+	repositories.WebhookRepository
+
+	mu      sync.Mutex
+	now     time.Time
+	pending []*model.PendingWebhookDelivery
+	// delays are how far in the future FinishAttempt was asked to schedule
+	// the next attempt.
+	delays []time.Duration
+}
+
+func (f *fakeDeliveries) ClaimDueDeliveries(limit int, lease time.Duration) ([]model.PendingWebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var claimed []model.PendingWebhookDelivery
+	for _, pending := range f.pending {
+		delivery := &pending.Delivery
+		if len(claimed) == limit || delivery.Status != model.WebhookDeliveryPending || delivery.NextAttemptAt.After(f.now) {
+			continue
+		}
+		delivery.Attempts++
+		delivery.NextAttemptAt = f.now.Add(lease)
+		claimed = append(claimed, *pending)
+	}
+	return claimed, nil
+}
+
+func (f *fakeDeliveries) FinishAttempt(attempt model.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delay := time.Until(nextAttemptAt)
+	f.delays = append(f.delays, delay)
+	for _, pending := range f.pending {
+		if pending.Delivery.ID == attempt.DeliveryID {
+			pending.Delivery.Status = status
+			pending.Delivery.NextAttemptAt = f.now.Add(delay)
+			pending.Delivery.LastError = attempt.Error
+			pending.Delivery.LastResponseStatus = attempt.ResponseStatus
+		}
+	}
+	return nil
+}
+
+func (f *fakeDeliveries) ReplayDelivery(id int64) (*model.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, pending := range f.pending {
+		delivery := &pending.Delivery
+		if delivery.ID == id && delivery.Status != model.WebhookDeliveryPending {
+			delivery.Status = model.WebhookDeliveryPending
+			delivery.Attempts = 0
+			delivery.NextAttemptAt = f.now
+			replayed := *delivery
+			return &replayed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDeliveries) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func (f *fakeDeliveries) delivery(id int64) model.WebhookDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, pending := range f.pending {
+		if pending.Delivery.ID == id {
+			return pending.Delivery
+		}
+	}
+	return model.WebhookDelivery{}
+}
+
+// receivedRequest is what the test receiver saw of one request.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver answers with statuses in turn, then 204 once they run out.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, receivedRequest{header: request.Header.Clone(), body: body})
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.mu.Unlock()
+	if status >= 400 {
+		http.Error(w, "receiver is down", status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func newWebhookTest(t *testing.T, statuses ...int) (*fakeDeliveries, *receiver, *WebhookDispatcher) {
+	t.Helper()
+	receiver := &receiver{statuses: statuses}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	now := time.Now()
+	deliveries := &fakeDeliveries{
+		now: now,
+		pending: []*model.PendingWebhookDelivery{{
+			Delivery: model.WebhookDelivery{ID: 41, SubscriptionID: 5, EventID: 1001, EventType: "patient.registered",
+				Status: model.WebhookDeliveryPending, NextAttemptAt: now, CreatedAt: now},
+			Subscription: model.WebhookSubscription{ID: 5, URL: server.URL + "/hooks", EventTypes: []string{"patient.registered"},
+				Secret: testSecret, Active: true},
+			Event: model.DomainEvent{ID: 1001, Type: "patient.registered", AggregateID: "6f1c2f4e-0a51-4c0e-9a57-3f1f6d3f0b01",
+				Payload:    json.RawMessage(`{"patient_id":"6f1c2f4e-0a51-4c0e-9a57-3f1f6d3f0b01"}`),
+				OccurredAt: time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC)},
+		}},
+	}
+	dispatcher := NewWebhookDispatcher(deliveries, server.Client(), config.WebhookConfig{
+		PollInterval:    time.Second,
+		BatchSize:       10,
+		Timeout:         5 * time.Second,
+		MaxAttempts:     4,
+		RetryBackoff:    time.Minute,
+		MaxRetryBackoff: 3 * time.Minute,
+	})
+	return deliveries, receiver, dispatcher
+}
+
+func dispatch(t *testing.T, dispatcher *WebhookDispatcher, want int) {
+	t.Helper()
+	claimed, err := dispatcher.DispatchDue(context.Background())
+	if err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	if claimed != want {
+		t.Fatalf("DispatchDue claimed %d deliveries, want %d", claimed, want)
+	}
+}
+
+// checkSignature verifies a request the way a receiver would, without the
+// package's own Sign.
+func checkSignature(t *testing.T, request receivedRequest) {
+	t.Helper()
+	timestamp := request.header.Get(TimestampHeader)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Fatalf("%s = %q: %v", TimestampHeader, timestamp, err)
+	}
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(request.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := request.header.Get(SignatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+}
+
+func TestWebhookRequestIsSigned(t *testing.T) {
+	deliveries, receiver, dispatcher := newWebhookTest(t)
+	dispatch(t, dispatcher, 1)
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	checkSignature(t, request)
+	for header, want := range map[string]string{
+		"Content-Type":   "application/json",
+		EventIDHeader:    "1001",
+		EventTypeHeader:  "patient.registered",
+		DeliveryIDHeader: "41",
+	} {
+		if got := request.header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("body: %v", err)
+	}
+	if payload.ID != 1001 || payload.Type != "patient.registered" ||
+		string(payload.Data) != `{"patient_id":"6f1c2f4e-0a51-4c0e-9a57-3f1f6d3f0b01"}` {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	// A body signed with another secret or altered in transit does not verify.
+	tampered := append([]byte(nil), request.body...)
+	tampered[len(tampered)-2] = 'X'
+	timestamp, _ := strconv.ParseInt(request.header.Get(TimestampHeader), 10, 64)
+	if Sign(testSecret, timestamp, tampered) == request.header.Get(SignatureHeader) {
+		t.Error("a tampered body has the same signature")
+	}
+	if Sign("another secret", timestamp, request.body) == request.header.Get(SignatureHeader) {
+		t.Error("another secret gives the same signature")
+	}
+
+	if delivery := deliveries.delivery(41); delivery.Status != model.WebhookDeliveryDelivered {
+		t.Errorf("delivery status = %q, want delivered", delivery.Status)
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	deliveries, receiver, dispatcher := newWebhookTest(t,
+		http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	// Each failure schedules the next attempt after the retry backoff,
+	// doubling up to the maximum.
+	for i, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		dispatch(t, dispatcher, 1)
+		delivery := deliveries.delivery(41)
+		if delivery.Status != model.WebhookDeliveryPending || delivery.Attempts != i+1 {
+			t.Fatalf("after failure %d: status %q with %d attempts, want pending with %d",
+				i+1, delivery.Status, delivery.Attempts, i+1)
+		}
+		if delivery.LastResponseStatus != http.StatusInternalServerError || delivery.LastError == "" {
+			t.Errorf("after failure %d: last response %d, error %q", i+1, delivery.LastResponseStatus, delivery.LastError)
+		}
+		delay := deliveries.delays[i]
+		if delay > wantDelay || delay < wantDelay-5*time.Second {
+			t.Errorf("retry %d scheduled in %v, want %v", i+1, delay, wantDelay)
+		}
+
+		// Not due again until the backoff has passed.
+		deliveries.advance(wantDelay - time.Second)
+		dispatch(t, dispatcher, 0)
+		deliveries.advance(time.Second)
+	}
+
+	dispatch(t, dispatcher, 1)
+	delivery := deliveries.delivery(41)
+	if delivery.Status != model.WebhookDeliveryDelivered || delivery.Attempts != 4 {
+		t.Errorf("status %q after %d attempts, want delivered after 4", delivery.Status, delivery.Attempts)
+	}
+	requests := receiver.received()
+	if len(requests) != 4 {
+		t.Fatalf("receiver got %d requests, want 4", len(requests))
+	}
+	for _, request := range requests {
+		checkSignature(t, request)
+		if request.header.Get(EventIDHeader) != "1001" || request.header.Get(DeliveryIDHeader) != "41" {
+			t.Errorf("retry sent as event %s delivery %s, want event 1001 delivery 41",
+				request.header.Get(EventIDHeader), request.header.Get(DeliveryIDHeader))
+		}
+	}
+}
+
+func TestWebhookFailsAfterMaxAttempts(t *testing.T) {
+	deliveries, _, dispatcher := newWebhookTest(t, http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError)
+	for range 4 {
+		deliveries.advance(time.Hour)
+		dispatch(t, dispatcher, 1)
+	}
+	delivery := deliveries.delivery(41)
+	if delivery.Status != model.WebhookDeliveryFailed {
+		t.Errorf("status = %q after 4 failed attempts, want failed", delivery.Status)
+	}
+	deliveries.advance(time.Hour)
+	dispatch(t, dispatcher, 0)
+}
+
+func TestReplayedWebhookKeepsIDs(t *testing.T) {
+	deliveries, receiver, dispatcher := newWebhookTest(t)
+	dispatch(t, dispatcher, 1)
+
+	replayed, err := deliveries.ReplayDelivery(41)
+	if err != nil || replayed == nil {
+		t.Fatalf("ReplayDelivery = %v, %v", replayed, err)
+	}
+	dispatch(t, dispatcher, 1)
+
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(requests))
+	}
+	original, replay := requests[0], requests[1]
+	checkSignature(t, replay)
+	for _, header := range []string{EventIDHeader, EventTypeHeader, DeliveryIDHeader} {
+		if original.header.Get(header) != replay.header.Get(header) {
+			t.Errorf("replay changed %s from %q to %q", header, original.header.Get(header), replay.header.Get(header))
+		}
+	}
+	if string(original.body) != string(replay.body) {
+		t.Errorf("replay body %s differs from the original %s", replay.body, original.body)
+	}
+	if delivery := deliveries.delivery(41); delivery.Status != model.WebhookDeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("replayed delivery is %q after %d attempts, want delivered after 1", delivery.Status, delivery.Attempts)
+	}
+}
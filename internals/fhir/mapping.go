@@ -0,0 +1,439 @@
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidResource is returned when an incoming resource cannot be mapped.
+var ErrInvalidResource = errors.New("invalid FHIR resource")
+
+// Conditions come from two tables, so their ids carry the source as a prefix.
+const (
+	diagnosisPrefix = "diagnosis-"
+	problemPrefix   = "problem-"
+)
+
+// Mapper converts between internal models and FHIR resources. IdentifierSystem
+// namespaces the identifiers issued by this system; BaseURL is used for
+// absolute references in bundles.
+type Mapper struct {
+	IdentifierSystem string
+	BaseURL          string
+}
+
+func NewMapper(identifierSystem, baseURL string) Mapper {
+	return Mapper{IdentifierSystem: identifierSystem, BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// AgeExtensionURL carries the patient's age, which is all the system records
+// in place of a birth date.
+func (m Mapper) AgeExtensionURL() string {
+	return m.BaseURL + "/StructureDefinition/patient-age"
+}
+
+// FullURL is the absolute URL of a resource, used for bundle entries.
+func (m Mapper) FullURL(resourceType, id string) string {
+	return m.BaseURL + "/" + resourceType + "/" + id
+}
+
+func (m Mapper) Patient(patient model.Patient) Patient {
+	active := true
+	age := patient.Age
+	resource := Patient{
+		ResourceType: "Patient",
+		ID:           patient.ID.String(),
+		Extension:    []Extension{{URL: m.AgeExtensionURL(), ValueInteger: &age}},
+		Identifier:   []Identifier{{Use: "official", System: m.IdentifierSystem, Value: patient.ID.String()}},
+		Active:       &active,
+		Name:         []HumanName{humanName(patient.Name)},
+		Gender:       fhirGender(patient.Gender),
+	}
+	if patient.PhoneNumber != "" {
+		resource.Telecom = []ContactPoint{{System: "phone", Value: patient.PhoneNumber, Use: "mobile"}}
+	}
+	return resource
+}
+
+// ToPatient maps an incoming Patient. The id is taken from our own identifier
+// if present, otherwise from the resource id, and is left nil for new patients
+// from other systems. Age comes from birthDate relative to asOf, or from the
+// age extension.
+func (m Mapper) ToPatient(resource Patient, asOf time.Time) (model.Patient, error) {
+	if resource.ResourceType != "Patient" {
+		return model.Patient{}, fmt.Errorf("%w: expected resourceType Patient", ErrInvalidResource)
+	}
+	patient := model.Patient{
+		Name:   nameText(resource.Name),
+		Gender: modelGender(resource.Gender),
+	}
+	for _, identifier := range resource.Identifier {
+		if identifier.System == m.IdentifierSystem {
+			if id, err := uuid.Parse(identifier.Value); err == nil {
+				patient.ID = id
+			}
+		}
+	}
+	if id, err := uuid.Parse(resource.ID); patient.ID == uuid.Nil && err == nil {
+		patient.ID = id
+	}
+	for _, telecom := range resource.Telecom {
+		if telecom.System == "phone" && patient.PhoneNumber == "" {
+			patient.PhoneNumber = telecom.Value
+		}
+	}
+
+	switch {
+	case resource.BirthDate != "":
+		birthDate, err := parseDate(resource.BirthDate)
+		if err != nil {
+			return model.Patient{}, fmt.Errorf("%w: birthDate: %v", ErrInvalidResource, err)
+		}
+		patient.Age = yearsBetween(birthDate, asOf)
+	default:
+		for _, extension := range resource.Extension {
+			if extension.URL == m.AgeExtensionURL() && extension.ValueInteger != nil {
+				patient.Age = *extension.ValueInteger
+			}
+		}
+	}
+
+	if patient.Name == "" {
+		return model.Patient{}, fmt.Errorf("%w: patient name is required", ErrInvalidResource)
+	}
+	if patient.PhoneNumber == "" {
+		return model.Patient{}, fmt.Errorf("%w: a phone telecom is required", ErrInvalidResource)
+	}
+	return patient, nil
+}
+
+func (m Mapper) Practitioner(user model.User) Practitioner {
+	active := true
+	resource := Practitioner{
+		ResourceType: "Practitioner",
+		ID:           user.ID.String(),
+		Identifier:   []Identifier{{Use: "official", System: m.IdentifierSystem, Value: user.ID.String()}},
+		Active:       &active,
+		Name:         []HumanName{humanName(user.Name)},
+	}
+	resource.Name[0].Prefix = []string{"Dr."}
+	if user.PhoneNumber != "" {
+		resource.Telecom = []ContactPoint{{System: "phone", Value: user.PhoneNumber, Use: "work"}}
+	}
+	return resource
+}
+
+// ToUser maps a Practitioner to a doctor account. It carries no credentials.
+func (m Mapper) ToUser(resource Practitioner) (model.User, error) {
+	if resource.ResourceType != "Practitioner" {
+		return model.User{}, fmt.Errorf("%w: expected resourceType Practitioner", ErrInvalidResource)
+	}
+	user := model.User{Name: nameText(resource.Name), Role: model.RoleDoctor}
+	if id, err := uuid.Parse(resource.ID); err == nil {
+		user.ID = id
+	}
+	for _, telecom := range resource.Telecom {
+		if telecom.System == "phone" && user.PhoneNumber == "" {
+			user.PhoneNumber = telecom.Value
+		}
+	}
+	if user.Name == "" {
+		return model.User{}, fmt.Errorf("%w: practitioner name is required", ErrInvalidResource)
+	}
+	return user, nil
+}
+
+// DiagnosisCondition maps a diagnosis made during a visit to an
+// encounter-diagnosis Condition.
+func (m Mapper) DiagnosisCondition(diagnosis model.Diagnosis) Condition {
+	resource := Condition{
+		ResourceType:       "Condition",
+		ID:                 diagnosisPrefix + strconv.Itoa(diagnosis.ID),
+		ClinicalStatus:     codeable(SystemConditionClinical, "active", "Active"),
+		VerificationStatus: codeable(SystemConditionVerification, "confirmed", "Confirmed"),
+		Category:           []CodeableConcept{*codeable(SystemConditionCategory, "encounter-diagnosis", "Encounter Diagnosis")},
+		Code:               &CodeableConcept{Text: diagnosis.Description},
+		Subject:            Reference{Reference: "Patient/" + diagnosis.PatientID.String()},
+		RecordedDate:       formatDateTime(diagnosis.CreatedAt),
+		Recorder:           &Reference{Reference: "Practitioner/" + diagnosis.DoctorID.String()},
+	}
+	if diagnosis.EncounterID.Valid {
+		resource.Encounter = &Reference{Reference: "Encounter/" + diagnosis.EncounterID.UUID.String()}
+	}
+	return resource
+}
+
+// ProblemCondition maps a chronic condition to a problem-list-item Condition.
+func (m Mapper) ProblemCondition(condition model.ChronicCondition) Condition {
+	resource := Condition{
+		ResourceType:   "Condition",
+		ID:             problemPrefix + strconv.Itoa(condition.ID),
+		ClinicalStatus: codeable(SystemConditionClinical, condition.Status, ""),
+		Category:       []CodeableConcept{*codeable(SystemConditionCategory, "problem-list-item", "Problem List Item")},
+		Code:           &CodeableConcept{Text: condition.Name},
+		Subject:        Reference{Reference: "Patient/" + condition.PatientID.String()},
+		RecordedDate:   formatDateTime(condition.CreatedAt),
+	}
+	if condition.OnsetDate != nil {
+		resource.OnsetDateTime = condition.OnsetDate.Format(time.DateOnly)
+	}
+	if condition.RecordedBy != uuid.Nil {
+		resource.Recorder = &Reference{Reference: "Practitioner/" + condition.RecordedBy.String()}
+	}
+	if condition.Notes != "" {
+		resource.Note = []Annotation{{Text: condition.Notes}}
+	}
+	return resource
+}
+
+// ToDiagnosis maps an incoming Condition to a diagnosis. Subject and recorder
+// are required; the recorder must be a practitioner of this system.
+func (m Mapper) ToDiagnosis(resource Condition) (model.Diagnosis, error) {
+	if resource.ResourceType != "Condition" {
+		return model.Diagnosis{}, fmt.Errorf("%w: expected resourceType Condition", ErrInvalidResource)
+	}
+	if resource.Code == nil || conceptText(*resource.Code) == "" {
+		return model.Diagnosis{}, fmt.Errorf("%w: condition code is required", ErrInvalidResource)
+	}
+	patientID, err := ParseReference(resource.Subject, "Patient")
+	if err != nil {
+		return model.Diagnosis{}, err
+	}
+	if resource.Recorder == nil {
+		return model.Diagnosis{}, fmt.Errorf("%w: condition recorder is required", ErrInvalidResource)
+	}
+	doctorID, err := ParseReference(*resource.Recorder, "Practitioner")
+	if err != nil {
+		return model.Diagnosis{}, err
+	}
+	diagnosis := model.Diagnosis{
+		PatientID:   patientID,
+		DoctorID:    doctorID,
+		Description: conceptText(*resource.Code),
+	}
+	if resource.Encounter != nil {
+		encounterID, err := ParseReference(*resource.Encounter, "Encounter")
+		if err != nil {
+			return model.Diagnosis{}, err
+		}
+		diagnosis.EncounterID = uuid.NullUUID{UUID: encounterID, Valid: true}
+	}
+	return diagnosis, nil
+}
+
+var encounterStatuses = map[string]string{
+	model.EncounterStatusCheckedIn:      "arrived",
+	model.EncounterStatusInConsultation: "in-progress",
+	model.EncounterStatusDischarged:     "finished",
+	model.EncounterStatusCancelled:      "cancelled",
+}
+
+func (m Mapper) Encounter(encounter model.Encounter) Encounter {
+	resource := Encounter{
+		ResourceType: "Encounter",
+		ID:           encounter.ID.String(),
+		Status:       encounterStatuses[encounter.Status],
+		Class:        Coding{System: SystemActCode, Code: "AMB", Display: "ambulatory"},
+		Subject:      &Reference{Reference: "Patient/" + encounter.PatientID.String()},
+		Participant: []EncounterParticipant{{
+			Type:       []CodeableConcept{*codeable(SystemParticipationType, "ATND", "attender")},
+			Individual: &Reference{Reference: "Practitioner/" + encounter.DoctorID.String()},
+		}},
+		Period: &Period{Start: formatDateTime(encounter.CheckedInAt)},
+	}
+	if encounter.DischargedAt != nil {
+		resource.Period.End = formatDateTime(*encounter.DischargedAt)
+	}
+	if encounter.ChiefComplaint != "" {
+		resource.ReasonCode = []CodeableConcept{{Text: encounter.ChiefComplaint}}
+	}
+	if encounter.DischargeNotes != "" {
+		resource.Hospitalization = &EncounterHospitalization{
+			DischargeDisposition: &CodeableConcept{Text: encounter.DischargeNotes},
+		}
+	}
+	return resource
+}
+
+func (m Mapper) ToEncounter(resource Encounter) (model.Encounter, error) {
+	if resource.ResourceType != "Encounter" {
+		return model.Encounter{}, fmt.Errorf("%w: expected resourceType Encounter", ErrInvalidResource)
+	}
+	encounter := model.Encounter{}
+	for status, fhirStatus := range encounterStatuses {
+		if fhirStatus == resource.Status {
+			encounter.Status = status
+		}
+	}
+	if encounter.Status == "" {
+		return model.Encounter{}, fmt.Errorf("%w: unsupported encounter status %q", ErrInvalidResource, resource.Status)
+	}
+	if id, err := uuid.Parse(resource.ID); err == nil {
+		encounter.ID = id
+	}
+	if resource.Subject == nil {
+		return model.Encounter{}, fmt.Errorf("%w: encounter subject is required", ErrInvalidResource)
+	}
+	patientID, err := ParseReference(*resource.Subject, "Patient")
+	if err != nil {
+		return model.Encounter{}, err
+	}
+	encounter.PatientID = patientID
+	for _, participant := range resource.Participant {
+		if participant.Individual != nil {
+			if doctorID, err := ParseReference(*participant.Individual, "Practitioner"); err == nil {
+				encounter.DoctorID = doctorID
+				break
+			}
+		}
+	}
+	if resource.Period != nil {
+		if encounter.CheckedInAt, err = parseDateTime(resource.Period.Start); err != nil {
+			return model.Encounter{}, fmt.Errorf("%w: period.start: %v", ErrInvalidResource, err)
+		}
+		if resource.Period.End != "" {
+			end, err := parseDateTime(resource.Period.End)
+			if err != nil {
+				return model.Encounter{}, fmt.Errorf("%w: period.end: %v", ErrInvalidResource, err)
+			}
+			encounter.DischargedAt = &end
+		}
+	}
+	if len(resource.ReasonCode) > 0 {
+		encounter.ChiefComplaint = conceptText(resource.ReasonCode[0])
+	}
+	if resource.Hospitalization != nil && resource.Hospitalization.DischargeDisposition != nil {
+		encounter.DischargeNotes = conceptText(*resource.Hospitalization.DischargeDisposition)
+	}
+	return encounter, nil
+}
+
+// ParseReference returns the id of a relative reference such as "Patient/<id>".
+func ParseReference(reference Reference, resourceType string) (uuid.UUID, error) {
+	id, ok := strings.CutPrefix(reference.Reference, resourceType+"/")
+	if !ok {
+		return uuid.Nil, fmt.Errorf("%w: expected a %s reference, got %q", ErrInvalidResource, resourceType, reference.Reference)
+	}
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid %s id %q", ErrInvalidResource, resourceType, id)
+	}
+	return parsed, nil
+}
+
+// ParseConditionID splits a Condition id into its source and numeric id.
+// problem is true for problem-list entries and false for diagnoses.
+func ParseConditionID(id string) (problem bool, number int, err error) {
+	rest, problem := strings.CutPrefix(id, problemPrefix)
+	if !problem {
+		var ok bool
+		if rest, ok = strings.CutPrefix(id, diagnosisPrefix); !ok {
+			return false, 0, fmt.Errorf("%w: unknown condition id %q", ErrInvalidResource, id)
+		}
+	}
+	number, err = strconv.Atoi(rest)
+	if err != nil {
+		return false, 0, fmt.Errorf("%w: unknown condition id %q", ErrInvalidResource, id)
+	}
+	return problem, number, nil
+}
+
+func codeable(system, code, display string) *CodeableConcept {
+	return &CodeableConcept{Coding: []Coding{{System: system, Code: code, Display: display}}}
+}
+
+func conceptText(concept CodeableConcept) string {
+	if concept.Text != "" {
+		return concept.Text
+	}
+	for _, coding := range concept.Coding {
+		if coding.Display != "" {
+			return coding.Display
+		}
+	}
+	return ""
+}
+
+// humanName splits a full name on its last space into given and family names.
+func humanName(name string) HumanName {
+	result := HumanName{Use: "official", Text: name}
+	fields := strings.Fields(name)
+	if len(fields) > 1 {
+		result.Family = fields[len(fields)-1]
+		result.Given = fields[:len(fields)-1]
+	} else if len(fields) == 1 {
+		result.Given = fields
+	}
+	return result
+}
+
+func nameText(names []HumanName) string {
+	for _, name := range names {
+		if name.Text != "" {
+			return name.Text
+		}
+		parts := append(append([]string{}, name.Given...), name.Family)
+		if text := strings.TrimSpace(strings.Join(parts, " ")); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+func fhirGender(gender string) string {
+	switch gender {
+	case "male", "female":
+		return gender
+	case "":
+		return "unknown"
+	default:
+		return "other"
+	}
+}
+
+func modelGender(gender string) string {
+	switch gender {
+	case "male", "female":
+		return gender
+	default:
+		return "other"
+	}
+}
+
+func formatDateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func parseDateTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return parseDate(s)
+}
+
+// parseDate accepts the FHIR date forms YYYY, YYYY-MM and YYYY-MM-DD.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, "2006-01", "2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func yearsBetween(from, to time.Time) int {
+	years := to.Year() - from.Year()
+	if to.Month() < from.Month() || (to.Month() == from.Month() && to.Day() < from.Day()) {
+		years--
+	}
+	return max(years, 0)
+}
@@ -0,0 +1,376 @@
+package fhir
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+const (
+	testSystem  = "urn:example:pms"
+	testBaseURL = "https://pms.example/fhir"
+)
+
+var (
+	patientID   = uuid.MustParse("6f1c2f4e-0a51-4c0e-9a57-3f1f6d3f0b01")
+	doctorID    = uuid.MustParse("0b7d8c5a-2e7e-4c8e-8f3b-8a4e5d6c7b02")
+	encounterID = uuid.MustParse("a3e4f5d6-7b8c-4d9e-8f0a-1b2c3d4e5f03")
+	checkedIn   = time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC)
+	discharged  = time.Date(2024, time.March, 5, 10, 15, 0, 0, time.UTC)
+	onset       = time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	referencePattern = regexp.MustCompile(`^(Patient|Practitioner|Encounter)/[0-9a-f-]{36}$`)
+)
+
+// roundTrip marshals resource, checks the JSON carries resourceType and the
+// required elements, and unmarshals it into out.
+func roundTrip(t *testing.T, resource any, resourceType string, required []string, out any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(resource)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal into map: %v", err)
+	}
+	if fields["resourceType"] != resourceType {
+		t.Errorf("resourceType = %v, want %s", fields["resourceType"], resourceType)
+	}
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("%s is missing %q in %s", resourceType, name, data)
+		}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return fields
+}
+
+func checkReference(t *testing.T, name string, reference *Reference, want string) {
+	t.Helper()
+	if reference == nil {
+		t.Errorf("%s reference is missing", name)
+		return
+	}
+	if !referencePattern.MatchString(reference.Reference) {
+		t.Errorf("%s reference %q is not of the form Type/<uuid>", name, reference.Reference)
+	}
+	if reference.Reference != want {
+		t.Errorf("%s reference = %q, want %q", name, reference.Reference, want)
+	}
+}
+
+func TestPatientRoundTrip(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL+"/")
+	tests := []struct {
+		name    string
+		patient model.Patient
+		gender  string
+	}{
+		{"female with phone", model.Patient{ID: patientID, Name: "Asha Verma", Age: 34, Gender: "female", PhoneNumber: "+919800000001"}, "female"},
+		{"single name", model.Patient{ID: patientID, Name: "Madonna", Age: 65, Gender: "other", PhoneNumber: "+15550100"}, "other"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var decoded Patient
+			fields := roundTrip(t, mapper.Patient(test.patient), "Patient",
+				[]string{"id", "identifier", "name", "telecom", "gender", "extension"}, &decoded)
+			if fields["gender"] != test.gender {
+				t.Errorf("gender = %v, want %s", fields["gender"], test.gender)
+			}
+			if len(decoded.Identifier) != 1 || decoded.Identifier[0].System != testSystem ||
+				decoded.Identifier[0].Value != patientID.String() {
+				t.Errorf("identifier = %+v, want %s|%s", decoded.Identifier, testSystem, patientID)
+			}
+			if len(decoded.Extension) != 1 || decoded.Extension[0].URL != testBaseURL+"/StructureDefinition/patient-age" {
+				t.Errorf("extension = %+v, want the age extension", decoded.Extension)
+			}
+
+			got, err := mapper.ToPatient(decoded, checkedIn)
+			if err != nil {
+				t.Fatalf("ToPatient: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.patient) {
+				t.Errorf("ToPatient = %+v, want %+v", got, test.patient)
+			}
+		})
+	}
+}
+
+func TestToPatientErrors(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL)
+	phone := []ContactPoint{{System: "phone", Value: "+919800000001"}}
+	name := []HumanName{{Text: "Asha Verma"}}
+	tests := []struct {
+		name     string
+		resource Patient
+	}{
+		{"wrong resource type", Patient{ResourceType: "Practitioner", Name: name, Telecom: phone}},
+		{"missing resource type", Patient{Name: name, Telecom: phone}},
+		{"no name", Patient{ResourceType: "Patient", Telecom: phone}},
+		{"blank name", Patient{ResourceType: "Patient", Name: []HumanName{{Given: []string{" "}}}, Telecom: phone}},
+		{"no phone", Patient{ResourceType: "Patient", Name: name}},
+		{"email only", Patient{ResourceType: "Patient", Name: name, Telecom: []ContactPoint{{System: "email", Value: "a@example.com"}}}},
+		{"invalid birth date", Patient{ResourceType: "Patient", Name: name, Telecom: phone, BirthDate: "05/03/1990"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := mapper.ToPatient(test.resource, checkedIn); !errors.Is(err, ErrInvalidResource) {
+				t.Errorf("err = %v, want ErrInvalidResource", err)
+			}
+		})
+	}
+}
+
+func TestToPatientAge(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL)
+	age := 40
+	tests := []struct {
+		name      string
+		birthDate string
+		extension []Extension
+		want      int
+	}{
+		{"birthday passed", "1990-03-01", nil, 34},
+		{"birthday ahead", "1990-03-06", nil, 33},
+		{"year only", "1990", nil, 34},
+		{"birth date wins over extension", "1990-03-01", []Extension{{URL: mapper.AgeExtensionURL(), ValueInteger: &age}}, 34},
+		{"age extension", "", []Extension{{URL: mapper.AgeExtensionURL(), ValueInteger: &age}}, 40},
+		{"foreign extension", "", []Extension{{URL: "urn:other", ValueInteger: &age}}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patient, err := mapper.ToPatient(Patient{
+				ResourceType: "Patient",
+				Name:         []HumanName{{Given: []string{"Asha"}, Family: "Verma"}},
+				Telecom:      []ContactPoint{{System: "phone", Value: "+919800000001"}},
+				BirthDate:    test.birthDate,
+				Extension:    test.extension,
+			}, checkedIn)
+			if err != nil {
+				t.Fatalf("ToPatient: %v", err)
+			}
+			if patient.Age != test.want {
+				t.Errorf("age = %d, want %d", patient.Age, test.want)
+			}
+			if patient.Name != "Asha Verma" {
+				t.Errorf("name = %q, want the given and family names joined", patient.Name)
+			}
+		})
+	}
+}
+
+func TestPractitionerRoundTrip(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL)
+	tests := []struct {
+		name string
+		user model.User
+	}{
+		{"with phone", model.User{ID: doctorID, Name: "Rohan Mehta", Role: model.RoleDoctor, PhoneNumber: "+919800000002"}},
+		{"without phone", model.User{ID: doctorID, Name: "Rohan Mehta", Role: model.RoleDoctor}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var decoded Practitioner
+			roundTrip(t, mapper.Practitioner(test.user), "Practitioner", []string{"id", "identifier", "name"}, &decoded)
+			if len(decoded.Name) != 1 || decoded.Name[0].Family != "Mehta" || !reflect.DeepEqual(decoded.Name[0].Prefix, []string{"Dr."}) {
+				t.Errorf("name = %+v, want family Mehta with prefix Dr.", decoded.Name)
+			}
+			got, err := mapper.ToUser(decoded)
+			if err != nil {
+				t.Fatalf("ToUser: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.user) {
+				t.Errorf("ToUser = %+v, want %+v", got, test.user)
+			}
+		})
+	}
+
+	if _, err := mapper.ToUser(Practitioner{ResourceType: "Practitioner"}); !errors.Is(err, ErrInvalidResource) {
+		t.Errorf("ToUser without a name: err = %v, want ErrInvalidResource", err)
+	}
+}
+
+func TestConditionRoundTrip(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL)
+	diagnosis := model.Diagnosis{
+		ID:          7,
+		PatientID:   patientID,
+		DoctorID:    doctorID,
+		EncounterID: uuid.NullUUID{UUID: encounterID, Valid: true},
+		Description: "Acute pharyngitis",
+		CreatedAt:   checkedIn,
+	}
+	problem := model.ChronicCondition{
+		ID:         3,
+		PatientID:  patientID,
+		Name:       "Type 2 diabetes",
+		Status:     "active",
+		OnsetDate:  &onset,
+		Notes:      "Diet controlled",
+		RecordedBy: doctorID,
+		CreatedAt:  checkedIn,
+	}
+	tests := []struct {
+		name      string
+		resource  Condition
+		id        string
+		problem   bool
+		number    int
+		category  string
+		encounter bool
+	}{
+		{"diagnosis", mapper.DiagnosisCondition(diagnosis), "diagnosis-7", false, 7, "encounter-diagnosis", true},
+		{"problem", mapper.ProblemCondition(problem), "problem-3", true, 3, "problem-list-item", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var decoded Condition
+			roundTrip(t, test.resource, "Condition",
+				[]string{"id", "clinicalStatus", "category", "code", "subject", "recordedDate", "recorder"}, &decoded)
+			if decoded.ID != test.id {
+				t.Errorf("id = %q, want %q", decoded.ID, test.id)
+			}
+			problem, number, err := ParseConditionID(decoded.ID)
+			if err != nil || problem != test.problem || number != test.number {
+				t.Errorf("ParseConditionID(%q) = %v, %d, %v", decoded.ID, problem, number, err)
+			}
+			if len(decoded.Category) != 1 || decoded.Category[0].Coding[0].Code != test.category ||
+				decoded.Category[0].Coding[0].System != SystemConditionCategory {
+				t.Errorf("category = %+v, want %s", decoded.Category, test.category)
+			}
+			checkReference(t, "subject", &decoded.Subject, "Patient/"+patientID.String())
+			checkReference(t, "recorder", decoded.Recorder, "Practitioner/"+doctorID.String())
+			if test.encounter {
+				checkReference(t, "encounter", decoded.Encounter, "Encounter/"+encounterID.String())
+			} else if decoded.Encounter != nil {
+				t.Errorf("encounter = %+v, want none", decoded.Encounter)
+			}
+			if decoded.RecordedDate != "2024-03-05T09:30:00Z" {
+				t.Errorf("recordedDate = %q", decoded.RecordedDate)
+			}
+		})
+	}
+
+	var decoded Condition
+	roundTrip(t, mapper.DiagnosisCondition(diagnosis), "Condition", nil, &decoded)
+	got, err := mapper.ToDiagnosis(decoded)
+	if err != nil {
+		t.Fatalf("ToDiagnosis: %v", err)
+	}
+	want := diagnosis
+	want.ID, want.CreatedAt = 0, time.Time{}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToDiagnosis = %+v, want %+v", got, want)
+	}
+}
+
+func TestToDiagnosisErrors(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL)
+	subject := Reference{Reference: "Patient/" + patientID.String()}
+	recorder := &Reference{Reference: "Practitioner/" + doctorID.String()}
+	code := &CodeableConcept{Text: "Acute pharyngitis"}
+	tests := []struct {
+		name     string
+		resource Condition
+	}{
+		{"wrong resource type", Condition{ResourceType: "Patient", Code: code, Subject: subject, Recorder: recorder}},
+		{"no code", Condition{ResourceType: "Condition", Subject: subject, Recorder: recorder}},
+		{"subject not a patient", Condition{ResourceType: "Condition", Code: code, Subject: *recorder, Recorder: recorder}},
+		{"absolute subject", Condition{ResourceType: "Condition", Code: code,
+			Subject: Reference{Reference: testBaseURL + "/Patient/" + patientID.String()}, Recorder: recorder}},
+		{"no recorder", Condition{ResourceType: "Condition", Code: code, Subject: subject}},
+		{"invalid encounter id", Condition{ResourceType: "Condition", Code: code, Subject: subject, Recorder: recorder,
+			Encounter: &Reference{Reference: "Encounter/42"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := mapper.ToDiagnosis(test.resource); !errors.Is(err, ErrInvalidResource) {
+				t.Errorf("err = %v, want ErrInvalidResource", err)
+			}
+		})
+	}
+}
+
+func TestEncounterRoundTrip(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL)
+	tests := []struct {
+		name      string
+		encounter model.Encounter
+		status    string
+	}{
+		{"checked in", model.Encounter{ID: encounterID, PatientID: patientID, DoctorID: doctorID,
+			Status: model.EncounterStatusCheckedIn, CheckedInAt: checkedIn, ChiefComplaint: "Fever"}, "arrived"},
+		{"in consultation", model.Encounter{ID: encounterID, PatientID: patientID, DoctorID: doctorID,
+			Status: model.EncounterStatusInConsultation, CheckedInAt: checkedIn}, "in-progress"},
+		{"discharged", model.Encounter{ID: encounterID, PatientID: patientID, DoctorID: doctorID,
+			Status: model.EncounterStatusDischarged, CheckedInAt: checkedIn, DischargedAt: &discharged,
+			ChiefComplaint: "Fever", DischargeNotes: "Rest and fluids"}, "finished"},
+		{"cancelled", model.Encounter{ID: encounterID, PatientID: patientID, DoctorID: doctorID,
+			Status: model.EncounterStatusCancelled, CheckedInAt: checkedIn}, "cancelled"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var decoded Encounter
+			fields := roundTrip(t, mapper.Encounter(test.encounter), "Encounter",
+				[]string{"id", "status", "class", "subject", "participant", "period"}, &decoded)
+			if fields["status"] != test.status {
+				t.Errorf("status = %v, want %s", fields["status"], test.status)
+			}
+			if decoded.Class.Code != "AMB" || decoded.Class.System != SystemActCode {
+				t.Errorf("class = %+v, want ambulatory", decoded.Class)
+			}
+			checkReference(t, "subject", decoded.Subject, "Patient/"+patientID.String())
+			if len(decoded.Participant) != 1 {
+				t.Fatalf("participant = %+v, want the attending doctor", decoded.Participant)
+			}
+			checkReference(t, "participant", decoded.Participant[0].Individual, "Practitioner/"+doctorID.String())
+
+			got, err := mapper.ToEncounter(decoded)
+			if err != nil {
+				t.Fatalf("ToEncounter: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.encounter) {
+				t.Errorf("ToEncounter = %+v, want %+v", got, test.encounter)
+			}
+		})
+	}
+}
+
+func TestToEncounterErrors(t *testing.T) {
+	mapper := NewMapper(testSystem, testBaseURL)
+	subject := &Reference{Reference: "Patient/" + patientID.String()}
+	tests := []struct {
+		name     string
+		resource Encounter
+	}{
+		{"wrong resource type", Encounter{ResourceType: "Condition", Status: "arrived", Subject: subject}},
+		{"unsupported status", Encounter{ResourceType: "Encounter", Status: "planned", Subject: subject}},
+		{"no subject", Encounter{ResourceType: "Encounter", Status: "arrived"}},
+		{"invalid period", Encounter{ResourceType: "Encounter", Status: "arrived", Subject: subject,
+			Period: &Period{Start: "yesterday"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := mapper.ToEncounter(test.resource); !errors.Is(err, ErrInvalidResource) {
+				t.Errorf("err = %v, want ErrInvalidResource", err)
+			}
+		})
+	}
+}
+
+func TestParseConditionIDErrors(t *testing.T) {
+	for _, id := range []string{"", "7", "diagnosis-", "problem-x", "allergy-3"} {
+		if _, _, err := ParseConditionID(id); !errors.Is(err, ErrInvalidResource) {
+			t.Errorf("ParseConditionID(%q): err = %v, want ErrInvalidResource", id, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package fhir holds the subset of FHIR R4 resources the system exchanges with
+// other providers and the mapping between them and the internal models.
+// Field names and JSON layout follow https://hl7.org/fhir/R4.
+package fhir
+
+const ContentType = "application/fhir+json"
+
+// Code systems used by the mapped resources.
+const (
+	SystemConditionClinical     = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	SystemConditionVerification = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+	SystemConditionCategory     = "http://terminology.hl7.org/CodeSystem/condition-category"
+	SystemActCode               = "http://terminology.hl7.org/CodeSystem/v3-ActCode"
+	SystemParticipationType     = "http://terminology.hl7.org/CodeSystem/v3-ParticipationType"
+)
+
+type Meta struct {
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+type Identifier struct {
+	Use    string `json:"use,omitempty"`
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+	Prefix []string `json:"prefix,omitempty"`
+}
+
+type ContactPoint struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Use    string `json:"use,omitempty"`
+}
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type Extension struct {
+	URL          string `json:"url"`
+	ValueInteger *int   `json:"valueInteger,omitempty"`
+	ValueString  string `json:"valueString,omitempty"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id,omitempty"`
+	Meta         *Meta          `json:"meta,omitempty"`
+	Extension    []Extension    `json:"extension,omitempty"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+	Gender       string         `json:"gender,omitempty"`
+	BirthDate    string         `json:"birthDate,omitempty"`
+}
+
+type Practitioner struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id,omitempty"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+}
+
+type Condition struct {
+	ResourceType       string            `json:"resourceType"`
+	ID                 string            `json:"id,omitempty"`
+	ClinicalStatus     *CodeableConcept  `json:"clinicalStatus,omitempty"`
+	VerificationStatus *CodeableConcept  `json:"verificationStatus,omitempty"`
+	Category           []CodeableConcept `json:"category,omitempty"`
+	Code               *CodeableConcept  `json:"code,omitempty"`
+	Subject            Reference         `json:"subject"`
+	Encounter          *Reference        `json:"encounter,omitempty"`
+	OnsetDateTime      string            `json:"onsetDateTime,omitempty"`
+	RecordedDate       string            `json:"recordedDate,omitempty"`
+	Recorder           *Reference        `json:"recorder,omitempty"`
+	Note               []Annotation      `json:"note,omitempty"`
+}
+
+type EncounterParticipant struct {
+	Type       []CodeableConcept `json:"type,omitempty"`
+	Individual *Reference        `json:"individual,omitempty"`
+}
+
+type EncounterHospitalization struct {
+	DischargeDisposition *CodeableConcept `json:"dischargeDisposition,omitempty"`
+}
+
+type Encounter struct {
+	ResourceType    string                    `json:"resourceType"`
+	ID              string                    `json:"id,omitempty"`
+	Status          string                    `json:"status"`
+	Class           Coding                    `json:"class"`
+	Subject         *Reference                `json:"subject,omitempty"`
+	Participant     []EncounterParticipant    `json:"participant,omitempty"`
+	Period          *Period                   `json:"period,omitempty"`
+	ReasonCode      []CodeableConcept         `json:"reasonCode,omitempty"`
+	Hospitalization *EncounterHospitalization `json:"hospitalization,omitempty"`
+}
+
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+type BundleEntrySearch struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+// BundleEntry holds any resource; Resource is one of the resource structs above.
+type BundleEntry struct {
+	FullURL  string             `json:"fullUrl,omitempty"`
+	Resource any                `json:"resource"`
+	Search   *BundleEntrySearch `json:"search,omitempty"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp,omitempty"`
+	Total        *int          `json:"total,omitempty"`
+	Link         []BundleLink  `json:"link,omitempty"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+// OperationOutcome is returned instead of a resource when a request fails.
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+func NewOperationOutcome(code, diagnostics string) OperationOutcome {
+	return OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []OperationOutcomeIssue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
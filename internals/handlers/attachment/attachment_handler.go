@@ -0,0 +1,245 @@
+package attachment_handler
+
+// Package attachment_handler uploads documents and images to patients' records and streams them back.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	attachment_service "github.com/aaryansinhaa/patient-management-system/internals/service/attachment"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+// maxFieldBytes and maxFields bound the form fields sent before the file.
+const (
+	maxFieldBytes = 4096
+	maxFields     = 8
+)
+
+type AttachmentHandler struct {
+	service service.AttachmentService
+}
+
+func NewAttachmentHandler(service service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+func (h *AttachmentHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	records := auth.RequirePatient(middleware.PathID("id"), model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+	clinical := auth.RequirePatient(middleware.PathID("id"), model.RoleDoctor, model.RoleAdmin)
+
+	mux.Handle("GET /patients/{id}/attachments", records(h.ListPatientAttachments))
+	mux.Handle("POST /patients/{id}/attachments", records(h.UploadAttachment))
+	mux.Handle("GET /patients/{id}/attachments/{attachmentID}", records(h.GetAttachment))
+	mux.Handle("GET /patients/{id}/attachments/{attachmentID}/content", records(h.DownloadAttachment))
+	mux.Handle("DELETE /patients/{id}/attachments/{attachmentID}", clinical(h.DeleteAttachment))
+}
+
+// ListPatientAttachments serves GET /patients/{id}/attachments[?deleted=true].
+func (h *AttachmentHandler) ListPatientAttachments(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeDeleted := r.URL.Query().Get("deleted") == "true"
+	response, err := h.service.ListPatientAttachments(patientID, includeDeleted)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// UploadAttachment serves POST /patients/{id}/attachments, a multipart form
+// whose optional diagnosis_id and description fields come before the file
+// part. The file is streamed to the service rather than buffered. An upload
+// the patient already has answers 200 with the existing attachment.
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "request must be multipart/form-data")
+		return
+	}
+
+	var upload dto.AttachmentUpload
+	for fields := 0; ; fields++ {
+		if fields > maxFields {
+			utils.WriteError(w, http.StatusBadRequest, "too many form fields")
+			return
+		}
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			utils.WriteError(w, http.StatusBadRequest, "file is required")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid multipart body: %v", err))
+			return
+		}
+
+		switch part.FormName() {
+		case "file":
+			upload.Filename = part.FileName()
+			response, err := h.service.UploadAttachment(r.Context(), middleware.UserIDFromContext(r.Context()),
+				patientID, upload, part)
+			if err != nil {
+				writeServiceError(w, err)
+				return
+			}
+			status := http.StatusCreated
+			if response.Duplicate {
+				status = http.StatusOK
+			}
+			utils.WriteJSON(w, status, response)
+			return
+		case "diagnosis_id":
+			value, err := formValue(part)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if value == "" {
+				continue
+			}
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid diagnosis_id")
+				return
+			}
+			upload.DiagnosisID = &id
+		case "description":
+			if upload.Description, err = formValue(part); err != nil {
+				utils.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		default:
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown form field %q", part.FormName()))
+			return
+		}
+	}
+}
+
+func (h *AttachmentHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, ok := pathAttachmentID(w, r)
+	if !ok {
+		return
+	}
+	response, err := h.service.GetAttachment(patientID, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// DownloadAttachment serves GET /patients/{id}/attachments/{attachmentID}/content,
+// streaming the file with the type it was sniffed as.
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, ok := pathAttachmentID(w, r)
+	if !ok {
+		return
+	}
+	attachment, content, err := h.service.OpenAttachment(r.Context(), patientID, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	defer content.Close()
+
+	etag := `"` + attachment.SHA256 + `"`
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("attachment handler: streaming attachment %d: %v", attachment.ID, err)
+	}
+}
+
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, ok := pathAttachmentID(w, r)
+	if !ok {
+		return
+	}
+	response, err := h.service.DeleteAttachment(middleware.UserIDFromContext(r.Context()), patientID, id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func formValue(part io.Reader) (string, error) {
+	value, err := io.ReadAll(io.LimitReader(part, maxFieldBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("invalid multipart body: %w", err)
+	}
+	if len(value) > maxFieldBytes {
+		return "", fmt.Errorf("form field is longer than %d bytes", maxFieldBytes)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+func pathAttachmentID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("attachmentID"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid attachment ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, attachment_service.ErrPatientNotFound), errors.Is(err, attachment_service.ErrDiagnosisNotFound),
+		errors.Is(err, attachment_service.ErrAttachmentNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, attachment_service.ErrTooLarge):
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, attachment_service.ErrUnsupportedType):
+		utils.WriteError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, attachment_service.ErrInvalidAttachment):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("attachment handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,268 @@
+package auth_handler
+
+// Package auth_handler exposes login, two-factor and password management over HTTP.
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	auth_service "github.com/aaryansinhaa/patient-management-system/internals/service/auth"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type AuthHandler struct {
+	service service.AuthService
+}
+
+func NewAuthHandler(service service.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+func (h *AuthHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	mux.HandleFunc("POST /auth/login", h.Login)
+	mux.HandleFunc("POST /auth/mfa/verify", h.VerifyMFA)
+	mux.HandleFunc("POST /auth/password/reset", h.ResetPassword)
+	mux.Handle("POST /auth/password", auth.Require()(h.ChangePassword))
+	mux.Handle("POST /auth/totp/enroll", auth.RequireEnrollment()(h.BeginTOTPEnrollment))
+	mux.Handle("POST /auth/totp/confirm", auth.RequireEnrollment()(h.ConfirmTOTPEnrollment))
+	mux.Handle("POST /auth/totp/disable", auth.Require()(h.DisableTOTP))
+	mux.Handle("POST /auth/recovery-codes", auth.Require()(h.RegenerateRecoveryCodes))
+	mux.Handle("GET /auth/clinics", auth.Require()(h.ListMyClinics))
+	mux.Handle("POST /auth/clinic", auth.Require()(h.SwitchClinic))
+
+	mux.Handle("POST /admin/users", auth.Require(model.RoleAdmin)(h.Register))
+	mux.Handle("POST /admin/users/{username}/unlock", auth.Require(model.RoleAdmin)(h.UnlockAccount))
+	mux.Handle("POST /admin/users/{id}/password-reset", auth.Require(model.RoleAdmin)(h.IssuePasswordReset))
+	mux.Handle("POST /admin/users/{id}/deactivate", auth.Require(model.RoleAdmin)(h.DeactivateUser))
+	mux.Handle("PUT /admin/mfa-policy", auth.Require(model.RoleAdmin)(h.SetMFAPolicy))
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var request dto.LoginRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	request.ClientIP = utils.ClientIP(r)
+	response, err := h.service.Login(request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var request dto.MFAVerifyRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	request.ClientIP = utils.ClientIP(r)
+	response, err := h.service.VerifyMFA(request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var request dto.ResetPasswordRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.service.ResetPassword(request); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var request dto.ChangePasswordRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.service.ChangePassword(middleware.UserIDFromContext(r.Context()), request); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) BeginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.BeginTOTPEnrollment(middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) ConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	var request dto.TOTPCodeRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.ConfirmTOTPEnrollment(middleware.UserIDFromContext(r.Context()), request.Code)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	var request dto.TOTPCodeRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.service.DisableTOTP(middleware.UserIDFromContext(r.Context()), request.Code); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var request dto.TOTPCodeRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.RegenerateRecoveryCodes(middleware.UserIDFromContext(r.Context()), request.Code)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) ListMyClinics(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.ListMyClinics(middleware.UserIDFromContext(r.Context()))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// SwitchClinic exchanges the caller's token for one working in another of
+// their clinics.
+func (h *AuthHandler) SwitchClinic(w http.ResponseWriter, r *http.Request) {
+	var request dto.SwitchClinicRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.SwitchClinic(middleware.UserIDFromContext(r.Context()), request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var request dto.RegisterUserRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.Register(request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *AuthHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	err := h.service.UnlockAccount(middleware.UserIDFromContext(r.Context()), r.PathValue("username"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) IssuePasswordReset(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.IssuePasswordReset(middleware.UserIDFromContext(r.Context()), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *AuthHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.DeactivateUser(middleware.UserIDFromContext(r.Context()), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) SetMFAPolicy(w http.ResponseWriter, r *http.Request) {
+	var request dto.MFAPolicyRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.service.SetMFAPolicy(middleware.UserIDFromContext(r.Context()), request); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	var throttled *auth_service.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		utils.WriteError(w, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, auth_service.ErrInvalidCredentials), errors.Is(err, auth_service.ErrInvalidMFACode),
+		errors.Is(err, utils.ErrInvalidToken):
+		utils.WriteError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, auth_service.ErrNotAdmin), errors.Is(err, auth_service.ErrNoClinic),
+		errors.Is(err, auth_service.ErrNotClinicMember):
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, auth_service.ErrPasswordTooShort), errors.Is(err, auth_service.ErrPasswordTooLong),
+		errors.Is(err, auth_service.ErrPasswordBreached), errors.Is(err, auth_service.ErrPasswordReused),
+		errors.Is(err, auth_service.ErrInvalidResetToken), errors.Is(err, auth_service.ErrMFAAlreadyEnabled),
+		errors.Is(err, auth_service.ErrMFANotEnabled), errors.Is(err, auth_service.ErrMFAEnrollmentMissing),
+		errors.Is(err, auth_service.ErrDeactivateSelf):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, auth_service.ErrUserNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, auth_service.ErrAlreadyDeactivated):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("auth handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
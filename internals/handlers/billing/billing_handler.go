@@ -0,0 +1,244 @@
+package billing_handler
+
+// Package billing_handler exposes the service catalogue, invoices, payments
+// and refunds over HTTP. Billing is done by receptionists; the catalogue and
+// tax rates are maintained by admins.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	billing_service "github.com/aaryansinhaa/patient-management-system/internals/service/billing"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type BillingHandler struct {
+	service service.BillingService
+}
+
+func NewBillingHandler(service service.BillingService) *BillingHandler {
+	return &BillingHandler{service: service}
+}
+
+func (h *BillingHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+	billing := auth.Require(model.RoleReceptionist, model.RoleAdmin)
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("GET /catalogue", staff(h.ListCatalogue))
+	mux.Handle("POST /catalogue", admin(h.CreateCatalogueItem))
+	mux.Handle("PUT /catalogue/{id}", admin(h.UpdateCatalogueItem))
+	mux.Handle("GET /tax-rates", staff(h.ListTaxRates))
+	mux.Handle("POST /tax-rates", admin(h.CreateTaxRate))
+
+	mux.Handle("POST /encounters/{id}/charges", staff(h.AddEncounterCharge))
+	mux.Handle("POST /encounters/{id}/invoice", billing(h.GenerateInvoice))
+	mux.Handle("GET /invoices/{id}", billing(h.GetInvoice))
+	mux.Handle("POST /invoices/{id}/payments", billing(h.RecordPayment))
+	mux.Handle("POST /payments/{id}/refunds", billing(h.RefundPayment))
+	mux.Handle("GET /patients/{id}/invoices", billing(h.ListPatientInvoices))
+	mux.Handle("GET /patients/{id}/balance", billing(h.GetPatientBalance))
+}
+
+// ListCatalogue returns active items, or all items with ?all=true.
+func (h *BillingHandler) ListCatalogue(w http.ResponseWriter, r *http.Request) {
+	includeInactive := r.URL.Query().Get("all") == "true"
+	response, err := h.service.ListCatalogue(includeInactive)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BillingHandler) CreateCatalogueItem(w http.ResponseWriter, r *http.Request) {
+	var request dto.CatalogueItemRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CreateCatalogueItem(request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *BillingHandler) UpdateCatalogueItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid catalogue item id")
+		return
+	}
+	var request dto.CatalogueItemRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.UpdateCatalogueItem(id, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BillingHandler) ListTaxRates(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.ListTaxRates()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BillingHandler) CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	var request dto.TaxRateRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CreateTaxRate(request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *BillingHandler) AddEncounterCharge(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.EncounterChargeRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.AddEncounterCharge(middleware.UserIDFromContext(r.Context()), encounterID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *BillingHandler) GenerateInvoice(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GenerateInvoice(middleware.UserIDFromContext(r.Context()), encounterID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *BillingHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GetInvoice(invoiceID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BillingHandler) RecordPayment(w http.ResponseWriter, r *http.Request) {
+	invoiceID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.PaymentRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.RecordPayment(middleware.UserIDFromContext(r.Context()), invoiceID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *BillingHandler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid payment id")
+		return
+	}
+	var request dto.RefundRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.RefundPayment(middleware.UserIDFromContext(r.Context()), paymentID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *BillingHandler) ListPatientInvoices(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.ListPatientInvoices(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BillingHandler) GetPatientBalance(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GetPatientBalance(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, billing_service.ErrTaxRateNotFound), errors.Is(err, billing_service.ErrCatalogueItemNotFound),
+		errors.Is(err, billing_service.ErrEncounterNotFound), errors.Is(err, billing_service.ErrPatientNotFound),
+		errors.Is(err, billing_service.ErrInvoiceNotFound), errors.Is(err, billing_service.ErrPaymentNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, billing_service.ErrAlreadyInvoiced), errors.Is(err, billing_service.ErrNotBillable),
+		errors.Is(err, billing_service.ErrNothingToInvoice), errors.Is(err, billing_service.ErrAmountExceedsBalance):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, billing_service.ErrInvalidInput):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("billing handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,107 @@
+package clinic_handler
+
+// Package clinic_handler lets admins manage clinics and who works at them.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	clinic_service "github.com/aaryansinhaa/patient-management-system/internals/service/clinic"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type ClinicHandler struct {
+	service service.ClinicService
+}
+
+func NewClinicHandler(service service.ClinicService) *ClinicHandler {
+	return &ClinicHandler{service: service}
+}
+
+func (h *ClinicHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("POST /admin/clinics", admin(h.CreateClinic))
+	mux.Handle("GET /admin/clinics", admin(h.ListClinics))
+	mux.Handle("POST /admin/clinics/{id}/members", admin(h.AddMember))
+	mux.Handle("DELETE /admin/clinics/{id}/members/{userID}", admin(h.RemoveMember))
+}
+
+func (h *ClinicHandler) CreateClinic(w http.ResponseWriter, r *http.Request) {
+	var request dto.CreateClinicRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CreateClinic(middleware.UserIDFromContext(r.Context()), request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *ClinicHandler) ListClinics(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.ListClinics()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *ClinicHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	clinicID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.AddClinicMemberRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.service.AddMember(clinicID, request); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ClinicHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	clinicID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, err := utils.PathUUID(r, "userID")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.service.RemoveMember(clinicID, userID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, clinic_service.ErrClinicNotFound), errors.Is(err, clinic_service.ErrUserNotFound),
+		errors.Is(err, clinic_service.ErrMemberNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, clinic_service.ErrClinicCodeTaken):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, clinic_service.ErrInvalidClinicData):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("clinic handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,121 @@
+package consent_handler
+
+// Package consent_handler exposes consent texts and what each patient has consented to.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	consent_service "github.com/aaryansinhaa/patient-management-system/internals/service/consent"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type ConsentHandler struct {
+	service service.ConsentService
+}
+
+func NewConsentHandler(service service.ConsentService) *ConsentHandler {
+	return &ConsentHandler{service: service}
+}
+
+func (h *ConsentHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("GET /consent-texts", staff(h.ListConsentTexts))
+	mux.Handle("POST /admin/consent-texts", admin(h.PublishConsentText))
+	mux.Handle("GET /patients/{id}/consents", staff(h.GetPatientConsents))
+	mux.Handle("POST /patients/{id}/consents", staff(h.GrantConsent))
+	mux.Handle("DELETE /patients/{id}/consents/{scope}", staff(h.WithdrawConsent))
+}
+
+// ListConsentTexts serves GET /consent-texts[?scope=<scope>].
+func (h *ConsentHandler) ListConsentTexts(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.ListConsentTexts(r.URL.Query().Get("scope"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *ConsentHandler) PublishConsentText(w http.ResponseWriter, r *http.Request) {
+	var request dto.ConsentTextRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.PublishConsentText(middleware.UserIDFromContext(r.Context()), request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *ConsentHandler) GetPatientConsents(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GetPatientConsents(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *ConsentHandler) GrantConsent(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.GrantConsentRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GrantConsent(middleware.UserIDFromContext(r.Context()), patientID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+// WithdrawConsent serves DELETE /patients/{id}/consents/{scope}. The consent
+// is kept in the patient's history as withdrawn.
+func (h *ConsentHandler) WithdrawConsent(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.WithdrawConsent(middleware.UserIDFromContext(r.Context()), patientID, r.PathValue("scope"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, consent_service.ErrPatientNotFound), errors.Is(err, consent_service.ErrConsentTextNotFound),
+		errors.Is(err, consent_service.ErrConsentNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, consent_service.ErrInvalidConsent):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("consent handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
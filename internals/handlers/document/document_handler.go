@@ -0,0 +1,69 @@
+package document_handler
+
+// Package document_handler serves generated PDF documents for download.
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	document_service "github.com/aaryansinhaa/patient-management-system/internals/service/document"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+type DocumentHandler struct {
+	service service.DocumentService
+}
+
+func NewDocumentHandler(service service.DocumentService) *DocumentHandler {
+	return &DocumentHandler{service: service}
+}
+
+func (h *DocumentHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	records := auth.RequireEncounter(middleware.PathID("id"), model.RoleDoctor, model.RoleReceptionist)
+	billing := auth.Require(model.RoleReceptionist, model.RoleAdmin)
+
+	mux.Handle("GET /encounters/{id}/documents/visit-summary", records(h.serve(h.service.VisitSummaryPDF)))
+	mux.Handle("GET /encounters/{id}/documents/prescription", records(h.serve(h.service.PrescriptionPDF)))
+	mux.Handle("GET /invoices/{id}/document", billing(h.serve(h.service.InvoicePDF)))
+}
+
+// serve downloads the document generated for the record in the id path value.
+func (h *DocumentHandler) serve(generate func(id uuid.UUID) (*dto.DocumentFile, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathUUID(r, "id")
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		file, err := generate(id)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", file.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+		w.Header().Set("Content-Length", strconv.Itoa(len(file.Content)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(file.Content)
+	}
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, document_service.ErrEncounterNotFound), errors.Is(err, document_service.ErrInvoiceNotFound),
+		errors.Is(err, document_service.ErrPatientNotFound), errors.Is(err, document_service.ErrDoctorNotFound),
+		errors.Is(err, document_service.ErrNoPrescriptions):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	default:
+		log.Printf("document handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,177 @@
+package emergency_access_handler
+
+// Package emergency_access_handler lets doctors break the glass on a patient
+// and admins follow and review those accesses.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	emergency_access_service "github.com/aaryansinhaa/patient-management-system/internals/service/emergency_access"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+// keepAliveInterval is how often a comment is sent on an idle alert stream so
+// proxies do not close it.
+const keepAliveInterval = 30 * time.Second
+
+type EmergencyAccessHandler struct {
+	service service.EmergencyAccessService
+}
+
+func NewEmergencyAccessHandler(service service.EmergencyAccessService) *EmergencyAccessHandler {
+	return &EmergencyAccessHandler{service: service}
+}
+
+func (h *EmergencyAccessHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	doctor := auth.Require(model.RoleDoctor)
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("POST /patients/{id}/emergency-access", doctor(h.BreakGlass))
+	mux.Handle("GET /admin/emergency-access", admin(h.ListEmergencyAccesses))
+	mux.Handle("GET /admin/emergency-access/alerts", admin(h.StreamAlerts))
+	mux.Handle("GET /admin/emergency-access/{id}", admin(h.GetEmergencyAccess))
+	mux.Handle("POST /admin/emergency-access/{id}/review", admin(h.ReviewEmergencyAccess))
+}
+
+func (h *EmergencyAccessHandler) BreakGlass(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.BreakGlassRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.BreakGlass(middleware.UserIDFromContext(r.Context()), patientID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+// ListEmergencyAccesses serves GET /admin/emergency-access, the review queue.
+// ?all=true includes accesses already reviewed.
+func (h *EmergencyAccessHandler) ListEmergencyAccesses(w http.ResponseWriter, r *http.Request) {
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+	response, err := h.service.ListEmergencyAccesses(!all)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *EmergencyAccessHandler) GetEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	accessID, ok := accessIDPath(w, r)
+	if !ok {
+		return
+	}
+	response, err := h.service.GetEmergencyAccess(accessID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *EmergencyAccessHandler) ReviewEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	accessID, ok := accessIDPath(w, r)
+	if !ok {
+		return
+	}
+	var request dto.ReviewEmergencyAccessRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.ReviewEmergencyAccess(middleware.UserIDFromContext(r.Context()), accessID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// StreamAlerts sends an "emergency-access" event for every access granted
+// from now on, until the client disconnects.
+func (h *EmergencyAccessHandler) StreamAlerts(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.WriteError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	alerts, unsubscribe := h.service.SubscribeAlerts()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case alert, ok := <-alerts:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, alert); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, alert dto.EmergencyAccessResponse) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: emergency-access\ndata: %s\n\n", data)
+	return err
+}
+
+func accessIDPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	accessID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid emergency access id")
+		return 0, false
+	}
+	return accessID, true
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, emergency_access_service.ErrPatientNotFound), errors.Is(err, emergency_access_service.ErrAccessNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, emergency_access_service.ErrAlreadyReviewed), errors.Is(err, emergency_access_service.ErrAlreadyTreating):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, emergency_access_service.ErrInvalidReason):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("emergency access handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
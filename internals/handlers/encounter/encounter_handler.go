@@ -0,0 +1,215 @@
+package encounter_handler
+
+// Package encounter_handler exposes visits and the clinical data recorded during them over HTTP.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	encounter_service "github.com/aaryansinhaa/patient-management-system/internals/service/encounter"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type EncounterHandler struct {
+	service service.EncounterService
+}
+
+func NewEncounterHandler(service service.EncounterService) *EncounterHandler {
+	return &EncounterHandler{service: service}
+}
+
+func (h *EncounterHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist)
+	doctor := auth.Require(model.RoleDoctor)
+	records := auth.RequireEncounter(middleware.PathID("id"), model.RoleDoctor)
+
+	mux.Handle("POST /encounters", staff(h.OpenEncounter))
+	mux.Handle("GET /encounters/{id}", records(h.GetEncounter))
+	mux.Handle("POST /encounters/{id}/consultation", doctor(h.StartConsultation))
+	mux.Handle("POST /encounters/{id}/close", staff(h.CloseEncounter))
+	mux.Handle("POST /encounters/{id}/cancel", staff(h.CancelEncounter))
+	mux.Handle("POST /encounters/{id}/diagnoses", doctor(h.RecordDiagnosis))
+	mux.Handle("POST /encounters/{id}/vitals", staff(h.RecordVitals))
+	mux.Handle("POST /encounters/{id}/prescriptions", doctor(h.AddPrescription))
+	mux.Handle("GET /doctors/{id}/encounters/open", staff(h.ListOpenEncounters))
+}
+
+func (h *EncounterHandler) OpenEncounter(w http.ResponseWriter, r *http.Request) {
+	var request dto.OpenEncounterRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.OpenEncounter(middleware.UserIDFromContext(r.Context()), request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *EncounterHandler) GetEncounter(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GetEncounter(encounterID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *EncounterHandler) StartConsultation(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.StartConsultation(middleware.UserIDFromContext(r.Context()), encounterID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *EncounterHandler) CloseEncounter(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.CloseEncounterRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CloseEncounter(encounterID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *EncounterHandler) CancelEncounter(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CancelEncounter(encounterID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *EncounterHandler) RecordDiagnosis(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.EncounterDiagnosisRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.RecordDiagnosis(middleware.UserIDFromContext(r.Context()), encounterID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *EncounterHandler) RecordVitals(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.VitalsRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.RecordVitals(middleware.UserIDFromContext(r.Context()), encounterID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *EncounterHandler) AddPrescription(w http.ResponseWriter, r *http.Request) {
+	encounterID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.PrescriptionRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.AddPrescription(middleware.UserIDFromContext(r.Context()), encounterID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+// ListOpenEncounters lists a doctor's open encounters for today, or for the
+// day given as ?date=YYYY-MM-DD.
+func (h *EncounterHandler) ListOpenEncounters(w http.ResponseWriter, r *http.Request) {
+	doctorID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	day := time.Now()
+	if date := r.URL.Query().Get("date"); date != "" {
+		day, err = time.ParseInLocation(time.DateOnly, date, time.Local)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+			return
+		}
+	}
+	response, err := h.service.ListOpenEncountersForDay(doctorID, day)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, encounter_service.ErrEncounterNotFound), errors.Is(err, encounter_service.ErrPatientNotFound),
+		errors.Is(err, encounter_service.ErrDoctorNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, encounter_service.ErrNotAttending), errors.Is(err, encounter_service.ErrConsentRequired),
+		errors.Is(err, encounter_service.ErrSelfAssigned):
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, encounter_service.ErrEncounterClosed), errors.Is(err, encounter_service.ErrInvalidTransition):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, encounter_service.ErrInvalidInput):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("encounter handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
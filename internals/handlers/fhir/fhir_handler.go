@@ -0,0 +1,251 @@
+package fhir_handler
+
+// Package fhir_handler exposes patients, practitioners, conditions and
+// encounters as FHIR R4 resources under /fhir. Errors are returned as
+// OperationOutcome resources.
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/fhir"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	fhir_service "github.com/aaryansinhaa/patient-management-system/internals/service/fhir"
+	"github.com/google/uuid"
+)
+
+// maxResourceBytes bounds the size of incoming resources.
+const maxResourceBytes = 1 << 20
+
+type FHIRHandler struct {
+	service service.FHIRService
+}
+
+func NewFHIRHandler(service service.FHIRService) *FHIRHandler {
+	return &FHIRHandler{service: service}
+}
+
+func (h *FHIRHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist)
+	doctor := auth.Require(model.RoleDoctor)
+	patientRecords := auth.RequirePatient(middleware.PathID("id"), model.RoleDoctor)
+	searchRecords := auth.RequirePatient(searchedPatient, model.RoleDoctor)
+	encounterRecords := auth.RequireEncounter(middleware.PathID("id"), model.RoleDoctor)
+	conditionRecords := auth.RequirePatient(h.conditionPatient, model.RoleDoctor)
+
+	mux.Handle("GET /fhir/Patient", staff(h.SearchPatients))
+	mux.Handle("POST /fhir/Patient", staff(h.CreatePatient))
+	mux.Handle("GET /fhir/Patient/{id}", staff(h.GetPatient))
+	mux.Handle("PUT /fhir/Patient/{id}", staff(h.UpdatePatient))
+	mux.Handle("GET /fhir/Patient/{id}/$everything", patientRecords(h.ExportPatient))
+	mux.Handle("GET /fhir/Practitioner", staff(h.SearchPractitioners))
+	mux.Handle("GET /fhir/Practitioner/{id}", staff(h.GetPractitioner))
+	mux.Handle("GET /fhir/Condition", searchRecords(h.SearchConditions))
+	mux.Handle("POST /fhir/Condition", doctor(h.CreateCondition))
+	mux.Handle("GET /fhir/Condition/{id}", conditionRecords(h.GetCondition))
+	mux.Handle("GET /fhir/Encounter", searchRecords(h.SearchEncounters))
+	mux.Handle("GET /fhir/Encounter/{id}", encounterRecords(h.GetEncounter))
+}
+
+func (h *FHIRHandler) GetPatient(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	resource, err := h.service.GetPatient(id)
+	writeResult(w, http.StatusOK, resource, err)
+}
+
+func (h *FHIRHandler) SearchPatients(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	bundle, err := h.service.SearchPatients(query.Get("identifier"), query.Get("phone"), query.Get("name"))
+	writeResult(w, http.StatusOK, bundle, err)
+}
+
+func (h *FHIRHandler) CreatePatient(w http.ResponseWriter, r *http.Request) {
+	var resource fhir.Patient
+	if !decodeResource(w, r, &resource) {
+		return
+	}
+	created, err := h.service.CreatePatient(resource)
+	if err == nil {
+		w.Header().Set("Location", "Patient/"+created.ID)
+	}
+	writeResult(w, http.StatusCreated, created, err)
+}
+
+func (h *FHIRHandler) UpdatePatient(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	var resource fhir.Patient
+	if !decodeResource(w, r, &resource) {
+		return
+	}
+	updated, err := h.service.UpdatePatient(id, resource)
+	writeResult(w, http.StatusOK, updated, err)
+}
+
+func (h *FHIRHandler) ExportPatient(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	bundle, err := h.service.ExportPatient(id)
+	writeResult(w, http.StatusOK, bundle, err)
+}
+
+func (h *FHIRHandler) GetPractitioner(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	resource, err := h.service.GetPractitioner(id)
+	writeResult(w, http.StatusOK, resource, err)
+}
+
+func (h *FHIRHandler) SearchPractitioners(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	bundle, err := h.service.SearchPractitioners(query.Get("identifier"), query.Get("name"))
+	writeResult(w, http.StatusOK, bundle, err)
+}
+
+func (h *FHIRHandler) GetCondition(w http.ResponseWriter, r *http.Request) {
+	resource, err := h.service.GetCondition(r.PathValue("id"))
+	writeResult(w, http.StatusOK, resource, err)
+}
+
+func (h *FHIRHandler) SearchConditions(w http.ResponseWriter, r *http.Request) {
+	patientID, ok := patientParam(w, r)
+	if !ok {
+		return
+	}
+	bundle, err := h.service.SearchConditions(patientID)
+	writeResult(w, http.StatusOK, bundle, err)
+}
+
+func (h *FHIRHandler) CreateCondition(w http.ResponseWriter, r *http.Request) {
+	var resource fhir.Condition
+	if !decodeResource(w, r, &resource) {
+		return
+	}
+	created, err := h.service.CreateCondition(middleware.UserIDFromContext(r.Context()), resource)
+	if err == nil {
+		w.Header().Set("Location", "Condition/"+created.ID)
+	}
+	writeResult(w, http.StatusCreated, created, err)
+}
+
+func (h *FHIRHandler) GetEncounter(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	resource, err := h.service.GetEncounter(id)
+	writeResult(w, http.StatusOK, resource, err)
+}
+
+func (h *FHIRHandler) SearchEncounters(w http.ResponseWriter, r *http.Request) {
+	patientID, ok := patientParam(w, r)
+	if !ok {
+		return
+	}
+	bundle, err := h.service.SearchEncounters(patientID)
+	writeResult(w, http.StatusOK, bundle, err)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeOutcome(w, http.StatusNotFound, "not-found", "unknown resource id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// searchedPatient is the patient of a search by the patient parameter, given
+// as an id or a "Patient/<id>" reference.
+func searchedPatient(r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(strings.TrimPrefix(r.URL.Query().Get("patient"), "Patient/"))
+	return id, err == nil
+}
+
+// conditionPatient is the patient of the condition named in the path. An
+// unknown condition is left for the handler to report; a failed lookup
+// locates no patient anyone may see, so access is refused.
+func (h *FHIRHandler) conditionPatient(r *http.Request) (uuid.UUID, bool) {
+	id, err := h.service.ConditionPatient(r.PathValue("id"))
+	if errors.Is(err, fhir_service.ErrNotFound) {
+		return uuid.Nil, false
+	}
+	if err != nil {
+		log.Printf("fhir handler: %v", err)
+		return uuid.Nil, true
+	}
+	return id, true
+}
+
+// patientParam reads the required patient search parameter, given either as
+// an id or as a "Patient/<id>" reference.
+func patientParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, ok := searchedPatient(r)
+	if !ok {
+		writeOutcome(w, http.StatusBadRequest, "required", "search requires a valid patient parameter")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// decodeResource reads a resource body. Unlike the rest of the API unknown
+// fields are ignored, since other systems send elements we do not map.
+func decodeResource(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceBytes))
+	if err := decoder.Decode(v); err != nil {
+		writeOutcome(w, http.StatusBadRequest, "structure", "invalid resource: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeResult(w http.ResponseWriter, status int, resource any, err error) {
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeResource(w, status, resource)
+}
+
+func writeResource(w http.ResponseWriter, status int, resource any) {
+	w.Header().Set("Content-Type", fhir.ContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resource)
+}
+
+func writeOutcome(w http.ResponseWriter, status int, code, diagnostics string) {
+	writeResource(w, status, fhir.NewOperationOutcome(code, diagnostics))
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fhir_service.ErrNotFound):
+		writeOutcome(w, http.StatusNotFound, "not-found", err.Error())
+	case errors.Is(err, fhir_service.ErrUnsupportedSearch):
+		writeOutcome(w, http.StatusBadRequest, "not-supported", err.Error())
+	case errors.Is(err, fhir_service.ErrConsentRequired), errors.Is(err, fhir_service.ErrNotRecorder),
+		errors.Is(err, fhir_service.ErrNotAttending):
+		writeOutcome(w, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, fhir_service.ErrEncounterClosed):
+		writeOutcome(w, http.StatusConflict, "conflict", err.Error())
+	case errors.Is(err, fhir.ErrInvalidResource):
+		writeOutcome(w, http.StatusUnprocessableEntity, "invalid", err.Error())
+	default:
+		log.Printf("fhir handler: %v", err)
+		writeOutcome(w, http.StatusInternalServerError, "exception", "internal server error")
+	}
+}
@@ -0,0 +1,81 @@
+package hl7_handler
+
+// Package hl7_handler lets admins review HL7 messages that could not be processed.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/hl7"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	hl7_service "github.com/aaryansinhaa/patient-management-system/internals/service/hl7"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type HL7Handler struct {
+	service service.HL7Service
+}
+
+func NewHL7Handler(service service.HL7Service) *HL7Handler {
+	return &HL7Handler{service: service}
+}
+
+func (h *HL7Handler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("GET /admin/hl7/dead-letters", admin(h.ListDeadLetters))
+	mux.Handle("POST /admin/hl7/dead-letters/{id}/retry", admin(h.RetryDeadLetter))
+	mux.Handle("DELETE /admin/hl7/dead-letters/{id}", admin(h.DismissDeadLetter))
+}
+
+func (h *HL7Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.ListDeadLetters()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *HL7Handler) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid dead letter id")
+		return
+	}
+	if err := h.service.RetryDeadLetter(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HL7Handler) DismissDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid dead letter id")
+		return
+	}
+	if err := h.service.DismissDeadLetter(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, hl7_service.ErrDeadLetterNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, hl7.ErrMalformed), errors.Is(err, hl7_service.ErrUnsupportedMessage),
+		errors.Is(err, hl7_service.ErrInvalidPatient):
+		utils.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		log.Printf("hl7 handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,27 @@
+package jwks_handler
+
+// Package jwks_handler publishes the public JWT signing keys so other internal
+// services can verify our tokens without sharing a secret.
+
+import (
+	"net/http"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type JWKSHandler struct {
+	jwtManager *utils.JWTManager
+}
+
+func NewJWKSHandler(jwtManager *utils.JWTManager) *JWKSHandler {
+	return &JWKSHandler{jwtManager: jwtManager}
+}
+
+func (h *JWKSHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /.well-known/jwks.json", h.GetJWKS)
+}
+
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.WriteJSON(w, http.StatusOK, h.jwtManager.JWKS())
+}
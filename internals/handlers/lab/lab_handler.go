@@ -0,0 +1,222 @@
+package lab_handler
+
+// Package lab_handler exposes the lab test catalogue, patients' lab orders and results, and the pending-results worklist.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	lab_service "github.com/aaryansinhaa/patient-management-system/internals/service/lab"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type LabHandler struct {
+	service service.LabService
+}
+
+func NewLabHandler(service service.LabService) *LabHandler {
+	return &LabHandler{service: service}
+}
+
+func (h *LabHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+	admin := auth.Require(model.RoleAdmin)
+	worklist := auth.Require(model.RoleDoctor, model.RoleAdmin)
+	records := auth.RequirePatient(middleware.PathID("id"), model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+	ordering := auth.RequirePatient(middleware.PathID("id"), model.RoleDoctor)
+	resulting := auth.RequirePatient(middleware.PathID("id"), model.RoleDoctor, model.RoleReceptionist)
+
+	mux.Handle("GET /lab/tests", staff(h.ListLabTests))
+	mux.Handle("POST /admin/lab/tests", admin(h.CreateLabTest))
+	mux.Handle("PUT /admin/lab/tests/{id}", admin(h.UpdateLabTest))
+	mux.Handle("GET /lab/worklist", worklist(h.PendingWorklist))
+	mux.Handle("GET /patients/{id}/lab-orders", records(h.ListPatientLabOrders))
+	mux.Handle("POST /patients/{id}/lab-orders", ordering(h.OrderLabTest))
+	mux.Handle("GET /patients/{id}/lab-orders/{orderID}", records(h.GetLabOrder))
+	mux.Handle("POST /patients/{id}/lab-orders/{orderID}/result", resulting(h.RecordLabResult))
+	mux.Handle("POST /patients/{id}/lab-orders/{orderID}/cancel", ordering(h.CancelLabOrder))
+}
+
+// ListLabTests returns active tests, or all tests with ?all=true.
+func (h *LabHandler) ListLabTests(w http.ResponseWriter, r *http.Request) {
+	includeInactive := r.URL.Query().Get("all") == "true"
+	response, err := h.service.ListLabTests(includeInactive)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *LabHandler) CreateLabTest(w http.ResponseWriter, r *http.Request) {
+	var request dto.LabTestRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CreateLabTest(request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *LabHandler) UpdateLabTest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid lab test ID")
+		return
+	}
+	var request dto.LabTestRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.UpdateLabTest(id, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// PendingWorklist serves GET /lab/worklist[?all=true]. Doctors see the orders
+// they placed unless they ask for all; admins always see all.
+func (h *LabHandler) PendingWorklist(w http.ResponseWriter, r *http.Request) {
+	claims, _ := middleware.ClaimsFromContext(r.Context())
+	all := claims.Role == model.RoleAdmin || r.URL.Query().Get("all") == "true"
+	response, err := h.service.PendingWorklist(middleware.UserIDFromContext(r.Context()), all)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *LabHandler) ListPatientLabOrders(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.ListPatientLabOrders(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *LabHandler) OrderLabTest(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.LabOrderRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.OrderLabTest(middleware.UserIDFromContext(r.Context()), patientID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *LabHandler) GetLabOrder(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	orderID, ok := pathOrderID(w, r)
+	if !ok {
+		return
+	}
+	response, err := h.service.GetLabOrder(patientID, orderID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// RecordLabResult serves POST /patients/{id}/lab-orders/{orderID}/result. The
+// result is flagged against the reference range for the patient.
+func (h *LabHandler) RecordLabResult(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	orderID, ok := pathOrderID(w, r)
+	if !ok {
+		return
+	}
+	var request dto.LabResultRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.RecordLabResult(middleware.UserIDFromContext(r.Context()), patientID, orderID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *LabHandler) CancelLabOrder(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	orderID, ok := pathOrderID(w, r)
+	if !ok {
+		return
+	}
+	response, err := h.service.CancelLabOrder(patientID, orderID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func pathOrderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("orderID"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid lab order ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, lab_service.ErrPatientNotFound), errors.Is(err, lab_service.ErrDiagnosisNotFound),
+		errors.Is(err, lab_service.ErrLabTestNotFound), errors.Is(err, lab_service.ErrLabOrderNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, lab_service.ErrConsentRequired):
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, lab_service.ErrLabTestExists), errors.Is(err, lab_service.ErrOrderNotPending):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, lab_service.ErrInvalidLabTest), errors.Is(err, lab_service.ErrInvalidLabOrder),
+		errors.Is(err, lab_service.ErrInvalidLabResult):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("lab handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
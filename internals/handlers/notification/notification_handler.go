@@ -0,0 +1,119 @@
+package notification_handler
+
+// Package notification_handler lets staff send patients reminders and
+// results-ready messages and follow their delivery.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/notification"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	notification_service "github.com/aaryansinhaa/patient-management-system/internals/service/notification"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type NotificationHandler struct {
+	service service.NotificationService
+}
+
+func NewNotificationHandler(service service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+func (h *NotificationHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("GET /patients/{id}/notifications", staff(h.ListPatientNotifications))
+	mux.Handle("POST /patients/{id}/notifications/appointment-reminder", staff(h.SendAppointmentReminder))
+	mux.Handle("POST /patients/{id}/notifications/results-ready", staff(h.SendResultsReady))
+	mux.Handle("POST /admin/notifications/{id}/retry", admin(h.RetryNotification))
+}
+
+func (h *NotificationHandler) ListPatientNotifications(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.ListPatientNotifications(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *NotificationHandler) SendAppointmentReminder(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.AppointmentReminderRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.SendAppointmentReminder(patientID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, response)
+}
+
+func (h *NotificationHandler) SendResultsReady(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.ResultsReadyRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.SendResultsReady(patientID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, response)
+}
+
+func (h *NotificationHandler) RetryNotification(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid notification id")
+		return
+	}
+	response, err := h.service.RetryNotification(notificationID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, response)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, notification_service.ErrPatientNotFound), errors.Is(err, notification_service.ErrDoctorNotFound),
+		errors.Is(err, notification_service.ErrNotificationNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, notification.ErrNoSMSConsent):
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, notification_service.ErrInvalidNotification), errors.Is(err, notification.ErrUnknownChannel),
+		errors.Is(err, notification.ErrNoRecipient):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("notification handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
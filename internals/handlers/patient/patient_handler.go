@@ -0,0 +1,153 @@
+package patient_handler
+
+// Package patient_handler exposes patient summaries, contacts and contact lookup.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	patient_service "github.com/aaryansinhaa/patient-management-system/internals/service/patient"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+type PatientHandler struct {
+	service service.PatientService
+}
+
+func NewPatientHandler(service service.PatientService) *PatientHandler {
+	return &PatientHandler{service: service}
+}
+
+func (h *PatientHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+	records := auth.Require(model.RoleReceptionist, model.RoleAdmin)
+	clinical := auth.RequirePatient(middleware.PathID("id"), model.RoleDoctor, model.RoleReceptionist, model.RoleAdmin)
+
+	mux.Handle("GET /patients/lookup", staff(h.FindPatientsByContact))
+	mux.Handle("GET /patients/{id}/summary", clinical(h.GetPatientSummary))
+	mux.Handle("GET /patients/{id}/contacts", staff(h.ListContacts))
+	mux.Handle("POST /patients/{id}/contacts", records(h.AddContact))
+	mux.Handle("PUT /patients/{id}/contacts/{contactID}", records(h.UpdateContact))
+	mux.Handle("DELETE /patients/{id}/contacts/{contactID}", records(h.RemoveContact))
+}
+
+// FindPatientsByContact serves GET /patients/lookup?contact=<phone or email>.
+func (h *PatientHandler) FindPatientsByContact(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.FindPatientsByContact(r.URL.Query().Get("contact"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *PatientHandler) GetPatientSummary(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GetPatientSummary(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *PatientHandler) ListContacts(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.ListContacts(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *PatientHandler) AddContact(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.PatientContactRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.AddContact(patientID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *PatientHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	patientID, contactID, ok := contactPath(w, r)
+	if !ok {
+		return
+	}
+	var request dto.PatientContactRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.UpdateContact(patientID, contactID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *PatientHandler) RemoveContact(w http.ResponseWriter, r *http.Request) {
+	patientID, contactID, ok := contactPath(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.RemoveContact(patientID, contactID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// contactPath parses the patient and contact ids, writing a 400 if either is invalid.
+func contactPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, int, bool) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return uuid.Nil, 0, false
+	}
+	contactID, err := strconv.Atoi(r.PathValue("contactID"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid contact id")
+		return uuid.Nil, 0, false
+	}
+	return patientID, contactID, true
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, patient_service.ErrPatientNotFound), errors.Is(err, patient_service.ErrContactNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, patient_service.ErrInvalidContact):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("patient handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,94 @@
+package patient_csv_handler
+
+// Package patient_csv_handler imports and exports patients as CSV files.
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	patient_csv_service "github.com/aaryansinhaa/patient-management-system/internals/service/patient_csv"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+// maxImportBodyBytes bounds the size of an uploaded CSV file.
+const maxImportBodyBytes = 64 << 20
+
+type PatientCSVHandler struct {
+	service service.PatientCSVService
+}
+
+func NewPatientCSVHandler(service service.PatientCSVService) *PatientCSVHandler {
+	return &PatientCSVHandler{service: service}
+}
+
+func (h *PatientCSVHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	onboarding := auth.Require(model.RoleReceptionist, model.RoleAdmin)
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("POST /patients/import", onboarding(h.ImportPatients))
+	mux.Handle("GET /patients/export", admin(h.ExportPatients))
+}
+
+// ImportPatients accepts the CSV either as the raw request body or as the
+// "file" part of a multipart form, and responds with the per-row report.
+func (h *PatientCSVHandler) ImportPatients(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	var body io.Reader = r.Body
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid multipart body")
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, `multipart body has no "file" part`)
+				return
+			}
+			if part.FormName() == "file" {
+				body = part
+				break
+			}
+		}
+	}
+
+	report, err := h.service.ImportPatients(body)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, report)
+}
+
+// ExportPatients streams every patient as CSV. Once the body has started an
+// error can only be logged; the client sees a truncated file.
+func (h *PatientCSVHandler) ExportPatients(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="patients.csv"`)
+	w.WriteHeader(http.StatusOK)
+	if err := h.service.ExportPatients(w); err != nil {
+		log.Printf("patient csv handler: export: %v", err)
+	}
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, "CSV file is too large")
+	case errors.Is(err, patient_csv_service.ErrInvalidCSV):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("patient csv handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,121 @@
+package patient_merge_handler
+
+// Package patient_merge_handler finds duplicate patients and merges them.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	patient_merge_service "github.com/aaryansinhaa/patient-management-system/internals/service/patient_merge"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type PatientMergeHandler struct {
+	service service.PatientMergeService
+}
+
+func NewPatientMergeHandler(service service.PatientMergeService) *PatientMergeHandler {
+	return &PatientMergeHandler{service: service}
+}
+
+func (h *PatientMergeHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	records := auth.Require(model.RoleReceptionist, model.RoleAdmin)
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("GET /patients/duplicates", records(h.FindDuplicates))
+	mux.Handle("GET /patients/{id}/duplicates", records(h.FindDuplicatesOf))
+	mux.Handle("GET /patients/{id}/merges", records(h.ListMerges))
+	mux.Handle("POST /patients/{id}/merge", admin(h.MergePatients))
+	mux.Handle("POST /patient-merges/{id}/undo", admin(h.UndoMerge))
+}
+
+func (h *PatientMergeHandler) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.FindDuplicates()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *PatientMergeHandler) FindDuplicatesOf(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.FindDuplicatesOf(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *PatientMergeHandler) ListMerges(w http.ResponseWriter, r *http.Request) {
+	patientID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.ListMerges(patientID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// MergePatients merges the patient named in the body into the patient in the path.
+func (h *PatientMergeHandler) MergePatients(w http.ResponseWriter, r *http.Request) {
+	survivorID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var request dto.MergePatientsRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.MergePatients(middleware.UserIDFromContext(r.Context()), survivorID, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *PatientMergeHandler) UndoMerge(w http.ResponseWriter, r *http.Request) {
+	mergeID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid merge id")
+		return
+	}
+	response, err := h.service.UndoMerge(middleware.UserIDFromContext(r.Context()), mergeID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, patient_merge_service.ErrPatientNotFound), errors.Is(err, patient_merge_service.ErrMergeNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, patient_merge_service.ErrSamePatient), errors.Is(err, patient_merge_service.ErrInvalidInput):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, patient_merge_service.ErrMergeConflict):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("patient merge handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
@@ -0,0 +1,176 @@
+package queue_handler
+
+// Package queue_handler exposes the per-doctor waiting-room queue over HTTP,
+// including a Server-Sent Events stream for waiting-room displays.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	queue_service "github.com/aaryansinhaa/patient-management-system/internals/service/queue"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+// keepAliveInterval is how often a comment is sent on idle streams so proxies
+// do not close them.
+const keepAliveInterval = 30 * time.Second
+
+type QueueHandler struct {
+	service service.QueueService
+}
+
+func NewQueueHandler(service service.QueueService) *QueueHandler {
+	return &QueueHandler{service: service}
+}
+
+func (h *QueueHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	staff := auth.Require(model.RoleDoctor, model.RoleReceptionist)
+
+	mux.Handle("POST /queue", staff(h.Enqueue))
+	mux.Handle("DELETE /queue/{entryID}", staff(h.LeaveQueue))
+	mux.Handle("GET /doctors/{id}/queue", staff(h.GetQueue))
+	mux.Handle("POST /doctors/{id}/queue/next", staff(h.CallNext))
+	mux.Handle("GET /doctors/{id}/queue/stream", staff(h.StreamQueue))
+}
+
+func (h *QueueHandler) Enqueue(w http.ResponseWriter, r *http.Request) {
+	var request dto.EnqueueRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.Enqueue(request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *QueueHandler) LeaveQueue(w http.ResponseWriter, r *http.Request) {
+	entryID, err := strconv.ParseInt(r.PathValue("entryID"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid queue entry id")
+		return
+	}
+	if err := h.service.LeaveQueue(entryID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *QueueHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	doctorID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.GetQueue(doctorID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *QueueHandler) CallNext(w http.ResponseWriter, r *http.Request) {
+	doctorID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CallNext(doctorID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// StreamQueue sends the current queue, then a "queue" event with the full
+// queue every time it changes, until the client disconnects.
+func (h *QueueHandler) StreamQueue(w http.ResponseWriter, r *http.Request) {
+	doctorID, err := utils.PathUUID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.WriteError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	// Subscribe before reading the snapshot so no change in between is lost.
+	updates, unsubscribe := h.service.Subscribe(doctorID)
+	defer unsubscribe()
+	current, err := h.service.GetQueue(doctorID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := writeEvent(w, *current); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case snapshot, ok := <-updates:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, snapshot); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, snapshot dto.QueueResponse) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: queue\ndata: %s\n\n", data)
+	return err
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, queue_service.ErrEncounterNotFound), errors.Is(err, queue_service.ErrQueueEntryNotFound),
+		errors.Is(err, queue_service.ErrQueueEmpty):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, queue_service.ErrEncounterNotWaiting):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, queue_service.ErrInvalidPriority):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("queue handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
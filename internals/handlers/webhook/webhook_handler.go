@@ -0,0 +1,168 @@
+package webhook_handler
+
+// Package webhook_handler lets admins manage the webhook subscriptions of
+// partner systems and inspect and replay their deliveries.
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/middleware"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/service"
+	webhook_service "github.com/aaryansinhaa/patient-management-system/internals/service/webhook"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+type WebhookHandler struct {
+	service service.WebhookService
+}
+
+func NewWebhookHandler(service service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) RegisterRoutes(mux *http.ServeMux, auth *middleware.Auth) {
+	admin := auth.Require(model.RoleAdmin)
+
+	mux.Handle("GET /admin/webhooks", admin(h.ListSubscriptions))
+	mux.Handle("POST /admin/webhooks", admin(h.CreateSubscription))
+	mux.Handle("GET /admin/webhooks/{id}", admin(h.GetSubscription))
+	mux.Handle("PUT /admin/webhooks/{id}", admin(h.UpdateSubscription))
+	mux.Handle("DELETE /admin/webhooks/{id}", admin(h.DeleteSubscription))
+	mux.Handle("GET /admin/webhooks/{id}/deliveries", admin(h.ListDeliveries))
+	mux.Handle("GET /admin/webhook-deliveries/{id}", admin(h.GetDelivery))
+	mux.Handle("POST /admin/webhook-deliveries/{id}/replay", admin(h.ReplayDelivery))
+}
+
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	response, err := h.service.ListSubscriptions()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var request dto.WebhookSubscriptionRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.CreateSubscription(middleware.UserIDFromContext(r.Context()), request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, response)
+}
+
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "invalid webhook subscription id")
+	if !ok {
+		return
+	}
+	response, err := h.service.GetSubscription(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "invalid webhook subscription id")
+	if !ok {
+		return
+	}
+	var request dto.WebhookSubscriptionRequest
+	if err := utils.DecodeJSON(w, r, &request); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response, err := h.service.UpdateSubscription(id, request)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "invalid webhook subscription id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSubscription(id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries takes an optional ?status=pending|delivered|failed filter.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "invalid webhook subscription id")
+	if !ok {
+		return
+	}
+	response, err := h.service.ListDeliveries(id, r.URL.Query().Get("status"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "invalid webhook delivery id")
+	if !ok {
+		return
+	}
+	response, err := h.service.GetDelivery(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "invalid webhook delivery id")
+	if !ok {
+		return
+	}
+	response, err := h.service.ReplayDelivery(id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, response)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, message string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, message)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook_service.ErrSubscriptionNotFound), errors.Is(err, webhook_service.ErrDeliveryNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, webhook_service.ErrDeliveryPending):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, webhook_service.ErrInvalidSubscription):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("webhook handler: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
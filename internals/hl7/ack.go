@@ -0,0 +1,53 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Acknowledgement codes for MSA-1.
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// timestampFormat is the HL7 DTM format used in MSH-7.
+const timestampFormat = "20060102150405-0700"
+
+// Sender identifies this system in MSH-3 and MSH-4 of acknowledgements.
+type Sender struct {
+	Application string
+	Facility    string
+}
+
+// Ack builds the acknowledgement for original. original may be nil when the
+// message could not be parsed; the ACK then has no control id to refer to.
+// text is placed in MSA-3 and should explain errors and rejections.
+func Ack(original *Message, code, text string, sender Sender, now time.Time) []byte {
+	delimiters := DefaultDelimiters
+	var receivingApplication, receivingFacility, event, controlID, version string
+	if original != nil {
+		delimiters = original.Delimiters
+		receivingApplication = original.Field("MSH", 3)
+		receivingFacility = original.Field("MSH", 4)
+		_, event = original.Type()
+		controlID = original.ControlID()
+		version = original.Field("MSH", 12)
+	}
+	if version == "" {
+		version = "2.5"
+	}
+
+	field := string(delimiters.Field)
+	component := string(delimiters.Component)
+	encoding := string([]byte{delimiters.Component, delimiters.Repetition, delimiters.Escape, delimiters.Subcomponent})
+	msh := []string{
+		"MSH", encoding, delimiters.Encode(sender.Application), delimiters.Encode(sender.Facility),
+		receivingApplication, receivingFacility, now.Format(timestampFormat), "",
+		"ACK" + component + event + component + "ACK", fmt.Sprintf("%d", now.UnixNano()), "P", version,
+	}
+	msa := []string{"MSA", code, controlID, delimiters.Encode(text)}
+	return []byte(strings.Join(msh, field) + "\r" + strings.Join(msa, field) + "\r")
+}
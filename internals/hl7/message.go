@@ -0,0 +1,163 @@
+// Package hl7 parses HL7 v2 messages, builds acknowledgements and speaks the
+// MLLP framing partner systems use to send them over TCP.
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrMalformed = errors.New("malformed HL7 message")
+
+// Delimiters are read from the MSH segment of each message.
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+var DefaultDelimiters = Delimiters{Field: '|', Component: '^', Repetition: '~', Escape: '\\', Subcomponent: '&'}
+
+// Segment is one line of a message. Fields are numbered as in the HL7
+// specification: Fields[1] is the first field after the segment name, and for
+// MSH Fields[1] is the field separator itself.
+type Segment struct {
+	Name   string
+	Fields []string
+}
+
+type Message struct {
+	Delimiters Delimiters
+	Segments   []Segment
+}
+
+// Parse splits a message into segments and fields. Segments may be separated
+// by CR, LF or CRLF. The message must start with an MSH segment.
+func Parse(raw []byte) (*Message, error) {
+	text := strings.ReplaceAll(string(raw), "\r\n", "\r")
+	text = strings.ReplaceAll(text, "\n", "\r")
+	if !strings.HasPrefix(text, "MSH") || len(text) < 8 {
+		return nil, fmt.Errorf("%w: message does not start with MSH", ErrMalformed)
+	}
+
+	delimiters := Delimiters{Field: text[3]}
+	encoding, _, _ := strings.Cut(text[4:], "\r")
+	encoding, _, _ = strings.Cut(encoding, string(delimiters.Field))
+	if len(encoding) < 4 {
+		return nil, fmt.Errorf("%w: MSH-2 must hold four encoding characters", ErrMalformed)
+	}
+	delimiters.Component = encoding[0]
+	delimiters.Repetition = encoding[1]
+	delimiters.Escape = encoding[2]
+	delimiters.Subcomponent = encoding[3]
+
+	message := &Message{Delimiters: delimiters}
+	for _, line := range strings.Split(text, "\r") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, string(delimiters.Field))
+		name := fields[0]
+		if len(name) != 3 {
+			return nil, fmt.Errorf("%w: invalid segment name %q", ErrMalformed, name)
+		}
+		if name == "MSH" {
+			// MSH-1 is the separator between the name and MSH-2.
+			fields = append([]string{name, string(delimiters.Field)}, fields[1:]...)
+		}
+		message.Segments = append(message.Segments, Segment{Name: name, Fields: fields})
+	}
+	return message, nil
+}
+
+// Segment returns the first segment with the given name.
+func (m *Message) Segment(name string) (*Segment, bool) {
+	for i := range m.Segments {
+		if m.Segments[i].Name == name {
+			return &m.Segments[i], true
+		}
+	}
+	return nil, false
+}
+
+// Field returns field n of the first segment with the given name, or "" when
+// either is missing. Escape sequences are not decoded.
+func (m *Message) Field(segment string, n int) string {
+	s, ok := m.Segment(segment)
+	if !ok || n >= len(s.Fields) {
+		return ""
+	}
+	return s.Fields[n]
+}
+
+// Repetitions splits a field into its repetitions.
+func (m *Message) Repetitions(field string) []string {
+	if field == "" {
+		return nil
+	}
+	return strings.Split(field, string(m.Delimiters.Repetition))
+}
+
+// Component returns component n (1-based) of a field or repetition, with
+// escape sequences decoded.
+func (m *Message) Component(value string, n int) string {
+	components := strings.Split(value, string(m.Delimiters.Component))
+	if n < 1 || n > len(components) {
+		return ""
+	}
+	component := components[n-1]
+	// Subcomponents are not used by the mapped fields; keep the first.
+	if i := strings.IndexByte(component, m.Delimiters.Subcomponent); i >= 0 {
+		component = component[:i]
+	}
+	return m.Unescape(component)
+}
+
+// Value returns component n of the first repetition of field f of a segment.
+func (m *Message) Value(segment string, f, n int) string {
+	repetitions := m.Repetitions(m.Field(segment, f))
+	if len(repetitions) == 0 {
+		return ""
+	}
+	return m.Component(repetitions[0], n)
+}
+
+// Unescape decodes the standard escape sequences for the delimiters.
+func (m *Message) Unescape(s string) string {
+	esc := string(m.Delimiters.Escape)
+	if !strings.Contains(s, esc) {
+		return s
+	}
+	return strings.NewReplacer(
+		esc+"F"+esc, string(m.Delimiters.Field),
+		esc+"S"+esc, string(m.Delimiters.Component),
+		esc+"R"+esc, string(m.Delimiters.Repetition),
+		esc+"T"+esc, string(m.Delimiters.Subcomponent),
+		esc+"E"+esc, esc,
+	).Replace(s)
+}
+
+// Encode escapes s so it can be placed in a field.
+func (d Delimiters) Encode(s string) string {
+	esc := string(d.Escape)
+	return strings.NewReplacer(
+		esc, esc+"E"+esc,
+		string(d.Field), esc+"F"+esc,
+		string(d.Component), esc+"S"+esc,
+		string(d.Repetition), esc+"R"+esc,
+		string(d.Subcomponent), esc+"T"+esc,
+	).Replace(s)
+}
+
+// ControlID returns MSH-10, which the acknowledgement must echo.
+func (m *Message) ControlID() string {
+	return m.Field("MSH", 10)
+}
+
+// Type returns the message code and trigger event from MSH-9, e.g. "ADT", "A04".
+func (m *Message) Type() (code, event string) {
+	return m.Value("MSH", 9, 1), m.Value("MSH", 9, 2)
+}
@@ -0,0 +1,145 @@
+package hl7
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// MLLP frames each message as <VT> message <FS><CR>.
+const (
+	startBlock = 0x0b
+	endBlock   = 0x1c
+	carriageCR = 0x0d
+)
+
+// maxFrameBytes bounds a single framed message.
+const maxFrameBytes = 1 << 20
+
+var errFrameTooLarge = errors.New("MLLP frame too large")
+
+// ReadFrame reads the next MLLP framed message. Bytes before the start block
+// are discarded. io.EOF is returned when the peer closes between messages.
+func ReadFrame(r *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+	var frame []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if b == endBlock {
+			next, err := r.ReadByte()
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if err == nil && next != carriageCR {
+				r.UnreadByte()
+			}
+			return frame, nil
+		}
+		if len(frame) >= maxFrameBytes {
+			return nil, errFrameTooLarge
+		}
+		frame = append(frame, b)
+	}
+}
+
+// WriteFrame writes message in MLLP framing.
+func WriteFrame(w io.Writer, message []byte) error {
+	frame := make([]byte, 0, len(message)+3)
+	frame = append(frame, startBlock)
+	frame = append(frame, message...)
+	frame = append(frame, endBlock, carriageCR)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Handler processes one message and returns the acknowledgement to send back.
+type Handler func(message []byte, remoteAddr string) []byte
+
+// Server accepts MLLP connections and answers every message with the ACK
+// returned by Handler. Connections idle for longer than IdleTimeout are closed.
+type Server struct {
+	Addr        string
+	Handler     Handler
+	IdleTimeout time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for MLLP on %s: %w", s.Addr, err)
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept MLLP connection: %w", err)
+		}
+		go s.serve(conn)
+	}
+}
+
+// Close stops accepting connections. Connections already open finish their
+// current message and are closed when the peer disconnects or goes idle.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	remoteAddr := conn.RemoteAddr().String()
+	reader := bufio.NewReader(conn)
+	for {
+		if s.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+		message, err := ReadFrame(reader)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return
+			}
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("mllp: connection from %s: %v", remoteAddr, err)
+			}
+			return
+		}
+		ack := s.Handler(message, remoteAddr)
+		if err := WriteFrame(conn, ack); err != nil {
+			log.Printf("mllp: failed to acknowledge %s: %v", remoteAddr, err)
+			return
+		}
+	}
+}
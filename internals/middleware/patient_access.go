@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+// PatientAuthorizer decides whether a user may see a patient's records, or
+// those of an encounter's patient. action describes the request, for the
+// access log.
+type PatientAuthorizer interface {
+	AuthorizePatient(userID uuid.UUID, role string, patientID uuid.UUID, action string) (bool, error)
+	AuthorizeEncounter(userID uuid.UUID, role string, encounterID uuid.UUID, action string) (bool, error)
+}
+
+// PatientLocator finds the patient or encounter a request is about. ok is
+// false if the request does not name a valid one; the handler then rejects
+// the request itself.
+type PatientLocator func(r *http.Request) (id uuid.UUID, ok bool)
+
+// PathID locates the record by the named path value.
+func PathID(name string) PatientLocator {
+	return func(r *http.Request) (uuid.UUID, bool) {
+		id, err := uuid.Parse(r.PathValue(name))
+		return id, err == nil
+	}
+}
+
+// WithPatientAuthorizer returns a copy of a whose RequirePatient and
+// RequireEncounter check access with authorizer.
+func (a *Auth) WithPatientAuthorizer(authorizer PatientAuthorizer) *Auth {
+	scoped := *a
+	scoped.patients = authorizer
+	return &scoped
+}
+
+// RequirePatient is Require for routes that show the records of the patient
+// locate finds. Users the authorizer turns away get a 403.
+func (a *Auth) RequirePatient(locate PatientLocator, roles ...string) func(http.HandlerFunc) http.Handler {
+	return a.requireAccess(locate, roles, func(userID uuid.UUID, role string, id uuid.UUID, action string) (bool, error) {
+		return a.patients.AuthorizePatient(userID, role, id, action)
+	})
+}
+
+// RequireEncounter is RequirePatient for routes about an encounter, whose
+// patient's records they show.
+func (a *Auth) RequireEncounter(locate PatientLocator, roles ...string) func(http.HandlerFunc) http.Handler {
+	return a.requireAccess(locate, roles, func(userID uuid.UUID, role string, id uuid.UUID, action string) (bool, error) {
+		return a.patients.AuthorizeEncounter(userID, role, id, action)
+	})
+}
+
+func (a *Auth) requireAccess(locate PatientLocator, roles []string,
+	authorize func(userID uuid.UUID, role string, id uuid.UUID, action string) (bool, error)) func(http.HandlerFunc) http.Handler {
+	require := a.Require(roles...)
+	return func(next http.HandlerFunc) http.Handler {
+		return require(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := locate(r)
+			if a.patients == nil || !ok {
+				next(w, r)
+				return
+			}
+			claims, _ := ClaimsFromContext(r.Context())
+			allowed, err := authorize(UserIDFromContext(r.Context()), claims.Role, id, r.Method+" "+r.URL.RequestURI())
+			if err != nil {
+				log.Printf("patient access: %v", err)
+				utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if !allowed {
+				utils.WriteError(w, http.StatusForbidden, "you are not treating this patient; request emergency access to see their records")
+				return
+			}
+			next(w, r)
+		})
+	}
+}
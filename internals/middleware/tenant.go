@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+// Tenants sends each request to the handler of the clinic named in its access
+// token. Clinic handlers are built on first use and kept. Requests without a
+// valid access token, such as logins, go to the shared handler, whose routes
+// either need no token or reject the request themselves.
+type Tenants struct {
+	jwtManager *utils.JWTManager
+	shared     http.Handler
+	build      func(clinicID uuid.UUID) (http.Handler, error)
+
+	mu       sync.Mutex
+	handlers map[uuid.UUID]http.Handler
+}
+
+func NewTenants(jwtManager *utils.JWTManager, shared http.Handler,
+	build func(clinicID uuid.UUID) (http.Handler, error)) *Tenants {
+	return &Tenants{
+		jwtManager: jwtManager,
+		shared:     shared,
+		build:      build,
+		handlers:   make(map[uuid.UUID]http.Handler),
+	}
+}
+
+func (t *Tenants) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		t.shared.ServeHTTP(w, r)
+		return
+	}
+	// Enrolment-only tokens still belong to a clinic; Require turns them
+	// away from every route but enrolment.
+	claims, err := t.jwtManager.VerifyEnrollment(token)
+	if err != nil {
+		t.shared.ServeHTTP(w, r)
+		return
+	}
+	clinicID, err := claims.ClinicID()
+	if err != nil {
+		// Tokens issued before clinics existed carry none.
+		utils.WriteError(w, http.StatusUnauthorized, "token has no clinic, log in again")
+		return
+	}
+	handler, err := t.handler(clinicID)
+	if err != nil {
+		log.Printf("tenants: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func (t *Tenants) handler(clinicID uuid.UUID) (http.Handler, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if handler, ok := t.handlers[clinicID]; ok {
+		return handler, nil
+	}
+	handler, err := t.build(clinicID)
+	if err != nil {
+		return nil, err
+	}
+	t.handlers[clinicID] = handler
+	return handler, nil
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a document or image uploaded to a patient's record,
+// optionally for one of their diagnoses. Its content is kept in the blob
+// store under StorageKey, shared by every attachment with the same SHA256.
+// A deleted attachment stays in the record with DeletedAt set.
+type Attachment struct {
+	ID          int64
+	PatientID   uuid.UUID
+	DiagnosisID *int
+	Filename    string
+	Description string
+	MimeType    string
+	Size        int64
+	SHA256      string
+	StorageKey  string
+	UploadedBy  uuid.UUID
+	UploadedAt  time.Time
+	DeletedBy   uuid.NullUUID
+	DeletedAt   *time.Time
+}
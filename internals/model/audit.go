@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditActionLoginLocked     = "login.locked"
+	AuditActionAccountUnlocked = "account.unlocked"
+	AuditActionMFAEnabled      = "mfa.enabled"
+	AuditActionMFADisabled     = "mfa.disabled"
+	AuditActionMFAPolicySet    = "mfa.policy_set"
+	AuditActionPasswordChanged = "password.changed"
+	AuditActionPasswordReset   = "password.reset"
+	AuditActionResetIssued     = "password.reset_issued"
+	AuditActionUserDeactivated = "user.deactivated"
+)
+
+type AuditEntry struct {
+	ID        int64
+	ActorID   uuid.NullUUID
+	Action    string
+	Subject   string
+	Details   string
+	ClientIP  string
+	CreatedAt time.Time
+}
@@ -0,0 +1,132 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Money is an amount in the minor unit of the configured currency (e.g. paise
+// or cents). Amounts are never stored as floats.
+type Money int64
+
+// String formats the amount with two decimal places, e.g. 12345 as "123.45".
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/100, m%100)
+}
+
+// BasisPoints is a rate in hundredths of a percent; 1800 is 18%.
+type BasisPoints int
+
+// Of returns the rate applied to amount, rounded half up to the minor unit.
+func (b BasisPoints) Of(amount Money) Money {
+	return (amount*Money(b) + 5000) / 10000
+}
+
+const (
+	CatalogueKindService    = "service"
+	CatalogueKindMedication = "medication"
+)
+
+const (
+	InvoiceStatusIssued        = "issued"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+)
+
+type TaxRate struct {
+	ID   int
+	Name string
+	Rate BasisPoints
+}
+
+// CatalogueItem is a billable service or medication with its current price.
+type CatalogueItem struct {
+	ID        int
+	Code      string
+	Name      string
+	Kind      string
+	UnitPrice Money
+	TaxRateID *int
+	Active    bool
+}
+
+// EncounterCharge is a catalogue service rendered during an encounter.
+type EncounterCharge struct {
+	ID              int
+	EncounterID     uuid.UUID
+	CatalogueItemID int
+	Quantity        int
+	AddedBy         uuid.UUID
+	CreatedAt       time.Time
+}
+
+// InvoiceLine copies the price and tax rate at the time of invoicing so later
+// catalogue changes do not alter issued invoices.
+type InvoiceLine struct {
+	ID              int
+	InvoiceID       uuid.UUID
+	CatalogueItemID *int
+	Description     string
+	Quantity        int
+	UnitPrice       Money
+	TaxRate         BasisPoints
+	TaxAmount       Money
+	LineTotal       Money
+}
+
+type Invoice struct {
+	ID            uuid.UUID
+	InvoiceNumber string
+	PatientID     uuid.UUID
+	EncounterID   uuid.NullUUID
+	Status        string
+	Currency      string
+	Subtotal      Money
+	TaxTotal      Money
+	Total         Money
+	AmountPaid    Money
+	IssuedBy      uuid.UUID
+	IssuedAt      time.Time
+	Lines         []InvoiceLine
+}
+
+// Outstanding is what the patient still owes on the invoice.
+func (i Invoice) Outstanding() Money {
+	return i.Total - i.AmountPaid
+}
+
+type Payment struct {
+	ID         int
+	InvoiceID  uuid.UUID
+	Amount     Money
+	Refunded   Money
+	Method     string
+	Reference  string
+	ReceivedBy uuid.UUID
+	ReceivedAt time.Time
+}
+
+type Refund struct {
+	ID         int
+	PaymentID  int
+	InvoiceID  uuid.UUID
+	Amount     Money
+	Reason     string
+	RefundedBy uuid.UUID
+	CreatedAt  time.Time
+}
+
+const (
+	PaymentMethodCash         = "cash"
+	PaymentMethodCard         = "card"
+	PaymentMethodUPI          = "upi"
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodInsurance    = "insurance"
+)
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultClinicID is the clinic every record created before clinics existed
+// was assigned to.
+var DefaultClinicID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// Clinic is one branch of the organisation. Patients, their diagnoses and
+// staff belong to a clinic and are only visible from within it.
+type Clinic struct {
+	ID        uuid.UUID
+	Name      string
+	Code      string
+	Address   string
+	CreatedAt time.Time
+}
+
+// ClinicMembership lets a user work at a clinic. A user's default clinic is
+// the one they land in when they log in.
+type ClinicMembership struct {
+	UserID    uuid.UUID
+	Clinic    Clinic
+	IsDefault bool
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Consent scopes: what a patient can agree to.
+const (
+	ConsentScopeTreatment    = "treatment"
+	ConsentScopeDataSharing  = "data_sharing"
+	ConsentScopeSMSReminders = "sms_reminders"
+)
+
+// ConsentText is the wording a patient agrees to for one scope. Texts are
+// never edited; publishing new wording adds the next version.
+type ConsentText struct {
+	ID        int
+	Scope     string
+	Version   int
+	Text      string
+	CreatedBy uuid.UUID
+	CreatedAt time.Time
+}
+
+// PatientConsent is a patient agreeing to one version of a consent text. A
+// withdrawn consent is kept with who withdrew it and when, and a later grant
+// is a new record.
+type PatientConsent struct {
+	ID          int64
+	PatientID   uuid.UUID
+	Scope       string
+	TextID      int
+	TextVersion int
+	GrantedBy   uuid.UUID
+	GrantedAt   time.Time
+	WithdrawnBy uuid.NullUUID
+	WithdrawnAt *time.Time
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// DataKey is a key that encrypts sensitive columns, stored wrapped (encrypted)
+// by the master key MasterKeyID. Only the active data key encrypts new
+// values; older ones are kept to decrypt rows written with them.
+type DataKey struct {
+	ID          int
+	MasterKeyID string
+	WrappedKey  []byte
+	Active      bool
+	CreatedAt   time.Time
+}
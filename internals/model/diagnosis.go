@@ -1,10 +1,16 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Diagnosis struct {
 	ID          int
 	PatientID   uuid.UUID
 	DoctorID    uuid.UUID
+	EncounterID uuid.NullUUID
 	Description string
+	CreatedAt   time.Time
 }
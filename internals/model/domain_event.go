@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Domain event types. Events leave the system through webhooks, so their
+// payloads carry ids only and never patient data.
+const (
+	EventPatientRegistered = "patient.registered"
+	EventDiagnosisRecorded = "diagnosis.recorded"
+	EventUserDeactivated   = "user.deactivated"
+)
+
+// DomainEventTypes lists every event type, e.g. for validating webhook
+// subscriptions.
+var DomainEventTypes = []string{EventPatientRegistered, EventDiagnosisRecorded, EventUserDeactivated}
+
+// An event is pending until every subscriber has handled it, or failed once
+// every attempt to deliver it has.
+const (
+	DomainEventStatusPending   = "pending"
+	DomainEventStatusDelivered = "delivered"
+	DomainEventStatusFailed    = "failed"
+)
+
+// DomainEvent is a change other parts of the system may react to, stored in
+// the outbox with the change itself. AggregateID is the id of the record it
+// is about.
+type DomainEvent struct {
+	ID            int64
+	ClinicID      uuid.NullUUID
+	Type          string
+	AggregateID   string
+	Payload       json.RawMessage
+	OccurredAt    time.Time
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	DeliveredAt   *time.Time
+}
+
+type PatientRegisteredPayload struct {
+	PatientID uuid.UUID `json:"patient_id"`
+}
+
+type DiagnosisRecordedPayload struct {
+	DiagnosisID int           `json:"diagnosis_id"`
+	PatientID   uuid.UUID     `json:"patient_id"`
+	DoctorID    uuid.UUID     `json:"doctor_id"`
+	EncounterID uuid.NullUUID `json:"encounter_id"`
+}
+
+type UserDeactivatedPayload struct {
+	UserID        uuid.UUID `json:"user_id"`
+	DeactivatedBy uuid.UUID `json:"deactivated_by"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmergencyAccess is a doctor breaking the glass: elevated access to the
+// records of a patient they are not treating, for a limited time. Every
+// grant waits in the admins' review queue until ReviewedAt is set.
+type EmergencyAccess struct {
+	ID         int64
+	DoctorID   uuid.UUID
+	PatientID  uuid.UUID
+	Reason     string
+	GrantedAt  time.Time
+	ExpiresAt  time.Time
+	ReviewedBy uuid.NullUUID
+	ReviewedAt *time.Time
+	ReviewNote string
+}
+
+// IsActive reports whether the access still lets the doctor in at now.
+func (a EmergencyAccess) IsActive(now time.Time) bool {
+	return now.Before(a.ExpiresAt)
+}
+
+// EmergencyAccessLog is one request made under an emergency access.
+type EmergencyAccessLog struct {
+	ID         int64
+	AccessID   int64
+	Action     string
+	AccessedAt time.Time
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// An encounter moves from checked_in to in_consultation to discharged. It can
+// be cancelled before the consultation starts, e.g. when the patient leaves.
+const (
+	EncounterStatusCheckedIn      = "checked_in"
+	EncounterStatusInConsultation = "in_consultation"
+	EncounterStatusDischarged     = "discharged"
+	EncounterStatusCancelled      = "cancelled"
+)
+
+// Encounter is a single visit of a patient. Diagnoses, vitals and
+// prescriptions recorded during the visit are attached to it.
+type Encounter struct {
+	ID                    uuid.UUID
+	PatientID             uuid.UUID
+	DoctorID              uuid.UUID
+	ChiefComplaint        string
+	Status                string
+	CheckedInAt           time.Time
+	ConsultationStartedAt *time.Time
+	DischargedAt          *time.Time
+	DischargeNotes        string
+	CreatedBy             uuid.UUID
+}
+
+// IsOpen reports whether clinical data can still be recorded against the encounter.
+func (e Encounter) IsOpen() bool {
+	return e.Status == EncounterStatusCheckedIn || e.Status == EncounterStatusInConsultation
+}
+
+// Vitals are one set of measurements. Unmeasured values are nil.
+type Vitals struct {
+	ID               int
+	EncounterID      uuid.UUID
+	PatientID        uuid.UUID
+	RecordedBy       uuid.UUID
+	TemperatureC     *float64
+	PulseBPM         *int
+	SystolicBP       *int
+	DiastolicBP      *int
+	RespiratoryRate  *int
+	OxygenSaturation *int
+	WeightKg         *float64
+	HeightCm         *float64
+	RecordedAt       time.Time
+}
+
+// Prescription optionally links to a medication in the catalogue so it can be billed.
+type Prescription struct {
+	ID              int
+	EncounterID     uuid.UUID
+	PatientID       uuid.UUID
+	DoctorID        uuid.UUID
+	CatalogueItemID *int
+	Medication      string
+	Dosage          string
+	Frequency       string
+	DurationDays    int
+	Instructions    string
+	CreatedAt       time.Time
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PatientIdentifier links a patient to the id another system knows them by,
+// e.g. a hospital's medical record number. System names the issuer.
+type PatientIdentifier struct {
+	System    string
+	Value     string
+	PatientID uuid.UUID
+	CreatedAt time.Time
+}
+
+// HL7DeadLetter is an inbound HL7 message that could not be processed,
+// kept for review.
+type HL7DeadLetter struct {
+	ID         int64
+	RemoteAddr string
+	Message    string
+	Error      string
+	ReceivedAt time.Time
+	ResolvedAt *time.Time
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A lab order is ordered until its result is recorded or it is cancelled.
+const (
+	LabOrderOrdered   = "ordered"
+	LabOrderResulted  = "resulted"
+	LabOrderCancelled = "cancelled"
+)
+
+// How a result compares with the reference range of the patient. A test
+// with no range for the patient cannot be judged.
+const (
+	LabFlagNormal  = "normal"
+	LabFlagLow     = "low"
+	LabFlagHigh    = "high"
+	LabFlagNoRange = "no_range"
+)
+
+// LabTest is a test in the catalogue. Its results are numbers in Unit,
+// judged against the reference range that matches the patient.
+type LabTest struct {
+	ID     int
+	Code   string
+	Name   string
+	Unit   string
+	Active bool
+	Ranges []LabReferenceRange
+}
+
+// LabReferenceRange is the normal range of a test for patients of Gender,
+// any when empty, aged MinAge to MaxAge years inclusive; a nil MaxAge has no
+// upper limit. Either end of the range may be open.
+type LabReferenceRange struct {
+	Gender string
+	MinAge int
+	MaxAge *int
+	Low    *float64
+	High   *float64
+}
+
+// LabOrder is a test a doctor ordered for a patient, optionally for one of
+// their diagnoses. Result is set once the order is resulted.
+type LabOrder struct {
+	ID          int64
+	PatientID   uuid.UUID
+	DiagnosisID *int
+	TestID      int
+	TestCode    string
+	TestName    string
+	Unit        string
+	OrderedBy   uuid.UUID
+	Notes       string
+	Status      string
+	OrderedAt   time.Time
+	CancelledAt *time.Time
+	Result      *LabResult
+}
+
+// LabResult keeps the unit and reference range it was judged against, so
+// later catalogue changes do not change how it reads.
+type LabResult struct {
+	OrderID       int64
+	Value         float64
+	Unit          string
+	ReferenceLow  *float64
+	ReferenceHigh *float64
+	Flag          string
+	Comment       string
+	RecordedBy    uuid.UUID
+	RecordedAt    time.Time
+}
+
+func (r LabResult) Abnormal() bool {
+	return r.Flag == LabFlagLow || r.Flag == LabFlagHigh
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+const (
+	LoginScopeUsername = "username"
+	LoginScopeIP       = "ip"
+)
+
+// LoginAttempt tracks consecutive failed logins for a username or client IP.
+type LoginAttempt struct {
+	Scope         string
+	Key           string
+	Failures      int
+	LastFailureAt *time.Time
+	LockedUntil   *time.Time
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AllergySeverityMild            = "mild"
+	AllergySeverityModerate        = "moderate"
+	AllergySeveritySevere          = "severe"
+	AllergySeverityLifeThreatening = "life_threatening"
+)
+
+const (
+	ConditionStatusActive   = "active"
+	ConditionStatusInactive = "inactive"
+	ConditionStatusResolved = "resolved"
+)
+
+type Allergy struct {
+	ID         int
+	PatientID  uuid.UUID
+	Substance  string
+	Reaction   string
+	Severity   string
+	RecordedBy uuid.UUID
+	CreatedAt  time.Time
+}
+
+// ChronicCondition is an entry on the patient's problem list.
+type ChronicCondition struct {
+	ID         int
+	PatientID  uuid.UUID
+	Name       string
+	Status     string
+	OnsetDate  *time.Time
+	Notes      string
+	RecordedBy uuid.UUID
+	CreatedAt  time.Time
+}
+
+// FamilyHistory records a condition present in a relative, e.g. "mother" / "type 2 diabetes".
+type FamilyHistory struct {
+	ID         int
+	PatientID  uuid.UUID
+	Relation   string
+	Condition  string
+	Notes      string
+	RecordedBy uuid.UUID
+	CreatedAt  time.Time
+}
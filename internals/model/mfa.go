@@ -0,0 +1,17 @@
+package model
+
+import "github.com/google/uuid"
+
+// TOTPSettings is the two-factor state of a user. LastStep is the last TOTP
+// time step accepted, which stops a code from being replayed.
+type TOTPSettings struct {
+	UserID   uuid.UUID
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+type MFARolePolicy struct {
+	Role     string
+	Required bool
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	NotificationChannelSMS   = "sms"
+	NotificationChannelEmail = "email"
+)
+
+// Message templates a notification can be rendered from.
+const (
+	NotificationAppointmentReminder = "appointment_reminder"
+	NotificationResultsReady        = "results_ready"
+)
+
+// A notification is pending until it is sent, or failed once every attempt
+// to send it has.
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"
+)
+
+// Notification is a message to a patient in the outbox. It is rendered when
+// it is queued and sent at NextAttemptAt, which moves forward after each
+// failed attempt.
+type Notification struct {
+	ID            int64
+	PatientID     uuid.UUID
+	Channel       string
+	Template      string
+	Recipient     string
+	Subject       string
+	Body          string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PatientMerge records one duplicate patient being folded into a survivor.
+// Merged is a snapshot of the duplicate as it was just before it was removed,
+// so the merge can be undone.
+type PatientMerge struct {
+	ID         int64
+	SurvivorID uuid.UUID
+	Merged     Patient
+	MergedBy   uuid.UUID
+	MergedAt   time.Time
+	UndoneBy   uuid.NullUUID
+	UndoneAt   *time.Time
+}
+
+// DuplicateCandidate is a pair of patients that probably describe the same
+// person. Score is between 0 and 1; Reasons lists what matched.
+type DuplicateCandidate struct {
+	Patient   Patient
+	Duplicate Patient
+	Score     float64
+	Reasons   []string
+}
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Patient struct {
 	ID          uuid.UUID
@@ -8,4 +12,40 @@ type Patient struct {
 	Age         int
 	Gender      string
 	PhoneNumber string
+
+	// Medical history, only loaded when a caller asks for it.
+	Allergies         []Allergy
+	ChronicConditions []ChronicCondition
+	FamilyHistory     []FamilyHistory
+
+	// Contacts, only loaded when a caller asks for them.
+	Contacts []PatientContact
+}
+
+// How a contact relates to the patient. A "self" contact is another way to
+// reach the patient directly, e.g. a second phone or an email address.
+const (
+	ContactRelationSelf      = "self"
+	ContactRelationParent    = "parent"
+	ContactRelationGuardian  = "guardian"
+	ContactRelationSpouse    = "spouse"
+	ContactRelationChild     = "child"
+	ContactRelationSibling   = "sibling"
+	ContactRelationCaregiver = "caregiver"
+	ContactRelationOther     = "other"
+)
+
+// PatientContact is one way to reach a patient or someone acting for them.
+// IsGuardian marks the person who holds consent on the patient's behalf.
+type PatientContact struct {
+	ID          int
+	PatientID   uuid.UUID
+	Name        string
+	Relation    string
+	PhoneNumber string
+	Email       string
+	Address     string
+	IsEmergency bool
+	IsGuardian  bool
+	CreatedAt   time.Time
 }
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	QueuePriorityEmergency = "emergency"
+	QueuePriorityElderly   = "elderly"
+	QueuePriorityNormal    = "normal"
+)
+
+const (
+	QueueStatusWaiting = "waiting"
+	QueueStatusCalled  = "called"
+	QueueStatusDone    = "done"
+	QueueStatusLeft    = "left"
+)
+
+// QueueEntry is a checked-in patient waiting for a doctor. TokenNumber is the
+// number shown on the waiting-room display and restarts every day per doctor.
+type QueueEntry struct {
+	ID          int64
+	EncounterID uuid.UUID
+	DoctorID    uuid.UUID
+	PatientID   uuid.UUID
+	TokenNumber int
+	Priority    string
+	Status      string
+	QueueDate   time.Time
+	CheckedInAt time.Time
+	CalledAt    *time.Time
+}
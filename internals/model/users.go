@@ -1,12 +1,39 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleAdmin        = "admin"
+	RoleDoctor       = "doctor"
+	RoleReceptionist = "receptionist"
+)
 
 type User struct {
 	ID          uuid.UUID
 	Name        string
 	Role        string
 	Username    string
-	Password    string
 	PhoneNumber string
 }
+
+// UserCredentials holds the secret material of a user. It is only ever
+// loaded through the CredentialRepository and must not leave the auth service.
+type UserCredentials struct {
+	UserID       uuid.UUID
+	Username     string
+	PasswordHash string
+}
+
+// PasswordResetToken is an admin-issued, single-use token. Only the SHA-256 of
+// the token is stored.
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedBy uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A delivery is pending until the receiver accepts it, or failed once every
+// attempt has been refused. Failed and delivered deliveries can be replayed.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription is a partner endpoint that receives the clinic's
+// events of EventTypes, signed with Secret.
+type WebhookSubscription struct {
+	ID         int64
+	URL        string
+	EventTypes []string
+	Secret     string
+	Active     bool
+	CreatedBy  uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// WebhookDelivery is one event sent to one subscription. It is retried with
+// a growing delay until the receiver accepts it.
+type WebhookDelivery struct {
+	ID                 int64
+	SubscriptionID     int64
+	EventID            int64
+	EventType          string
+	Status             string
+	Attempts           int
+	NextAttemptAt      time.Time
+	LastError          string
+	LastResponseStatus int
+	CreatedAt          time.Time
+	DeliveredAt        *time.Time
+}
+
+// WebhookAttempt is one try at a delivery. ResponseStatus is 0 when the
+// receiver could not be reached.
+type WebhookAttempt struct {
+	ID             int64
+	DeliveryID     int64
+	AttemptedAt    time.Time
+	ResponseStatus int
+	Error          string
+	Duration       time.Duration
+}
+
+// PendingWebhookDelivery is a claimed delivery with the subscription and
+// event needed to send it.
+type PendingWebhookDelivery struct {
+	Delivery     WebhookDelivery
+	Subscription WebhookSubscription
+	Event        DomainEvent
+}
@@ -0,0 +1,140 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+)
+
+// Dispatcher sends the notifications in the outbox as they fall due. Delivery
+// is at least once: a message whose send succeeded but could not be marked
+// sent is sent again.
+type Dispatcher struct {
+	outbox          repositories.NotificationRepository
+	consents        repositories.ConsentRepository
+	providers       map[string]Provider
+	pollInterval    time.Duration
+	batchSize       int
+	sendTimeout     time.Duration
+	maxAttempts     int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+}
+
+func NewDispatcher(outbox repositories.NotificationRepository, consents repositories.ConsentRepository,
+	providers map[string]Provider, cfg config.NotificationConfig) *Dispatcher {
+	return &Dispatcher{
+		outbox:          outbox,
+		consents:        consents,
+		providers:       providers,
+		pollInterval:    cfg.PollInterval,
+		batchSize:       cfg.BatchSize,
+		sendTimeout:     cfg.SendTimeout,
+		maxAttempts:     cfg.MaxAttempts,
+		retryBackoff:    cfg.RetryBackoff,
+		maxRetryBackoff: cfg.MaxRetryBackoff,
+	}
+}
+
+// Run sends due notifications until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		// Keep going while batches come back full, then wait for the next poll.
+		for {
+			claimed, err := d.DispatchDue(ctx)
+			if err != nil {
+				log.Printf("notifications: %v", err)
+				break
+			}
+			if claimed < d.batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due notifications and returns how many it
+// took from the outbox.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// A claimed notification is only retried by another dispatcher once every
+	// notification in the batch could have timed out.
+	lease := time.Duration(d.batchSize) * d.sendTimeout
+	due, err := d.outbox.ClaimDueNotifications(d.batchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, notification := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		d.send(ctx, notification)
+	}
+	return len(due), nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, notification model.Notification) {
+	provider, ok := d.providers[notification.Channel]
+	if !ok {
+		// Retrying cannot help until a provider is configured; an admin can
+		// retry the notification then.
+		d.record(d.outbox.MarkFailed(notification.ID, fmt.Sprintf("no %s provider is configured", notification.Channel)))
+		return
+	}
+	// Reminders are queued well ahead, so consent is checked again before
+	// anything is sent.
+	if notification.Channel == model.NotificationChannelSMS {
+		consent, err := d.consents.GetActiveConsent(notification.PatientID.String(), model.ConsentScopeSMSReminders)
+		if err != nil {
+			log.Printf("notifications: %v", err)
+			return
+		}
+		if consent == nil {
+			d.record(d.outbox.MarkFailed(notification.ID, ErrNoSMSConsent.Error()))
+			return
+		}
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.sendTimeout)
+	err := provider.Send(sendCtx, Message{
+		Channel:   notification.Channel,
+		Recipient: notification.Recipient,
+		Subject:   notification.Subject,
+		Body:      notification.Body,
+	})
+	cancel()
+	switch {
+	case err == nil:
+		d.record(d.outbox.MarkSent(notification.ID))
+	case notification.Attempts >= d.maxAttempts:
+		d.record(d.outbox.MarkFailed(notification.ID, err.Error()))
+	default:
+		d.record(d.outbox.MarkRetry(notification.ID, time.Now().Add(d.backoff(notification.Attempts)), err.Error()))
+	}
+}
+
+// backoff is how long to wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.retryBackoff
+	for i := 1; i < attempts && backoff < d.maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.maxRetryBackoff)
+}
+
+func (d *Dispatcher) record(err error) {
+	if err != nil {
+		log.Printf("notifications: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package notification
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+)
+
+// EmailProvider sends plain text emails through an SMTP server.
+type EmailProvider struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+func NewEmailProvider(cfg config.EmailConfig) *EmailProvider {
+	return &EmailProvider{
+		host:     cfg.Host,
+		port:     cfg.Port,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+	}
+}
+
+func (p *EmailProvider) Send(ctx context.Context, message Message) error {
+	to, err := mail.ParseAddress(message.Recipient)
+	if err != nil {
+		return fmt.Errorf("invalid email address: %w", err)
+	}
+	from, err := mail.ParseAddress(p.from)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	if strings.ContainsAny(message.Subject, "\r\n") {
+		return errors.New("email subject must be a single line")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(p.host, strconv.Itoa(p.port)))
+	if err != nil {
+		return fmt.Errorf("failed to reach mail server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, p.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to reach mail server: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: p.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if p.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", p.username, p.password, p.host)); err != nil {
+			return fmt.Errorf("failed to authenticate with mail server: %w", err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("mail server rejected sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("mail server rejected recipient: %w", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := writer.Write(composeEmail(from, to, message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// composeEmail builds the email with LF line endings; the SMTP data writer turns
+// them into CRLF and escapes lines starting with a dot.
+func composeEmail(from, to *mail.Address, message Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from.String() + "\n")
+	b.WriteString("To: " + to.String() + "\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", message.Subject) + "\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\n")
+	b.WriteString("MIME-Version: 1.0\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\n\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\r\n", "\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,41 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileProvider appends every message to a file as a line of JSON instead of
+// sending it, so development setups and tests can see what would be sent.
+type FileProvider struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open notification file: %w", err)
+	}
+	return &FileProvider{file: file}, nil
+}
+
+func (p *FileProvider) Send(_ context.Context, message Message) error {
+	line, err := json.Marshal(struct {
+		SentAt time.Time `json:"sent_at"`
+		Message
+	}{time.Now(), message})
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+	return nil
+}
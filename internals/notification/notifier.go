@@ -0,0 +1,106 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownChannel  = errors.New("unknown notification channel")
+	ErrPatientNotFound = errors.New("patient not found")
+	ErrNoRecipient     = errors.New("patient has no address for this channel")
+	ErrNoSMSConsent    = errors.New("patient has not consented to SMS messages")
+)
+
+// Notifier renders messages to patients and queues them in the outbox. It is
+// shared by the services that notify patients; the Dispatcher sends what it
+// queues.
+type Notifier struct {
+	outbox     repositories.NotificationRepository
+	patients   repositories.PatientRepository
+	contacts   repositories.PatientContactRepository
+	consents   repositories.ConsentRepository
+	clinicName string
+}
+
+func NewNotifier(outbox repositories.NotificationRepository, patients repositories.PatientRepository,
+	contacts repositories.PatientContactRepository, consents repositories.ConsentRepository,
+	clinicConfig config.ClinicConfig) *Notifier {
+	return &Notifier{
+		outbox:     outbox,
+		patients:   patients,
+		contacts:   contacts,
+		consents:   consents,
+		clinicName: clinicConfig.Name,
+	}
+}
+
+// Notify renders the named template for the patient and queues it on
+// channel, to be sent at sendAt. Text messages go to the patient's phone
+// number and need their consent to SMS reminders; emails go to the first
+// email address among the patient's own contacts.
+func (n *Notifier) Notify(patientID uuid.UUID, channel, templateName string, data Data, sendAt time.Time) (*model.Notification, error) {
+	patient, err := n.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+	recipient, err := n.recipient(*patient, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	data.PatientName = patient.Name
+	data.ClinicName = n.clinicName
+	message, err := Render(templateName, channel, recipient, data)
+	if err != nil {
+		return nil, err
+	}
+	return n.outbox.CreateNotification(model.Notification{
+		PatientID:     patientID,
+		Channel:       channel,
+		Template:      templateName,
+		Recipient:     message.Recipient,
+		Subject:       message.Subject,
+		Body:          message.Body,
+		NextAttemptAt: sendAt,
+	})
+}
+
+func (n *Notifier) recipient(patient model.Patient, channel string) (string, error) {
+	switch channel {
+	case model.NotificationChannelSMS:
+		consent, err := n.consents.GetActiveConsent(patient.ID.String(), model.ConsentScopeSMSReminders)
+		if err != nil {
+			return "", err
+		}
+		if consent == nil {
+			return "", ErrNoSMSConsent
+		}
+		if patient.PhoneNumber == "" {
+			return "", fmt.Errorf("%w: no phone number", ErrNoRecipient)
+		}
+		return patient.PhoneNumber, nil
+	case model.NotificationChannelEmail:
+		contacts, err := n.contacts.GetContactsByPatientID(patient.ID.String())
+		if err != nil {
+			return "", err
+		}
+		for _, contact := range contacts {
+			if contact.Relation == model.ContactRelationSelf && contact.Email != "" {
+				return contact.Email, nil
+			}
+		}
+		return "", fmt.Errorf("%w: no email address among the patient's own contacts", ErrNoRecipient)
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownChannel, channel)
+	}
+}
@@ -0,0 +1,52 @@
+// Package notification sends messages to patients. Messages are rendered from
+// templates, queued in a Postgres outbox and delivered by a dispatcher through
+// the provider of their channel, with retries.
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+// Message is one rendered message ready to be delivered. Subject is only
+// used by channels that have one.
+type Message struct {
+	Channel   string
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// Provider delivers messages over one channel. A returned error means the
+// message may not have been delivered and will be retried.
+type Provider interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewProviders builds the provider of each channel that is configured. With
+// a file path configured, every channel is written to that file.
+func NewProviders(cfg config.NotificationConfig) (map[string]Provider, error) {
+	providers := map[string]Provider{}
+	if cfg.FilePath != "" {
+		file, err := NewFileProvider(cfg.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		providers[model.NotificationChannelSMS] = file
+		providers[model.NotificationChannelEmail] = file
+		return providers, nil
+	}
+	if cfg.SMS.URL != "" {
+		providers[model.NotificationChannelSMS] = NewSMSProvider(cfg.SMS)
+	}
+	if cfg.Email.Host != "" {
+		if cfg.Email.From == "" {
+			return nil, fmt.Errorf("email provider needs a from address")
+		}
+		providers[model.NotificationChannelEmail] = NewEmailProvider(cfg.Email)
+	}
+	return providers, nil
+}
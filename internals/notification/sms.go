@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+)
+
+// maxErrorBody bounds how much of a gateway's error response is kept.
+const maxErrorBody = 512
+
+// SMSProvider sends text messages through an HTTP SMS gateway, posting
+// {"from", "to", "body"} as JSON.
+type SMSProvider struct {
+	url    string
+	apiKey string
+	from   string
+	client *http.Client
+}
+
+func NewSMSProvider(cfg config.SMSConfig) *SMSProvider {
+	return &SMSProvider{
+		url:    cfg.URL,
+		apiKey: cfg.APIKey,
+		from:   cfg.From,
+		client: &http.Client{},
+	}
+}
+
+func (p *SMSProvider) Send(ctx context.Context, message Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"from": p.from,
+		"to":   message.Recipient,
+		"body": message.Body,
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to reach SMS gateway: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return fmt.Errorf("SMS gateway returned %s: %s", response.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+var ErrUnknownTemplate = errors.New("unknown notification template")
+
+// Data is what templates can refer to. Fields a template does not use may be
+// left empty.
+type Data struct {
+	PatientName string
+	ClinicName  string
+	DoctorName  string
+	// AppointmentAt is shown in its own location, so callers pass it in the
+	// clinic's time zone.
+	AppointmentAt time.Time
+	TestName      string
+}
+
+// messageTemplate is the wording of one kind of message. SMS only uses the
+// short text; emails use the subject and the longer body.
+type messageTemplate struct {
+	sms     *template.Template
+	subject *template.Template
+	email   *template.Template
+}
+
+func newMessageTemplate(name, sms, subject, email string) messageTemplate {
+	funcs := template.FuncMap{"when": func(t time.Time) string { return t.Format("Mon 02 Jan 2006 at 15:04") }}
+	return messageTemplate{
+		sms:     template.Must(template.New(name + ".sms").Funcs(funcs).Parse(sms)),
+		subject: template.Must(template.New(name + ".subject").Funcs(funcs).Parse(subject)),
+		email:   template.Must(template.New(name + ".email").Funcs(funcs).Parse(email)),
+	}
+}
+
+var templates = map[string]messageTemplate{
+	model.NotificationAppointmentReminder: newMessageTemplate(model.NotificationAppointmentReminder,
+		`{{.ClinicName}}: Hi {{.PatientName}}, a reminder of your appointment on {{when .AppointmentAt}}`+
+			`{{with .DoctorName}} with Dr. {{.}}{{end}}. Call us if you cannot make it.`,
+		`Appointment reminder: {{when .AppointmentAt}}`,
+		`Dear {{.PatientName}},
+
+This is a reminder of your appointment at {{.ClinicName}} on {{when .AppointmentAt}}{{with .DoctorName}} with Dr. {{.}}{{end}}.
+
+If you cannot make it, please let us know so we can offer the time to someone else.
+
+{{.ClinicName}}
+`),
+	model.NotificationResultsReady: newMessageTemplate(model.NotificationResultsReady,
+		`{{.ClinicName}}: Hi {{.PatientName}}, your {{with .TestName}}{{.}} {{end}}results are ready. `+
+			`Please contact the clinic to discuss them.`,
+		`Your {{with .TestName}}{{.}} {{end}}results are ready`,
+		`Dear {{.PatientName}},
+
+Your {{with .TestName}}{{.}} {{end}}results are ready. Please contact {{.ClinicName}} to discuss them with your doctor.
+
+{{.ClinicName}}
+`),
+}
+
+// Render renders the named template for channel.
+func Render(name, channel, recipient string, data Data) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("%w: %q", ErrUnknownTemplate, name)
+	}
+	message := Message{Channel: channel, Recipient: recipient}
+	var err error
+	switch channel {
+	case model.NotificationChannelSMS:
+		message.Body, err = execute(tmpl.sms, data)
+	case model.NotificationChannelEmail:
+		if message.Subject, err = execute(tmpl.subject, data); err == nil {
+			message.Body, err = execute(tmpl.email, data)
+		}
+	default:
+		return Message{}, fmt.Errorf("%w: %q", ErrUnknownChannel, channel)
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return message, nil
+}
+
+func execute(tmpl *template.Template, data Data) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
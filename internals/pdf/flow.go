@@ -0,0 +1,133 @@
+package pdf
+
+import "strings"
+
+type Align int
+
+const (
+	Left Align = iota
+	Right
+)
+
+// cellPadding keeps the text of adjacent table cells apart.
+const cellPadding = 4
+
+// Column is one cell of a table row. Widths are fractions of the text width.
+type Column struct {
+	Width float64
+	Align Align
+}
+
+// Flow lays text out top to bottom within the page margins and starts a new
+// page when the current one is full.
+type Flow struct {
+	doc    *Document
+	margin float64
+	y      float64
+}
+
+func NewFlow(doc *Document, margin float64) *Flow {
+	doc.AddPage()
+	return &Flow{doc: doc, margin: margin, y: margin}
+}
+
+// Width is the usable width between the margins.
+func (f *Flow) Width() float64 {
+	return PageWidth - 2*f.margin
+}
+
+// Space moves down by h points.
+func (f *Flow) Space(h float64) {
+	f.y += h
+}
+
+// Paragraph writes s, wrapping it at word boundaries to the text width.
+func (f *Flow) Paragraph(font Font, size float64, s string) {
+	for _, line := range strings.Split(s, "\n") {
+		for _, wrapped := range wrap(font, size, line, f.Width()) {
+			f.line(size)
+			f.doc.Text(f.margin, f.y, font, size, wrapped)
+		}
+	}
+}
+
+// Centered writes a single line centred on the page.
+func (f *Flow) Centered(font Font, size float64, s string) {
+	f.line(size)
+	f.doc.Text((PageWidth-TextWidth(font, size, s))/2, f.y, font, size, s)
+}
+
+// Field writes a bold label followed by its value on one line.
+func (f *Flow) Field(size float64, label, value string) {
+	f.line(size)
+	f.doc.Text(f.margin, f.y, Bold, size, label)
+	f.doc.Text(f.margin+TextWidth(Bold, size, label+" "), f.y, Regular, size, value)
+}
+
+// Row writes one table row. Cells that do not fit their column are wrapped,
+// and the row is as tall as its tallest cell.
+func (f *Flow) Row(font Font, size float64, columns []Column, cells []string) {
+	wrapped := make([][]string, len(cells))
+	lines := 1
+	for i, cell := range cells {
+		wrapped[i] = wrap(font, size, cell, columns[i].Width*f.Width()-cellPadding)
+		lines = max(lines, len(wrapped[i]))
+	}
+	for l := 0; l < lines; l++ {
+		f.line(size)
+		x := f.margin
+		for i, column := range columns {
+			width := column.Width * f.Width()
+			if l < len(wrapped[i]) {
+				text := wrapped[i][l]
+				if column.Align == Right {
+					f.doc.Text(x+width-cellPadding-TextWidth(font, size, text), f.y, font, size, text)
+				} else {
+					f.doc.Text(x, f.y, font, size, text)
+				}
+			}
+			x += width
+		}
+	}
+}
+
+// Rule draws a horizontal line across the text width.
+func (f *Flow) Rule() {
+	f.ensure(6)
+	f.y += 4
+	f.doc.Line(f.margin, f.y, PageWidth-f.margin, f.y, 0.5)
+	f.y += 2
+}
+
+// line advances to the baseline of the next line of the given font size.
+func (f *Flow) line(size float64) {
+	f.ensure(size * 1.4)
+	f.y += size * 1.4
+}
+
+func (f *Flow) ensure(h float64) {
+	if f.y+h > PageHeight-f.margin {
+		f.doc.AddPage()
+		f.y = f.margin
+	}
+}
+
+// wrap splits s into lines no wider than width, breaking between words.
+// A single word longer than the width is left on its own line.
+func wrap(font Font, size float64, s string, width float64) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	current := words[0]
+	for _, word := range words[1:] {
+		if TextWidth(font, size, current+" "+word) > width {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current += " " + word
+	}
+	return append(lines, current)
+}
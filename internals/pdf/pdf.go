@@ -0,0 +1,144 @@
+// Package pdf writes simple text documents as PDF without external
+// dependencies. It uses the standard Helvetica fonts every PDF reader ships
+// with, so nothing is embedded, and output is byte-for-byte deterministic for
+// the same input.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+var fontNames = [...]string{Regular: "Helvetica", Bold: "Helvetica-Bold"}
+
+// Document is a PDF being built page by page. Coordinates are in points from
+// the top-left corner of the page.
+type Document struct {
+	title   string
+	created time.Time
+	pages   []*bytes.Buffer
+}
+
+// NewDocument starts an empty document. created is written as the creation
+// date; pass the date of the underlying record rather than time.Now to keep
+// output reproducible.
+func NewDocument(title string, created time.Time) *Document {
+	return &Document{title: title, created: created}
+}
+
+// AddPage starts a new page; later drawing goes on it.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// PageCount returns the number of pages added so far.
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Text draws s with its baseline at y.
+func (d *Document) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(d.page(), "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		font+1, num(size), num(x), num(PageHeight-y), escape(s))
+}
+
+// Line draws a straight line of the given width.
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.page(), "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// WriteTo writes the finished document.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	out := &bytes.Buffer{}
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-4 are fixed; each page then takes a page and a content object.
+	const firstPage = 5
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	infoID := firstPage + 2*len(d.pages)
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for _, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(PageWidth), num(PageHeight), firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.Bytes()))
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (patient-management-system) /CreationDate (D:%s) >>",
+		escape(d.title), d.created.UTC().Format("20060102150405Z")))
+
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, infoID, xref)
+	return out.WriteTo(w)
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// num formats a coordinate with at most two decimals and no trailing zeros.
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// escape encodes s as WinAnsi for a PDF string literal. Characters outside
+// Latin-1 are replaced with '?'.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
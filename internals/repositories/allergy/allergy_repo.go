@@ -0,0 +1,95 @@
+package allergy_repo
+
+// Package allergy_repo provides the implementation of the AllergyRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+const allergyColumns = `id, patient_id, substance, reaction, severity, recorded_by, created_at`
+
+type AllergyStorage struct {
+	connection *sql.DB
+}
+
+func NewAllergyStorage(db *sql.DB) *AllergyStorage {
+	return &AllergyStorage{
+		connection: db,
+	}
+}
+
+func (s *AllergyStorage) CreateAllergy(allergy model.Allergy) (*model.Allergy, error) {
+	query := `INSERT INTO allergies (patient_id, substance, reaction, severity, recorded_by)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING ` + allergyColumns
+	row := s.connection.QueryRow(query, allergy.PatientID, allergy.Substance, allergy.Reaction, allergy.Severity, allergy.RecordedBy)
+	created, err := scanAllergy(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create allergy: %w", err)
+	}
+	return created, nil
+}
+
+func (s *AllergyStorage) DeleteAllergy(id string) (*model.Allergy, error) {
+	query := `DELETE FROM allergies WHERE id = $1 RETURNING ` + allergyColumns
+	row := s.connection.QueryRow(query, id)
+	deleted, err := scanAllergy(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no allergy found with id: %s", id)
+		}
+		return nil, fmt.Errorf("failed to delete allergy: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *AllergyStorage) UpdateAllergy(allergy model.Allergy) (*model.Allergy, error) {
+	query := `UPDATE allergies SET substance = $1, reaction = $2, severity = $3, updated_at = NOW()
+	          WHERE id = $4 RETURNING ` + allergyColumns
+	row := s.connection.QueryRow(query, allergy.Substance, allergy.Reaction, allergy.Severity, allergy.ID)
+	updated, err := scanAllergy(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update allergy: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *AllergyStorage) GetAllergiesByPatientID(patientID string) ([]model.Allergy, error) {
+	query := `SELECT ` + allergyColumns + ` FROM allergies WHERE patient_id = $1 ORDER BY created_at`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allergies by patient ID: %w", err)
+	}
+	defer rows.Close()
+
+	var allergies []model.Allergy
+	for rows.Next() {
+		allergy, err := scanAllergy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan allergy: %w", err)
+		}
+		allergies = append(allergies, *allergy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over allergy rows: %w", err)
+	}
+	return allergies, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAllergy(row scanner) (*model.Allergy, error) {
+	var allergy model.Allergy
+	var recordedBy uuid.NullUUID
+	err := row.Scan(&allergy.ID, &allergy.PatientID, &allergy.Substance, &allergy.Reaction, &allergy.Severity, &recordedBy, &allergy.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	allergy.RecordedBy = recordedBy.UUID
+	return &allergy, nil
+}
@@ -0,0 +1,221 @@
+package attachment_repo
+
+// Package attachment_repo provides the implementation of the AttachmentRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+// Encrypted attachment columns.
+const (
+	filenameField    = "attachments.filename"
+	descriptionField = "attachments.description"
+)
+
+const attachmentColumns = `id, patient_id, diagnosis_id, filename, description, mime_type, size, sha256,
+	storage_key, uploaded_by, uploaded_at, deleted_by, deleted_at, data_key_id`
+
+type AttachmentStorage struct {
+	connection *sql.DB
+	keyring    *utils.Keyring
+}
+
+func NewAttachmentStorage(db *sql.DB, keyring *utils.Keyring) *AttachmentStorage {
+	return &AttachmentStorage{
+		connection: db,
+		keyring:    keyring,
+	}
+}
+
+func (s *AttachmentStorage) CreateAttachment(attachment model.Attachment) (*model.Attachment, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+	filename, err := key.Seal(filenameField, attachment.Filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+	description, err := key.Seal(descriptionField, attachment.Description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	query := `INSERT INTO attachments (patient_id, diagnosis_id, filename, description, mime_type, size, sha256,
+	              storage_key, data_key_id, uploaded_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING ` + attachmentColumns
+	row := s.connection.QueryRow(query, attachment.PatientID, attachment.DiagnosisID, filename, description,
+		attachment.MimeType, attachment.Size, attachment.SHA256, attachment.StorageKey, key.ID, attachment.UploadedBy)
+	created, err := s.scanAttachment(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return created, nil
+}
+
+// GetAttachmentByID returns the attachment even if it was deleted.
+func (s *AttachmentStorage) GetAttachmentByID(id int64) (*model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1 AND clinic_id = current_clinic_id()`
+	attachment, err := s.scanAttachment(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Attachment not found
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// GetAttachmentBySHA256 returns the patient's attachment with the given
+// content, or nil if they have none that is not deleted.
+func (s *AttachmentStorage) GetAttachmentBySHA256(patientID, sha256 string) (*model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+	          WHERE patient_id = $1 AND sha256 = $2 AND deleted_at IS NULL AND clinic_id = current_clinic_id()
+	          ORDER BY uploaded_at, id LIMIT 1`
+	attachment, err := s.scanAttachment(s.connection.QueryRow(query, patientID, sha256))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Attachment not found
+		}
+		return nil, fmt.Errorf("failed to get attachment by SHA-256: %w", err)
+	}
+	return attachment, nil
+}
+
+// GetAttachmentsByPatientID returns the patient's attachments, newest first,
+// leaving out deleted ones unless includeDeleted is set.
+func (s *AttachmentStorage) GetAttachmentsByPatientID(patientID string, includeDeleted bool) ([]model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+	          WHERE patient_id = $1 AND clinic_id = current_clinic_id() AND ($2 OR deleted_at IS NULL)
+	          ORDER BY uploaded_at DESC, id DESC`
+	rows, err := s.connection.Query(query, patientID, includeDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments by patient ID: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []model.Attachment
+	for rows.Next() {
+		attachment, err := s.scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over attachment rows: %w", err)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment marks the attachment deleted. Its content stays in the
+// blob store. It returns nil if the attachment does not exist or was
+// already deleted.
+func (s *AttachmentStorage) DeleteAttachment(id int64, deletedBy string) (*model.Attachment, error) {
+	query := `UPDATE attachments SET deleted_by = $1, deleted_at = NOW()
+	          WHERE id = $2 AND clinic_id = current_clinic_id() AND deleted_at IS NULL
+	          RETURNING ` + attachmentColumns
+	attachment, err := s.scanAttachment(s.connection.QueryRow(query, deletedBy, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Attachment not found
+		}
+		return nil, fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *AttachmentStorage) scanAttachment(row scanner) (*model.Attachment, error) {
+	var attachment model.Attachment
+	var diagnosisID sql.NullInt32
+	var uploadedBy uuid.NullUUID
+	var deletedAt sql.NullTime
+	var keyID sql.NullInt32
+	err := row.Scan(&attachment.ID, &attachment.PatientID, &diagnosisID, &attachment.Filename, &attachment.Description,
+		&attachment.MimeType, &attachment.Size, &attachment.SHA256, &attachment.StorageKey, &uploadedBy,
+		&attachment.UploadedAt, &attachment.DeletedBy, &deletedAt, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	if diagnosisID.Valid {
+		id := int(diagnosisID.Int32)
+		attachment.DiagnosisID = &id
+	}
+	attachment.UploadedBy = uploadedBy.UUID
+	if deletedAt.Valid {
+		attachment.DeletedAt = &deletedAt.Time
+	}
+	if attachment.Filename, err = s.keyring.Open(keyID, filenameField, attachment.Filename); err != nil {
+		return nil, err
+	}
+	if attachment.Description, err = s.keyring.Open(keyID, descriptionField, attachment.Description); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ReencryptAttachments re-encrypts the filenames and descriptions of up to
+// limit attachments, deleted ones included, not yet encrypted with the active
+// data key and returns how many it rewrote; zero means the clinic is done.
+// Contents in the blob store are not encrypted with data keys and stay as
+// they are.
+func (s *AttachmentStorage) ReencryptAttachments(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt attachments: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+	          WHERE clinic_id = current_clinic_id() AND data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get attachments to re-encrypt: %w", err)
+	}
+	var attachments []model.Attachment
+	for rows.Next() {
+		attachment, err := s.scanAttachment(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error occurred while iterating over attachment rows: %w", err)
+	}
+	for _, attachment := range attachments {
+		filename, err := key.Seal(filenameField, attachment.Filename)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt attachment: %w", err)
+		}
+		description, err := key.Seal(descriptionField, attachment.Description)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt attachment: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE attachments SET filename = $1, description = $2, data_key_id = $3 WHERE id = $4`,
+			filename, description, key.ID, attachment.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt attachment: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(attachments), nil
+}
@@ -0,0 +1,52 @@
+package audit_repo
+
+// Package audit_repo provides the implementation of the AuditRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+type AuditStorage struct {
+	connection *sql.DB
+}
+
+func NewAuditStorage(db *sql.DB) *AuditStorage {
+	return &AuditStorage{
+		connection: db,
+	}
+}
+
+func (s *AuditStorage) CreateAuditEntry(entry model.AuditEntry) error {
+	query := `INSERT INTO audit_log (actor_id, action, subject, details, client_ip) VALUES ($1, $2, $3, $4, $5)`
+	_, err := s.connection.Exec(query, entry.ActorID, entry.Action, entry.Subject, entry.Details, entry.ClientIP)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+	return nil
+}
+
+func (s *AuditStorage) GetAuditEntriesByAction(action string) ([]model.AuditEntry, error) {
+	query := `SELECT id, actor_id, action, subject, details, client_ip, created_at FROM audit_log
+	          WHERE action = $1 ORDER BY created_at DESC`
+	rows, err := s.connection.Query(query, action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+	defer rows.Close()
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var entry model.AuditEntry
+		err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.Subject, &entry.Details, &entry.ClientIP, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over audit rows: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,146 @@
+package catalogue_repo
+
+// Package catalogue_repo provides the implementation of the CatalogueRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+const catalogueColumns = `id, code, name, kind, unit_price, tax_rate_id, active`
+
+type CatalogueStorage struct {
+	connection *sql.DB
+}
+
+func NewCatalogueStorage(db *sql.DB) *CatalogueStorage {
+	return &CatalogueStorage{
+		connection: db,
+	}
+}
+
+func (s *CatalogueStorage) CreateTaxRate(rate model.TaxRate) (*model.TaxRate, error) {
+	query := `INSERT INTO tax_rates (name, rate_bp) VALUES ($1, $2) RETURNING id, name, rate_bp`
+	var created model.TaxRate
+	err := s.connection.QueryRow(query, rate.Name, rate.Rate).Scan(&created.ID, &created.Name, &created.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tax rate: %w", err)
+	}
+	return &created, nil
+}
+
+func (s *CatalogueStorage) GetTaxRateByID(id int) (*model.TaxRate, error) {
+	query := `SELECT id, name, rate_bp FROM tax_rates WHERE id = $1`
+	var rate model.TaxRate
+	err := s.connection.QueryRow(query, id).Scan(&rate.ID, &rate.Name, &rate.Rate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Tax rate not found
+		}
+		return nil, fmt.Errorf("failed to get tax rate: %w", err)
+	}
+	return &rate, nil
+}
+
+func (s *CatalogueStorage) GetAllTaxRates() ([]model.TaxRate, error) {
+	rows, err := s.connection.Query(`SELECT id, name, rate_bp FROM tax_rates ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tax rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []model.TaxRate
+	for rows.Next() {
+		var rate model.TaxRate
+		if err := rows.Scan(&rate.ID, &rate.Name, &rate.Rate); err != nil {
+			return nil, fmt.Errorf("failed to scan tax rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over tax rate rows: %w", err)
+	}
+	return rates, nil
+}
+
+func (s *CatalogueStorage) CreateCatalogueItem(item model.CatalogueItem) (*model.CatalogueItem, error) {
+	query := `INSERT INTO catalogue_items (code, name, kind, unit_price, tax_rate_id, active)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + catalogueColumns
+	row := s.connection.QueryRow(query, item.Code, item.Name, item.Kind, item.UnitPrice, item.TaxRateID, item.Active)
+	created, err := scanCatalogueItem(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create catalogue item: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateCatalogueItem changes the item's current price. Invoices already
+// issued keep the price they were issued with.
+func (s *CatalogueStorage) UpdateCatalogueItem(item model.CatalogueItem) (*model.CatalogueItem, error) {
+	query := `UPDATE catalogue_items
+	          SET code = $2, name = $3, kind = $4, unit_price = $5, tax_rate_id = $6, active = $7, updated_at = NOW()
+	          WHERE id = $1 RETURNING ` + catalogueColumns
+	row := s.connection.QueryRow(query, item.ID, item.Code, item.Name, item.Kind, item.UnitPrice, item.TaxRateID, item.Active)
+	updated, err := scanCatalogueItem(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Catalogue item not found
+		}
+		return nil, fmt.Errorf("failed to update catalogue item: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *CatalogueStorage) GetCatalogueItemByID(id int) (*model.CatalogueItem, error) {
+	query := `SELECT ` + catalogueColumns + ` FROM catalogue_items WHERE id = $1`
+	item, err := scanCatalogueItem(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Catalogue item not found
+		}
+		return nil, fmt.Errorf("failed to get catalogue item: %w", err)
+	}
+	return item, nil
+}
+
+func (s *CatalogueStorage) GetAllCatalogueItems(includeInactive bool) ([]model.CatalogueItem, error) {
+	query := `SELECT ` + catalogueColumns + ` FROM catalogue_items WHERE active OR $1 ORDER BY kind, name`
+	rows, err := s.connection.Query(query, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalogue items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []model.CatalogueItem
+	for rows.Next() {
+		item, err := scanCatalogueItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan catalogue item: %w", err)
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over catalogue item rows: %w", err)
+	}
+	return items, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCatalogueItem(row scanner) (*model.CatalogueItem, error) {
+	var item model.CatalogueItem
+	var taxRateID sql.NullInt64
+	err := row.Scan(&item.ID, &item.Code, &item.Name, &item.Kind, &item.UnitPrice, &taxRateID, &item.Active)
+	if err != nil {
+		return nil, err
+	}
+	if taxRateID.Valid {
+		id := int(taxRateID.Int64)
+		item.TaxRateID = &id
+	}
+	return &item, nil
+}
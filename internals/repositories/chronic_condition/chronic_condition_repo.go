@@ -0,0 +1,111 @@
+package chronic_condition_repo
+
+// Package chronic_condition_repo provides the implementation of the ChronicConditionRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+const conditionColumns = `id, patient_id, name, status, onset_date, notes, recorded_by, created_at`
+
+type ChronicConditionStorage struct {
+	connection *sql.DB
+}
+
+func NewChronicConditionStorage(db *sql.DB) *ChronicConditionStorage {
+	return &ChronicConditionStorage{
+		connection: db,
+	}
+}
+
+func (s *ChronicConditionStorage) CreateChronicCondition(condition model.ChronicCondition) (*model.ChronicCondition, error) {
+	query := `INSERT INTO chronic_conditions (patient_id, name, status, onset_date, notes, recorded_by)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + conditionColumns
+	row := s.connection.QueryRow(query, condition.PatientID, condition.Name, condition.Status, condition.OnsetDate, condition.Notes, condition.RecordedBy)
+	created, err := scanChronicCondition(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chronic condition: %w", err)
+	}
+	return created, nil
+}
+
+func (s *ChronicConditionStorage) DeleteChronicCondition(id string) (*model.ChronicCondition, error) {
+	query := `DELETE FROM chronic_conditions WHERE id = $1 RETURNING ` + conditionColumns
+	row := s.connection.QueryRow(query, id)
+	deleted, err := scanChronicCondition(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no chronic condition found with id: %s", id)
+		}
+		return nil, fmt.Errorf("failed to delete chronic condition: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *ChronicConditionStorage) UpdateChronicCondition(condition model.ChronicCondition) (*model.ChronicCondition, error) {
+	query := `UPDATE chronic_conditions SET name = $1, status = $2, onset_date = $3, notes = $4, updated_at = NOW()
+	          WHERE id = $5 RETURNING ` + conditionColumns
+	row := s.connection.QueryRow(query, condition.Name, condition.Status, condition.OnsetDate, condition.Notes, condition.ID)
+	updated, err := scanChronicCondition(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update chronic condition: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *ChronicConditionStorage) GetChronicConditionByID(id string) (*model.ChronicCondition, error) {
+	query := `SELECT ` + conditionColumns + ` FROM chronic_conditions WHERE id = $1`
+	condition, err := scanChronicCondition(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Chronic condition not found
+		}
+		return nil, fmt.Errorf("failed to get chronic condition: %w", err)
+	}
+	return condition, nil
+}
+
+func (s *ChronicConditionStorage) GetChronicConditionsByPatientID(patientID string) ([]model.ChronicCondition, error) {
+	query := `SELECT ` + conditionColumns + ` FROM chronic_conditions WHERE patient_id = $1 ORDER BY created_at`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chronic conditions by patient ID: %w", err)
+	}
+	defer rows.Close()
+
+	var conditions []model.ChronicCondition
+	for rows.Next() {
+		condition, err := scanChronicCondition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chronic condition: %w", err)
+		}
+		conditions = append(conditions, *condition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over chronic condition rows: %w", err)
+	}
+	return conditions, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanChronicCondition(row scanner) (*model.ChronicCondition, error) {
+	var condition model.ChronicCondition
+	var onsetDate sql.NullTime
+	var recordedBy uuid.NullUUID
+	err := row.Scan(&condition.ID, &condition.PatientID, &condition.Name, &condition.Status, &onsetDate, &condition.Notes, &recordedBy, &condition.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if onsetDate.Valid {
+		condition.OnsetDate = &onsetDate.Time
+	}
+	condition.RecordedBy = recordedBy.UUID
+	return &condition, nil
+}
@@ -0,0 +1,167 @@
+package clinic_repo
+
+// Package clinic_repo provides the implementation of the ClinicRepository interface
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/lib/pq"
+)
+
+const clinicColumns = `id, name, code, address, created_at`
+
+// foreignKeyViolation is the Postgres error code for a missing referenced row.
+const foreignKeyViolation = "23503"
+
+type ClinicStorage struct {
+	connection *sql.DB
+}
+
+func NewClinicStorage(db *sql.DB) *ClinicStorage {
+	return &ClinicStorage{
+		connection: db,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanClinic(row scanner) (*model.Clinic, error) {
+	var clinic model.Clinic
+	err := row.Scan(&clinic.ID, &clinic.Name, &clinic.Code, &clinic.Address, &clinic.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &clinic, nil
+}
+
+func (s *ClinicStorage) CreateClinic(clinic model.Clinic) (*model.Clinic, error) {
+	query := `INSERT INTO clinics (id, name, code, address) VALUES ($1, $2, $3, $4) RETURNING ` + clinicColumns
+	created, err := scanClinic(s.connection.QueryRow(query, clinic.ID, clinic.Name, clinic.Code, clinic.Address))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clinic: %w", err)
+	}
+	return created, nil
+}
+
+func (s *ClinicStorage) GetClinicByID(id string) (*model.Clinic, error) {
+	query := `SELECT ` + clinicColumns + ` FROM clinics WHERE id = $1`
+	clinic, err := scanClinic(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Clinic not found
+		}
+		return nil, fmt.Errorf("failed to get clinic by ID: %w", err)
+	}
+	return clinic, nil
+}
+
+func (s *ClinicStorage) GetClinicByCode(code string) (*model.Clinic, error) {
+	query := `SELECT ` + clinicColumns + ` FROM clinics WHERE code = $1`
+	clinic, err := scanClinic(s.connection.QueryRow(query, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Clinic not found
+		}
+		return nil, fmt.Errorf("failed to get clinic by code: %w", err)
+	}
+	return clinic, nil
+}
+
+func (s *ClinicStorage) GetAllClinics() ([]model.Clinic, error) {
+	query := `SELECT ` + clinicColumns + ` FROM clinics ORDER BY name`
+	rows, err := s.connection.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all clinics: %w", err)
+	}
+	defer rows.Close()
+
+	var clinics []model.Clinic
+	for rows.Next() {
+		clinic, err := scanClinic(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan clinic: %w", err)
+		}
+		clinics = append(clinics, *clinic)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over clinic rows: %w", err)
+	}
+	return clinics, nil
+}
+
+// GetMembershipsByUserID returns the clinics the user works at, the default
+// one first.
+func (s *ClinicStorage) GetMembershipsByUserID(userID string) ([]model.ClinicMembership, error) {
+	query := `SELECT uc.user_id, uc.is_default, c.id, c.name, c.code, c.address, c.created_at
+	          FROM user_clinics uc JOIN clinics c ON c.id = uc.clinic_id
+	          WHERE uc.user_id = $1 ORDER BY uc.is_default DESC, uc.created_at, c.name`
+	rows, err := s.connection.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clinic memberships: %w", err)
+	}
+	defer rows.Close()
+
+	var memberships []model.ClinicMembership
+	for rows.Next() {
+		var membership model.ClinicMembership
+		err := rows.Scan(&membership.UserID, &membership.IsDefault, &membership.Clinic.ID, &membership.Clinic.Name,
+			&membership.Clinic.Code, &membership.Clinic.Address, &membership.Clinic.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan clinic membership: %w", err)
+		}
+		memberships = append(memberships, membership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over clinic membership rows: %w", err)
+	}
+	return memberships, nil
+}
+
+// AddMember lets the user work at the clinic. Making it their default clinic
+// clears the flag on their other memberships. It reports false if the user or
+// the clinic does not exist; the user is found through the foreign key, since
+// a clinic's connection cannot see staff of other clinics.
+func (s *ClinicStorage) AddMember(userID, clinicID string, isDefault bool) (bool, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if isDefault {
+		if _, err := tx.Exec(`UPDATE user_clinics SET is_default = FALSE WHERE user_id = $1`, userID); err != nil {
+			return false, fmt.Errorf("failed to clear default clinic: %w", err)
+		}
+	}
+	query := `INSERT INTO user_clinics (user_id, clinic_id, is_default) VALUES ($1, $2, $3)
+	          ON CONFLICT (user_id, clinic_id) DO UPDATE SET is_default = EXCLUDED.is_default`
+	if _, err := tx.Exec(query, userID, clinicID, isDefault); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return false, nil // User or clinic not found
+		}
+		return false, fmt.Errorf("failed to add clinic member: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// RemoveMember reports whether the user was a member of the clinic.
+func (s *ClinicStorage) RemoveMember(userID, clinicID string) (bool, error) {
+	result, err := s.connection.Exec(`DELETE FROM user_clinics WHERE user_id = $1 AND clinic_id = $2`, userID, clinicID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove clinic member: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove clinic member: %w", err)
+	}
+	return removed > 0, nil
+}
@@ -0,0 +1,210 @@
+package consent_repo
+
+// Package consent_repo provides the implementation of the ConsentRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+const textColumns = `id, scope, version, text, created_by, created_at`
+
+const consentColumns = `c.id, c.patient_id, t.scope, t.id, t.version, c.granted_by, c.granted_at,
+	c.withdrawn_by, c.withdrawn_at`
+
+type ConsentStorage struct {
+	connection *sql.DB
+}
+
+func NewConsentStorage(db *sql.DB) *ConsentStorage {
+	return &ConsentStorage{
+		connection: db,
+	}
+}
+
+// CreateConsentText publishes text as the next version of its scope.
+func (s *ConsentStorage) CreateConsentText(text model.ConsentText) (*model.ConsentText, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialise publishers of the same scope so they cannot pick the same version.
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('consent_texts:' || current_clinic_id() || $1))`,
+		text.Scope); err != nil {
+		return nil, fmt.Errorf("failed to lock consent texts: %w", err)
+	}
+	query := `INSERT INTO consent_texts (scope, version, text, created_by)
+	          SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3 FROM consent_texts
+	          WHERE scope = $1 AND clinic_id = current_clinic_id()
+	          RETURNING ` + textColumns
+	created, err := scanText(tx.QueryRow(query, text.Scope, text.Text, text.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consent text: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+func (s *ConsentStorage) GetConsentText(scope string, version int) (*model.ConsentText, error) {
+	query := `SELECT ` + textColumns + ` FROM consent_texts
+	          WHERE scope = $1 AND version = $2 AND clinic_id = current_clinic_id()`
+	text, err := scanText(s.connection.QueryRow(query, scope, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Consent text not found
+		}
+		return nil, fmt.Errorf("failed to get consent text: %w", err)
+	}
+	return text, nil
+}
+
+func (s *ConsentStorage) GetLatestConsentText(scope string) (*model.ConsentText, error) {
+	query := `SELECT ` + textColumns + ` FROM consent_texts
+	          WHERE scope = $1 AND clinic_id = current_clinic_id() ORDER BY version DESC LIMIT 1`
+	text, err := scanText(s.connection.QueryRow(query, scope))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Consent text not found
+		}
+		return nil, fmt.Errorf("failed to get latest consent text: %w", err)
+	}
+	return text, nil
+}
+
+// GetConsentTexts returns every version of every scope, or of one scope if
+// scope is not empty, newest version first.
+func (s *ConsentStorage) GetConsentTexts(scope string) ([]model.ConsentText, error) {
+	query := `SELECT ` + textColumns + ` FROM consent_texts
+	          WHERE ($1 = '' OR scope = $1) AND clinic_id = current_clinic_id() ORDER BY scope, version DESC`
+	rows, err := s.connection.Query(query, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent texts: %w", err)
+	}
+	defer rows.Close()
+
+	var texts []model.ConsentText
+	for rows.Next() {
+		text, err := scanText(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consent text: %w", err)
+		}
+		texts = append(texts, *text)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over consent text rows: %w", err)
+	}
+	return texts, nil
+}
+
+// GrantConsent records the patient agreeing to the consent text.
+func (s *ConsentStorage) GrantConsent(patientID string, textID int, grantedBy string) (*model.PatientConsent, error) {
+	query := `WITH granted AS (
+	              INSERT INTO patient_consents (patient_id, consent_text_id, granted_by)
+	              VALUES ($1, $2, $3) RETURNING *
+	          )
+	          SELECT ` + consentColumns + ` FROM granted c JOIN consent_texts t ON t.id = c.consent_text_id`
+	consent, err := scanConsent(s.connection.QueryRow(query, patientID, textID, grantedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant consent: %w", err)
+	}
+	return consent, nil
+}
+
+// WithdrawConsent withdraws every grant of scope the patient still holds. It
+// returns nil if there was none.
+func (s *ConsentStorage) WithdrawConsent(patientID, scope, withdrawnBy string) (*model.PatientConsent, error) {
+	query := `WITH withdrawn AS (
+	              UPDATE patient_consents c SET withdrawn_by = $3, withdrawn_at = NOW()
+	              FROM consent_texts t
+	              WHERE t.id = c.consent_text_id AND c.patient_id = $1 AND t.scope = $2 AND c.withdrawn_at IS NULL
+	              RETURNING c.*
+	          )
+	          SELECT ` + consentColumns + ` FROM withdrawn c JOIN consent_texts t ON t.id = c.consent_text_id
+	          ORDER BY c.granted_at DESC, c.id DESC LIMIT 1`
+	consent, err := scanConsent(s.connection.QueryRow(query, patientID, scope, withdrawnBy))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Consent not found
+		}
+		return nil, fmt.Errorf("failed to withdraw consent: %w", err)
+	}
+	return consent, nil
+}
+
+// GetActiveConsent returns the patient's latest grant of scope that has not
+// been withdrawn, or nil if they have not consented.
+func (s *ConsentStorage) GetActiveConsent(patientID, scope string) (*model.PatientConsent, error) {
+	query := `SELECT ` + consentColumns + ` FROM patient_consents c JOIN consent_texts t ON t.id = c.consent_text_id
+	          WHERE c.patient_id = $1 AND t.scope = $2 AND c.withdrawn_at IS NULL
+	          ORDER BY c.granted_at DESC, c.id DESC LIMIT 1`
+	consent, err := scanConsent(s.connection.QueryRow(query, patientID, scope))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Consent not found
+		}
+		return nil, fmt.Errorf("failed to get active consent: %w", err)
+	}
+	return consent, nil
+}
+
+// GetConsentsByPatientID returns the patient's consent history, newest first.
+func (s *ConsentStorage) GetConsentsByPatientID(patientID string) ([]model.PatientConsent, error) {
+	query := `SELECT ` + consentColumns + ` FROM patient_consents c JOIN consent_texts t ON t.id = c.consent_text_id
+	          WHERE c.patient_id = $1 ORDER BY c.granted_at DESC, c.id DESC`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient consents: %w", err)
+	}
+	defer rows.Close()
+
+	var consents []model.PatientConsent
+	for rows.Next() {
+		consent, err := scanConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan patient consent: %w", err)
+		}
+		consents = append(consents, *consent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over patient consent rows: %w", err)
+	}
+	return consents, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanText(row scanner) (*model.ConsentText, error) {
+	var text model.ConsentText
+	var createdBy uuid.NullUUID
+	err := row.Scan(&text.ID, &text.Scope, &text.Version, &text.Text, &createdBy, &text.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	text.CreatedBy = createdBy.UUID
+	return &text, nil
+}
+
+func scanConsent(row scanner) (*model.PatientConsent, error) {
+	var consent model.PatientConsent
+	var grantedBy uuid.NullUUID
+	var withdrawnAt sql.NullTime
+	err := row.Scan(&consent.ID, &consent.PatientID, &consent.Scope, &consent.TextID, &consent.TextVersion,
+		&grantedBy, &consent.GrantedAt, &consent.WithdrawnBy, &withdrawnAt)
+	if err != nil {
+		return nil, err
+	}
+	consent.GrantedBy = grantedBy.UUID
+	if withdrawnAt.Valid {
+		consent.WithdrawnAt = &withdrawnAt.Time
+	}
+	return &consent, nil
+}
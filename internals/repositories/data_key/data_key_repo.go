@@ -0,0 +1,117 @@
+package data_key_repo
+
+// Package data_key_repo provides the implementation of the DataKeyRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+const dataKeyColumns = `id, master_key_id, wrapped_key, active, created_at`
+
+type DataKeyStorage struct {
+	connection *sql.DB
+}
+
+func NewDataKeyStorage(db *sql.DB) *DataKeyStorage {
+	return &DataKeyStorage{
+		connection: db,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDataKey(row scanner) (*model.DataKey, error) {
+	var key model.DataKey
+	err := row.Scan(&key.ID, &key.MasterKeyID, &key.WrappedKey, &key.Active, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateActiveDataKey stores a new data key and makes it the active one. The
+// table is locked so instances starting at once cannot both activate a key.
+func (s *DataKeyStorage) CreateActiveDataKey(masterKeyID string, wrappedKey []byte) (*model.DataKey, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`LOCK TABLE data_keys IN EXCLUSIVE MODE`); err != nil {
+		return nil, fmt.Errorf("failed to lock data keys: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE data_keys SET active = FALSE WHERE active`); err != nil {
+		return nil, fmt.Errorf("failed to deactivate data key: %w", err)
+	}
+	query := `INSERT INTO data_keys (master_key_id, wrapped_key, active) VALUES ($1, $2, TRUE) RETURNING ` + dataKeyColumns
+	key, err := scanDataKey(tx.QueryRow(query, masterKeyID, wrappedKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data key: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return key, nil
+}
+
+func (s *DataKeyStorage) GetDataKeyByID(id int) (*model.DataKey, error) {
+	query := `SELECT ` + dataKeyColumns + ` FROM data_keys WHERE id = $1`
+	key, err := scanDataKey(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Data key not found
+		}
+		return nil, fmt.Errorf("failed to get data key by ID: %w", err)
+	}
+	return key, nil
+}
+
+func (s *DataKeyStorage) GetActiveDataKey() (*model.DataKey, error) {
+	query := `SELECT ` + dataKeyColumns + ` FROM data_keys WHERE active`
+	key, err := scanDataKey(s.connection.QueryRow(query))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // No data key yet
+		}
+		return nil, fmt.Errorf("failed to get active data key: %w", err)
+	}
+	return key, nil
+}
+
+func (s *DataKeyStorage) GetAllDataKeys() ([]model.DataKey, error) {
+	rows, err := s.connection.Query(`SELECT ` + dataKeyColumns + ` FROM data_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []model.DataKey
+	for rows.Next() {
+		key, err := scanDataKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over data key rows: %w", err)
+	}
+	return keys, nil
+}
+
+// RewrapDataKey replaces the wrapped form of a data key after it was wrapped
+// again with another master key. The data key itself does not change.
+func (s *DataKeyStorage) RewrapDataKey(id int, masterKeyID string, wrappedKey []byte) error {
+	_, err := s.connection.Exec(`UPDATE data_keys SET master_key_id = $1, wrapped_key = $2 WHERE id = $3`,
+		masterKeyID, wrappedKey, id)
+	if err != nil {
+		return fmt.Errorf("failed to rewrap data key: %w", err)
+	}
+	return nil
+}
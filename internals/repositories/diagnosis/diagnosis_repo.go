@@ -3,75 +3,225 @@ package diagnosis_repo
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 
 	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	domain_event_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/domain_event"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
 )
 
 // Package diagnosis_repo provides the implementation of the DiagnosisRepository interface
 
+// descriptionField is the encrypted description column. Rows with no data
+// key predate encryption and are plaintext until re-encrypted.
+const descriptionField = "diagnoses.description"
+
+const diagnosisColumns = `id, patient_id, doctor_id, encounter_id, description, created_at, data_key_id`
+
 type DiagnosisStorage struct {
 	connection *sql.DB
+	keyring    *utils.Keyring
 }
 
-func NewDiagnosisStorage(db *sql.DB) *DiagnosisStorage {
+func NewDiagnosisStorage(db *sql.DB, keyring *utils.Keyring) *DiagnosisStorage {
 	return &DiagnosisStorage{
 		connection: db,
+		keyring:    keyring,
+	}
+}
+
+// sealDescription encrypts description with the active data key.
+func (s *DiagnosisStorage) sealDescription(description string) (string, int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return "", 0, err
+	}
+	sealed, err := key.Seal(descriptionField, description)
+	if err != nil {
+		return "", 0, err
 	}
+	return sealed, key.ID, nil
 }
 
-func (s *DiagnosisStorage) CreateDiagnosis(diagnosis model.Diagnosis) error {
-	query := `INSERT INTO diagnoses (id, patient_id, description, created_at) VALUES ($1, $2, $3, NOW())`
-	_, err := s.connection.Exec(query, diagnosis.ID, diagnosis.PatientID, diagnosis.Description)
+// CreateDiagnosis stores the diagnosis together with a DiagnosisRecorded event.
+func (s *DiagnosisStorage) CreateDiagnosis(diagnosis model.Diagnosis) (*model.Diagnosis, error) {
+	description, keyID, err := s.sealDescription(diagnosis.Description)
 	if err != nil {
-		return fmt.Errorf("failed to create diagnosis: %w", err)
+		return nil, fmt.Errorf("failed to create diagnosis: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO diagnoses (patient_id, doctor_id, encounter_id, description, data_key_id, created_at)
+	          VALUES ($1, $2, $3, $4, $5, NOW())
+	          RETURNING ` + diagnosisColumns
+	row := tx.QueryRow(query, diagnosis.PatientID, diagnosis.DoctorID, diagnosis.EncounterID, description, keyID)
+	created, err := s.scanDiagnosis(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diagnosis: %w", err)
+	}
+	err = domain_event_repo.AppendEvent(tx, model.EventDiagnosisRecorded, strconv.Itoa(created.ID),
+		model.DiagnosisRecordedPayload{
+			DiagnosisID: created.ID,
+			PatientID:   created.PatientID,
+			DoctorID:    created.DoctorID,
+			EncounterID: created.EncounterID,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	return nil
+	return created, nil
 }
 
 func (s *DiagnosisStorage) DeleteDiagnosis(id string) (*model.Diagnosis, error) {
-	query := `DELETE FROM diagnoses WHERE id = $1 RETURNING id, patient_id,doctor_id, description`
+	query := `DELETE FROM diagnoses WHERE id = $1 AND clinic_id = current_clinic_id() RETURNING ` + diagnosisColumns
 	row := s.connection.QueryRow(query, id)
 
-	var diagnosis model.Diagnosis
-	err := row.Scan(&diagnosis.ID, &diagnosis.PatientID, &diagnosis.DoctorID, &diagnosis.Description)
+	diagnosis, err := s.scanDiagnosis(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("no diagnosis found with id: %s", id)
 		}
 		return nil, fmt.Errorf("failed to delete diagnosis: %w", err)
 	}
-	return &diagnosis, nil
+	return diagnosis, nil
 }
 
 func (s *DiagnosisStorage) UpdateDiagnosis(diagnosis model.Diagnosis) (*model.Diagnosis, error) {
-	query := `UPDATE diagnoses SET patient_id = $1, doctor_id = $2, description = $3, updated_at = NOW()
-	          WHERE id = $4 RETURNING id, patient_id, doctor_id, description`
-	row := s.connection.QueryRow(query, diagnosis.PatientID, diagnosis.DoctorID, diagnosis.Description, diagnosis.ID)
+	description, keyID, err := s.sealDescription(diagnosis.Description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update diagnosis: %w", err)
+	}
+	query := `UPDATE diagnoses SET patient_id = $1, doctor_id = $2, description = $3, data_key_id = $4, updated_at = NOW()
+	          WHERE id = $5 AND clinic_id = current_clinic_id() RETURNING ` + diagnosisColumns
+	row := s.connection.QueryRow(query, diagnosis.PatientID, diagnosis.DoctorID, description, keyID, diagnosis.ID)
 
-	var updatedDiagnosis model.Diagnosis
-	err := row.Scan(&updatedDiagnosis.ID, &updatedDiagnosis.PatientID, &updatedDiagnosis.DoctorID, &updatedDiagnosis.Description)
+	updatedDiagnosis, err := s.scanDiagnosis(row)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update diagnosis: %w", err)
 	}
-	return &updatedDiagnosis, nil
+	return updatedDiagnosis, nil
+}
+
+func (s *DiagnosisStorage) GetDiagnosisByID(id string) (*model.Diagnosis, error) {
+	query := `SELECT ` + diagnosisColumns + ` FROM diagnoses WHERE id = $1 AND clinic_id = current_clinic_id()`
+	diagnosis, err := s.scanDiagnosis(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Diagnosis not found
+		}
+		return nil, fmt.Errorf("failed to get diagnosis: %w", err)
+	}
+	return diagnosis, nil
 }
 
 func (s *DiagnosisStorage) GetDiagnosisByPatientID(patientID string) ([]model.Diagnosis, error) {
-	query := `SELECT id, patient_id, doctor_id, description FROM diagnoses WHERE patient_id = $1`
+	query := `SELECT ` + diagnosisColumns + ` FROM diagnoses WHERE patient_id = $1 AND clinic_id = current_clinic_id()`
 	rows, err := s.connection.Query(query, patientID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get diagnoses by patient ID: %w", err)
 	}
+	return s.scanDiagnoses(rows)
+}
+
+// GetRecentDiagnosesByPatientID returns at most limit diagnoses, newest first.
+func (s *DiagnosisStorage) GetRecentDiagnosesByPatientID(patientID string, limit int) ([]model.Diagnosis, error) {
+	query := `SELECT ` + diagnosisColumns + ` FROM diagnoses
+	          WHERE patient_id = $1 AND clinic_id = current_clinic_id() ORDER BY created_at DESC LIMIT $2`
+	rows, err := s.connection.Query(query, patientID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent diagnoses by patient ID: %w", err)
+	}
+	return s.scanDiagnoses(rows)
+}
+
+func (s *DiagnosisStorage) GetDiagnosesByEncounterID(encounterID string) ([]model.Diagnosis, error) {
+	query := `SELECT ` + diagnosisColumns + ` FROM diagnoses WHERE encounter_id = $1 AND clinic_id = current_clinic_id() ORDER BY created_at`
+	rows, err := s.connection.Query(query, encounterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diagnoses by encounter ID: %w", err)
+	}
+	return s.scanDiagnoses(rows)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *DiagnosisStorage) scanDiagnosis(row scanner) (*model.Diagnosis, error) {
+	var diagnosis model.Diagnosis
+	var keyID sql.NullInt32
+	err := row.Scan(&diagnosis.ID, &diagnosis.PatientID, &diagnosis.DoctorID, &diagnosis.EncounterID, &diagnosis.Description, &diagnosis.CreatedAt, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	if diagnosis.Description, err = s.keyring.Open(keyID, descriptionField, diagnosis.Description); err != nil {
+		return nil, err
+	}
+	return &diagnosis, nil
+}
+
+func (s *DiagnosisStorage) scanDiagnoses(rows *sql.Rows) ([]model.Diagnosis, error) {
 	defer rows.Close()
 
 	var diagnoses []model.Diagnosis
 	for rows.Next() {
-		var diagnosis model.Diagnosis
-		if err := rows.Scan(&diagnosis.ID, &diagnosis.PatientID, &diagnosis.DoctorID, &diagnosis.Description); err != nil {
+		diagnosis, err := s.scanDiagnosis(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan diagnosis: %w", err)
 		}
-		diagnoses = append(diagnoses, diagnosis)
+		diagnoses = append(diagnoses, *diagnosis)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over diagnosis rows: %w", err)
 	}
 	return diagnoses, nil
 }
 
+// ReencryptDiagnoses re-encrypts up to limit diagnoses not yet encrypted with
+// the active data key, including plaintext rows, and returns how many it
+// rewrote; zero means the clinic is done.
+func (s *DiagnosisStorage) ReencryptDiagnoses(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt diagnoses: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + diagnosisColumns + ` FROM diagnoses
+	          WHERE clinic_id = current_clinic_id() AND data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get diagnoses to re-encrypt: %w", err)
+	}
+	diagnoses, err := s.scanDiagnoses(rows)
+	if err != nil {
+		return 0, err
+	}
+	for _, diagnosis := range diagnoses {
+		description, err := key.Seal(descriptionField, diagnosis.Description)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt diagnosis: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE diagnoses SET description = $1, data_key_id = $2 WHERE id = $3`,
+			description, key.ID, diagnosis.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt diagnosis: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(diagnoses), nil
+}
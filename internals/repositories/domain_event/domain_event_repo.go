@@ -0,0 +1,122 @@
+package domain_event_repo
+
+// Package domain_event_repo provides the implementation of the DomainEventRepository interface
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+const eventColumns = `id, clinic_id, type, aggregate_id, payload, occurred_at, status, attempts,
+	next_attempt_at, last_error, delivered_at`
+
+// AppendEvent writes an event to the outbox in tx, so it is stored exactly
+// when the change it describes is committed. Repositories call it from the
+// transaction making the change.
+func AppendEvent(tx *sql.Tx, eventType, aggregateID string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	_, err = tx.Exec(`INSERT INTO domain_events (type, aggregate_id, payload) VALUES ($1, $2, $3)`,
+		eventType, aggregateID, data)
+	if err != nil {
+		return fmt.Errorf("failed to append %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+type DomainEventStorage struct {
+	connection *sql.DB
+}
+
+func NewDomainEventStorage(db *sql.DB) *DomainEventStorage {
+	return &DomainEventStorage{
+		connection: db,
+	}
+}
+
+// ClaimDueEvents takes up to limit pending events that are due, oldest first,
+// and counts an attempt on each. Their next attempt is pushed lease into the
+// future, so a dispatcher that dies while delivering leaves them to be
+// retried rather than lost, and concurrent dispatchers never claim the same one.
+func (s *DomainEventStorage) ClaimDueEvents(limit int, lease time.Duration) ([]model.DomainEvent, error) {
+	query := `UPDATE domain_events SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+	          WHERE id IN (
+	              SELECT id FROM domain_events WHERE status = 'pending' AND next_attempt_at <= NOW()
+	              ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+	          )
+	          RETURNING ` + eventColumns
+	rows, err := s.connection.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due events: %w", err)
+	}
+	return scanEvents(rows)
+}
+
+func (s *DomainEventStorage) MarkDelivered(id int64) error {
+	_, err := s.connection.Exec(`UPDATE domain_events SET status = 'delivered', delivered_at = NOW(), last_error = ''
+		WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark event delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkRetry records a failed attempt and when to try again.
+func (s *DomainEventStorage) MarkRetry(id int64, nextAttemptAt time.Time, lastError string) error {
+	_, err := s.connection.Exec(`UPDATE domain_events SET next_attempt_at = $1, last_error = $2 WHERE id = $3`,
+		nextAttemptAt, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule event retry: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed gives up on an event after its last failed attempt.
+func (s *DomainEventStorage) MarkFailed(id int64, lastError string) error {
+	_, err := s.connection.Exec(`UPDATE domain_events SET status = 'failed', last_error = $1 WHERE id = $2`,
+		lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark event failed: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEvent(row scanner) (*model.DomainEvent, error) {
+	var event model.DomainEvent
+	var deliveredAt sql.NullTime
+	err := row.Scan(&event.ID, &event.ClinicID, &event.Type, &event.AggregateID, &event.Payload, &event.OccurredAt,
+		&event.Status, &event.Attempts, &event.NextAttemptAt, &event.LastError, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	if deliveredAt.Valid {
+		event.DeliveredAt = &deliveredAt.Time
+	}
+	return &event, nil
+}
+
+func scanEvents(rows *sql.Rows) ([]model.DomainEvent, error) {
+	defer rows.Close()
+	var events []model.DomainEvent
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, *event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over event rows: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,152 @@
+package emergency_access_repo
+
+// Package emergency_access_repo provides the implementation of the EmergencyAccessRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+const accessColumns = `id, doctor_id, patient_id, reason, granted_at, expires_at, reviewed_by, reviewed_at, review_note`
+
+type EmergencyAccessStorage struct {
+	connection *sql.DB
+}
+
+func NewEmergencyAccessStorage(db *sql.DB) *EmergencyAccessStorage {
+	return &EmergencyAccessStorage{
+		connection: db,
+	}
+}
+
+func (s *EmergencyAccessStorage) CreateAccess(access model.EmergencyAccess) (*model.EmergencyAccess, error) {
+	query := `INSERT INTO emergency_accesses (doctor_id, patient_id, reason, granted_at, expires_at)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING ` + accessColumns
+	row := s.connection.QueryRow(query, access.DoctorID, access.PatientID, access.Reason, access.GrantedAt, access.ExpiresAt)
+	created, err := scanAccess(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create emergency access: %w", err)
+	}
+	return created, nil
+}
+
+func (s *EmergencyAccessStorage) GetAccessByID(id int64) (*model.EmergencyAccess, error) {
+	query := `SELECT ` + accessColumns + ` FROM emergency_accesses WHERE id = $1 AND clinic_id = current_clinic_id()`
+	access, err := scanAccess(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Emergency access not found
+		}
+		return nil, fmt.Errorf("failed to get emergency access by ID: %w", err)
+	}
+	return access, nil
+}
+
+// GetActiveAccess returns the doctor's emergency access to the patient that
+// lasts longest past now, or nil if none is active.
+func (s *EmergencyAccessStorage) GetActiveAccess(doctorID, patientID string, now time.Time) (*model.EmergencyAccess, error) {
+	query := `SELECT ` + accessColumns + ` FROM emergency_accesses
+	          WHERE doctor_id = $1 AND patient_id = $2 AND expires_at > $3 AND clinic_id = current_clinic_id()
+	          ORDER BY expires_at DESC LIMIT 1`
+	access, err := scanAccess(s.connection.QueryRow(query, doctorID, patientID, now))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Emergency access not found
+		}
+		return nil, fmt.Errorf("failed to get active emergency access: %w", err)
+	}
+	return access, nil
+}
+
+// GetAccesses returns the clinic's emergency accesses, oldest first, or only
+// those not reviewed yet if pendingOnly is set.
+func (s *EmergencyAccessStorage) GetAccesses(pendingOnly bool) ([]model.EmergencyAccess, error) {
+	query := `SELECT ` + accessColumns + ` FROM emergency_accesses
+	          WHERE clinic_id = current_clinic_id() AND (NOT $1 OR reviewed_at IS NULL) ORDER BY granted_at, id`
+	rows, err := s.connection.Query(query, pendingOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emergency accesses: %w", err)
+	}
+	defer rows.Close()
+
+	var accesses []model.EmergencyAccess
+	for rows.Next() {
+		access, err := scanAccess(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan emergency access: %w", err)
+		}
+		accesses = append(accesses, *access)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over emergency access rows: %w", err)
+	}
+	return accesses, nil
+}
+
+// ReviewAccess marks an access as reviewed. It returns nil if the access does
+// not exist or was already reviewed.
+func (s *EmergencyAccessStorage) ReviewAccess(id int64, reviewedBy, note string) (*model.EmergencyAccess, error) {
+	query := `UPDATE emergency_accesses SET reviewed_by = $1, reviewed_at = NOW(), review_note = $2
+	          WHERE id = $3 AND clinic_id = current_clinic_id() AND reviewed_at IS NULL
+	          RETURNING ` + accessColumns
+	access, err := scanAccess(s.connection.QueryRow(query, reviewedBy, note, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Emergency access not found
+		}
+		return nil, fmt.Errorf("failed to review emergency access: %w", err)
+	}
+	return access, nil
+}
+
+func (s *EmergencyAccessStorage) LogAccess(accessID int64, action string) error {
+	_, err := s.connection.Exec(`INSERT INTO emergency_access_log (access_id, action) VALUES ($1, $2)`, accessID, action)
+	if err != nil {
+		return fmt.Errorf("failed to log emergency access: %w", err)
+	}
+	return nil
+}
+
+func (s *EmergencyAccessStorage) GetAccessLog(accessID int64) ([]model.EmergencyAccessLog, error) {
+	query := `SELECT id, access_id, action, accessed_at FROM emergency_access_log
+	          WHERE access_id = $1 ORDER BY accessed_at, id`
+	rows, err := s.connection.Query(query, accessID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get emergency access log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.EmergencyAccessLog
+	for rows.Next() {
+		var entry model.EmergencyAccessLog
+		if err := rows.Scan(&entry.ID, &entry.AccessID, &entry.Action, &entry.AccessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan emergency access log entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over emergency access log rows: %w", err)
+	}
+	return entries, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAccess(row scanner) (*model.EmergencyAccess, error) {
+	var access model.EmergencyAccess
+	var reviewedAt sql.NullTime
+	err := row.Scan(&access.ID, &access.DoctorID, &access.PatientID, &access.Reason, &access.GrantedAt,
+		&access.ExpiresAt, &access.ReviewedBy, &reviewedAt, &access.ReviewNote)
+	if err != nil {
+		return nil, err
+	}
+	if reviewedAt.Valid {
+		access.ReviewedAt = &reviewedAt.Time
+	}
+	return &access, nil
+}
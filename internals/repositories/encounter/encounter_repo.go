@@ -0,0 +1,165 @@
+package encounter_repo
+
+// Package encounter_repo provides the implementation of the EncounterRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+const encounterColumns = `id, patient_id, doctor_id, chief_complaint, status, checked_in_at,
+	consultation_started_at, discharged_at, discharge_notes, created_by`
+
+type EncounterStorage struct {
+	connection *sql.DB
+}
+
+func NewEncounterStorage(db *sql.DB) *EncounterStorage {
+	return &EncounterStorage{
+		connection: db,
+	}
+}
+
+func (s *EncounterStorage) CreateEncounter(encounter model.Encounter) (*model.Encounter, error) {
+	query := `INSERT INTO encounters (id, patient_id, doctor_id, chief_complaint, status, checked_in_at, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ` + encounterColumns
+	row := s.connection.QueryRow(query, encounter.ID, encounter.PatientID, encounter.DoctorID, encounter.ChiefComplaint,
+		encounter.Status, encounter.CheckedInAt, encounter.CreatedBy)
+	created, err := scanEncounter(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encounter: %w", err)
+	}
+	return created, nil
+}
+
+func (s *EncounterStorage) UpdateEncounter(encounter model.Encounter) (*model.Encounter, error) {
+	query := `UPDATE encounters SET doctor_id = $1, chief_complaint = $2, status = $3, consultation_started_at = $4,
+	          discharged_at = $5, discharge_notes = $6, updated_at = NOW()
+	          WHERE id = $7 RETURNING ` + encounterColumns
+	row := s.connection.QueryRow(query, encounter.DoctorID, encounter.ChiefComplaint, encounter.Status,
+		encounter.ConsultationStartedAt, encounter.DischargedAt, encounter.DischargeNotes, encounter.ID)
+	updated, err := scanEncounter(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update encounter: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *EncounterStorage) GetEncounterByID(id string) (*model.Encounter, error) {
+	query := `SELECT ` + encounterColumns + ` FROM encounters WHERE id = $1`
+	row := s.connection.QueryRow(query, id)
+	encounter, err := scanEncounter(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Encounter not found
+		}
+		return nil, fmt.Errorf("failed to get encounter by ID: %w", err)
+	}
+	return encounter, nil
+}
+
+func (s *EncounterStorage) GetEncountersByPatientID(patientID string) ([]model.Encounter, error) {
+	query := `SELECT ` + encounterColumns + ` FROM encounters WHERE patient_id = $1 ORDER BY checked_in_at DESC`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encounters by patient ID: %w", err)
+	}
+	return scanEncounters(rows)
+}
+
+// GetOpenEncountersByDoctorID returns the doctor's encounters checked in from
+// since up to but excluding until that have not been discharged or cancelled,
+// oldest first.
+func (s *EncounterStorage) GetOpenEncountersByDoctorID(doctorID string, since, until time.Time) ([]model.Encounter, error) {
+	query := `SELECT ` + encounterColumns + ` FROM encounters
+	          WHERE doctor_id = $1 AND checked_in_at >= $2 AND checked_in_at < $3
+	            AND status IN ('checked_in', 'in_consultation')
+	          ORDER BY checked_in_at`
+	rows, err := s.connection.Query(query, doctorID, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open encounters by doctor ID: %w", err)
+	}
+	return scanEncounters(rows)
+}
+
+// GetAverageConsultationDuration averages the time from the start of the
+// consultation to discharge over the doctor's encounters since the given time.
+// It also returns how many encounters the average is based on.
+func (s *EncounterStorage) GetAverageConsultationDuration(doctorID string, since time.Time) (time.Duration, int, error) {
+	query := `SELECT COALESCE(AVG(EXTRACT(EPOCH FROM discharged_at - consultation_started_at)), 0), COUNT(*)
+	          FROM encounters
+	          WHERE doctor_id = $1 AND status = 'discharged' AND consultation_started_at IS NOT NULL
+	            AND discharged_at > consultation_started_at AND checked_in_at >= $2`
+	var seconds float64
+	var count int
+	err := s.connection.QueryRow(query, doctorID, since).Scan(&seconds, &count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get average consultation duration: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), count, nil
+}
+
+// IsTreatingDoctor reports whether the doctor treats the patient: they have
+// been assigned one of the patient's encounters or have diagnosed them.
+// Encounters the doctor opened with themselves, and diagnoses recorded in
+// them, do not count.
+func (s *EncounterStorage) IsTreatingDoctor(doctorID, patientID string) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM encounters
+	              WHERE doctor_id = $1 AND patient_id = $2 AND status <> 'cancelled'
+	                AND created_by IS DISTINCT FROM doctor_id
+	          ) OR EXISTS (
+	              SELECT 1 FROM diagnoses d
+	              LEFT JOIN encounters e ON e.id = d.encounter_id
+	              WHERE d.doctor_id = $1 AND d.patient_id = $2
+	                AND (e.id IS NULL OR e.created_by IS DISTINCT FROM e.doctor_id)
+	          )`
+	var treating bool
+	if err := s.connection.QueryRow(query, doctorID, patientID).Scan(&treating); err != nil {
+		return false, fmt.Errorf("failed to check treating doctor: %w", err)
+	}
+	return treating, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEncounter(row scanner) (*model.Encounter, error) {
+	var encounter model.Encounter
+	var consultationStartedAt, dischargedAt sql.NullTime
+	var createdBy uuid.NullUUID
+	err := row.Scan(&encounter.ID, &encounter.PatientID, &encounter.DoctorID, &encounter.ChiefComplaint, &encounter.Status,
+		&encounter.CheckedInAt, &consultationStartedAt, &dischargedAt, &encounter.DischargeNotes, &createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if consultationStartedAt.Valid {
+		encounter.ConsultationStartedAt = &consultationStartedAt.Time
+	}
+	if dischargedAt.Valid {
+		encounter.DischargedAt = &dischargedAt.Time
+	}
+	encounter.CreatedBy = createdBy.UUID
+	return &encounter, nil
+}
+
+func scanEncounters(rows *sql.Rows) ([]model.Encounter, error) {
+	defer rows.Close()
+	var encounters []model.Encounter
+	for rows.Next() {
+		encounter, err := scanEncounter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan encounter: %w", err)
+		}
+		encounters = append(encounters, *encounter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over encounter rows: %w", err)
+	}
+	return encounters, nil
+}
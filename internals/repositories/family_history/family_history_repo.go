@@ -0,0 +1,95 @@
+package family_history_repo
+
+// Package family_history_repo provides the implementation of the FamilyHistoryRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+const familyHistoryColumns = `id, patient_id, relation, condition, notes, recorded_by, created_at`
+
+type FamilyHistoryStorage struct {
+	connection *sql.DB
+}
+
+func NewFamilyHistoryStorage(db *sql.DB) *FamilyHistoryStorage {
+	return &FamilyHistoryStorage{
+		connection: db,
+	}
+}
+
+func (s *FamilyHistoryStorage) CreateFamilyHistory(entry model.FamilyHistory) (*model.FamilyHistory, error) {
+	query := `INSERT INTO family_history (patient_id, relation, condition, notes, recorded_by)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING ` + familyHistoryColumns
+	row := s.connection.QueryRow(query, entry.PatientID, entry.Relation, entry.Condition, entry.Notes, entry.RecordedBy)
+	created, err := scanFamilyHistory(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create family history: %w", err)
+	}
+	return created, nil
+}
+
+func (s *FamilyHistoryStorage) DeleteFamilyHistory(id string) (*model.FamilyHistory, error) {
+	query := `DELETE FROM family_history WHERE id = $1 RETURNING ` + familyHistoryColumns
+	row := s.connection.QueryRow(query, id)
+	deleted, err := scanFamilyHistory(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no family history found with id: %s", id)
+		}
+		return nil, fmt.Errorf("failed to delete family history: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *FamilyHistoryStorage) UpdateFamilyHistory(entry model.FamilyHistory) (*model.FamilyHistory, error) {
+	query := `UPDATE family_history SET relation = $1, condition = $2, notes = $3, updated_at = NOW()
+	          WHERE id = $4 RETURNING ` + familyHistoryColumns
+	row := s.connection.QueryRow(query, entry.Relation, entry.Condition, entry.Notes, entry.ID)
+	updated, err := scanFamilyHistory(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update family history: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *FamilyHistoryStorage) GetFamilyHistoryByPatientID(patientID string) ([]model.FamilyHistory, error) {
+	query := `SELECT ` + familyHistoryColumns + ` FROM family_history WHERE patient_id = $1 ORDER BY created_at`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get family history by patient ID: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.FamilyHistory
+	for rows.Next() {
+		entry, err := scanFamilyHistory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan family history: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over family history rows: %w", err)
+	}
+	return entries, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFamilyHistory(row scanner) (*model.FamilyHistory, error) {
+	var entry model.FamilyHistory
+	var recordedBy uuid.NullUUID
+	err := row.Scan(&entry.ID, &entry.PatientID, &entry.Relation, &entry.Condition, &entry.Notes, &recordedBy, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	entry.RecordedBy = recordedBy.UUID
+	return &entry, nil
+}
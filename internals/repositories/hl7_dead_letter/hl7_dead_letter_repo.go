@@ -0,0 +1,159 @@
+package hl7_dead_letter_repo
+
+// Package hl7_dead_letter_repo provides the implementation of the HL7DeadLetterRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+// messageField is the encrypted message column: the raw message carries the
+// patient's name, phone number and identifiers. Rows with no data key predate
+// encryption and are plaintext until re-encrypted.
+const messageField = "hl7_dead_letters.message"
+
+const deadLetterColumns = `id, remote_addr, message, error, received_at, resolved_at, data_key_id`
+
+type HL7DeadLetterStorage struct {
+	connection *sql.DB
+	keyring    *utils.Keyring
+}
+
+func NewHL7DeadLetterStorage(db *sql.DB, keyring *utils.Keyring) *HL7DeadLetterStorage {
+	return &HL7DeadLetterStorage{
+		connection: db,
+		keyring:    keyring,
+	}
+}
+
+func (s *HL7DeadLetterStorage) CreateDeadLetter(letter model.HL7DeadLetter) (*model.HL7DeadLetter, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hl7 dead letter: %w", err)
+	}
+	message, err := key.Seal(messageField, letter.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hl7 dead letter: %w", err)
+	}
+	query := `INSERT INTO hl7_dead_letters (remote_addr, message, error, data_key_id) VALUES ($1, $2, $3, $4)
+	          RETURNING ` + deadLetterColumns
+	created, err := s.scanDeadLetter(s.connection.QueryRow(query, letter.RemoteAddr, message, letter.Error, key.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hl7 dead letter: %w", err)
+	}
+	return created, nil
+}
+
+func (s *HL7DeadLetterStorage) GetDeadLetterByID(id int64) (*model.HL7DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM hl7_dead_letters WHERE id = $1`
+	letter, err := s.scanDeadLetter(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Dead letter not found
+		}
+		return nil, fmt.Errorf("failed to get hl7 dead letter: %w", err)
+	}
+	return letter, nil
+}
+
+// GetUnresolvedDeadLetters returns the messages still awaiting review, oldest first.
+func (s *HL7DeadLetterStorage) GetUnresolvedDeadLetters() ([]model.HL7DeadLetter, error) {
+	query := `SELECT ` + deadLetterColumns + ` FROM hl7_dead_letters WHERE resolved_at IS NULL ORDER BY received_at`
+	rows, err := s.connection.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hl7 dead letters: %w", err)
+	}
+	return s.scanDeadLetters(rows)
+}
+
+func (s *HL7DeadLetterStorage) ResolveDeadLetter(id int64) error {
+	_, err := s.connection.Exec(`UPDATE hl7_dead_letters SET resolved_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve hl7 dead letter: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *HL7DeadLetterStorage) scanDeadLetter(row scanner) (*model.HL7DeadLetter, error) {
+	var letter model.HL7DeadLetter
+	var resolvedAt sql.NullTime
+	var keyID sql.NullInt32
+	err := row.Scan(&letter.ID, &letter.RemoteAddr, &letter.Message, &letter.Error, &letter.ReceivedAt, &resolvedAt,
+		&keyID)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		letter.ResolvedAt = &resolvedAt.Time
+	}
+	if letter.Message, err = s.keyring.Open(keyID, messageField, letter.Message); err != nil {
+		return nil, err
+	}
+	return &letter, nil
+}
+
+func (s *HL7DeadLetterStorage) scanDeadLetters(rows *sql.Rows) ([]model.HL7DeadLetter, error) {
+	defer rows.Close()
+
+	var letters []model.HL7DeadLetter
+	for rows.Next() {
+		letter, err := s.scanDeadLetter(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hl7 dead letter: %w", err)
+		}
+		letters = append(letters, *letter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over hl7 dead letter rows: %w", err)
+	}
+	return letters, nil
+}
+
+// ReencryptDeadLetters re-encrypts up to limit dead letters not yet encrypted
+// with the active data key, including plaintext rows, and returns how many it
+// rewrote; zero means the clinic is done.
+func (s *HL7DeadLetterStorage) ReencryptDeadLetters(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt hl7 dead letters: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + deadLetterColumns + ` FROM hl7_dead_letters
+	          WHERE clinic_id = current_clinic_id() AND data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get hl7 dead letters to re-encrypt: %w", err)
+	}
+	letters, err := s.scanDeadLetters(rows)
+	if err != nil {
+		return 0, err
+	}
+	for _, letter := range letters {
+		message, err := key.Seal(messageField, letter.Message)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt hl7 dead letter: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE hl7_dead_letters SET message = $1, data_key_id = $2 WHERE id = $3`,
+			message, key.ID, letter.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt hl7 dead letter: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(letters), nil
+}
@@ -1,17 +1,46 @@
 package repositories
 
-import "github.com/aaryansinhaa/patient-management-system/internals/model"
+import (
+	"errors"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+// ErrAmountExceedsBalance is returned when a payment is more than the invoice's
+// outstanding balance or a refund is more than what is left of the payment.
+var ErrAmountExceedsBalance = errors.New("amount exceeds balance")
+
+// ErrMergeConflict is returned when a patient merge cannot be undone because
+// the records have changed since.
+var ErrMergeConflict = errors.New("merge conflicts with current records")
 
 type UserRepository interface {
-	CreateUser(user model.User) error
+	CreateUser(user model.User, passwordHash string) error
 	DeleteUser(id string) (*model.User, error)
+	DeactivateUser(id string, deactivatedBy string) (*model.User, error)
 	UpdateUser(user model.User) (*model.User, error)
 	GetUserByID(id string) (*model.User, error)
 	GetUserByUsername(username string) (*model.User, error)
 	GetAllUsers() ([]model.User, error)
 	GetAllUsersByRole(role string) ([]model.User, error)
 	GetUserByPhoneNumber(phoneNumber string) (*model.User, error)
-	GetUsernameAndPasswordById(id string) (string, string, error)
+}
+
+// CredentialRepository exposes password hashes and is only to be used by the auth service.
+type CredentialRepository interface {
+	GetCredentialsByUsername(username string) (*model.UserCredentials, error)
+	GetCredentialsByUserID(id string) (*model.UserCredentials, error)
+	UpdatePasswordHash(userID string, passwordHash string) error
+	SetPassword(userID string, passwordHash string, historyDepth int) error
+	GetPasswordHistory(userID string, limit int) ([]string, error)
+}
+
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(token model.PasswordResetToken) error
+	GetPasswordResetToken(tokenHash string) (*model.PasswordResetToken, error)
+	ConsumePasswordResetToken(tokenHash string) (bool, error)
+	DeletePasswordResetTokensByUser(userID string) error
 }
 
 type PatientRepository interface {
@@ -19,14 +48,247 @@ type PatientRepository interface {
 	DeletePatient(id string) (*model.Patient, error)
 	UpdatePatient(patient model.Patient) (*model.Patient, error)
 	GetPatientByID(id string) (*model.Patient, error)
-	GetPatientByPhoneNumber(phoneNumber string) (*model.Patient, error)
+	GetPatientsByContact(contact string) ([]model.Patient, error)
 	GetAllPatients() ([]model.Patient, error)
 	GetAllPatientsByName(name string) ([]model.Patient, error)
+	CreatePatients(patients []model.Patient) error
+	GetExistingPhoneNumbers(phoneNumbers []string) ([]string, error)
+	EachPatient(fn func(patient model.Patient) error) error
+	ReencryptPatients(limit int) (int, error)
 }
 
 type DiagnosisRepository interface {
-	CreateDiagnosis(diagnosis model.Diagnosis) error
+	CreateDiagnosis(diagnosis model.Diagnosis) (*model.Diagnosis, error)
 	DeleteDiagnosis(id string) (*model.Diagnosis, error)
 	UpdateDiagnosis(diagnosis model.Diagnosis) (*model.Diagnosis, error)
+	GetDiagnosisByID(id string) (*model.Diagnosis, error)
 	GetDiagnosisByPatientID(patientID string) ([]model.Diagnosis, error)
+	GetRecentDiagnosesByPatientID(patientID string, limit int) ([]model.Diagnosis, error)
+	GetDiagnosesByEncounterID(encounterID string) ([]model.Diagnosis, error)
+	ReencryptDiagnoses(limit int) (int, error)
+}
+
+type AllergyRepository interface {
+	CreateAllergy(allergy model.Allergy) (*model.Allergy, error)
+	DeleteAllergy(id string) (*model.Allergy, error)
+	UpdateAllergy(allergy model.Allergy) (*model.Allergy, error)
+	GetAllergiesByPatientID(patientID string) ([]model.Allergy, error)
+}
+
+type ChronicConditionRepository interface {
+	CreateChronicCondition(condition model.ChronicCondition) (*model.ChronicCondition, error)
+	DeleteChronicCondition(id string) (*model.ChronicCondition, error)
+	UpdateChronicCondition(condition model.ChronicCondition) (*model.ChronicCondition, error)
+	GetChronicConditionByID(id string) (*model.ChronicCondition, error)
+	GetChronicConditionsByPatientID(patientID string) ([]model.ChronicCondition, error)
+}
+
+type FamilyHistoryRepository interface {
+	CreateFamilyHistory(entry model.FamilyHistory) (*model.FamilyHistory, error)
+	DeleteFamilyHistory(id string) (*model.FamilyHistory, error)
+	UpdateFamilyHistory(entry model.FamilyHistory) (*model.FamilyHistory, error)
+	GetFamilyHistoryByPatientID(patientID string) ([]model.FamilyHistory, error)
+}
+
+type LoginAttemptRepository interface {
+	GetLoginAttempt(scope, key string) (*model.LoginAttempt, error)
+	RecordLoginFailure(scope, key string, window time.Duration) (*model.LoginAttempt, error)
+	LockLogin(scope, key string, until time.Time) error
+	ResetLoginAttempts(scope, key string) error
+}
+
+type AuditRepository interface {
+	CreateAuditEntry(entry model.AuditEntry) error
+	GetAuditEntriesByAction(action string) ([]model.AuditEntry, error)
+}
+
+type MFARepository interface {
+	GetTOTPSettings(userID string) (*model.TOTPSettings, error)
+	SetTOTPSecret(userID string, secret string) error
+	EnableTOTP(userID string) error
+	DisableTOTP(userID string) error
+	AdvanceTOTPStep(userID string, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID string, codeHashes []string) error
+	ConsumeRecoveryCode(userID string, codeHash string) (bool, error)
+	GetMFARolePolicy(role string) (*model.MFARolePolicy, error)
+	SetMFARolePolicy(policy model.MFARolePolicy) error
+}
+
+type EncounterRepository interface {
+	CreateEncounter(encounter model.Encounter) (*model.Encounter, error)
+	UpdateEncounter(encounter model.Encounter) (*model.Encounter, error)
+	GetEncounterByID(id string) (*model.Encounter, error)
+	GetEncountersByPatientID(patientID string) ([]model.Encounter, error)
+	GetOpenEncountersByDoctorID(doctorID string, since, until time.Time) ([]model.Encounter, error)
+	GetAverageConsultationDuration(doctorID string, since time.Time) (time.Duration, int, error)
+	IsTreatingDoctor(doctorID, patientID string) (bool, error)
+}
+
+type VitalsRepository interface {
+	CreateVitals(vitals model.Vitals) (*model.Vitals, error)
+	GetVitalsByEncounterID(encounterID string) ([]model.Vitals, error)
+}
+
+type PrescriptionRepository interface {
+	CreatePrescription(prescription model.Prescription) (*model.Prescription, error)
+	DeletePrescription(id string) (*model.Prescription, error)
+	GetPrescriptionsByEncounterID(encounterID string) ([]model.Prescription, error)
+	GetPrescriptionsByPatientID(patientID string) ([]model.Prescription, error)
+}
+
+type QueueRepository interface {
+	CreateQueueEntry(entry model.QueueEntry) (*model.QueueEntry, error)
+	GetQueueEntryByID(id int64) (*model.QueueEntry, error)
+	GetActiveQueueByDoctorID(doctorID string) ([]model.QueueEntry, error)
+	CallNextQueueEntry(doctorID string) (*model.QueueEntry, error)
+	UpdateQueueEntryStatus(id int64, status string) (*model.QueueEntry, error)
+}
+
+type CatalogueRepository interface {
+	CreateTaxRate(rate model.TaxRate) (*model.TaxRate, error)
+	GetTaxRateByID(id int) (*model.TaxRate, error)
+	GetAllTaxRates() ([]model.TaxRate, error)
+	CreateCatalogueItem(item model.CatalogueItem) (*model.CatalogueItem, error)
+	UpdateCatalogueItem(item model.CatalogueItem) (*model.CatalogueItem, error)
+	GetCatalogueItemByID(id int) (*model.CatalogueItem, error)
+	GetAllCatalogueItems(includeInactive bool) ([]model.CatalogueItem, error)
+}
+
+type InvoiceRepository interface {
+	CreateEncounterCharge(charge model.EncounterCharge) (*model.EncounterCharge, error)
+	GetEncounterChargesByEncounterID(encounterID string) ([]model.EncounterCharge, error)
+	CreateInvoice(invoice model.Invoice) (*model.Invoice, error)
+	GetInvoiceByID(id string) (*model.Invoice, error)
+	GetInvoiceByEncounterID(encounterID string) (*model.Invoice, error)
+	GetInvoicesByPatientID(patientID string) ([]model.Invoice, error)
+	GetOutstandingInvoicesByPatientID(patientID string) ([]model.Invoice, error)
+	CreatePayment(payment model.Payment) (*model.Payment, error)
+	GetPaymentByID(id int) (*model.Payment, error)
+	GetPaymentsByInvoiceID(invoiceID string) ([]model.Payment, error)
+	CreateRefund(refund model.Refund) (*model.Refund, error)
+	GetRefundsByInvoiceID(invoiceID string) ([]model.Refund, error)
+}
+
+type PatientIdentifierRepository interface {
+	LinkPatientIdentifier(identifier model.PatientIdentifier) error
+	GetPatientIdentifier(system, value string) (*model.PatientIdentifier, error)
+	GetIdentifiersByPatientID(patientID string) ([]model.PatientIdentifier, error)
+}
+
+type HL7DeadLetterRepository interface {
+	CreateDeadLetter(letter model.HL7DeadLetter) (*model.HL7DeadLetter, error)
+	GetDeadLetterByID(id int64) (*model.HL7DeadLetter, error)
+	GetUnresolvedDeadLetters() ([]model.HL7DeadLetter, error)
+	ResolveDeadLetter(id int64) error
+}
+
+type PatientMergeRepository interface {
+	MergePatients(survivorID, mergedID, mergedBy string) (*model.PatientMerge, error)
+	UndoMerge(mergeID int64, undoneBy string) (*model.PatientMerge, error)
+	GetMergeByID(id int64) (*model.PatientMerge, error)
+	GetMergesByPatientID(patientID string) ([]model.PatientMerge, error)
+}
+
+type PatientContactRepository interface {
+	CreateContact(contact model.PatientContact) (*model.PatientContact, error)
+	UpdateContact(contact model.PatientContact) (*model.PatientContact, error)
+	DeleteContact(id int) (*model.PatientContact, error)
+	GetContactByID(id int) (*model.PatientContact, error)
+	GetContactsByPatientID(patientID string) ([]model.PatientContact, error)
+}
+
+type ClinicRepository interface {
+	CreateClinic(clinic model.Clinic) (*model.Clinic, error)
+	GetClinicByID(id string) (*model.Clinic, error)
+	GetClinicByCode(code string) (*model.Clinic, error)
+	GetAllClinics() ([]model.Clinic, error)
+	GetMembershipsByUserID(userID string) ([]model.ClinicMembership, error)
+	AddMember(userID, clinicID string, isDefault bool) (bool, error)
+	RemoveMember(userID, clinicID string) (bool, error)
+}
+
+type DataKeyRepository interface {
+	CreateActiveDataKey(masterKeyID string, wrappedKey []byte) (*model.DataKey, error)
+	GetDataKeyByID(id int) (*model.DataKey, error)
+	GetActiveDataKey() (*model.DataKey, error)
+	GetAllDataKeys() ([]model.DataKey, error)
+	RewrapDataKey(id int, masterKeyID string, wrappedKey []byte) error
+}
+
+type ConsentRepository interface {
+	CreateConsentText(text model.ConsentText) (*model.ConsentText, error)
+	GetConsentText(scope string, version int) (*model.ConsentText, error)
+	GetLatestConsentText(scope string) (*model.ConsentText, error)
+	GetConsentTexts(scope string) ([]model.ConsentText, error)
+	GrantConsent(patientID string, textID int, grantedBy string) (*model.PatientConsent, error)
+	WithdrawConsent(patientID, scope, withdrawnBy string) (*model.PatientConsent, error)
+	GetActiveConsent(patientID, scope string) (*model.PatientConsent, error)
+	GetConsentsByPatientID(patientID string) ([]model.PatientConsent, error)
+}
+
+type EmergencyAccessRepository interface {
+	CreateAccess(access model.EmergencyAccess) (*model.EmergencyAccess, error)
+	GetAccessByID(id int64) (*model.EmergencyAccess, error)
+	GetActiveAccess(doctorID, patientID string, now time.Time) (*model.EmergencyAccess, error)
+	GetAccesses(pendingOnly bool) ([]model.EmergencyAccess, error)
+	ReviewAccess(id int64, reviewedBy, note string) (*model.EmergencyAccess, error)
+	LogAccess(accessID int64, action string) error
+	GetAccessLog(accessID int64) ([]model.EmergencyAccessLog, error)
+}
+
+type NotificationRepository interface {
+	CreateNotification(notification model.Notification) (*model.Notification, error)
+	ClaimDueNotifications(limit int, lease time.Duration) ([]model.Notification, error)
+	MarkSent(id int64) error
+	MarkRetry(id int64, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(id int64, lastError string) error
+	RetryNotification(id int64) (*model.Notification, error)
+	GetNotificationByID(id int64) (*model.Notification, error)
+	GetNotificationsByPatientID(patientID string) ([]model.Notification, error)
+}
+
+// DomainEventRepository drains the outbox. Events are written by the
+// repositories making the change, in the same transaction.
+type DomainEventRepository interface {
+	ClaimDueEvents(limit int, lease time.Duration) ([]model.DomainEvent, error)
+	MarkDelivered(id int64) error
+	MarkRetry(id int64, nextAttemptAt time.Time, lastError string) error
+	MarkFailed(id int64, lastError string) error
+}
+
+type WebhookRepository interface {
+	CreateSubscription(subscription model.WebhookSubscription) (*model.WebhookSubscription, error)
+	UpdateSubscription(subscription model.WebhookSubscription) (*model.WebhookSubscription, error)
+	DeleteSubscription(id int64) (*model.WebhookSubscription, error)
+	GetSubscriptionByID(id int64) (*model.WebhookSubscription, error)
+	GetSubscriptions() ([]model.WebhookSubscription, error)
+	EnqueueDeliveries(event model.DomainEvent) (int, error)
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]model.PendingWebhookDelivery, error)
+	FinishAttempt(attempt model.WebhookAttempt, status string, nextAttemptAt time.Time) error
+	GetDeliveriesBySubscriptionID(subscriptionID int64, status string) ([]model.WebhookDelivery, error)
+	GetDeliveryByID(id int64) (*model.WebhookDelivery, error)
+	GetAttemptsByDeliveryID(deliveryID int64) ([]model.WebhookAttempt, error)
+	ReplayDelivery(id int64) (*model.WebhookDelivery, error)
+}
+
+type LabRepository interface {
+	CreateLabTest(test model.LabTest) (*model.LabTest, error)
+	UpdateLabTest(test model.LabTest) (*model.LabTest, error)
+	GetLabTestByID(id int) (*model.LabTest, error)
+	GetLabTestByCode(code string) (*model.LabTest, error)
+	GetAllLabTests(includeInactive bool) ([]model.LabTest, error)
+	CreateLabOrder(order model.LabOrder) (*model.LabOrder, error)
+	GetLabOrderByID(id int64) (*model.LabOrder, error)
+	GetLabOrdersByPatientID(patientID string) ([]model.LabOrder, error)
+	GetPendingLabOrders(orderedBy string) ([]model.LabOrder, error)
+	RecordLabResult(result model.LabResult) (*model.LabOrder, error)
+	CancelLabOrder(id int64) (*model.LabOrder, error)
+}
+
+type AttachmentRepository interface {
+	CreateAttachment(attachment model.Attachment) (*model.Attachment, error)
+	GetAttachmentByID(id int64) (*model.Attachment, error)
+	GetAttachmentBySHA256(patientID, sha256 string) (*model.Attachment, error)
+	GetAttachmentsByPatientID(patientID string, includeDeleted bool) ([]model.Attachment, error)
+	DeleteAttachment(id int64, deletedBy string) (*model.Attachment, error)
 }
@@ -0,0 +1,409 @@
+package invoice_repo
+
+// Package invoice_repo provides the implementation of the InvoiceRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+const invoiceColumns = `id, invoice_number, patient_id, encounter_id, status, currency, subtotal, tax_total, total,
+	amount_paid, issued_by, issued_at`
+
+const invoiceLineColumns = `id, invoice_id, catalogue_item_id, description, quantity, unit_price, tax_rate_bp,
+	tax_amount, line_total`
+
+const paymentColumns = `id, invoice_id, amount, refunded, method, reference, received_by, received_at`
+
+const refundColumns = `id, payment_id, invoice_id, amount, reason, refunded_by, created_at`
+
+// invoiceStatus derives the status from amount_paid once it has been updated.
+const invoiceStatus = `CASE WHEN amount_paid >= total THEN 'paid'
+	WHEN amount_paid > 0 THEN 'partially_paid' ELSE 'issued' END`
+
+type InvoiceStorage struct {
+	connection *sql.DB
+}
+
+func NewInvoiceStorage(db *sql.DB) *InvoiceStorage {
+	return &InvoiceStorage{
+		connection: db,
+	}
+}
+
+func (s *InvoiceStorage) CreateEncounterCharge(charge model.EncounterCharge) (*model.EncounterCharge, error) {
+	query := `INSERT INTO encounter_charges (encounter_id, catalogue_item_id, quantity, added_by)
+	          VALUES ($1, $2, $3, $4) RETURNING id, encounter_id, catalogue_item_id, quantity, added_by, created_at`
+	row := s.connection.QueryRow(query, charge.EncounterID, charge.CatalogueItemID, charge.Quantity, charge.AddedBy)
+	created, err := scanEncounterCharge(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encounter charge: %w", err)
+	}
+	return created, nil
+}
+
+func (s *InvoiceStorage) GetEncounterChargesByEncounterID(encounterID string) ([]model.EncounterCharge, error) {
+	query := `SELECT id, encounter_id, catalogue_item_id, quantity, added_by, created_at
+	          FROM encounter_charges WHERE encounter_id = $1 ORDER BY created_at`
+	rows, err := s.connection.Query(query, encounterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encounter charges: %w", err)
+	}
+	defer rows.Close()
+
+	var charges []model.EncounterCharge
+	for rows.Next() {
+		charge, err := scanEncounterCharge(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan encounter charge: %w", err)
+		}
+		charges = append(charges, *charge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over encounter charge rows: %w", err)
+	}
+	return charges, nil
+}
+
+// CreateInvoice stores the invoice and its lines in one transaction and
+// assigns the next invoice number.
+func (s *InvoiceStorage) CreateInvoice(invoice model.Invoice) (*model.Invoice, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO invoices (id, invoice_number, patient_id, encounter_id, status, currency, subtotal, tax_total,
+	          total, issued_by)
+	          VALUES ($1, 'INV-' || LPAD(nextval('invoice_number_seq')::TEXT, 6, '0'), $2, $3, $4, $5, $6, $7, $8, $9)
+	          RETURNING ` + invoiceColumns
+	row := tx.QueryRow(query, invoice.ID, invoice.PatientID, invoice.EncounterID, invoice.Status, invoice.Currency,
+		invoice.Subtotal, invoice.TaxTotal, invoice.Total, invoice.IssuedBy)
+	created, err := scanInvoice(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	lineQuery := `INSERT INTO invoice_lines (invoice_id, catalogue_item_id, description, quantity, unit_price, tax_rate_bp,
+	              tax_amount, line_total)
+	              VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + invoiceLineColumns
+	for _, line := range invoice.Lines {
+		row := tx.QueryRow(lineQuery, created.ID, line.CatalogueItemID, line.Description, line.Quantity, line.UnitPrice,
+			line.TaxRate, line.TaxAmount, line.LineTotal)
+		createdLine, err := scanInvoiceLine(row)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create invoice line: %w", err)
+		}
+		created.Lines = append(created.Lines, *createdLine)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invoice: %w", err)
+	}
+	return created, nil
+}
+
+// GetInvoiceByID returns the invoice with its lines.
+func (s *InvoiceStorage) GetInvoiceByID(id string) (*model.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE id = $1`
+	invoice, err := scanInvoice(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Invoice not found
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	if invoice.Lines, err = s.getInvoiceLines(id); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// GetInvoiceByEncounterID returns the invoice issued for the encounter, without its lines.
+func (s *InvoiceStorage) GetInvoiceByEncounterID(encounterID string) (*model.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE encounter_id = $1`
+	invoice, err := scanInvoice(s.connection.QueryRow(query, encounterID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Invoice not found
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return invoice, nil
+}
+
+// GetInvoicesByPatientID returns the patient's invoices, newest first, without their lines.
+func (s *InvoiceStorage) GetInvoicesByPatientID(patientID string) ([]model.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE patient_id = $1 ORDER BY issued_at DESC`
+	return s.queryInvoices(query, patientID)
+}
+
+// GetOutstandingInvoicesByPatientID returns the patient's invoices that are not
+// fully paid, oldest first, without their lines.
+func (s *InvoiceStorage) GetOutstandingInvoicesByPatientID(patientID string) ([]model.Invoice, error) {
+	query := `SELECT ` + invoiceColumns + ` FROM invoices
+	          WHERE patient_id = $1 AND amount_paid < total ORDER BY issued_at`
+	return s.queryInvoices(query, patientID)
+}
+
+// CreatePayment records a payment against the invoice. The invoice row is
+// locked so concurrent payments cannot together exceed the amount due;
+// repositories.ErrAmountExceedsBalance is returned if this one would.
+func (s *InvoiceStorage) CreatePayment(payment model.Payment) (*model.Payment, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var outstanding model.Money
+	err = tx.QueryRow(`SELECT total - amount_paid FROM invoices WHERE id = $1 FOR UPDATE`, payment.InvoiceID).
+		Scan(&outstanding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock invoice: %w", err)
+	}
+	if payment.Amount > outstanding {
+		return nil, repositories.ErrAmountExceedsBalance
+	}
+
+	query := `INSERT INTO payments (invoice_id, amount, method, reference, received_by)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING ` + paymentColumns
+	row := tx.QueryRow(query, payment.InvoiceID, payment.Amount, payment.Method, payment.Reference, payment.ReceivedBy)
+	created, err := scanPayment(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+	if err := adjustAmountPaid(tx, payment.InvoiceID.String(), payment.Amount); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payment: %w", err)
+	}
+	return created, nil
+}
+
+func (s *InvoiceStorage) GetPaymentByID(id int) (*model.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1`
+	payment, err := scanPayment(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Payment not found
+		}
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+	return payment, nil
+}
+
+func (s *InvoiceStorage) GetPaymentsByInvoiceID(invoiceID string) ([]model.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE invoice_id = $1 ORDER BY received_at`
+	rows, err := s.connection.Query(query, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []model.Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		payments = append(payments, *payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over payment rows: %w", err)
+	}
+	return payments, nil
+}
+
+// CreateRefund returns part or all of a payment and reopens the invoice
+// balance by the same amount. repositories.ErrAmountExceedsBalance is returned
+// when the refund is more than what is left of the payment.
+func (s *InvoiceStorage) CreateRefund(refund model.Refund) (*model.Refund, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var refundable model.Money
+	err = tx.QueryRow(`SELECT amount - refunded, invoice_id FROM payments WHERE id = $1 FOR UPDATE`, refund.PaymentID).
+		Scan(&refundable, &refund.InvoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock payment: %w", err)
+	}
+	if refund.Amount > refundable {
+		return nil, repositories.ErrAmountExceedsBalance
+	}
+
+	_, err = tx.Exec(`UPDATE payments SET refunded = refunded + $2 WHERE id = $1`, refund.PaymentID, refund.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+	query := `INSERT INTO refunds (payment_id, invoice_id, amount, reason, refunded_by)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING ` + refundColumns
+	row := tx.QueryRow(query, refund.PaymentID, refund.InvoiceID, refund.Amount, refund.Reason, refund.RefundedBy)
+	created, err := scanRefund(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+	if err := adjustAmountPaid(tx, refund.InvoiceID.String(), -refund.Amount); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refund: %w", err)
+	}
+	return created, nil
+}
+
+func (s *InvoiceStorage) GetRefundsByInvoiceID(invoiceID string) ([]model.Refund, error) {
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE invoice_id = $1 ORDER BY created_at`
+	rows, err := s.connection.Query(query, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	defer rows.Close()
+
+	var refunds []model.Refund
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		refunds = append(refunds, *refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over refund rows: %w", err)
+	}
+	return refunds, nil
+}
+
+func (s *InvoiceStorage) getInvoiceLines(invoiceID string) ([]model.InvoiceLine, error) {
+	query := `SELECT ` + invoiceLineColumns + ` FROM invoice_lines WHERE invoice_id = $1 ORDER BY id`
+	rows, err := s.connection.Query(query, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []model.InvoiceLine
+	for rows.Next() {
+		line, err := scanInvoiceLine(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invoice line: %w", err)
+		}
+		lines = append(lines, *line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over invoice line rows: %w", err)
+	}
+	return lines, nil
+}
+
+func (s *InvoiceStorage) queryInvoices(query string, args ...any) ([]model.Invoice, error) {
+	rows, err := s.connection.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var invoices []model.Invoice
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invoice: %w", err)
+		}
+		invoices = append(invoices, *invoice)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over invoice rows: %w", err)
+	}
+	return invoices, nil
+}
+
+func adjustAmountPaid(tx *sql.Tx, invoiceID string, delta model.Money) error {
+	_, err := tx.Exec(`UPDATE invoices SET amount_paid = amount_paid + $2 WHERE id = $1`, invoiceID, delta)
+	if err != nil {
+		return fmt.Errorf("failed to update invoice balance: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE invoices SET status = `+invoiceStatus+` WHERE id = $1`, invoiceID)
+	if err != nil {
+		return fmt.Errorf("failed to update invoice status: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEncounterCharge(row scanner) (*model.EncounterCharge, error) {
+	var charge model.EncounterCharge
+	var addedBy uuid.NullUUID
+	err := row.Scan(&charge.ID, &charge.EncounterID, &charge.CatalogueItemID, &charge.Quantity, &addedBy,
+		&charge.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	charge.AddedBy = addedBy.UUID
+	return &charge, nil
+}
+
+func scanInvoice(row scanner) (*model.Invoice, error) {
+	var invoice model.Invoice
+	var issuedBy uuid.NullUUID
+	err := row.Scan(&invoice.ID, &invoice.InvoiceNumber, &invoice.PatientID, &invoice.EncounterID, &invoice.Status,
+		&invoice.Currency, &invoice.Subtotal, &invoice.TaxTotal, &invoice.Total, &invoice.AmountPaid, &issuedBy,
+		&invoice.IssuedAt)
+	if err != nil {
+		return nil, err
+	}
+	invoice.IssuedBy = issuedBy.UUID
+	return &invoice, nil
+}
+
+func scanInvoiceLine(row scanner) (*model.InvoiceLine, error) {
+	var line model.InvoiceLine
+	var catalogueItemID sql.NullInt64
+	err := row.Scan(&line.ID, &line.InvoiceID, &catalogueItemID, &line.Description, &line.Quantity, &line.UnitPrice,
+		&line.TaxRate, &line.TaxAmount, &line.LineTotal)
+	if err != nil {
+		return nil, err
+	}
+	if catalogueItemID.Valid {
+		id := int(catalogueItemID.Int64)
+		line.CatalogueItemID = &id
+	}
+	return &line, nil
+}
+
+func scanPayment(row scanner) (*model.Payment, error) {
+	var payment model.Payment
+	var receivedBy uuid.NullUUID
+	err := row.Scan(&payment.ID, &payment.InvoiceID, &payment.Amount, &payment.Refunded, &payment.Method,
+		&payment.Reference, &receivedBy, &payment.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+	payment.ReceivedBy = receivedBy.UUID
+	return &payment, nil
+}
+
+func scanRefund(row scanner) (*model.Refund, error) {
+	var refund model.Refund
+	var refundedBy uuid.NullUUID
+	err := row.Scan(&refund.ID, &refund.PaymentID, &refund.InvoiceID, &refund.Amount, &refund.Reason,
+		&refundedBy, &refund.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	refund.RefundedBy = refundedBy.UUID
+	return &refund, nil
+}
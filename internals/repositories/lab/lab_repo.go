@@ -0,0 +1,463 @@
+package lab_repo
+
+// Package lab_repo provides the implementation of the LabRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+// Encrypted lab result columns.
+const (
+	valueField   = "lab_results.value"
+	commentField = "lab_results.comment"
+)
+
+const testColumns = `id, code, name, unit, active`
+
+const orderSelect = `SELECT o.id, o.patient_id, o.diagnosis_id, o.test_id, t.code, t.name, t.unit, o.ordered_by,
+	o.notes, o.status, o.ordered_at, o.cancelled_at, r.order_id, r.value, r.unit, r.reference_low,
+	r.reference_high, r.flag, r.comment, r.recorded_by, r.recorded_at, r.data_key_id
+	FROM lab_orders o
+	JOIN lab_tests t ON t.id = o.test_id
+	LEFT JOIN lab_results r ON r.order_id = o.id`
+
+type LabStorage struct {
+	connection *sql.DB
+	keyring    *utils.Keyring
+}
+
+func NewLabStorage(db *sql.DB, keyring *utils.Keyring) *LabStorage {
+	return &LabStorage{
+		connection: db,
+		keyring:    keyring,
+	}
+}
+
+// CreateLabTest stores the test together with its reference ranges.
+func (s *LabStorage) CreateLabTest(test model.LabTest) (*model.LabTest, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO lab_tests (code, name, unit, active) VALUES ($1, $2, $3, $4) RETURNING ` + testColumns
+	created, err := scanLabTest(tx.QueryRow(query, test.Code, test.Name, test.Unit, test.Active))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lab test: %w", err)
+	}
+	if err := insertRanges(tx, created.ID, test.Ranges); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	created.Ranges = test.Ranges
+	return created, nil
+}
+
+// UpdateLabTest replaces the test's details and reference ranges. Results
+// already recorded keep the range they were judged against. It returns nil
+// if the test does not exist.
+func (s *LabStorage) UpdateLabTest(test model.LabTest) (*model.LabTest, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE lab_tests SET code = $1, name = $2, unit = $3, active = $4, updated_at = NOW()
+	          WHERE id = $5 RETURNING ` + testColumns
+	updated, err := scanLabTest(tx.QueryRow(query, test.Code, test.Name, test.Unit, test.Active, test.ID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Lab test not found
+		}
+		return nil, fmt.Errorf("failed to update lab test: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM lab_reference_ranges WHERE test_id = $1`, updated.ID); err != nil {
+		return nil, fmt.Errorf("failed to clear reference ranges: %w", err)
+	}
+	if err := insertRanges(tx, updated.ID, test.Ranges); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	updated.Ranges = test.Ranges
+	return updated, nil
+}
+
+func insertRanges(tx *sql.Tx, testID int, ranges []model.LabReferenceRange) error {
+	for _, r := range ranges {
+		gender := sql.NullString{String: r.Gender, Valid: r.Gender != ""}
+		_, err := tx.Exec(`INSERT INTO lab_reference_ranges (test_id, gender, min_age, max_age, low, high)
+		                   VALUES ($1, $2, $3, $4, $5, $6)`, testID, gender, r.MinAge, r.MaxAge, r.Low, r.High)
+		if err != nil {
+			return fmt.Errorf("failed to create reference range: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *LabStorage) GetLabTestByID(id int) (*model.LabTest, error) {
+	query := `SELECT ` + testColumns + ` FROM lab_tests WHERE id = $1`
+	return s.getLabTest(query, id)
+}
+
+func (s *LabStorage) GetLabTestByCode(code string) (*model.LabTest, error) {
+	query := `SELECT ` + testColumns + ` FROM lab_tests WHERE code = $1`
+	return s.getLabTest(query, code)
+}
+
+func (s *LabStorage) getLabTest(query string, arg any) (*model.LabTest, error) {
+	test, err := scanLabTest(s.connection.QueryRow(query, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Lab test not found
+		}
+		return nil, fmt.Errorf("failed to get lab test: %w", err)
+	}
+	ranges, err := s.getRanges(test.ID)
+	if err != nil {
+		return nil, err
+	}
+	test.Ranges = ranges
+	return test, nil
+}
+
+// GetAllLabTests returns the catalogue by code, leaving out inactive tests
+// unless includeInactive is set.
+func (s *LabStorage) GetAllLabTests(includeInactive bool) ([]model.LabTest, error) {
+	query := `SELECT ` + testColumns + ` FROM lab_tests WHERE $1 OR active ORDER BY code`
+	rows, err := s.connection.Query(query, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab tests: %w", err)
+	}
+	defer rows.Close()
+
+	var tests []model.LabTest
+	for rows.Next() {
+		test, err := scanLabTest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lab test: %w", err)
+		}
+		tests = append(tests, *test)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over lab test rows: %w", err)
+	}
+	rows.Close()
+
+	for i := range tests {
+		ranges, err := s.getRanges(tests[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		tests[i].Ranges = ranges
+	}
+	return tests, nil
+}
+
+func (s *LabStorage) getRanges(testID int) ([]model.LabReferenceRange, error) {
+	query := `SELECT gender, min_age, max_age, low, high FROM lab_reference_ranges
+	          WHERE test_id = $1 ORDER BY gender NULLS LAST, min_age, id`
+	rows, err := s.connection.Query(query, testID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reference ranges: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []model.LabReferenceRange
+	for rows.Next() {
+		var r model.LabReferenceRange
+		var gender sql.NullString
+		var maxAge sql.NullInt32
+		var low, high sql.NullFloat64
+		if err := rows.Scan(&gender, &r.MinAge, &maxAge, &low, &high); err != nil {
+			return nil, fmt.Errorf("failed to scan reference range: %w", err)
+		}
+		r.Gender = gender.String
+		if maxAge.Valid {
+			age := int(maxAge.Int32)
+			r.MaxAge = &age
+		}
+		r.Low = nullFloat(low)
+		r.High = nullFloat(high)
+		ranges = append(ranges, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over reference range rows: %w", err)
+	}
+	return ranges, nil
+}
+
+func (s *LabStorage) CreateLabOrder(order model.LabOrder) (*model.LabOrder, error) {
+	var id int64
+	query := `INSERT INTO lab_orders (patient_id, diagnosis_id, test_id, ordered_by, notes)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := s.connection.QueryRow(query, order.PatientID, order.DiagnosisID, order.TestID, order.OrderedBy, order.Notes).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lab order: %w", err)
+	}
+	return s.GetLabOrderByID(id)
+}
+
+func (s *LabStorage) GetLabOrderByID(id int64) (*model.LabOrder, error) {
+	query := orderSelect + ` WHERE o.id = $1 AND o.clinic_id = current_clinic_id()`
+	order, err := s.scanLabOrder(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Lab order not found
+		}
+		return nil, fmt.Errorf("failed to get lab order: %w", err)
+	}
+	return order, nil
+}
+
+// GetLabOrdersByPatientID returns the patient's lab orders, newest first.
+func (s *LabStorage) GetLabOrdersByPatientID(patientID string) ([]model.LabOrder, error) {
+	query := orderSelect + ` WHERE o.patient_id = $1 AND o.clinic_id = current_clinic_id()
+	          ORDER BY o.ordered_at DESC, o.id DESC`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lab orders by patient ID: %w", err)
+	}
+	return s.scanLabOrders(rows)
+}
+
+// GetPendingLabOrders returns the orders still waiting for a result, oldest
+// first, only those ordered by orderedBy unless it is empty.
+func (s *LabStorage) GetPendingLabOrders(orderedBy string) ([]model.LabOrder, error) {
+	query := orderSelect + ` WHERE o.status = 'ordered' AND o.clinic_id = current_clinic_id()
+	          AND ($1 = '' OR o.ordered_by::text = $1) ORDER BY o.ordered_at, o.id`
+	rows, err := s.connection.Query(query, orderedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending lab orders: %w", err)
+	}
+	return s.scanLabOrders(rows)
+}
+
+// RecordLabResult stores the result and marks its order resulted. It returns
+// nil if the order does not exist or is no longer waiting for a result.
+func (s *LabStorage) RecordLabResult(result model.LabResult) (*model.LabOrder, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record lab result: %w", err)
+	}
+	value, err := key.Seal(valueField, strconv.FormatFloat(result.Value, 'g', -1, 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record lab result: %w", err)
+	}
+	comment, err := key.Seal(commentField, result.Comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record lab result: %w", err)
+	}
+
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE lab_orders SET status = 'resulted'
+	                     WHERE id = $1 AND clinic_id = current_clinic_id() AND status = 'ordered'`, result.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update lab order: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to update lab order: %w", err)
+	} else if n == 0 {
+		return nil, nil // Lab order not found or not pending
+	}
+	query := `INSERT INTO lab_results (order_id, value, unit, reference_low, reference_high, flag, comment, data_key_id, recorded_by, recorded_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())`
+	_, err = tx.Exec(query, result.OrderID, value, result.Unit, result.ReferenceLow, result.ReferenceHigh,
+		result.Flag, comment, key.ID, result.RecordedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record lab result: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetLabOrderByID(result.OrderID)
+}
+
+// CancelLabOrder cancels an order still waiting for a result. It returns nil
+// if the order does not exist or is no longer pending.
+func (s *LabStorage) CancelLabOrder(id int64) (*model.LabOrder, error) {
+	res, err := s.connection.Exec(`UPDATE lab_orders SET status = 'cancelled', cancelled_at = NOW()
+	                               WHERE id = $1 AND clinic_id = current_clinic_id() AND status = 'ordered'`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel lab order: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to cancel lab order: %w", err)
+	} else if n == 0 {
+		return nil, nil // Lab order not found or not pending
+	}
+	return s.GetLabOrderByID(id)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLabTest(row scanner) (*model.LabTest, error) {
+	var test model.LabTest
+	if err := row.Scan(&test.ID, &test.Code, &test.Name, &test.Unit, &test.Active); err != nil {
+		return nil, err
+	}
+	return &test, nil
+}
+
+func (s *LabStorage) scanLabOrders(rows *sql.Rows) ([]model.LabOrder, error) {
+	defer rows.Close()
+
+	var orders []model.LabOrder
+	for rows.Next() {
+		order, err := s.scanLabOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lab order: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over lab order rows: %w", err)
+	}
+	return orders, nil
+}
+
+func (s *LabStorage) scanLabOrder(row scanner) (*model.LabOrder, error) {
+	var order model.LabOrder
+	var diagnosisID sql.NullInt32
+	var orderedBy, recordedBy uuid.NullUUID
+	var cancelledAt, recordedAt sql.NullTime
+	var resultOrderID sql.NullInt64
+	var value, unit, flag, comment sql.NullString
+	var low, high sql.NullFloat64
+	var keyID sql.NullInt32
+	err := row.Scan(&order.ID, &order.PatientID, &diagnosisID, &order.TestID, &order.TestCode, &order.TestName,
+		&order.Unit, &orderedBy, &order.Notes, &order.Status, &order.OrderedAt, &cancelledAt,
+		&resultOrderID, &value, &unit, &low, &high, &flag, &comment, &recordedBy, &recordedAt, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	if diagnosisID.Valid {
+		id := int(diagnosisID.Int32)
+		order.DiagnosisID = &id
+	}
+	order.OrderedBy = orderedBy.UUID
+	if cancelledAt.Valid {
+		order.CancelledAt = &cancelledAt.Time
+	}
+	if !resultOrderID.Valid {
+		return &order, nil
+	}
+
+	plainValue, err := s.keyring.Open(keyID, valueField, value.String)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := strconv.ParseFloat(plainValue, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid lab result value: %w", err)
+	}
+	plainComment, err := s.keyring.Open(keyID, commentField, comment.String)
+	if err != nil {
+		return nil, err
+	}
+	order.Result = &model.LabResult{
+		OrderID:       resultOrderID.Int64,
+		Value:         parsed,
+		Unit:          unit.String,
+		ReferenceLow:  nullFloat(low),
+		ReferenceHigh: nullFloat(high),
+		Flag:          flag.String,
+		Comment:       plainComment,
+		RecordedBy:    recordedBy.UUID,
+		RecordedAt:    recordedAt.Time,
+	}
+	return &order, nil
+}
+
+func nullFloat(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+// ReencryptLabResults re-encrypts the values and comments of up to limit lab
+// results not yet encrypted with the active data key and returns how many it
+// rewrote; zero means the clinic is done.
+func (s *LabStorage) ReencryptLabResults(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt lab results: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT order_id, value, comment, data_key_id FROM lab_results
+	          WHERE clinic_id = current_clinic_id() AND data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get lab results to re-encrypt: %w", err)
+	}
+	type result struct {
+		orderID        int64
+		value, comment string
+	}
+	var results []result
+	for rows.Next() {
+		var r result
+		var keyID sql.NullInt32
+		if err := rows.Scan(&r.orderID, &r.value, &r.comment, &keyID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan lab result: %w", err)
+		}
+		if r.value, err = s.keyring.Open(keyID, valueField, r.value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if r.comment, err = s.keyring.Open(keyID, commentField, r.comment); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		results = append(results, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error occurred while iterating over lab result rows: %w", err)
+	}
+	for _, r := range results {
+		value, err := key.Seal(valueField, r.value)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt lab result: %w", err)
+		}
+		comment, err := key.Seal(commentField, r.comment)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt lab result: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE lab_results SET value = $1, comment = $2, data_key_id = $3 WHERE order_id = $4`,
+			value, comment, key.ID, r.orderID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt lab result: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(results), nil
+}
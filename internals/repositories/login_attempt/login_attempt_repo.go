@@ -0,0 +1,88 @@
+package login_attempt_repo
+
+// Package login_attempt_repo provides the implementation of the LoginAttemptRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+type LoginAttemptStorage struct {
+	connection *sql.DB
+}
+
+func NewLoginAttemptStorage(db *sql.DB) *LoginAttemptStorage {
+	return &LoginAttemptStorage{
+		connection: db,
+	}
+}
+
+func (s *LoginAttemptStorage) GetLoginAttempt(scope, key string) (*model.LoginAttempt, error) {
+	query := `SELECT scope, key, failures, last_failure_at, locked_until FROM login_attempts WHERE scope = $1 AND key = $2`
+	row := s.connection.QueryRow(query, scope, key)
+	attempt, err := scanLoginAttempt(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // No failures recorded
+		}
+		return nil, fmt.Errorf("failed to get login attempt: %w", err)
+	}
+	return attempt, nil
+}
+
+// RecordLoginFailure increments the failure counter, starting again from one
+// when the previous failure is older than window.
+func (s *LoginAttemptStorage) RecordLoginFailure(scope, key string, window time.Duration) (*model.LoginAttempt, error) {
+	query := `INSERT INTO login_attempts (scope, key, failures, last_failure_at)
+	          VALUES ($1, $2, 1, NOW())
+	          ON CONFLICT (scope, key) DO UPDATE SET
+	              failures = CASE
+	                  WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $3) THEN 1
+	                  ELSE login_attempts.failures + 1
+	              END,
+	              last_failure_at = NOW()
+	          RETURNING scope, key, failures, last_failure_at, locked_until`
+	row := s.connection.QueryRow(query, scope, key, window.Seconds())
+	attempt, err := scanLoginAttempt(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return attempt, nil
+}
+
+func (s *LoginAttemptStorage) LockLogin(scope, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = $1 WHERE scope = $2 AND key = $3`
+	_, err := s.connection.Exec(query, until, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (s *LoginAttemptStorage) ResetLoginAttempts(scope, key string) error {
+	query := `DELETE FROM login_attempts WHERE scope = $1 AND key = $2`
+	_, err := s.connection.Exec(query, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+func scanLoginAttempt(row *sql.Row) (*model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	var lastFailureAt, lockedUntil sql.NullTime
+	err := row.Scan(&attempt.Scope, &attempt.Key, &attempt.Failures, &lastFailureAt, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	if lastFailureAt.Valid {
+		attempt.LastFailureAt = &lastFailureAt.Time
+	}
+	if lockedUntil.Valid {
+		attempt.LockedUntil = &lockedUntil.Time
+	}
+	return &attempt, nil
+}
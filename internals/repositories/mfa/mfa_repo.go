@@ -0,0 +1,147 @@
+package mfa_repo
+
+// Package mfa_repo provides the implementation of the MFARepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+type MFAStorage struct {
+	connection *sql.DB
+}
+
+func NewMFAStorage(db *sql.DB) *MFAStorage {
+	return &MFAStorage{
+		connection: db,
+	}
+}
+
+func (s *MFAStorage) GetTOTPSettings(userID string) (*model.TOTPSettings, error) {
+	query := `SELECT id, COALESCE(totp_secret, ''), totp_enabled, totp_last_step FROM users WHERE id = $1`
+	row := s.connection.QueryRow(query, userID)
+
+	var settings model.TOTPSettings
+	err := row.Scan(&settings.UserID, &settings.Secret, &settings.Enabled, &settings.LastStep)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // User not found
+		}
+		return nil, fmt.Errorf("failed to get totp settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SetTOTPSecret stores a pending secret. It only takes effect once EnableTOTP is called.
+func (s *MFAStorage) SetTOTPSecret(userID string, secret string) error {
+	query := `UPDATE users SET totp_secret = $1, totp_enabled = FALSE, totp_last_step = 0, updated_at = NOW() WHERE id = $2`
+	_, err := s.connection.Exec(query, secret, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+	return nil
+}
+
+func (s *MFAStorage) EnableTOTP(userID string) error {
+	query := `UPDATE users SET totp_enabled = TRUE, updated_at = NOW() WHERE id = $1 AND totp_secret IS NOT NULL`
+	_, err := s.connection.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	return nil
+}
+
+// DisableTOTP removes the secret and every recovery code of the user.
+func (s *MFAStorage) DisableTOTP(userID string) error {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0, updated_at = NOW() WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return tx.Commit()
+}
+
+// AdvanceTOTPStep records step as used. It reports false when the step, or a
+// later one, was already used, i.e. the code is being replayed.
+func (s *MFAStorage) AdvanceTOTPStep(userID string, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`
+	result, err := s.connection.Exec(query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance totp step: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to advance totp step: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (s *MFAStorage) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, codeHash := range codeHashes {
+		_, err = tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, codeHash)
+		if err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ConsumeRecoveryCode marks a matching unused code as used and reports whether one existed.
+func (s *MFAStorage) ConsumeRecoveryCode(userID string, codeHash string) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := s.connection.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (s *MFAStorage) GetMFARolePolicy(role string) (*model.MFARolePolicy, error) {
+	query := `SELECT role, required FROM mfa_role_policy WHERE role = $1`
+	row := s.connection.QueryRow(query, role)
+
+	var policy model.MFARolePolicy
+	err := row.Scan(&policy.Role, &policy.Required)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &model.MFARolePolicy{Role: role}, nil // Optional unless configured
+		}
+		return nil, fmt.Errorf("failed to get mfa role policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func (s *MFAStorage) SetMFARolePolicy(policy model.MFARolePolicy) error {
+	query := `INSERT INTO mfa_role_policy (role, required) VALUES ($1, $2)
+	          ON CONFLICT (role) DO UPDATE SET required = EXCLUDED.required, updated_at = NOW()`
+	_, err := s.connection.Exec(query, policy.Role, policy.Required)
+	if err != nil {
+		return fmt.Errorf("failed to set mfa role policy: %w", err)
+	}
+	return nil
+}
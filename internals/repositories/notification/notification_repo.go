@@ -0,0 +1,247 @@
+package notification_repo
+
+// Package notification_repo provides the implementation of the NotificationRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+const notificationColumns = `id, patient_id, channel, template, recipient, subject, body, status, attempts,
+	next_attempt_at, last_error, created_at, sent_at, data_key_id`
+
+const (
+	recipientField = "notifications.recipient"
+	subjectField   = "notifications.subject"
+	bodyField      = "notifications.body"
+)
+
+type NotificationStorage struct {
+	connection *sql.DB
+	keyring    *utils.Keyring
+}
+
+func NewNotificationStorage(db *sql.DB, keyring *utils.Keyring) *NotificationStorage {
+	return &NotificationStorage{
+		connection: db,
+		keyring:    keyring,
+	}
+}
+
+// seal encrypts the notification's recipient, subject and body, in that order.
+func seal(key *utils.DataKey, notification model.Notification) ([]string, error) {
+	sealed := make([]string, 0, 3)
+	for _, field := range []struct{ name, value string }{
+		{recipientField, notification.Recipient},
+		{subjectField, notification.Subject},
+		{bodyField, notification.Body},
+	} {
+		value, err := key.Seal(field.name, field.value)
+		if err != nil {
+			return nil, err
+		}
+		sealed = append(sealed, value)
+	}
+	return sealed, nil
+}
+
+// CreateNotification queues a notification to be sent at its NextAttemptAt.
+func (s *NotificationStorage) CreateNotification(notification model.Notification) (*model.Notification, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	sealed, err := seal(key, notification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	query := `INSERT INTO notifications (patient_id, channel, template, recipient, subject, body, data_key_id, next_attempt_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ` + notificationColumns
+	row := s.connection.QueryRow(query, notification.PatientID, notification.Channel, notification.Template,
+		sealed[0], sealed[1], sealed[2], key.ID, notification.NextAttemptAt)
+	created, err := s.scanNotification(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	return created, nil
+}
+
+// ClaimDueNotifications takes up to limit pending notifications that are due
+// and counts an attempt on each. Their next attempt is pushed lease into the
+// future, so a dispatcher that dies while sending leaves them to be retried
+// rather than lost, and concurrent dispatchers never claim the same one.
+func (s *NotificationStorage) ClaimDueNotifications(limit int, lease time.Duration) ([]model.Notification, error) {
+	query := `UPDATE notifications SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+	          WHERE id IN (
+	              SELECT id FROM notifications WHERE status = 'pending' AND next_attempt_at <= NOW()
+	              ORDER BY next_attempt_at, id LIMIT $1 FOR UPDATE SKIP LOCKED
+	          )
+	          RETURNING ` + notificationColumns
+	rows, err := s.connection.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due notifications: %w", err)
+	}
+	return s.scanNotifications(rows)
+}
+
+func (s *NotificationStorage) MarkSent(id int64) error {
+	_, err := s.connection.Exec(`UPDATE notifications SET status = 'sent', sent_at = NOW(), last_error = ''
+		WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification sent: %w", err)
+	}
+	return nil
+}
+
+// MarkRetry records a failed attempt and when to try again.
+func (s *NotificationStorage) MarkRetry(id int64, nextAttemptAt time.Time, lastError string) error {
+	_, err := s.connection.Exec(`UPDATE notifications SET next_attempt_at = $1, last_error = $2 WHERE id = $3`,
+		nextAttemptAt, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule notification retry: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed gives up on a notification after its last failed attempt.
+func (s *NotificationStorage) MarkFailed(id int64, lastError string) error {
+	_, err := s.connection.Exec(`UPDATE notifications SET status = 'failed', last_error = $1 WHERE id = $2`,
+		lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification failed: %w", err)
+	}
+	return nil
+}
+
+// RetryNotification queues a failed notification of the current clinic again
+// with a fresh set of attempts. It returns nil if there is no such failed
+// notification.
+func (s *NotificationStorage) RetryNotification(id int64) (*model.Notification, error) {
+	query := `UPDATE notifications SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+	          WHERE id = $1 AND status = 'failed' AND clinic_id = current_clinic_id()
+	          RETURNING ` + notificationColumns
+	notification, err := s.scanNotification(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Notification not found
+		}
+		return nil, fmt.Errorf("failed to retry notification: %w", err)
+	}
+	return notification, nil
+}
+
+func (s *NotificationStorage) GetNotificationByID(id int64) (*model.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id = $1 AND clinic_id = current_clinic_id()`
+	notification, err := s.scanNotification(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Notification not found
+		}
+		return nil, fmt.Errorf("failed to get notification by ID: %w", err)
+	}
+	return notification, nil
+}
+
+// GetNotificationsByPatientID returns the patient's notifications, newest first.
+func (s *NotificationStorage) GetNotificationsByPatientID(patientID string) ([]model.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE patient_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications by patient ID: %w", err)
+	}
+	return s.scanNotifications(rows)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *NotificationStorage) scanNotification(row scanner) (*model.Notification, error) {
+	var notification model.Notification
+	var sentAt sql.NullTime
+	var keyID sql.NullInt32
+	err := row.Scan(&notification.ID, &notification.PatientID, &notification.Channel, &notification.Template,
+		&notification.Recipient, &notification.Subject, &notification.Body, &notification.Status,
+		&notification.Attempts, &notification.NextAttemptAt, &notification.LastError, &notification.CreatedAt,
+		&sentAt, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	if notification.Recipient, err = s.keyring.Open(keyID, recipientField, notification.Recipient); err != nil {
+		return nil, err
+	}
+	if notification.Subject, err = s.keyring.Open(keyID, subjectField, notification.Subject); err != nil {
+		return nil, err
+	}
+	if notification.Body, err = s.keyring.Open(keyID, bodyField, notification.Body); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		notification.SentAt = &sentAt.Time
+	}
+	return &notification, nil
+}
+
+func (s *NotificationStorage) scanNotifications(rows *sql.Rows) ([]model.Notification, error) {
+	defer rows.Close()
+	var notifications []model.Notification
+	for rows.Next() {
+		notification, err := s.scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, *notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over notification rows: %w", err)
+	}
+	return notifications, nil
+}
+
+// ReencryptNotifications re-encrypts up to limit notifications not yet
+// encrypted with the active data key and returns how many it rewrote; zero
+// means the clinic is done. Notifications being sent are skipped and picked up
+// by a later batch.
+func (s *NotificationStorage) ReencryptNotifications(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt notifications: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + notificationColumns + ` FROM notifications
+	          WHERE clinic_id = current_clinic_id() AND data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get notifications to re-encrypt: %w", err)
+	}
+	notifications, err := s.scanNotifications(rows)
+	if err != nil {
+		return 0, err
+	}
+	for _, notification := range notifications {
+		sealed, err := seal(key, notification)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt notification: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE notifications SET recipient = $1, subject = $2, body = $3, data_key_id = $4
+		                  WHERE id = $5`, sealed[0], sealed[1], sealed[2], key.ID, notification.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt notification: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(notifications), nil
+}
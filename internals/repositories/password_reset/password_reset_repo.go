@@ -0,0 +1,74 @@
+package password_reset_repo
+
+// Package password_reset_repo provides the implementation of the PasswordResetRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+type PasswordResetStorage struct {
+	connection *sql.DB
+}
+
+func NewPasswordResetStorage(db *sql.DB) *PasswordResetStorage {
+	return &PasswordResetStorage{
+		connection: db,
+	}
+}
+
+func (s *PasswordResetStorage) CreatePasswordResetToken(token model.PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (token_hash, user_id, created_by, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := s.connection.Exec(query, token.TokenHash, token.UserID, token.CreatedBy, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+	return nil
+}
+
+// GetPasswordResetToken returns the token only while it is unused and unexpired.
+func (s *PasswordResetStorage) GetPasswordResetToken(tokenHash string) (*model.PasswordResetToken, error) {
+	query := `SELECT token_hash, user_id, created_by, expires_at FROM password_reset_tokens
+	          WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+	row := s.connection.QueryRow(query, tokenHash)
+
+	var token model.PasswordResetToken
+	var createdBy uuid.NullUUID
+	err := row.Scan(&token.TokenHash, &token.UserID, &createdBy, &token.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Token not found, used or expired
+		}
+		return nil, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+	token.CreatedBy = createdBy.UUID
+	return &token, nil
+}
+
+// ConsumePasswordResetToken marks the token used and reports whether it was
+// still valid, so two concurrent resets cannot both succeed.
+func (s *PasswordResetStorage) ConsumePasswordResetToken(tokenHash string) (bool, error) {
+	query := `UPDATE password_reset_tokens SET used_at = NOW()
+	          WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()`
+	result, err := s.connection.Exec(query, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+	return affected == 1, nil
+}
+
+func (s *PasswordResetStorage) DeletePasswordResetTokensByUser(userID string) error {
+	query := `DELETE FROM password_reset_tokens WHERE user_id = $1`
+	_, err := s.connection.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+	return nil
+}
@@ -5,36 +5,143 @@ package patient_repo
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	domain_event_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/domain_event"
+	patient_contact_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/patient_contact"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// Encrypted columns. Names and phone numbers are stored encrypted under the
+// data key in data_key_id; phone_number_index is the blind index used to
+// look a patient up by phone. Rows with no data key predate encryption and
+// are plaintext until the key rotation command re-encrypts them.
+const (
+	nameField        = "patients.name"
+	phoneNumberField = "patients.phone_number"
+)
+
+const patientColumns = `id, name, age, phone_number, gender, data_key_id`
+
 type PatientStorage struct {
 	connection *sql.DB
+	keyring    *utils.Keyring
 }
 
-func NewPatientStorage(db *sql.DB) *PatientStorage {
+func NewPatientStorage(db *sql.DB, keyring *utils.Keyring) *PatientStorage {
 	return &PatientStorage{
 		connection: db,
+		keyring:    keyring,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *PatientStorage) scanPatient(row scanner) (*model.Patient, error) {
+	var patient model.Patient
+	var keyID sql.NullInt32
+	err := row.Scan(&patient.ID, &patient.Name, &patient.Age, &patient.PhoneNumber, &patient.Gender, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	if patient.Name, err = s.keyring.Open(keyID, nameField, patient.Name); err != nil {
+		return nil, err
+	}
+	if patient.PhoneNumber, err = s.keyring.Open(keyID, phoneNumberField, patient.PhoneNumber); err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+func (s *PatientStorage) scanPatients(rows *sql.Rows) ([]model.Patient, error) {
+	defer rows.Close()
+	var patients []model.Patient
+	for rows.Next() {
+		patient, err := s.scanPatient(rows)
+		if err != nil {
+			err = fmt.Errorf("failed to scan patient row: %w", err)
+			return nil, err
+		}
+		patients = append(patients, *patient)
+	}
+	if err := rows.Err(); err != nil {
+		err = fmt.Errorf("error occurred while iterating over patient rows: %w", err)
+		return nil, err
 	}
+	return patients, nil
+}
+
+// sealedPatient is a patient's encrypted columns.
+type sealedPatient struct {
+	name, phoneNumber, phoneNumberIndex string
+	keyID                               int
 }
 
+func (s *PatientStorage) seal(key *utils.DataKey, patient model.Patient) (sealedPatient, error) {
+	name, err := key.Seal(nameField, patient.Name)
+	if err != nil {
+		return sealedPatient{}, fmt.Errorf("failed to encrypt patient: %w", err)
+	}
+	phoneNumber, err := key.Seal(phoneNumberField, patient.PhoneNumber)
+	if err != nil {
+		return sealedPatient{}, fmt.Errorf("failed to encrypt patient: %w", err)
+	}
+	return sealedPatient{
+		name:             name,
+		phoneNumber:      phoneNumber,
+		phoneNumberIndex: s.keyring.BlindIndex(phoneNumberField, patient.PhoneNumber),
+		keyID:            key.ID,
+	}, nil
+}
+
+// CreatePatient stores the patient together with a PatientRegistered event.
 func (s *PatientStorage) CreatePatient(patient model.Patient) error {
-	query := `INSERT INTO patients (id, name, age, gender, phone_number) VALUES ($1, $2, $3, $4, $5)`
-	_, err := s.connection.Exec(query, patient.ID, patient.Name, patient.Age, patient.Gender, patient.PhoneNumber)
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return fmt.Errorf("failed to create patient: %w", err)
+	}
+	sealed, err := s.seal(key, patient)
+	if err != nil {
+		return err
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO patients (id, name, age, gender, phone_number, phone_number_index, data_key_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.Exec(query, patient.ID, sealed.name, patient.Age, patient.Gender, sealed.phoneNumber,
+		sealed.phoneNumberIndex, sealed.keyID)
 	if err != nil {
 		err = fmt.Errorf("failed to create patient: %w", err)
 		return err
 
 	}
+	if err := appendPatientRegistered(tx, patient.ID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
+func appendPatientRegistered(tx *sql.Tx, patientID uuid.UUID) error {
+	return domain_event_repo.AppendEvent(tx, model.EventPatientRegistered, patientID.String(),
+		model.PatientRegisteredPayload{PatientID: patientID})
+}
+
 func (s *PatientStorage) DeletePatient(id string) (*model.Patient, error) {
-	query := `DELETE FROM patients WHERE id = $1 RETURNING id, name, age, phone_number, gender`
-	row := s.connection.QueryRow(query, id)
-	var patient model.Patient
-	err := row.Scan(&patient.ID, &patient.Name, &patient.Age, &patient.PhoneNumber, &patient.Gender)
+	query := `DELETE FROM patients WHERE id = $1 AND clinic_id = current_clinic_id() RETURNING ` + patientColumns
+	patient, err := s.scanPatient(s.connection.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Patient not found
@@ -42,27 +149,34 @@ func (s *PatientStorage) DeletePatient(id string) (*model.Patient, error) {
 		err = fmt.Errorf("failed to delete patient: %w", err)
 		return nil, err
 	}
-	return &patient, nil
+	return patient, nil
 }
 
 func (s *PatientStorage) UpdatePatient(patient model.Patient) (*model.Patient, error) {
-	query := `UPDATE patients SET name = $1, age=$2, phone_number=$3, gender=$4, updated_at = NOW()
-	          WHERE id = $5 RETURNING id, name, age, phone_number, gender`
-	row := s.connection.QueryRow(query, patient.Name, patient.Age, patient.PhoneNumber, patient.Gender, patient.ID)
-	var updatedPatient model.Patient
-	err := row.Scan(&updatedPatient.ID, &updatedPatient.Name, &updatedPatient.Age, &updatedPatient.PhoneNumber, &updatedPatient.Gender, &updatedPatient.ID)
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update patient: %w", err)
+	}
+	sealed, err := s.seal(key, patient)
+	if err != nil {
+		return nil, err
+	}
+	query := `UPDATE patients SET name = $1, age=$2, phone_number=$3, gender=$4, phone_number_index = $5,
+	          data_key_id = $6, updated_at = NOW()
+	          WHERE id = $7 AND clinic_id = current_clinic_id() RETURNING ` + patientColumns
+	row := s.connection.QueryRow(query, sealed.name, patient.Age, sealed.phoneNumber, patient.Gender,
+		sealed.phoneNumberIndex, sealed.keyID, patient.ID)
+	updatedPatient, err := s.scanPatient(row)
 	if err != nil {
 		err = fmt.Errorf("failed to update patient: %w", err)
 		return nil, err
 	}
-	return &updatedPatient, nil
+	return updatedPatient, nil
 }
 
 func (s *PatientStorage) GetPatientByID(id string) (*model.Patient, error) {
-	query := `SELECT id, name, age, phone_number, gender FROM patients WHERE id = $1`
-	row := s.connection.QueryRow(query, id)
-	var patient model.Patient
-	err := row.Scan(&patient.ID, &patient.Name, &patient.Age, &patient.PhoneNumber, &patient.Gender)
+	query := `SELECT ` + patientColumns + ` FROM patients WHERE id = $1 AND clinic_id = current_clinic_id()`
+	patient, err := s.scanPatient(s.connection.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Patient not found
@@ -70,70 +184,216 @@ func (s *PatientStorage) GetPatientByID(id string) (*model.Patient, error) {
 		err = fmt.Errorf("failed to get patient by ID: %w", err)
 		return nil, err
 	}
-	return &patient, nil
+	return patient, nil
 }
 
-func (s *PatientStorage) GetPatientByPhoneNumber(phoneNumber string) (*model.Patient, error) {
-	query := `SELECT id, name, age, phone_number, gender FROM patients WHERE phone_number = $1`
-	row := s.connection.QueryRow(query, phoneNumber)
-	var patient model.Patient
-	err := row.Scan(&patient.ID, &patient.Name, &patient.Age, &patient.PhoneNumber, &patient.Gender)
+// GetPatientsByContact returns every patient reachable through the given
+// phone number or email address, whether it is their own phone number or one
+// of their contacts. Several patients can share a contact, e.g. siblings
+// registered under a parent's phone.
+func (s *PatientStorage) GetPatientsByContact(contact string) ([]model.Patient, error) {
+	query := `SELECT ` + patientColumns + ` FROM patients p
+	          WHERE p.clinic_id = current_clinic_id()
+	            AND (p.phone_number_index = $2 OR (p.data_key_id IS NULL AND p.phone_number = $1)
+	             OR EXISTS (SELECT 1 FROM patient_contacts c WHERE c.patient_id = p.id
+	                        AND (c.phone_number_index = $3 OR c.email_index = $4
+	                         OR (c.data_key_id IS NULL AND (c.phone_number = $1 OR LOWER(c.email) = LOWER($1))))))`
+	rows, err := s.connection.Query(query, contact, s.keyring.BlindIndex(phoneNumberField, contact),
+		patient_contact_repo.PhoneNumberIndex(s.keyring, contact), patient_contact_repo.EmailIndex(s.keyring, contact))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Patient not found
-		}
-		err = fmt.Errorf("failed to get patient by phone number: %w", err)
+		err = fmt.Errorf("failed to get patients by contact: %w", err)
 		return nil, err
 	}
-	return &patient, nil
+	patients, err := s.scanPatients(rows)
+	if err != nil {
+		return nil, err
+	}
+	// Names are encrypted, so they can only be ordered once decrypted.
+	sortByName(patients)
+	return patients, nil
 }
 
 func (s *PatientStorage) GetAllPatients() ([]model.Patient, error) {
-	query := `SELECT id, name, age, phone_number, gender FROM patients`
+	query := `SELECT ` + patientColumns + ` FROM patients WHERE clinic_id = current_clinic_id()`
 	rows, err := s.connection.Query(query)
 	if err != nil {
 		err = fmt.Errorf("failed to get all patients: %w", err)
 		return nil, err
 	}
-	defer rows.Close()
-	var patients []model.Patient
-	for rows.Next() {
-		var patient model.Patient
-		err := rows.Scan(&patient.ID, &patient.Name, &patient.Age, &patient.PhoneNumber, &patient.Gender)
+	return s.scanPatients(rows)
+}
+
+// GetAllPatientsByName matches name case-insensitively anywhere in the
+// patient's name. Names are encrypted, so the clinic's patients are decrypted
+// and matched here rather than in the query.
+func (s *PatientStorage) GetAllPatientsByName(name string) ([]model.Patient, error) {
+	patients, err := s.GetAllPatients()
+	if err != nil {
+		err = fmt.Errorf("failed to get patients by name: %w", err)
+		return nil, err
+	}
+	name = strings.ToLower(name)
+	matches := patients[:0]
+	for _, patient := range patients {
+		if strings.Contains(strings.ToLower(patient.Name), name) {
+			matches = append(matches, patient)
+		}
+	}
+	return matches, nil
+}
+
+// CreatePatients inserts a batch of patients with COPY in one transaction, so
+// either the whole batch is stored or none of it is, along with a
+// PatientRegistered event for each.
+func (s *PatientStorage) CreatePatients(patients []model.Patient) error {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return fmt.Errorf("failed to copy patients: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(pq.CopyIn("patients", "id", "name", "age", "gender", "phone_number",
+		"phone_number_index", "data_key_id"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare patient copy: %w", err)
+	}
+	for _, patient := range patients {
+		sealed, err := s.seal(key, patient)
 		if err != nil {
-			err = fmt.Errorf("failed to scan patient row: %w", err)
-			return nil, err
+			stmt.Close()
+			return err
+		}
+		_, err = stmt.Exec(patient.ID, sealed.name, patient.Age, patient.Gender, sealed.phoneNumber,
+			sealed.phoneNumberIndex, sealed.keyID)
+		if err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to copy patient: %w", err)
 		}
-		patients = append(patients, patient)
 	}
-	if err = rows.Err(); err != nil {
-		err = fmt.Errorf("error occurred while iterating over patient rows: %w", err)
-		return nil, err
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("failed to copy patients: %w", err)
 	}
-	return patients, nil
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("failed to copy patients: %w", err)
+	}
+	for _, patient := range patients {
+		if err := appendPatientRegistered(tx, patient.ID); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
-func (s *PatientStorage) GetPatientsByName(name string) ([]model.Patient, error) {
-	query := `SELECT id, name, age, phone_number, gender FROM patients WHERE name ILIKE $1`
-	rows, err := s.connection.Query(query, "%"+name+"%")
+// GetExistingPhoneNumbers returns those of the given phone numbers that already
+// belong to a patient.
+func (s *PatientStorage) GetExistingPhoneNumbers(phoneNumbers []string) ([]string, error) {
+	indexes := make([]string, 0, len(phoneNumbers))
+	for _, phoneNumber := range phoneNumbers {
+		indexes = append(indexes, s.keyring.BlindIndex(phoneNumberField, phoneNumber))
+	}
+	query := `SELECT ` + patientColumns + ` FROM patients
+	          WHERE clinic_id = current_clinic_id()
+	            AND (phone_number_index = ANY($1) OR (data_key_id IS NULL AND phone_number = ANY($2)))`
+	rows, err := s.connection.Query(query, pq.Array(indexes), pq.Array(phoneNumbers))
+	if err != nil {
+		err = fmt.Errorf("failed to get existing phone numbers: %w", err)
+		return nil, err
+	}
+	patients, err := s.scanPatients(rows)
 	if err != nil {
-		err = fmt.Errorf("failed to get patients by name: %w", err)
 		return nil, err
 	}
+	existing := make([]string, 0, len(patients))
+	for _, patient := range patients {
+		existing = append(existing, patient.PhoneNumber)
+	}
+	return existing, nil
+}
+
+// EachPatient calls fn for every patient in registration order while the rows
+// are still being read, so callers can stream patients without holding them
+// all. Iteration stops at the first error fn returns.
+func (s *PatientStorage) EachPatient(fn func(patient model.Patient) error) error {
+	query := `SELECT ` + patientColumns + ` FROM patients WHERE clinic_id = current_clinic_id() ORDER BY created_at, id`
+	rows, err := s.connection.Query(query)
+	if err != nil {
+		err = fmt.Errorf("failed to get all patients: %w", err)
+		return err
+	}
 	defer rows.Close()
-	var patients []model.Patient
 	for rows.Next() {
-		var patient model.Patient
-		err := rows.Scan(&patient.ID, &patient.Name, &patient.Age, &patient.PhoneNumber, &patient.Gender)
+		patient, err := s.scanPatient(rows)
 		if err != nil {
 			err = fmt.Errorf("failed to scan patient row: %w", err)
-			return nil, err
+			return err
+		}
+		if err := fn(*patient); err != nil {
+			return err
 		}
-		patients = append(patients, patient)
 	}
 	if err = rows.Err(); err != nil {
 		err = fmt.Errorf("error occurred while iterating over patient rows: %w", err)
-		return nil, err
+		return err
 	}
-	return patients, nil
+	return nil
+}
+
+// ReencryptPatients re-encrypts up to limit patients not yet encrypted with
+// the active data key, including plaintext rows, and returns how many it
+// rewrote; zero means the clinic is done. Rows being edited are skipped and
+// picked up by a later batch.
+func (s *PatientStorage) ReencryptPatients(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt patients: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + patientColumns + ` FROM patients
+	          WHERE clinic_id = current_clinic_id() AND data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get patients to re-encrypt: %w", err)
+	}
+	patients, err := s.scanPatients(rows)
+	if err != nil {
+		return 0, err
+	}
+	for _, patient := range patients {
+		sealed, err := s.seal(key, patient)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`UPDATE patients SET name = $1, phone_number = $2, phone_number_index = $3, data_key_id = $4
+		                  WHERE id = $5`, sealed.name, sealed.phoneNumber, sealed.phoneNumberIndex, sealed.keyID, patient.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt patient: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(patients), nil
+}
+
+func sortByName(patients []model.Patient) {
+	sort.Slice(patients, func(i, j int) bool {
+		if patients[i].Name != patients[j].Name {
+			return patients[i].Name < patients[j].Name
+		}
+		return patients[i].ID.String() < patients[j].ID.String()
+	})
 }
@@ -0,0 +1,265 @@
+package patient_contact_repo
+
+// Package patient_contact_repo provides the implementation of the PatientContactRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+)
+
+// Encrypted columns. Names, phone numbers, email addresses and addresses are
+// stored encrypted under the data key in data_key_id; phone_number_index and
+// email_index are the blind indexes a patient is looked up by. Rows with no
+// data key predate encryption and are plaintext until the key rotation
+// command re-encrypts them.
+const (
+	nameField        = "patient_contacts.name"
+	phoneNumberField = "patient_contacts.phone_number"
+	emailField       = "patient_contacts.email"
+	addressField     = "patient_contacts.address"
+)
+
+const contactColumns = `id, patient_id, name, relation, phone_number, email, address, is_emergency, is_guardian,
+	created_at, data_key_id`
+
+type PatientContactStorage struct {
+	connection *sql.DB
+	keyring    *utils.Keyring
+}
+
+func NewPatientContactStorage(db *sql.DB, keyring *utils.Keyring) *PatientContactStorage {
+	return &PatientContactStorage{
+		connection: db,
+		keyring:    keyring,
+	}
+}
+
+// PhoneNumberIndex is the blind index of a contact phone number, or NULL for
+// none so contacts without one never match.
+func PhoneNumberIndex(keyring *utils.Keyring, phoneNumber string) sql.NullString {
+	if phoneNumber == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: keyring.BlindIndex(phoneNumberField, phoneNumber), Valid: true}
+}
+
+// EmailIndex is the blind index of a contact email address. Email addresses
+// are matched case-insensitively.
+func EmailIndex(keyring *utils.Keyring, email string) sql.NullString {
+	if email == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: keyring.BlindIndex(emailField, strings.ToLower(email)), Valid: true}
+}
+
+// sealedContact is a contact's encrypted columns.
+type sealedContact struct {
+	name, phoneNumber, email, address string
+	phoneNumberIndex, emailIndex      sql.NullString
+	keyID                             int
+}
+
+func (s *PatientContactStorage) seal(key *utils.DataKey, contact model.PatientContact) (sealedContact, error) {
+	sealed := sealedContact{
+		phoneNumberIndex: PhoneNumberIndex(s.keyring, contact.PhoneNumber),
+		emailIndex:       EmailIndex(s.keyring, contact.Email),
+		keyID:            key.ID,
+	}
+	for _, column := range []struct {
+		field      string
+		value      string
+		ciphertext *string
+	}{
+		{nameField, contact.Name, &sealed.name},
+		{phoneNumberField, contact.PhoneNumber, &sealed.phoneNumber},
+		{emailField, contact.Email, &sealed.email},
+		{addressField, contact.Address, &sealed.address},
+	} {
+		ciphertext, err := key.Seal(column.field, column.value)
+		if err != nil {
+			return sealedContact{}, fmt.Errorf("failed to encrypt patient contact: %w", err)
+		}
+		*column.ciphertext = ciphertext
+	}
+	return sealed, nil
+}
+
+func (s *PatientContactStorage) CreateContact(contact model.PatientContact) (*model.PatientContact, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patient contact: %w", err)
+	}
+	sealed, err := s.seal(key, contact)
+	if err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO patient_contacts (patient_id, name, relation, phone_number, email, address, is_emergency,
+	          is_guardian, phone_number_index, email_index, data_key_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING ` + contactColumns
+	row := s.connection.QueryRow(query, contact.PatientID, sealed.name, contact.Relation, sealed.phoneNumber,
+		sealed.email, sealed.address, contact.IsEmergency, contact.IsGuardian, sealed.phoneNumberIndex,
+		sealed.emailIndex, sealed.keyID)
+	created, err := s.scanContact(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patient contact: %w", err)
+	}
+	return created, nil
+}
+
+func (s *PatientContactStorage) UpdateContact(contact model.PatientContact) (*model.PatientContact, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update patient contact: %w", err)
+	}
+	sealed, err := s.seal(key, contact)
+	if err != nil {
+		return nil, err
+	}
+	query := `UPDATE patient_contacts SET name = $1, relation = $2, phone_number = $3, email = $4, address = $5,
+	          is_emergency = $6, is_guardian = $7, phone_number_index = $8, email_index = $9, data_key_id = $10,
+	          updated_at = NOW()
+	          WHERE id = $11 RETURNING ` + contactColumns
+	row := s.connection.QueryRow(query, sealed.name, contact.Relation, sealed.phoneNumber, sealed.email,
+		sealed.address, contact.IsEmergency, contact.IsGuardian, sealed.phoneNumberIndex, sealed.emailIndex,
+		sealed.keyID, contact.ID)
+	updated, err := s.scanContact(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Contact not found
+		}
+		return nil, fmt.Errorf("failed to update patient contact: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *PatientContactStorage) DeleteContact(id int) (*model.PatientContact, error) {
+	query := `DELETE FROM patient_contacts WHERE id = $1 RETURNING ` + contactColumns
+	deleted, err := s.scanContact(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Contact not found
+		}
+		return nil, fmt.Errorf("failed to delete patient contact: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *PatientContactStorage) GetContactByID(id int) (*model.PatientContact, error) {
+	query := `SELECT ` + contactColumns + ` FROM patient_contacts WHERE id = $1`
+	contact, err := s.scanContact(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Contact not found
+		}
+		return nil, fmt.Errorf("failed to get patient contact by ID: %w", err)
+	}
+	return contact, nil
+}
+
+func (s *PatientContactStorage) GetContactsByPatientID(patientID string) ([]model.PatientContact, error) {
+	query := `SELECT ` + contactColumns + ` FROM patient_contacts WHERE patient_id = $1 ORDER BY created_at, id`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient contacts by patient ID: %w", err)
+	}
+	defer rows.Close()
+
+	var contacts []model.PatientContact
+	for rows.Next() {
+		contact, err := s.scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan patient contact: %w", err)
+		}
+		contacts = append(contacts, *contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over patient contact rows: %w", err)
+	}
+	return contacts, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *PatientContactStorage) scanContact(row scanner) (*model.PatientContact, error) {
+	var contact model.PatientContact
+	var keyID sql.NullInt32
+	err := row.Scan(&contact.ID, &contact.PatientID, &contact.Name, &contact.Relation, &contact.PhoneNumber,
+		&contact.Email, &contact.Address, &contact.IsEmergency, &contact.IsGuardian, &contact.CreatedAt, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range []struct {
+		field string
+		value *string
+	}{
+		{nameField, &contact.Name},
+		{phoneNumberField, &contact.PhoneNumber},
+		{emailField, &contact.Email},
+		{addressField, &contact.Address},
+	} {
+		if *column.value, err = s.keyring.Open(keyID, column.field, *column.value); err != nil {
+			return nil, err
+		}
+	}
+	return &contact, nil
+}
+
+// ReencryptContacts re-encrypts up to limit contacts not yet encrypted with
+// the active data key, including plaintext rows, and returns how many it
+// rewrote; zero means the clinic is done. Rows being edited are skipped and
+// picked up by a later batch.
+func (s *PatientContactStorage) ReencryptContacts(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt patient contacts: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + contactColumns + ` FROM patient_contacts
+	          WHERE clinic_id = current_clinic_id() AND data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get patient contacts to re-encrypt: %w", err)
+	}
+	var contacts []model.PatientContact
+	for rows.Next() {
+		contact, err := s.scanContact(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan patient contact: %w", err)
+		}
+		contacts = append(contacts, *contact)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error occurred while iterating over patient contact rows: %w", err)
+	}
+	for _, contact := range contacts {
+		sealed, err := s.seal(key, contact)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`UPDATE patient_contacts SET name = $1, phone_number = $2, email = $3, address = $4,
+		                  phone_number_index = $5, email_index = $6, data_key_id = $7
+		                  WHERE id = $8`, sealed.name, sealed.phoneNumber, sealed.email, sealed.address,
+			sealed.phoneNumberIndex, sealed.emailIndex, sealed.keyID, contact.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt patient contact: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(contacts), nil
+}
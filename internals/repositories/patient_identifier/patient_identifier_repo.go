@@ -0,0 +1,71 @@
+package patient_identifier_repo
+
+// Package patient_identifier_repo provides the implementation of the PatientIdentifierRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+const identifierColumns = `system, value, patient_id, created_at`
+
+type PatientIdentifierStorage struct {
+	connection *sql.DB
+}
+
+func NewPatientIdentifierStorage(db *sql.DB) *PatientIdentifierStorage {
+	return &PatientIdentifierStorage{
+		connection: db,
+	}
+}
+
+// LinkPatientIdentifier records the identifier for the patient. An identifier
+// already linked to a patient keeps its existing link.
+func (s *PatientIdentifierStorage) LinkPatientIdentifier(identifier model.PatientIdentifier) error {
+	query := `INSERT INTO patient_identifiers (system, value, patient_id) VALUES ($1, $2, $3)
+	          ON CONFLICT (clinic_id, system, value) DO NOTHING`
+	_, err := s.connection.Exec(query, identifier.System, identifier.Value, identifier.PatientID)
+	if err != nil {
+		return fmt.Errorf("failed to link patient identifier: %w", err)
+	}
+	return nil
+}
+
+func (s *PatientIdentifierStorage) GetPatientIdentifier(system, value string) (*model.PatientIdentifier, error) {
+	query := `SELECT ` + identifierColumns + ` FROM patient_identifiers WHERE system = $1 AND value = $2`
+	var identifier model.PatientIdentifier
+	err := s.connection.QueryRow(query, system, value).Scan(&identifier.System, &identifier.Value,
+		&identifier.PatientID, &identifier.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Identifier not found
+		}
+		return nil, fmt.Errorf("failed to get patient identifier: %w", err)
+	}
+	return &identifier, nil
+}
+
+func (s *PatientIdentifierStorage) GetIdentifiersByPatientID(patientID string) ([]model.PatientIdentifier, error) {
+	query := `SELECT ` + identifierColumns + ` FROM patient_identifiers WHERE patient_id = $1 ORDER BY system, value`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient identifiers: %w", err)
+	}
+	defer rows.Close()
+
+	var identifiers []model.PatientIdentifier
+	for rows.Next() {
+		var identifier model.PatientIdentifier
+		err := rows.Scan(&identifier.System, &identifier.Value, &identifier.PatientID, &identifier.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan patient identifier: %w", err)
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over patient identifier rows: %w", err)
+	}
+	return identifiers, nil
+}
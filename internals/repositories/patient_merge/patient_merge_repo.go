@@ -0,0 +1,309 @@
+package patient_merge_repo
+
+// Package patient_merge_repo provides the implementation of the PatientMergeRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+// childTables are the tables whose rows belong to a patient and move to the
+// survivor on a merge. key identifies a row so an undo can move it back.
+var childTables = []struct {
+	name string
+	key  string
+}{
+	{"diagnoses", "id::text"},
+	{"allergies", "id::text"},
+	{"chronic_conditions", "id::text"},
+	{"family_history", "id::text"},
+	{"encounters", "id::text"},
+	{"vitals", "id::text"},
+	{"prescriptions", "id::text"},
+	{"queue_entries", "id::text"},
+	{"invoices", "id::text"},
+	{"patient_identifiers", "jsonb_build_array(system, value)::text"},
+	{"patient_contacts", "id::text"},
+	{"patient_consents", "id::text"},
+	{"emergency_accesses", "id::text"},
+	{"notifications", "id::text"},
+	{"lab_orders", "id::text"},
+	{"attachments", "id::text"},
+}
+
+const mergeColumns = `id, survivor_id, merged_patient_id, merged_name, merged_age, merged_gender,
+	merged_phone_number, merged_by, merged_at, undone_by, undone_at, merged_data_key_id`
+
+// The snapshot keeps the merged patient's name and phone number exactly as
+// they were stored, so they decrypt as the patients columns they came from.
+const (
+	nameField        = "patients.name"
+	phoneNumberField = "patients.phone_number"
+)
+
+type PatientMergeStorage struct {
+	connection *sql.DB
+	keyring    *utils.Keyring
+}
+
+func NewPatientMergeStorage(db *sql.DB, keyring *utils.Keyring) *PatientMergeStorage {
+	return &PatientMergeStorage{
+		connection: db,
+		keyring:    keyring,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *PatientMergeStorage) scanMerge(row scanner) (*model.PatientMerge, error) {
+	var merge model.PatientMerge
+	var mergedBy uuid.NullUUID
+	var undoneAt sql.NullTime
+	var keyID sql.NullInt32
+	err := row.Scan(&merge.ID, &merge.SurvivorID, &merge.Merged.ID, &merge.Merged.Name, &merge.Merged.Age,
+		&merge.Merged.Gender, &merge.Merged.PhoneNumber, &mergedBy, &merge.MergedAt, &merge.UndoneBy, &undoneAt, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	if merge.Merged.Name, err = s.keyring.Open(keyID, nameField, merge.Merged.Name); err != nil {
+		return nil, err
+	}
+	if merge.Merged.PhoneNumber, err = s.keyring.Open(keyID, phoneNumberField, merge.Merged.PhoneNumber); err != nil {
+		return nil, err
+	}
+	merge.MergedBy = mergedBy.UUID
+	if undoneAt.Valid {
+		merge.UndoneAt = &undoneAt.Time
+	}
+	return &merge, nil
+}
+
+// MergePatients moves every child record of the merged patient to the
+// survivor, records which rows moved and deletes the merged patient, all in
+// one transaction. It returns nil if either patient does not exist.
+func (s *PatientMergeStorage) MergePatients(survivorID, mergedID, mergedBy string) (*model.PatientMerge, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock both patients in a fixed order so concurrent merges cannot deadlock.
+	rows, err := tx.Query(`SELECT id FROM patients
+		WHERE id = ANY(ARRAY[$1, $2]::uuid[]) AND clinic_id = current_clinic_id() ORDER BY id FOR UPDATE`,
+		survivorID, mergedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock patients: %w", err)
+	}
+	locked := 0
+	for rows.Next() {
+		locked++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over patient rows: %w", err)
+	}
+	if locked != 2 {
+		return nil, nil // Patient not found
+	}
+
+	query := `INSERT INTO patient_merges (survivor_id, merged_patient_id, merged_name, merged_age, merged_gender,
+			merged_phone_number, merged_by, merged_data_key_id)
+		SELECT $1, id, name, age, gender, phone_number, $3, data_key_id FROM patients WHERE id = $2
+		RETURNING ` + mergeColumns
+	merge, err := s.scanMerge(tx.QueryRow(query, survivorID, mergedID, mergedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patient merge: %w", err)
+	}
+
+	for _, table := range childTables {
+		query := fmt.Sprintf(`WITH moved AS (
+				UPDATE %s SET patient_id = $1 WHERE patient_id = $2 RETURNING %s AS row_key
+			)
+			INSERT INTO patient_merge_rows (merge_id, table_name, row_key)
+			SELECT $3, $4, row_key FROM moved`, table.name, table.key)
+		if _, err := tx.Exec(query, survivorID, mergedID, merge.ID, table.name); err != nil {
+			return nil, fmt.Errorf("failed to move %s: %w", table.name, err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM patients WHERE id = $1`, mergedID); err != nil {
+		return nil, fmt.Errorf("failed to delete merged patient: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return merge, nil
+}
+
+// UndoMerge recreates the merged patient from its snapshot and moves back the
+// rows the merge moved, leaving records created since with the survivor. It
+// returns nil if the merge does not exist, and ErrMergeConflict if it was
+// already undone, the survivor is gone or the merged patient's id is in use.
+func (s *PatientMergeStorage) UndoMerge(mergeID int64, undoneBy string) (*model.PatientMerge, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	merge, err := s.scanMerge(tx.QueryRow(`SELECT `+mergeColumns+` FROM patient_merges WHERE id = $1 FOR UPDATE`, mergeID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Merge not found
+		}
+		return nil, fmt.Errorf("failed to get patient merge: %w", err)
+	}
+	if merge.UndoneAt != nil {
+		return nil, fmt.Errorf("%w: merge was already undone", repositories.ErrMergeConflict)
+	}
+
+	var survivorID uuid.UUID
+	err = tx.QueryRow(`SELECT id FROM patients WHERE id = $1 FOR UPDATE`, merge.SurvivorID).Scan(&survivorID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: the surviving patient no longer exists", repositories.ErrMergeConflict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock survivor: %w", err)
+	}
+
+	var taken bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM patients WHERE id = $1)`, merge.Merged.ID).Scan(&taken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check merged patient: %w", err)
+	}
+	if taken {
+		return nil, fmt.Errorf("%w: patient %s exists again", repositories.ErrMergeConflict, merge.Merged.ID)
+	}
+	// Plaintext snapshots from before encryption are restored as plaintext,
+	// without a blind index, like the other rows not yet re-encrypted.
+	phoneNumberIndex := s.keyring.BlindIndex(phoneNumberField, merge.Merged.PhoneNumber)
+	_, err = tx.Exec(`INSERT INTO patients (id, name, age, gender, phone_number, phone_number_index, data_key_id)
+		SELECT merged_patient_id, merged_name, merged_age, merged_gender, merged_phone_number,
+			CASE WHEN merged_data_key_id IS NULL THEN NULL ELSE $2 END, merged_data_key_id
+		FROM patient_merges WHERE id = $1`, merge.ID, phoneNumberIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore merged patient: %w", err)
+	}
+
+	for _, table := range childTables {
+		query := fmt.Sprintf(`UPDATE %s SET patient_id = $1 WHERE patient_id = $2 AND %s IN (
+				SELECT row_key FROM patient_merge_rows WHERE merge_id = $3 AND table_name = $4
+			)`, table.name, table.key)
+		if _, err := tx.Exec(query, merge.Merged.ID, merge.SurvivorID, merge.ID, table.name); err != nil {
+			return nil, fmt.Errorf("failed to move back %s: %w", table.name, err)
+		}
+	}
+
+	query := `UPDATE patient_merges SET undone_by = $1, undone_at = NOW() WHERE id = $2 RETURNING ` + mergeColumns
+	merge, err = s.scanMerge(tx.QueryRow(query, undoneBy, mergeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to undo patient merge: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return merge, nil
+}
+
+func (s *PatientMergeStorage) GetMergeByID(id int64) (*model.PatientMerge, error) {
+	query := `SELECT ` + mergeColumns + ` FROM patient_merges WHERE id = $1`
+	merge, err := s.scanMerge(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Merge not found
+		}
+		err = fmt.Errorf("failed to get patient merge by ID: %w", err)
+		return nil, err
+	}
+	return merge, nil
+}
+
+// GetMergesByPatientID returns the merges the patient took part in, either as
+// survivor or as the merged duplicate, newest first.
+func (s *PatientMergeStorage) GetMergesByPatientID(patientID string) ([]model.PatientMerge, error) {
+	query := `SELECT ` + mergeColumns + ` FROM patient_merges
+		WHERE survivor_id = $1 OR merged_patient_id = $1 ORDER BY merged_at DESC, id DESC`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		err = fmt.Errorf("failed to get patient merges: %w", err)
+		return nil, err
+	}
+	defer rows.Close()
+	var merges []model.PatientMerge
+	for rows.Next() {
+		merge, err := s.scanMerge(rows)
+		if err != nil {
+			err = fmt.Errorf("failed to scan patient merge row: %w", err)
+			return nil, err
+		}
+		merges = append(merges, *merge)
+	}
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("error occurred while iterating over patient merge rows: %w", err)
+		return nil, err
+	}
+	return merges, nil
+}
+
+// ReencryptMerges re-encrypts up to limit merge snapshots not yet encrypted
+// with the active data key, including plaintext ones, and returns how many it
+// rewrote; zero means the clinic is done.
+func (s *PatientMergeStorage) ReencryptMerges(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt patient merges: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + mergeColumns + ` FROM patient_merges
+		WHERE clinic_id = current_clinic_id() AND merged_data_key_id IS DISTINCT FROM $1
+		LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get patient merges to re-encrypt: %w", err)
+	}
+	var merges []model.PatientMerge
+	for rows.Next() {
+		merge, err := s.scanMerge(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan patient merge row: %w", err)
+		}
+		merges = append(merges, *merge)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error occurred while iterating over patient merge rows: %w", err)
+	}
+	for _, merge := range merges {
+		name, err := key.Seal(nameField, merge.Merged.Name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt patient merge: %w", err)
+		}
+		phoneNumber, err := key.Seal(phoneNumberField, merge.Merged.PhoneNumber)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt patient merge: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE patient_merges SET merged_name = $1, merged_phone_number = $2, merged_data_key_id = $3
+			WHERE id = $4`, name, phoneNumber, key.ID, merge.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt patient merge: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(merges), nil
+}
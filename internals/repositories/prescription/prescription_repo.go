@@ -0,0 +1,104 @@
+package prescription_repo
+
+// Package prescription_repo provides the implementation of the PrescriptionRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+const prescriptionColumns = `id, encounter_id, patient_id, doctor_id, catalogue_item_id, medication, dosage, frequency,
+	duration_days, instructions, created_at`
+
+type PrescriptionStorage struct {
+	connection *sql.DB
+}
+
+func NewPrescriptionStorage(db *sql.DB) *PrescriptionStorage {
+	return &PrescriptionStorage{
+		connection: db,
+	}
+}
+
+func (s *PrescriptionStorage) CreatePrescription(prescription model.Prescription) (*model.Prescription, error) {
+	query := `INSERT INTO prescriptions (encounter_id, patient_id, doctor_id, catalogue_item_id, medication, dosage, frequency,
+	          duration_days, instructions)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING ` + prescriptionColumns
+	row := s.connection.QueryRow(query, prescription.EncounterID, prescription.PatientID, prescription.DoctorID,
+		prescription.CatalogueItemID, prescription.Medication, prescription.Dosage, prescription.Frequency,
+		prescription.DurationDays, prescription.Instructions)
+	created, err := scanPrescription(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prescription: %w", err)
+	}
+	return created, nil
+}
+
+func (s *PrescriptionStorage) DeletePrescription(id string) (*model.Prescription, error) {
+	query := `DELETE FROM prescriptions WHERE id = $1 RETURNING ` + prescriptionColumns
+	row := s.connection.QueryRow(query, id)
+	deleted, err := scanPrescription(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no prescription found with id: %s", id)
+		}
+		return nil, fmt.Errorf("failed to delete prescription: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *PrescriptionStorage) GetPrescriptionsByEncounterID(encounterID string) ([]model.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + ` FROM prescriptions WHERE encounter_id = $1 ORDER BY created_at`
+	rows, err := s.connection.Query(query, encounterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prescriptions by encounter ID: %w", err)
+	}
+	return scanPrescriptions(rows)
+}
+
+func (s *PrescriptionStorage) GetPrescriptionsByPatientID(patientID string) ([]model.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + ` FROM prescriptions WHERE patient_id = $1 ORDER BY created_at DESC`
+	rows, err := s.connection.Query(query, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prescriptions by patient ID: %w", err)
+	}
+	return scanPrescriptions(rows)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPrescription(row scanner) (*model.Prescription, error) {
+	var prescription model.Prescription
+	var catalogueItemID sql.NullInt64
+	err := row.Scan(&prescription.ID, &prescription.EncounterID, &prescription.PatientID, &prescription.DoctorID,
+		&catalogueItemID, &prescription.Medication, &prescription.Dosage, &prescription.Frequency,
+		&prescription.DurationDays, &prescription.Instructions, &prescription.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if catalogueItemID.Valid {
+		id := int(catalogueItemID.Int64)
+		prescription.CatalogueItemID = &id
+	}
+	return &prescription, nil
+}
+
+func scanPrescriptions(rows *sql.Rows) ([]model.Prescription, error) {
+	defer rows.Close()
+	var prescriptions []model.Prescription
+	for rows.Next() {
+		prescription, err := scanPrescription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prescription: %w", err)
+		}
+		prescriptions = append(prescriptions, *prescription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over prescription rows: %w", err)
+	}
+	return prescriptions, nil
+}
@@ -0,0 +1,155 @@
+package queue_repo
+
+// Package queue_repo provides the implementation of the QueueRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+const queueColumns = `id, encounter_id, doctor_id, patient_id, token_number, priority, status, queue_date,
+	checked_in_at, called_at`
+
+// queueOrder puts the patient currently called first, then waiting patients by
+// priority and arrival.
+const queueOrder = `CASE status WHEN 'called' THEN 0 ELSE 1 END,
+	CASE priority WHEN 'emergency' THEN 0 WHEN 'elderly' THEN 1 ELSE 2 END,
+	checked_in_at`
+
+type QueueStorage struct {
+	connection *sql.DB
+}
+
+func NewQueueStorage(db *sql.DB) *QueueStorage {
+	return &QueueStorage{
+		connection: db,
+	}
+}
+
+// CreateQueueEntry adds the entry with the next token number of the doctor's
+// queue for today. An advisory lock per doctor keeps token numbers unique
+// when receptionists check patients in at the same time.
+func (s *QueueStorage) CreateQueueEntry(entry model.QueueEntry) (*model.QueueEntry, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, "queue:"+entry.DoctorID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock queue: %w", err)
+	}
+	query := `INSERT INTO queue_entries (encounter_id, doctor_id, patient_id, token_number, priority, status)
+	          SELECT $1, $2, $3, COALESCE(MAX(token_number), 0) + 1, $4, 'waiting'
+	          FROM queue_entries WHERE doctor_id = $2 AND queue_date = CURRENT_DATE
+	          RETURNING ` + queueColumns
+	row := tx.QueryRow(query, entry.EncounterID, entry.DoctorID, entry.PatientID, entry.Priority)
+	created, err := scanQueueEntry(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue entry: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit queue entry: %w", err)
+	}
+	return created, nil
+}
+
+func (s *QueueStorage) GetQueueEntryByID(id int64) (*model.QueueEntry, error) {
+	query := `SELECT ` + queueColumns + ` FROM queue_entries WHERE id = $1`
+	entry, err := scanQueueEntry(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Queue entry not found
+		}
+		return nil, fmt.Errorf("failed to get queue entry: %w", err)
+	}
+	return entry, nil
+}
+
+// GetActiveQueueByDoctorID returns today's waiting and called entries in queue order.
+func (s *QueueStorage) GetActiveQueueByDoctorID(doctorID string) ([]model.QueueEntry, error) {
+	query := `SELECT ` + queueColumns + ` FROM queue_entries
+	          WHERE doctor_id = $1 AND queue_date = CURRENT_DATE AND status IN ('waiting', 'called')
+	          ORDER BY ` + queueOrder
+	rows, err := s.connection.Query(query, doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []model.QueueEntry
+	for rows.Next() {
+		entry, err := scanQueueEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan queue entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over queue rows: %w", err)
+	}
+	return entries, nil
+}
+
+// CallNextQueueEntry finishes the patient currently called by the doctor and
+// calls the next waiting one. It returns nil when nobody is waiting.
+func (s *QueueStorage) CallNextQueueEntry(doctorID string) (*model.QueueEntry, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE queue_entries SET status = 'done', finished_at = NOW()
+	                  WHERE doctor_id = $1 AND status = 'called'`, doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish called queue entry: %w", err)
+	}
+	query := `UPDATE queue_entries SET status = 'called', called_at = NOW()
+	          WHERE id = (
+	              SELECT id FROM queue_entries
+	              WHERE doctor_id = $1 AND queue_date = CURRENT_DATE AND status = 'waiting'
+	              ORDER BY ` + queueOrder + `
+	              LIMIT 1 FOR UPDATE SKIP LOCKED)
+	          RETURNING ` + queueColumns
+	next, err := scanQueueEntry(tx.QueryRow(query, doctorID))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to call next queue entry: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit queue change: %w", err)
+	}
+	return next, nil
+}
+
+func (s *QueueStorage) UpdateQueueEntryStatus(id int64, status string) (*model.QueueEntry, error) {
+	query := `UPDATE queue_entries SET status = $1,
+	              finished_at = CASE WHEN $1 IN ('done', 'left') THEN NOW() ELSE finished_at END
+	          WHERE id = $2 RETURNING ` + queueColumns
+	entry, err := scanQueueEntry(s.connection.QueryRow(query, status, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update queue entry: %w", err)
+	}
+	return entry, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanQueueEntry(row scanner) (*model.QueueEntry, error) {
+	var entry model.QueueEntry
+	var calledAt sql.NullTime
+	err := row.Scan(&entry.ID, &entry.EncounterID, &entry.DoctorID, &entry.PatientID, &entry.TokenNumber, &entry.Priority,
+		&entry.Status, &entry.QueueDate, &entry.CheckedInAt, &calledAt)
+	if err != nil {
+		return nil, err
+	}
+	if calledAt.Valid {
+		entry.CalledAt = &calledAt.Time
+	}
+	return &entry, nil
+}
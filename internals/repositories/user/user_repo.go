@@ -3,8 +3,11 @@ package user_repo
 // Package user_repo provides the implementation of the UserRepository interface
 import (
 	"database/sql"
+	"fmt"
 
 	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	domain_event_repo "github.com/aaryansinhaa/patient-management-system/internals/repositories/domain_event"
+	"github.com/google/uuid"
 )
 
 type UserStorage struct {
@@ -17,40 +20,88 @@ func NewUserStorage(db *sql.DB) *UserStorage {
 	}
 }
 
-func (s *UserStorage) CreateUser(user model.User) error {
+// CreateUser also makes the user a member of the connection's clinic, their
+// default one, when the connection belongs to a clinic.
+func (s *UserStorage) CreateUser(user model.User, passwordHash string) error {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO users (id, name, role, username, password, phone_number) 
 	          VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := s.connection.Exec(query, user.ID, user.Name, user.Role, user.Username, user.Password, user.PhoneNumber)
+	_, err = tx.Exec(query, user.ID, user.Name, user.Role, user.Username, passwordHash, user.PhoneNumber)
 	if err != nil {
 		return err
 	}
-	return nil
+	_, err = tx.Exec(`INSERT INTO user_clinics (user_id, clinic_id, is_default)
+	                  SELECT $1, current_clinic_id(), TRUE WHERE current_clinic_id() IS NOT NULL`, user.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *UserStorage) DeleteUser(id string) (*model.User, error) {
 	query := `DELETE FROM users WHERE id = $1 
-	RETURNING id, name, role, username, password, phone_number`
+	RETURNING id, name, role, username, phone_number`
 	row := s.connection.QueryRow(query, id)
 
 	var user model.User
-	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.Password, &user.PhoneNumber)
+	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.PhoneNumber)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // User not found
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeactivateUser stops the user from logging in and records a UserDeactivated
+// event with it. It returns nil if the user does not exist or is already
+// deactivated.
+func (s *UserStorage) DeactivateUser(id string, deactivatedBy string) (*model.User, error) {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET deactivated_at = NOW(), deactivated_by = $2, updated_at = NOW()
+	          WHERE id = $1 AND deactivated_at IS NULL
+	          RETURNING id, name, role, username, phone_number, deactivated_by`
+	var user model.User
+	var by uuid.UUID
+	err = tx.QueryRow(query, id, deactivatedBy).Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.PhoneNumber, &by)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // User not found
 		}
+		return nil, fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	err = domain_event_repo.AppendEvent(tx, model.EventUserDeactivated, user.ID.String(), model.UserDeactivatedPayload{
+		UserID:        user.ID,
+		DeactivatedBy: by,
+	})
+	if err != nil {
 		return nil, err
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return &user, nil
 }
 
 func (s *UserStorage) UpdateUser(user model.User) (*model.User, error) {
-	query := `UPDATE users SET name = $1, role = $2, username = $3, password = $4, phone_number = $5, updated_at = NOW() 
-	          WHERE id = $6
-			  RETURNING id, name, role, username, password, phone_number`
-	row := s.connection.QueryRow(query, user.Name, user.Role, user.Username, user.Password, user.PhoneNumber, user.ID)
+	query := `UPDATE users SET name = $1, role = $2, username = $3, phone_number = $4, updated_at = NOW() 
+	          WHERE id = $5
+			  RETURNING id, name, role, username, phone_number`
+	row := s.connection.QueryRow(query, user.Name, user.Role, user.Username, user.PhoneNumber, user.ID)
 
 	var updatedUser model.User
-	err := row.Scan(&updatedUser.ID, &updatedUser.Name, &updatedUser.Role, &updatedUser.Username, &updatedUser.Password, &updatedUser.PhoneNumber)
+	err := row.Scan(&updatedUser.ID, &updatedUser.Name, &updatedUser.Role, &updatedUser.Username, &updatedUser.PhoneNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -58,11 +109,11 @@ func (s *UserStorage) UpdateUser(user model.User) (*model.User, error) {
 }
 
 func (s *UserStorage) GetUserByID(id string) (*model.User, error) {
-	query := `SELECT id, name, role, username, password, phone_number FROM users WHERE id = $1`
+	query := `SELECT id, name, role, username, phone_number FROM users WHERE id = $1`
 	row := s.connection.QueryRow(query, id)
 
 	var user model.User
-	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.Password, &user.PhoneNumber)
+	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.PhoneNumber)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // User not found
@@ -73,11 +124,11 @@ func (s *UserStorage) GetUserByID(id string) (*model.User, error) {
 }
 
 func (s *UserStorage) GetUserByUsername(username string) (*model.User, error) {
-	query := `SELECT id, name, role, username, password, phone_number FROM users WHERE username = $1`
+	query := `SELECT id, name, role, username, phone_number FROM users WHERE username = $1`
 	row := s.connection.QueryRow(query, username)
 
 	var user model.User
-	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.Password, &user.PhoneNumber)
+	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.PhoneNumber)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // User not found
@@ -103,7 +154,8 @@ func (s *UserStorage) GetUserIdByUsername(username string) (string, error) {
 }
 
 func (s *UserStorage) GetAllUsers() ([]model.User, error) {
-	query := `SELECT id, name, role, username, password, phone_number FROM users`
+	query := `SELECT id, name, role, username, phone_number FROM users
+	          WHERE (current_clinic_id() IS NULL OR EXISTS (SELECT 1 FROM user_clinics uc WHERE uc.user_id = users.id AND uc.clinic_id = current_clinic_id()))`
 	rows, err := s.connection.Query(query)
 	if err != nil {
 		return nil, err
@@ -112,7 +164,7 @@ func (s *UserStorage) GetAllUsers() ([]model.User, error) {
 	var users []model.User
 	for rows.Next() {
 		var user model.User
-		err := rows.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.Password, &user.PhoneNumber)
+		err := rows.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.PhoneNumber)
 		if err != nil {
 			return nil, err
 		}
@@ -126,7 +178,8 @@ func (s *UserStorage) GetAllUsers() ([]model.User, error) {
 }
 
 func (s *UserStorage) GetAllUsersByRole(role string) ([]model.User, error) {
-	query := `SELECT id, name, role, username, password, phone_number FROM users WHERE role = $1`
+	query := `SELECT id, name, role, username, phone_number FROM users
+	          WHERE role = $1 AND (current_clinic_id() IS NULL OR EXISTS (SELECT 1 FROM user_clinics uc WHERE uc.user_id = users.id AND uc.clinic_id = current_clinic_id()))`
 	rows, err := s.connection.Query(query, role)
 	if err != nil {
 		return nil, err
@@ -135,7 +188,7 @@ func (s *UserStorage) GetAllUsersByRole(role string) ([]model.User, error) {
 	var users []model.User
 	for rows.Next() {
 		var user model.User
-		err := rows.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.Password, &user.PhoneNumber)
+		err := rows.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.PhoneNumber)
 		if err != nil {
 			return nil, err
 		}
@@ -148,32 +201,105 @@ func (s *UserStorage) GetAllUsersByRole(role string) ([]model.User, error) {
 	return users, nil
 }
 
-func (s *UserStorage) GetUsernameAndPasswordById(id string) (string, string, error) {
-	query := `SELECT username, password FROM users WHERE id = $1`
-	row := s.connection.QueryRow(query, id)
+func (s *UserStorage) GetUserByPhoneNumber(phoneNumber string) (*model.User, error) {
+	query := `SELECT id, name, role, username, phone_number FROM users WHERE phone_number = $1`
+	row := s.connection.QueryRow(query, phoneNumber)
 
-	var username, password string
-	err := row.Scan(&username, &password)
+	var user model.User
+	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.PhoneNumber)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", "", nil // User not found
+			return nil, nil // User not found
 		}
-		return "", "", err
+		return nil, err
 	}
-	return username, password, nil
+	return &user, nil
 }
 
-func (s *UserStorage) GetUserByPhoneNumber(phoneNumber string) (*model.User, error) {
-	query := `SELECT id, name, role, username, password FROM users WHERE phone_number = $1`
-	row := s.connection.QueryRow(query, phoneNumber)
+// GetCredentialsByUsername is the only lookup that reads the password hash.
+// It backs the CredentialRepository and is meant for the auth service alone.
+// Deactivated users have no credentials, so they can neither log in nor set
+// a password.
+func (s *UserStorage) GetCredentialsByUsername(username string) (*model.UserCredentials, error) {
+	query := `SELECT id, username, password FROM users WHERE username = $1 AND deactivated_at IS NULL`
+	row := s.connection.QueryRow(query, username)
 
-	var user model.User
-	err := row.Scan(&user.ID, &user.Name, &user.Role, &user.Username, &user.Password)
+	var credentials model.UserCredentials
+	err := row.Scan(&credentials.UserID, &credentials.Username, &credentials.PasswordHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // User not found
 		}
 		return nil, err
 	}
-	return &user, nil
+	return &credentials, nil
+}
+
+func (s *UserStorage) GetCredentialsByUserID(id string) (*model.UserCredentials, error) {
+	query := `SELECT id, username, password FROM users WHERE id = $1 AND deactivated_at IS NULL`
+	row := s.connection.QueryRow(query, id)
+
+	var credentials model.UserCredentials
+	err := row.Scan(&credentials.UserID, &credentials.Username, &credentials.PasswordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // User not found
+		}
+		return nil, err
+	}
+	return &credentials, nil
+}
+
+// UpdatePasswordHash replaces the hash of the current password, e.g. to raise
+// the bcrypt cost, without touching the password history.
+func (s *UserStorage) UpdatePasswordHash(userID string, passwordHash string) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+	_, err := s.connection.Exec(query, passwordHash, userID)
+	return err
+}
+
+// SetPassword moves the current password into the history, stores the new one
+// and keeps only the historyDepth most recent history entries.
+func (s *UserStorage) SetPassword(userID string, passwordHash string, historyDepth int) error {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO password_history (user_id, password_hash) SELECT id, password FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	_, err = tx.Exec(`DELETE FROM password_history WHERE user_id = $1 AND id NOT IN (
+		SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2)`, userID, historyDepth)
+	if err != nil {
+		return fmt.Errorf("failed to trim password history: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *UserStorage) GetPasswordHistory(userID string, limit int) ([]string, error) {
+	query := `SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2`
+	rows, err := s.connection.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
 }
@@ -0,0 +1,77 @@
+package vitals_repo
+
+// Package vitals_repo provides the implementation of the VitalsRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+const vitalsColumns = `id, encounter_id, patient_id, recorded_by, temperature_c, pulse_bpm, systolic_bp, diastolic_bp,
+	respiratory_rate, oxygen_saturation, weight_kg, height_cm, recorded_at`
+
+type VitalsStorage struct {
+	connection *sql.DB
+}
+
+func NewVitalsStorage(db *sql.DB) *VitalsStorage {
+	return &VitalsStorage{
+		connection: db,
+	}
+}
+
+func (s *VitalsStorage) CreateVitals(vitals model.Vitals) (*model.Vitals, error) {
+	query := `INSERT INTO vitals (encounter_id, patient_id, recorded_by, temperature_c, pulse_bpm, systolic_bp, diastolic_bp,
+	          respiratory_rate, oxygen_saturation, weight_kg, height_cm)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING ` + vitalsColumns
+	row := s.connection.QueryRow(query, vitals.EncounterID, vitals.PatientID, vitals.RecordedBy, vitals.TemperatureC,
+		vitals.PulseBPM, vitals.SystolicBP, vitals.DiastolicBP, vitals.RespiratoryRate, vitals.OxygenSaturation,
+		vitals.WeightKg, vitals.HeightCm)
+	created, err := scanVitals(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vitals: %w", err)
+	}
+	return created, nil
+}
+
+func (s *VitalsStorage) GetVitalsByEncounterID(encounterID string) ([]model.Vitals, error) {
+	query := `SELECT ` + vitalsColumns + ` FROM vitals WHERE encounter_id = $1 ORDER BY recorded_at`
+	rows, err := s.connection.Query(query, encounterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vitals by encounter ID: %w", err)
+	}
+	defer rows.Close()
+
+	var all []model.Vitals
+	for rows.Next() {
+		vitals, err := scanVitals(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan vitals: %w", err)
+		}
+		all = append(all, *vitals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over vitals rows: %w", err)
+	}
+	return all, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanVitals(row scanner) (*model.Vitals, error) {
+	var vitals model.Vitals
+	var recordedBy uuid.NullUUID
+	err := row.Scan(&vitals.ID, &vitals.EncounterID, &vitals.PatientID, &recordedBy, &vitals.TemperatureC, &vitals.PulseBPM,
+		&vitals.SystolicBP, &vitals.DiastolicBP, &vitals.RespiratoryRate, &vitals.OxygenSaturation, &vitals.WeightKg,
+		&vitals.HeightCm, &vitals.RecordedAt)
+	if err != nil {
+		return nil, err
+	}
+	vitals.RecordedBy = recordedBy.UUID
+	return &vitals, nil
+}
@@ -0,0 +1,424 @@
+package webhook_repo
+
+// Package webhook_repo provides the implementation of the WebhookRepository interface
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const secretField = "webhook_subscriptions.secret"
+
+const subscriptionColumns = `s.id, s.url, s.event_types, s.secret, s.active, s.created_by, s.created_at,
+	s.updated_at, s.data_key_id`
+
+// Deliveries are always read joined with their event, as e, for its type.
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+	d.last_error, d.last_response_status, d.created_at, d.delivered_at`
+
+const eventColumns = `e.id, e.clinic_id, e.type, e.aggregate_id, e.payload, e.occurred_at, e.status, e.attempts,
+	e.next_attempt_at, e.last_error, e.delivered_at`
+
+type WebhookStorage struct {
+	connection *sql.DB
+	keyring    *utils.Keyring
+}
+
+func NewWebhookStorage(db *sql.DB, keyring *utils.Keyring) *WebhookStorage {
+	return &WebhookStorage{
+		connection: db,
+		keyring:    keyring,
+	}
+}
+
+func (s *WebhookStorage) sealSecret(secret string) (string, int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return "", 0, err
+	}
+	sealed, err := key.Seal(secretField, secret)
+	if err != nil {
+		return "", 0, err
+	}
+	return sealed, key.ID, nil
+}
+
+func (s *WebhookStorage) CreateSubscription(subscription model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	secret, keyID, err := s.sealSecret(subscription.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	query := `INSERT INTO webhook_subscriptions AS s (url, event_types, secret, data_key_id, active, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + subscriptionColumns
+	row := s.connection.QueryRow(query, subscription.URL, pq.Array(subscription.EventTypes), secret, keyID,
+		subscription.Active, subscription.CreatedBy)
+	created, err := s.scanSubscription(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateSubscription replaces the URL, event types, secret and active flag of
+// a subscription of the current clinic. It returns nil if there is none.
+func (s *WebhookStorage) UpdateSubscription(subscription model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	secret, keyID, err := s.sealSecret(subscription.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	query := `UPDATE webhook_subscriptions AS s
+	          SET url = $1, event_types = $2, secret = $3, data_key_id = $4, active = $5, updated_at = NOW()
+	          WHERE id = $6 AND clinic_id = current_clinic_id()
+	          RETURNING ` + subscriptionColumns
+	row := s.connection.QueryRow(query, subscription.URL, pq.Array(subscription.EventTypes), secret, keyID,
+		subscription.Active, subscription.ID)
+	updated, err := s.scanSubscription(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Subscription not found
+		}
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteSubscription removes a subscription with its delivery log.
+func (s *WebhookStorage) DeleteSubscription(id int64) (*model.WebhookSubscription, error) {
+	query := `DELETE FROM webhook_subscriptions AS s WHERE id = $1 AND clinic_id = current_clinic_id()
+	          RETURNING ` + subscriptionColumns
+	deleted, err := s.scanSubscription(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Subscription not found
+		}
+		return nil, fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return deleted, nil
+}
+
+func (s *WebhookStorage) GetSubscriptionByID(id int64) (*model.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions s
+	          WHERE s.id = $1 AND s.clinic_id = current_clinic_id()`
+	subscription, err := s.scanSubscription(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Subscription not found
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription by ID: %w", err)
+	}
+	return subscription, nil
+}
+
+func (s *WebhookStorage) GetSubscriptions() ([]model.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions s
+	          WHERE s.clinic_id = current_clinic_id() ORDER BY s.id`
+	rows, err := s.connection.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+	var subscriptions []model.WebhookSubscription
+	for rows.Next() {
+		subscription, err := s.scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over webhook subscription rows: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// EnqueueDeliveries creates a delivery of event for every active
+// subscription of its clinic to its type, and returns how many it created.
+// Enqueueing an event again creates none.
+func (s *WebhookStorage) EnqueueDeliveries(event model.DomainEvent) (int, error) {
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_id)
+	          SELECT id, $1 FROM webhook_subscriptions
+	          WHERE active AND clinic_id = $2 AND $3 = ANY(event_types)
+	          ON CONFLICT (subscription_id, event_id) DO NOTHING`
+	result, err := s.connection.Exec(query, event.ID, event.ClinicID, event.Type)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return int(created), nil
+}
+
+// ClaimDueDeliveries takes up to limit pending deliveries that are due and
+// counts an attempt on each. Their next attempt is pushed lease into the
+// future, so a sender that dies leaves them to be retried rather than lost,
+// and concurrent senders never claim the same one. Deliveries of inactive
+// subscriptions wait until the subscription is active again.
+func (s *WebhookStorage) ClaimDueDeliveries(limit int, lease time.Duration) ([]model.PendingWebhookDelivery, error) {
+	query := `WITH claimed AS (
+	              UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+	              WHERE id IN (
+	                  SELECT d.id FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	                  WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND s.active
+	                  ORDER BY d.next_attempt_at, d.id LIMIT $1 FOR UPDATE OF d SKIP LOCKED
+	              )
+	              RETURNING *
+	          )
+	          SELECT ` + deliveryColumns + `, ` + subscriptionColumns + `, ` + eventColumns + `
+	          FROM claimed d
+	          JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	          JOIN domain_events e ON e.id = d.event_id
+	          ORDER BY d.id`
+	rows, err := s.connection.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var claimed []model.PendingWebhookDelivery
+	for rows.Next() {
+		var pending model.PendingWebhookDelivery
+		delivery := &pending.Delivery
+		subscription := &pending.Subscription
+		event := &pending.Event
+		var deliveryDeliveredAt, eventDelivered sql.NullTime
+		var subscriptionCreatedBy uuid.NullUUID
+		var keyID sql.NullInt32
+		err := rows.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType,
+			&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError,
+			&delivery.LastResponseStatus, &delivery.CreatedAt, &deliveryDeliveredAt,
+			&subscription.ID, &subscription.URL, pq.Array(&subscription.EventTypes), &subscription.Secret,
+			&subscription.Active, &subscriptionCreatedBy, &subscription.CreatedAt, &subscription.UpdatedAt, &keyID,
+			&event.ID, &event.ClinicID, &event.Type, &event.AggregateID, &event.Payload, &event.OccurredAt,
+			&event.Status, &event.Attempts, &event.NextAttemptAt, &event.LastError, &eventDelivered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		if subscription.Secret, err = s.keyring.Open(keyID, secretField, subscription.Secret); err != nil {
+			return nil, err
+		}
+		subscription.CreatedBy = subscriptionCreatedBy.UUID
+		if deliveryDeliveredAt.Valid {
+			delivery.DeliveredAt = &deliveryDeliveredAt.Time
+		}
+		if eventDelivered.Valid {
+			event.DeliveredAt = &eventDelivered.Time
+		}
+		claimed = append(claimed, pending)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over webhook delivery rows: %w", err)
+	}
+	return claimed, nil
+}
+
+// FinishAttempt logs an attempt at a delivery and moves the delivery to
+// status; a pending delivery is tried again at nextAttemptAt.
+func (s *WebhookStorage) FinishAttempt(attempt model.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO webhook_attempts (delivery_id, response_status, error, duration_ms)
+		VALUES ($1, $2, $3, $4)`, attempt.DeliveryID, attempt.ResponseStatus, attempt.Error,
+		attempt.Duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to log webhook attempt: %w", err)
+	}
+	_, err = tx.Exec(`UPDATE webhook_deliveries SET status = $1, next_attempt_at = $2, last_error = $3,
+			last_response_status = $4, delivered_at = CASE WHEN $1 = 'delivered' THEN NOW() END
+		WHERE id = $5`, status, nextAttemptAt, attempt.Error, attempt.ResponseStatus, attempt.DeliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetDeliveriesBySubscriptionID returns the subscription's deliveries with
+// the given status, or all of them when status is empty, newest first.
+func (s *WebhookStorage) GetDeliveriesBySubscriptionID(subscriptionID int64, status string) ([]model.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d
+	          JOIN domain_events e ON e.id = d.event_id
+	          JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	          WHERE d.subscription_id = $1 AND s.clinic_id = current_clinic_id() AND ($2 = '' OR d.status = $2)
+	          ORDER BY d.created_at DESC, d.id DESC`
+	rows, err := s.connection.Query(query, subscriptionID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var deliveries []model.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over webhook delivery rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *WebhookStorage) GetDeliveryByID(id int64) (*model.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d
+	          JOIN domain_events e ON e.id = d.event_id
+	          JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	          WHERE d.id = $1 AND s.clinic_id = current_clinic_id()`
+	delivery, err := scanDelivery(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Delivery not found
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery by ID: %w", err)
+	}
+	return delivery, nil
+}
+
+// GetAttemptsByDeliveryID returns the delivery's attempts, oldest first.
+func (s *WebhookStorage) GetAttemptsByDeliveryID(deliveryID int64) ([]model.WebhookAttempt, error) {
+	query := `SELECT a.id, a.delivery_id, a.attempted_at, a.response_status, a.error, a.duration_ms
+	          FROM webhook_attempts a
+	          JOIN webhook_deliveries d ON d.id = a.delivery_id
+	          JOIN webhook_subscriptions s ON s.id = d.subscription_id
+	          WHERE a.delivery_id = $1 AND s.clinic_id = current_clinic_id()
+	          ORDER BY a.attempted_at, a.id`
+	rows, err := s.connection.Query(query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook attempts: %w", err)
+	}
+	defer rows.Close()
+	var attempts []model.WebhookAttempt
+	for rows.Next() {
+		var attempt model.WebhookAttempt
+		var durationMS int64
+		err := rows.Scan(&attempt.ID, &attempt.DeliveryID, &attempt.AttemptedAt, &attempt.ResponseStatus,
+			&attempt.Error, &durationMS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook attempt: %w", err)
+		}
+		attempt.Duration = time.Duration(durationMS) * time.Millisecond
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while iterating over webhook attempt rows: %w", err)
+	}
+	return attempts, nil
+}
+
+// ReplayDelivery sends a delivered or failed delivery again, with a fresh
+// set of attempts. Earlier attempts stay in the log. It returns nil if the
+// current clinic has no such delivery that is not already pending.
+func (s *WebhookStorage) ReplayDelivery(id int64) (*model.WebhookDelivery, error) {
+	query := `WITH replayed AS (
+	              UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW(),
+	                  delivered_at = NULL
+	              WHERE id = $1 AND status <> 'pending'
+	                AND subscription_id IN (SELECT id FROM webhook_subscriptions WHERE clinic_id = current_clinic_id())
+	              RETURNING *
+	          )
+	          SELECT ` + deliveryColumns + ` FROM replayed d JOIN domain_events e ON e.id = d.event_id`
+	delivery, err := scanDelivery(s.connection.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Delivery not found
+		}
+		return nil, fmt.Errorf("failed to replay webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *WebhookStorage) scanSubscription(row scanner) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+	var createdBy uuid.NullUUID
+	var keyID sql.NullInt32
+	err := row.Scan(&subscription.ID, &subscription.URL, pq.Array(&subscription.EventTypes), &subscription.Secret,
+		&subscription.Active, &createdBy, &subscription.CreatedAt, &subscription.UpdatedAt, &keyID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription.Secret, err = s.keyring.Open(keyID, secretField, subscription.Secret); err != nil {
+		return nil, err
+	}
+	subscription.CreatedBy = createdBy.UUID
+	return &subscription, nil
+}
+
+func scanDelivery(row scanner) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	var deliveredAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &delivery.LastResponseStatus,
+		&delivery.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
+
+// ReencryptSubscriptions re-encrypts the secrets of up to limit subscriptions
+// not yet encrypted with the active data key and returns how many it
+// rewrote; zero means the clinic is done.
+func (s *WebhookStorage) ReencryptSubscriptions(limit int) (int, error) {
+	key, err := s.keyring.ActiveKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt webhook subscriptions: %w", err)
+	}
+	tx, err := s.connection.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions s
+	          WHERE s.clinic_id = current_clinic_id() AND s.data_key_id IS DISTINCT FROM $1
+	          LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, key.ID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get webhook subscriptions to re-encrypt: %w", err)
+	}
+	var subscriptions []model.WebhookSubscription
+	for rows.Next() {
+		subscription, err := s.scanSubscription(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error occurred while iterating over webhook subscription rows: %w", err)
+	}
+	for _, subscription := range subscriptions {
+		secret, err := key.Seal(secretField, subscription.Secret)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt webhook subscription: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE webhook_subscriptions SET secret = $1, data_key_id = $2 WHERE id = $3`,
+			secret, key.ID, subscription.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt webhook subscription: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(subscriptions), nil
+}
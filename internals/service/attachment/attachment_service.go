@@ -0,0 +1,256 @@
+package attachment_service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/blobstore"
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+// maxFilenameLength bounds stored filenames, in characters.
+const maxFilenameLength = 255
+
+var (
+	ErrPatientNotFound    = errors.New("patient not found")
+	ErrDiagnosisNotFound  = errors.New("diagnosis not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrTooLarge           = errors.New("attachment is too large")
+	ErrUnsupportedType    = errors.New("unsupported attachment type")
+	ErrInvalidAttachment  = errors.New("invalid attachment")
+)
+
+type attachmentService struct {
+	attachments repositories.AttachmentRepository
+	patients    repositories.PatientRepository
+	diagnoses   repositories.DiagnosisRepository
+	store       blobstore.Store
+	tempDir     string
+	maxSize     int64
+}
+
+func NewAttachmentService(attachments repositories.AttachmentRepository, patients repositories.PatientRepository,
+	diagnoses repositories.DiagnosisRepository, store blobstore.Store, cfg config.AttachmentConfig) *attachmentService {
+	return &attachmentService{
+		attachments: attachments,
+		patients:    patients,
+		diagnoses:   diagnoses,
+		store:       store,
+		tempDir:     cfg.TempDir,
+		maxSize:     cfg.MaxSize,
+	}
+}
+
+// UploadAttachment stores content as an attachment to the patient's record.
+// The content is spooled to a temporary file while it is hashed and its size
+// checked, and its type is sniffed from its first bytes. A file the patient
+// already has is not attached again; the existing attachment is returned
+// marked as a duplicate. Identical files are stored once however many
+// patients they are attached to.
+func (s *attachmentService) UploadAttachment(ctx context.Context, userID, patientID uuid.UUID,
+	upload dto.AttachmentUpload, content io.Reader) (*dto.AttachmentResponse, error) {
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	if upload.DiagnosisID != nil {
+		diagnosis, err := s.diagnoses.GetDiagnosisByID(strconv.Itoa(*upload.DiagnosisID))
+		if err != nil {
+			return nil, err
+		}
+		if diagnosis == nil || diagnosis.PatientID != patientID {
+			return nil, ErrDiagnosisNotFound
+		}
+	}
+	filename, err := cleanFilename(upload.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	spool, err := os.CreateTemp(s.tempDir, "attachment-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool attachment: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), io.LimitReader(content, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool attachment: %w", err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidAttachment)
+	}
+	if size > s.maxSize {
+		return nil, fmt.Errorf("%w: the limit is %d bytes", ErrTooLarge, s.maxSize)
+	}
+	head := make([]byte, min(size, sniffLen))
+	if _, err := spool.ReadAt(head, 0); err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	mimeType, allowed := detectType(head)
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, mimeType)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	existing, err := s.attachments.GetAttachmentBySHA256(patientID.String(), sum)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		response := dto.ToAttachmentResponse(*existing)
+		response.Duplicate = true
+		return &response, nil
+	}
+
+	key := storageKey(sum)
+	stored, err := s.store.Exists(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		if err := s.store.Put(ctx, key, spool, size); err != nil {
+			return nil, err
+		}
+	}
+
+	attachment, err := s.attachments.CreateAttachment(model.Attachment{
+		PatientID:   patientID,
+		DiagnosisID: upload.DiagnosisID,
+		Filename:    filename,
+		Description: strings.TrimSpace(upload.Description),
+		MimeType:    mimeType,
+		Size:        size,
+		SHA256:      sum,
+		StorageKey:  key,
+		UploadedBy:  userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToAttachmentResponse(*attachment)
+	return &response, nil
+}
+
+func (s *attachmentService) ListPatientAttachments(patientID uuid.UUID, includeDeleted bool) ([]dto.AttachmentResponse, error) {
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachments.GetAttachmentsByPatientID(patientID.String(), includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToAttachmentResponses(attachments), nil
+}
+
+func (s *attachmentService) GetAttachment(patientID uuid.UUID, id int64) (*dto.AttachmentResponse, error) {
+	attachment, err := s.patientAttachment(patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToAttachmentResponse(*attachment)
+	return &response, nil
+}
+
+// OpenAttachment returns the attachment with its content, which the caller
+// must close. Deleted attachments cannot be opened.
+func (s *attachmentService) OpenAttachment(ctx context.Context, patientID uuid.UUID, id int64) (*dto.AttachmentResponse, io.ReadCloser, error) {
+	attachment, err := s.patientAttachment(patientID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.DeletedAt != nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	content, err := s.store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("attachment %d: %w", attachment.ID, err)
+	}
+	response := dto.ToAttachmentResponse(*attachment)
+	return &response, content, nil
+}
+
+// DeleteAttachment removes the attachment from the patient's record. It is
+// kept, marked deleted, along with its content.
+func (s *attachmentService) DeleteAttachment(userID, patientID uuid.UUID, id int64) (*dto.AttachmentResponse, error) {
+	attachment, err := s.patientAttachment(patientID, id)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := s.attachments.DeleteAttachment(attachment.ID, userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if deleted == nil {
+		return nil, ErrAttachmentNotFound
+	}
+	response := dto.ToAttachmentResponse(*deleted)
+	return &response, nil
+}
+
+func (s *attachmentService) requirePatient(patientID uuid.UUID) error {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return err
+	}
+	if patient == nil {
+		return ErrPatientNotFound
+	}
+	return nil
+}
+
+// patientAttachment loads an attachment, treating one of another patient's
+// as missing.
+func (s *attachmentService) patientAttachment(patientID uuid.UUID, id int64) (*model.Attachment, error) {
+	attachment, err := s.attachments.GetAttachmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil || attachment.PatientID != patientID {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+// storageKey addresses content by its hash, fanned out over subdirectories
+// so no one directory of a local store grows too large.
+func storageKey(sum string) string {
+	return path.Join("sha256", sum[:2], sum)
+}
+
+// cleanFilename keeps the base name the uploader gave, without any path or
+// control characters.
+func cleanFilename(filename string) (string, error) {
+	filename = strings.ReplaceAll(filename, `\`, "/")
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, path.Base(filename))
+	filename = strings.TrimSpace(filename)
+	if filename == "" || filename == "." || filename == "/" {
+		return "", fmt.Errorf("%w: filename is required", ErrInvalidAttachment)
+	}
+	if len([]rune(filename)) > maxFilenameLength {
+		return "", fmt.Errorf("%w: filename is longer than %d characters", ErrInvalidAttachment, maxFilenameLength)
+	}
+	return filename, nil
+}
@@ -0,0 +1,38 @@
+package attachment_service
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+)
+
+// sniffLen is how much of a file is looked at to tell its type.
+const sniffLen = 512
+
+// allowedTypes are the media types that may be attached: documents, photos
+// and DICOM scans.
+var allowedTypes = map[string]bool{
+	"application/pdf":   true,
+	"application/dicom": true,
+	"image/jpeg":        true,
+	"image/png":         true,
+	"image/gif":         true,
+	"image/webp":        true,
+	"image/bmp":         true,
+	"text/plain":        true,
+}
+
+// detectType tells a file's type from its first bytes, ignoring whatever
+// the uploader claimed. DICOM files, which net/http does not know, carry
+// "DICM" after a 128 byte preamble.
+func detectType(head []byte) (contentType string, allowed bool) {
+	if len(head) >= 132 && bytes.Equal(head[128:132], []byte("DICM")) {
+		return "application/dicom", true
+	}
+	contentType = http.DetectContentType(head)
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType, false
+	}
+	return contentType, allowedTypes[mediaType]
+}
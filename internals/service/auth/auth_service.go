@@ -2,7 +2,10 @@ package auth_service
 
 import (
 	"errors"
+	"time"
 
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
 	"github.com/aaryansinhaa/patient-management-system/internals/model"
 	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
 	"github.com/aaryansinhaa/patient-management-system/internals/utils"
@@ -10,40 +13,179 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrNotAdmin           = errors.New("only admins can perform this action")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrAlreadyDeactivated = errors.New("user is already deactivated")
+	ErrDeactivateSelf     = errors.New("admins cannot deactivate themselves")
+)
+
 type authService struct {
-	repo       repositories.UserRepository
-	jwtManager *utils.JWTManager
+	repo           repositories.UserRepository
+	credentials    repositories.CredentialRepository
+	audit          repositories.AuditRepository
+	mfa            repositories.MFARepository
+	resets         repositories.PasswordResetRepository
+	clinics        repositories.ClinicRepository
+	throttle       *loginThrottle
+	passwordPolicy *PasswordPolicy
+	jwtManager     *utils.JWTManager
+	mfaConfig      config.MFAConfig
 }
 
-func NewAuthService(repo repositories.UserRepository, jwtManager *utils.JWTManager) *authService {
-	return &authService{repo: repo, jwtManager: jwtManager}
+func NewAuthService(repo repositories.UserRepository, credentials repositories.CredentialRepository,
+	attempts repositories.LoginAttemptRepository, audit repositories.AuditRepository, mfa repositories.MFARepository,
+	resets repositories.PasswordResetRepository, clinics repositories.ClinicRepository, passwordPolicy *PasswordPolicy,
+	jwtManager *utils.JWTManager, authConfig config.AuthConfig) *authService {
+	return &authService{
+		repo:           repo,
+		credentials:    credentials,
+		audit:          audit,
+		mfa:            mfa,
+		resets:         resets,
+		clinics:        clinics,
+		throttle:       &loginThrottle{attempts: attempts, audit: audit, config: authConfig.LoginThrottle},
+		passwordPolicy: passwordPolicy,
+		jwtManager:     jwtManager,
+		mfaConfig:      authConfig.MFA,
+	}
 }
 
-func (s *authService) Register(user *model.User) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+func (s *authService) Register(request dto.RegisterUserRequest) (*dto.UserResponse, error) {
+	if err := s.passwordPolicy.Validate(request.Password); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.passwordPolicy.Hash(request.Password)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	user.Password = string(hashedPassword)
+	user := request.ToModel()
 	user.ID = uuid.New()
-	return s.repo.CreateUser(*user)
+	if err := s.repo.CreateUser(user, hashedPassword); err != nil {
+		return nil, err
+	}
+	response := dto.ToUserResponse(user)
+	return &response, nil
 }
 
-func (s *authService) Login(username, password string) (*model.User, string, error) {
-	user, err := s.repo.GetUserByUsername(username)
-	if err != nil || user == nil {
-		return nil, "", errors.New("invalid username or password")
+func (s *authService) Login(request dto.LoginRequest) (*dto.LoginResponse, error) {
+	now := time.Now()
+	if err := s.throttle.check(model.LoginScopeUsername, request.Username, now); err != nil {
+		return nil, err
+	}
+	if request.ClientIP != "" {
+		if err := s.throttle.check(model.LoginScopeIP, request.ClientIP, now); err != nil {
+			return nil, err
+		}
+	}
+
+	credentials, err := s.credentials.GetCredentialsByUsername(request.Username)
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		// Unknown and deactivated usernames must fail as slowly as a wrong
+		// password, or the response time tells which usernames exist.
+		s.passwordPolicy.CompareDummy(request.Password)
+		return nil, s.loginFailed(request, now)
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = bcrypt.CompareHashAndPassword([]byte(credentials.PasswordHash), []byte(request.Password))
 	if err != nil {
-		return nil, "", errors.New("invalid username or password")
+		return nil, s.loginFailed(request, now)
+	}
+
+	user, err := s.repo.GetUserByID(credentials.UserID.String())
+	if err != nil || user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.throttle.reset(model.LoginScopeUsername, request.Username); err != nil {
+		return nil, err
+	}
+
+	if s.passwordPolicy.NeedsRehash(credentials.PasswordHash) {
+		// The password is known to be correct here, so upgrade the hash to the
+		// current cost. A failure is not fatal and is retried on the next login.
+		if hash, err := s.passwordPolicy.Hash(request.Password); err == nil {
+			_ = s.credentials.UpdatePasswordHash(credentials.UserID.String(), hash)
+		}
+	}
+
+	return s.completeLogin(user)
+}
+
+// loginFailed records the failure against the username and client IP and
+// returns the error to hand back to the caller.
+func (s *authService) loginFailed(request dto.LoginRequest, now time.Time) error {
+	if err := s.throttle.recordFailure(model.LoginScopeUsername, request.Username, request.ClientIP, now); err != nil {
+		return err
+	}
+	if request.ClientIP != "" {
+		if err := s.throttle.recordFailure(model.LoginScopeIP, request.ClientIP, request.ClientIP, now); err != nil {
+			return err
+		}
 	}
+	return ErrInvalidCredentials
+}
 
-	token, err := s.jwtManager.Generate(user)
+// UnlockAccount clears the failed login history of username so it can log in again.
+func (s *authService) UnlockAccount(adminID uuid.UUID, username string) error {
+	if err := s.requireAdmin(adminID); err != nil {
+		return err
+	}
+	if err := s.throttle.reset(model.LoginScopeUsername, username); err != nil {
+		return err
+	}
+	return s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: adminID, Valid: true},
+		Action:  model.AuditActionAccountUnlocked,
+		Subject: model.LoginScopeUsername + ":" + username,
+	})
+}
+
+// DeactivateUser stops a user from logging in or resetting their password.
+// Access tokens already issued stay valid until they expire.
+func (s *authService) DeactivateUser(adminID, userID uuid.UUID) (*dto.UserResponse, error) {
+	if err := s.requireAdmin(adminID); err != nil {
+		return nil, err
+	}
+	if adminID == userID {
+		return nil, ErrDeactivateSelf
+	}
+	existing, err := s.repo.GetUserByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrUserNotFound
+	}
+	user, err := s.repo.DeactivateUser(userID.String(), adminID.String())
 	if err != nil {
-		return nil, "", err
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrAlreadyDeactivated
+	}
+	if err := s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: adminID, Valid: true},
+		Action:  model.AuditActionUserDeactivated,
+		Subject: "user:" + userID.String(),
+	}); err != nil {
+		return nil, err
 	}
+	response := dto.ToUserResponse(*user)
+	return &response, nil
+}
 
-	return user, token, nil
+func (s *authService) requireAdmin(userID uuid.UUID) error {
+	user, err := s.repo.GetUserByID(userID.String())
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Role != model.RoleAdmin {
+		return ErrNotAdmin
+	}
+	return nil
 }
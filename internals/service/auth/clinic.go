@@ -0,0 +1,94 @@
+package auth_service
+
+import (
+	"errors"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoClinic        = errors.New("user does not work at any clinic")
+	ErrNotClinicMember = errors.New("user does not work at this clinic")
+)
+
+// issueToken issues an access token for clinicID, or for the user's default
+// clinic when clinicID is uuid.Nil. Deactivated users get none, whichever
+// way they ask.
+func (s *authService) issueToken(user *model.User, clinicID uuid.UUID) (*dto.LoginResponse, error) {
+	return s.issue(user, clinicID, s.jwtManager.Generate)
+}
+
+// issueEnrollmentToken is issueToken for users who must set up two-factor
+// authentication before they get a full access token.
+func (s *authService) issueEnrollmentToken(user *model.User) (*dto.LoginResponse, error) {
+	response, err := s.issue(user, uuid.Nil, s.jwtManager.GenerateMFAEnrollment)
+	if err != nil {
+		return nil, err
+	}
+	response.MFAEnrollmentRequired = true
+	return response, nil
+}
+
+func (s *authService) issue(user *model.User, clinicID uuid.UUID, generate func(*model.User, uuid.UUID) (string, error)) (*dto.LoginResponse, error) {
+	credentials, err := s.credentials.GetCredentialsByUserID(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		return nil, ErrInvalidCredentials
+	}
+	memberships, err := s.clinics.GetMembershipsByUserID(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if len(memberships) == 0 {
+		return nil, ErrNoClinic
+	}
+	// Memberships come default first.
+	clinic := &memberships[0].Clinic
+	if clinicID != uuid.Nil {
+		clinic = nil
+		for i := range memberships {
+			if memberships[i].Clinic.ID == clinicID {
+				clinic = &memberships[i].Clinic
+				break
+			}
+		}
+		if clinic == nil {
+			return nil, ErrNotClinicMember
+		}
+	}
+
+	token, err := generate(user, clinic.ID)
+	if err != nil {
+		return nil, err
+	}
+	userResponse := dto.ToUserResponse(*user)
+	clinicResponse := dto.ToClinicResponse(*clinic)
+	return &dto.LoginResponse{User: &userResponse, Token: token, Clinic: &clinicResponse}, nil
+}
+
+// SwitchClinic issues a new access token for another clinic the user works at.
+func (s *authService) SwitchClinic(userID uuid.UUID, request dto.SwitchClinicRequest) (*dto.LoginResponse, error) {
+	if request.ClinicID == uuid.Nil {
+		return nil, ErrNotClinicMember
+	}
+	user, err := s.repo.GetUserByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.issueToken(user, request.ClinicID)
+}
+
+func (s *authService) ListMyClinics(userID uuid.UUID) ([]dto.ClinicMembershipResponse, error) {
+	memberships, err := s.clinics.GetMembershipsByUserID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToClinicMembershipResponses(memberships), nil
+}
@@ -0,0 +1,233 @@
+package auth_service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidMFACode       = errors.New("invalid two-factor code")
+	ErrMFAAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled        = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrollmentMissing = errors.New("no pending two-factor enrolment, start one first")
+)
+
+// completeLogin issues the access token, unless the user has a second factor
+// to present first. Users whose role requires a second factor they have not
+// set up only get a token for enrolling one, and log in again afterwards.
+func (s *authService) completeLogin(user *model.User) (*dto.LoginResponse, error) {
+	settings, err := s.mfa.GetTOTPSettings(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil && settings.Enabled {
+		challenge, err := s.jwtManager.GenerateMFAChallenge(user, s.mfaConfig.ChallengeDuration)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.LoginResponse{MFARequired: true, MFAToken: challenge}, nil
+	}
+
+	policy, err := s.mfa.GetMFARolePolicy(user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Required {
+		return s.issueEnrollmentToken(user)
+	}
+	return s.issueToken(user, uuid.Nil)
+}
+
+// VerifyMFA is the second step of a login for users with two-factor enabled.
+// Wrong codes count towards the same lockout as wrong passwords.
+func (s *authService) VerifyMFA(request dto.MFAVerifyRequest) (*dto.LoginResponse, error) {
+	userID, err := s.jwtManager.VerifyMFAChallenge(request.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetUserByID(userID.String())
+	if err != nil || user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	if err := s.throttle.check(model.LoginScopeUsername, user.Username, now); err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	if request.RecoveryCode != "" {
+		ok, err = s.mfa.ConsumeRecoveryCode(user.ID.String(), utils.HashToken(request.RecoveryCode))
+	} else {
+		ok, err = s.checkTOTP(user.ID, request.Code, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.throttle.recordFailure(model.LoginScopeUsername, user.Username, request.ClientIP, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidMFACode
+	}
+	if err := s.throttle.reset(model.LoginScopeUsername, user.Username); err != nil {
+		return nil, err
+	}
+
+	return s.issueToken(user, uuid.Nil)
+}
+
+// checkTOTP validates code against the enabled secret of userID and refuses a
+// code whose time step was already used.
+func (s *authService) checkTOTP(userID uuid.UUID, code string, now time.Time) (bool, error) {
+	settings, err := s.mfa.GetTOTPSettings(userID.String())
+	if err != nil {
+		return false, err
+	}
+	if settings == nil || !settings.Enabled {
+		return false, ErrMFANotEnabled
+	}
+	step, ok, err := utils.ValidateTOTPCode(settings.Secret, code, now, s.mfaConfig.Skew)
+	if err != nil || !ok {
+		return false, err
+	}
+	return s.mfa.AdvanceTOTPStep(userID.String(), step)
+}
+
+// BeginTOTPEnrollment generates a new secret for userID. It is not active
+// until confirmed with a code from the authenticator app.
+func (s *authService) BeginTOTPEnrollment(userID uuid.UUID) (*dto.TOTPEnrollmentResponse, error) {
+	user, err := s.repo.GetUserByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	settings, err := s.mfa.GetTOTPSettings(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil && settings.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfa.SetTOTPSecret(userID.String(), secret); err != nil {
+		return nil, err
+	}
+	return &dto.TOTPEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.mfaConfig.Issuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication once the user proves
+// their app produces valid codes, and returns a fresh set of recovery codes.
+func (s *authService) ConfirmTOTPEnrollment(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error) {
+	settings, err := s.mfa.GetTOTPSettings(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || settings.Secret == "" {
+		return nil, ErrMFAEnrollmentMissing
+	}
+	if settings.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok, err := utils.ValidateTOTPCode(settings.Secret, code, time.Now(), s.mfaConfig.Skew)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	if _, err := s.mfa.AdvanceTOTPStep(userID.String(), step); err != nil {
+		return nil, err
+	}
+	if err := s.mfa.EnableTOTP(userID.String()); err != nil {
+		return nil, err
+	}
+	if err := s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: userID, Valid: true},
+		Action:  model.AuditActionMFAEnabled,
+		Subject: "user:" + userID.String(),
+	}); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of userID after checking a current TOTP code.
+func (s *authService) RegenerateRecoveryCodes(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error) {
+	ok, err := s.checkTOTP(userID, code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+// DisableTOTP turns two-factor authentication off after checking a current TOTP code.
+func (s *authService) DisableTOTP(userID uuid.UUID, code string) error {
+	ok, err := s.checkTOTP(userID, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	if err := s.mfa.DisableTOTP(userID.String()); err != nil {
+		return err
+	}
+	return s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: userID, Valid: true},
+		Action:  model.AuditActionMFADisabled,
+		Subject: "user:" + userID.String(),
+	})
+}
+
+// SetMFAPolicy lets an admin make two-factor authentication mandatory for a role.
+func (s *authService) SetMFAPolicy(adminID uuid.UUID, request dto.MFAPolicyRequest) error {
+	if err := s.requireAdmin(adminID); err != nil {
+		return err
+	}
+	if err := s.mfa.SetMFARolePolicy(model.MFARolePolicy{Role: request.Role, Required: request.Required}); err != nil {
+		return err
+	}
+	details := "optional"
+	if request.Required {
+		details = "required"
+	}
+	return s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: adminID, Valid: true},
+		Action:  model.AuditActionMFAPolicySet,
+		Subject: "role:" + request.Role,
+		Details: details,
+	})
+}
+
+func (s *authService) issueRecoveryCodes(userID uuid.UUID) (*dto.RecoveryCodesResponse, error) {
+	codes, err := utils.GenerateRecoveryCodes(s.mfaConfig.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	if err := s.mfa.ReplaceRecoveryCodes(userID.String(), hashes); err != nil {
+		return nil, err
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
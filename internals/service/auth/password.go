@@ -0,0 +1,160 @@
+package auth_service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/utils"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// ChangePassword replaces the password of userID after checking the old one.
+func (s *authService) ChangePassword(userID uuid.UUID, request dto.ChangePasswordRequest) error {
+	credentials, err := s.credentials.GetCredentialsByUserID(userID.String())
+	if err != nil {
+		return err
+	}
+	if credentials == nil {
+		return ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(credentials.PasswordHash), []byte(request.OldPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+	if err := s.checkNewPassword(credentials, request.NewPassword); err != nil {
+		return err
+	}
+	if err := s.setPassword(credentials, request.NewPassword); err != nil {
+		return err
+	}
+	return s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: userID, Valid: true},
+		Action:  model.AuditActionPasswordChanged,
+		Subject: "user:" + userID.String(),
+	})
+}
+
+// IssuePasswordReset lets an admin create a single-use reset token for a user.
+// The token is returned once and only its hash is stored.
+func (s *authService) IssuePasswordReset(adminID uuid.UUID, userID uuid.UUID) (*dto.PasswordResetTokenResponse, error) {
+	if err := s.requireAdmin(adminID); err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetUserByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := time.Now().Add(s.passwordPolicy.config.ResetTokenTTL)
+
+	err = s.resets.CreatePasswordResetToken(model.PasswordResetToken{
+		TokenHash: utils.HashToken(token),
+		UserID:    userID,
+		CreatedBy: adminID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: adminID, Valid: true},
+		Action:  model.AuditActionResetIssued,
+		Subject: "user:" + userID.String(),
+	}); err != nil {
+		return nil, err
+	}
+	return &dto.PasswordResetTokenResponse{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// ResetPassword sets a new password using a token from IssuePasswordReset. It
+// also clears any login lockout on the account.
+func (s *authService) ResetPassword(request dto.ResetPasswordRequest) error {
+	tokenHash := utils.HashToken(request.Token)
+	token, err := s.resets.GetPasswordResetToken(tokenHash)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return ErrInvalidResetToken
+	}
+	credentials, err := s.credentials.GetCredentialsByUserID(token.UserID.String())
+	if err != nil {
+		return err
+	}
+	if credentials == nil {
+		return ErrInvalidResetToken
+	}
+
+	// Validate before consuming so a rejected password does not burn the token.
+	if err := s.checkNewPassword(credentials, request.NewPassword); err != nil {
+		return err
+	}
+	consumed, err := s.resets.ConsumePasswordResetToken(tokenHash)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidResetToken
+	}
+	if err := s.setPassword(credentials, request.NewPassword); err != nil {
+		return err
+	}
+	if err := s.throttle.reset(model.LoginScopeUsername, credentials.Username); err != nil {
+		return err
+	}
+	return s.audit.CreateAuditEntry(model.AuditEntry{
+		ActorID: uuid.NullUUID{UUID: token.UserID, Valid: true},
+		Action:  model.AuditActionPasswordReset,
+		Subject: "user:" + token.UserID.String(),
+	})
+}
+
+// checkNewPassword applies the policy and refuses the current password or any
+// of the ones kept in the history.
+func (s *authService) checkNewPassword(credentials *model.UserCredentials, password string) error {
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return err
+	}
+	previous := []string{credentials.PasswordHash}
+	if depth := s.passwordPolicy.config.HistoryDepth; depth > 0 {
+		history, err := s.credentials.GetPasswordHistory(credentials.UserID.String(), depth)
+		if err != nil {
+			return err
+		}
+		previous = append(previous, history...)
+	}
+	for _, hash := range previous {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// setPassword stores an already checked password.
+func (s *authService) setPassword(credentials *model.UserCredentials, password string) error {
+	hash, err := s.passwordPolicy.Hash(password)
+	if err != nil {
+		return err
+	}
+	userID := credentials.UserID.String()
+	if err := s.credentials.SetPassword(userID, hash, s.passwordPolicy.config.HistoryDepth); err != nil {
+		return err
+	}
+	// A changed password invalidates any reset token still outstanding.
+	return s.resets.DeletePasswordResetTokensByUser(userID)
+}
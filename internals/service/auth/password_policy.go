@@ -0,0 +1,121 @@
+package auth_service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordTooLong  = errors.New("password is too long")
+	ErrPasswordBreached = errors.New("password appears in a list of breached passwords")
+	ErrPasswordReused   = errors.New("password was used recently")
+)
+
+// PasswordPolicy validates new passwords and owns the bcrypt cost they are hashed with.
+type PasswordPolicy struct {
+	config config.PasswordConfig
+	// breached holds upper-case SHA-1 hex digests of known-breached passwords.
+	breached map[string]struct{}
+	// dummyHash is compared against when there is no user to check, so a
+	// failed login takes as long whether or not the username exists.
+	dummyHash []byte
+}
+
+// LoadPasswordPolicy builds the policy, reading the breached password list if one is configured.
+func LoadPasswordPolicy(cfg config.PasswordConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{config: cfg, breached: map[string]struct{}{}}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		policy.config.BcryptCost = bcrypt.DefaultCost
+	}
+	dummyHash, err := bcrypt.GenerateFromPassword([]byte("no user has this password"), policy.config.BcryptCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash dummy password: %w", err)
+	}
+	policy.dummyHash = dummyHash
+	if cfg.BreachedListPath == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(cfg.BreachedListPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if digest, _, _ := strings.Cut(line, ":"); isSHA1Hex(digest) {
+			policy.breached[strings.ToUpper(digest)] = struct{}{}
+			continue
+		}
+		policy.breached[sha1Hex(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return policy, nil
+}
+
+// Validate checks the length and breached-list rules. Reuse is checked separately
+// by the auth service because it needs the user's password history.
+func (p *PasswordPolicy) Validate(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrPasswordTooShort, p.config.MinLength)
+	}
+	// bcrypt ignores everything after 72 bytes, so enforce that too.
+	if (p.config.MaxLength > 0 && length > p.config.MaxLength) || len(password) > 72 {
+		return fmt.Errorf("%w: at most %d characters allowed", ErrPasswordTooLong, p.config.MaxLength)
+	}
+	if _, found := p.breached[sha1Hex(password)]; found {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
+func (p *PasswordPolicy) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.config.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CompareDummy spends as long as checking password against a real hash at
+// the current cost, for logins with no user to check it against.
+func (p *PasswordPolicy) CompareDummy(password string) {
+	_ = bcrypt.CompareHashAndPassword(p.dummyHash, []byte(password))
+}
+
+// NeedsRehash reports whether hash was made with a lower cost than the policy asks for.
+func (p *PasswordPolicy) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < p.config.BcryptCost
+}
+
+func sha1Hex(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(value string) bool {
+	if len(value) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,401 @@
+package billing_service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTaxRateNotFound       = errors.New("tax rate not found")
+	ErrCatalogueItemNotFound = errors.New("catalogue item not found")
+	ErrEncounterNotFound     = errors.New("encounter not found")
+	ErrPatientNotFound       = errors.New("patient not found")
+	ErrInvoiceNotFound       = errors.New("invoice not found")
+	ErrPaymentNotFound       = errors.New("payment not found")
+	ErrAlreadyInvoiced       = errors.New("encounter has already been invoiced")
+	ErrNotBillable           = errors.New("encounter cannot be billed")
+	ErrNothingToInvoice      = errors.New("encounter has no billable services or prescriptions")
+	ErrAmountExceedsBalance  = errors.New("amount exceeds balance")
+	ErrInvalidInput          = errors.New("invalid input")
+)
+
+type billingService struct {
+	catalogue     repositories.CatalogueRepository
+	invoices      repositories.InvoiceRepository
+	encounters    repositories.EncounterRepository
+	patients      repositories.PatientRepository
+	prescriptions repositories.PrescriptionRepository
+	config        config.BillingConfig
+}
+
+func NewBillingService(catalogue repositories.CatalogueRepository, invoices repositories.InvoiceRepository,
+	encounters repositories.EncounterRepository, patients repositories.PatientRepository,
+	prescriptions repositories.PrescriptionRepository, billingConfig config.BillingConfig) *billingService {
+	return &billingService{
+		catalogue:     catalogue,
+		invoices:      invoices,
+		encounters:    encounters,
+		patients:      patients,
+		prescriptions: prescriptions,
+		config:        billingConfig,
+	}
+}
+
+func (s *billingService) CreateTaxRate(request dto.TaxRateRequest) (*dto.TaxRateResponse, error) {
+	if request.Name == "" {
+		return nil, fmt.Errorf("%w: tax rate name is required", ErrInvalidInput)
+	}
+	if request.Rate < 0 || request.Rate > 10000 {
+		return nil, fmt.Errorf("%w: rate must be between 0 and 10000 basis points", ErrInvalidInput)
+	}
+	rate, err := s.catalogue.CreateTaxRate(request.ToModel())
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToTaxRateResponse(*rate)
+	return &response, nil
+}
+
+func (s *billingService) ListTaxRates() ([]dto.TaxRateResponse, error) {
+	rates, err := s.catalogue.GetAllTaxRates()
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToTaxRateResponses(rates), nil
+}
+
+func (s *billingService) CreateCatalogueItem(request dto.CatalogueItemRequest) (*dto.CatalogueItemResponse, error) {
+	if err := s.validateCatalogueItem(request); err != nil {
+		return nil, err
+	}
+	item, err := s.catalogue.CreateCatalogueItem(request.ToModel())
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToCatalogueItemResponse(*item)
+	return &response, nil
+}
+
+// UpdateCatalogueItem replaces the item. Price changes only affect invoices
+// generated afterwards.
+func (s *billingService) UpdateCatalogueItem(id int, request dto.CatalogueItemRequest) (*dto.CatalogueItemResponse, error) {
+	if err := s.validateCatalogueItem(request); err != nil {
+		return nil, err
+	}
+	item := request.ToModel()
+	item.ID = id
+	updated, err := s.catalogue.UpdateCatalogueItem(item)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrCatalogueItemNotFound
+	}
+	response := dto.ToCatalogueItemResponse(*updated)
+	return &response, nil
+}
+
+func (s *billingService) ListCatalogue(includeInactive bool) ([]dto.CatalogueItemResponse, error) {
+	items, err := s.catalogue.GetAllCatalogueItems(includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToCatalogueItemResponses(items), nil
+}
+
+// AddEncounterCharge bills a catalogue service to an encounter that has not
+// been invoiced yet.
+func (s *billingService) AddEncounterCharge(actorID, encounterID uuid.UUID, request dto.EncounterChargeRequest) (*dto.EncounterChargeResponse, error) {
+	if request.Quantity == 0 {
+		request.Quantity = 1
+	}
+	if request.Quantity < 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidInput)
+	}
+	encounter, err := s.getBillableEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.catalogue.GetCatalogueItemByID(request.CatalogueItemID)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || !item.Active {
+		return nil, ErrCatalogueItemNotFound
+	}
+	if item.Kind != model.CatalogueKindService {
+		return nil, fmt.Errorf("%w: medications are billed through prescriptions", ErrInvalidInput)
+	}
+
+	charge, err := s.invoices.CreateEncounterCharge(model.EncounterCharge{
+		EncounterID:     encounter.ID,
+		CatalogueItemID: item.ID,
+		Quantity:        request.Quantity,
+		AddedBy:         actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToEncounterChargeResponse(*charge)
+	return &response, nil
+}
+
+// GenerateInvoice issues the invoice for a discharged encounter from the
+// services charged to it and its prescriptions that link to a catalogue
+// medication. Prices and tax rates are taken from the catalogue now and
+// copied onto the invoice lines.
+func (s *billingService) GenerateInvoice(actorID, encounterID uuid.UUID) (*dto.InvoiceResponse, error) {
+	encounter, err := s.getBillableEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.Status != model.EncounterStatusDischarged {
+		return nil, fmt.Errorf("%w: patient has not been discharged", ErrNotBillable)
+	}
+
+	charges, err := s.invoices.GetEncounterChargesByEncounterID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	prescriptions, err := s.prescriptions.GetPrescriptionsByEncounterID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	pricer := newPricer(s.catalogue, model.BasisPoints(s.config.DefaultTaxRate))
+	var lines []model.InvoiceLine
+	for _, charge := range charges {
+		line, err := pricer.line(charge.CatalogueItemID, charge.Quantity, "")
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, *line)
+	}
+	for _, prescription := range prescriptions {
+		if prescription.CatalogueItemID == nil {
+			continue
+		}
+		description := strings.TrimSpace(prescription.Medication + " " + prescription.Dosage)
+		line, err := pricer.line(*prescription.CatalogueItemID, 1, description)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, *line)
+	}
+	if len(lines) == 0 {
+		return nil, ErrNothingToInvoice
+	}
+
+	invoice := model.Invoice{
+		ID:          uuid.New(),
+		PatientID:   encounter.PatientID,
+		EncounterID: uuid.NullUUID{UUID: encounter.ID, Valid: true},
+		Status:      model.InvoiceStatusIssued,
+		Currency:    s.config.Currency,
+		IssuedBy:    actorID,
+		Lines:       lines,
+	}
+	for _, line := range lines {
+		invoice.Subtotal += line.LineTotal - line.TaxAmount
+		invoice.TaxTotal += line.TaxAmount
+	}
+	invoice.Total = invoice.Subtotal + invoice.TaxTotal
+	if invoice.Total == 0 {
+		invoice.Status = model.InvoiceStatusPaid
+	}
+
+	created, err := s.invoices.CreateInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToInvoiceResponse(*created)
+	return &response, nil
+}
+
+// GetInvoice returns the invoice with its lines, payments and refunds.
+func (s *billingService) GetInvoice(invoiceID uuid.UUID) (*dto.InvoiceResponse, error) {
+	invoice, err := s.invoices.GetInvoiceByID(invoiceID.String())
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, ErrInvoiceNotFound
+	}
+	payments, err := s.invoices.GetPaymentsByInvoiceID(invoiceID.String())
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.invoices.GetRefundsByInvoiceID(invoiceID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	response := dto.ToInvoiceResponse(*invoice)
+	for _, payment := range payments {
+		response.Payments = append(response.Payments, dto.ToPaymentResponse(payment))
+	}
+	for _, refund := range refunds {
+		response.Refunds = append(response.Refunds, dto.ToRefundResponse(refund))
+	}
+	return &response, nil
+}
+
+func (s *billingService) ListPatientInvoices(patientID uuid.UUID) ([]dto.InvoiceResponse, error) {
+	if err := s.checkPatient(patientID); err != nil {
+		return nil, err
+	}
+	invoices, err := s.invoices.GetInvoicesByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToInvoiceResponses(invoices), nil
+}
+
+// GetPatientBalance returns what the patient owes across all unpaid invoices.
+func (s *billingService) GetPatientBalance(patientID uuid.UUID) (*dto.PatientBalanceResponse, error) {
+	if err := s.checkPatient(patientID); err != nil {
+		return nil, err
+	}
+	invoices, err := s.invoices.GetOutstandingInvoicesByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	response := dto.PatientBalanceResponse{
+		PatientID: patientID,
+		Currency:  s.config.Currency,
+		Invoices:  dto.ToInvoiceResponses(invoices),
+	}
+	for _, invoice := range invoices {
+		response.Outstanding += invoice.Outstanding()
+	}
+	return &response, nil
+}
+
+// RecordPayment takes a full or partial payment against the invoice and
+// returns the updated invoice.
+func (s *billingService) RecordPayment(actorID, invoiceID uuid.UUID, request dto.PaymentRequest) (*dto.InvoiceResponse, error) {
+	if request.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	switch request.Method {
+	case model.PaymentMethodCash, model.PaymentMethodCard, model.PaymentMethodUPI,
+		model.PaymentMethodBankTransfer, model.PaymentMethodInsurance:
+	default:
+		return nil, fmt.Errorf("%w: unknown payment method %q", ErrInvalidInput, request.Method)
+	}
+	invoice, err := s.invoices.GetInvoiceByID(invoiceID.String())
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, ErrInvoiceNotFound
+	}
+
+	_, err = s.invoices.CreatePayment(model.Payment{
+		InvoiceID:  invoiceID,
+		Amount:     request.Amount,
+		Method:     request.Method,
+		Reference:  request.Reference,
+		ReceivedBy: actorID,
+	})
+	if errors.Is(err, repositories.ErrAmountExceedsBalance) {
+		return nil, fmt.Errorf("%w: %s is outstanding", ErrAmountExceedsBalance, invoice.Outstanding())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetInvoice(invoiceID)
+}
+
+// RefundPayment returns part or all of a payment and returns the updated invoice.
+func (s *billingService) RefundPayment(actorID uuid.UUID, paymentID int, request dto.RefundRequest) (*dto.InvoiceResponse, error) {
+	if request.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	if request.Reason == "" {
+		return nil, fmt.Errorf("%w: refund reason is required", ErrInvalidInput)
+	}
+	payment, err := s.invoices.GetPaymentByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	_, err = s.invoices.CreateRefund(model.Refund{
+		PaymentID:  paymentID,
+		Amount:     request.Amount,
+		Reason:     request.Reason,
+		RefundedBy: actorID,
+	})
+	if errors.Is(err, repositories.ErrAmountExceedsBalance) {
+		return nil, fmt.Errorf("%w: %s is refundable", ErrAmountExceedsBalance, payment.Amount-payment.Refunded)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetInvoice(payment.InvoiceID)
+}
+
+// getBillableEncounter returns the encounter if it can still take charges.
+func (s *billingService) getBillableEncounter(encounterID uuid.UUID) (*model.Encounter, error) {
+	encounter, err := s.encounters.GetEncounterByID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if encounter == nil {
+		return nil, ErrEncounterNotFound
+	}
+	if encounter.Status == model.EncounterStatusCancelled {
+		return nil, fmt.Errorf("%w: encounter was cancelled", ErrNotBillable)
+	}
+	invoice, err := s.invoices.GetInvoiceByEncounterID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if invoice != nil {
+		return nil, fmt.Errorf("%w as %s", ErrAlreadyInvoiced, invoice.InvoiceNumber)
+	}
+	return encounter, nil
+}
+
+func (s *billingService) checkPatient(patientID uuid.UUID) error {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return err
+	}
+	if patient == nil {
+		return ErrPatientNotFound
+	}
+	return nil
+}
+
+func (s *billingService) validateCatalogueItem(request dto.CatalogueItemRequest) error {
+	if request.Code == "" || request.Name == "" {
+		return fmt.Errorf("%w: code and name are required", ErrInvalidInput)
+	}
+	if request.Kind != model.CatalogueKindService && request.Kind != model.CatalogueKindMedication {
+		return fmt.Errorf("%w: kind must be %q or %q", ErrInvalidInput, model.CatalogueKindService, model.CatalogueKindMedication)
+	}
+	if request.UnitPrice < 0 {
+		return fmt.Errorf("%w: unit price cannot be negative", ErrInvalidInput)
+	}
+	if request.TaxRateID != nil {
+		rate, err := s.catalogue.GetTaxRateByID(*request.TaxRateID)
+		if err != nil {
+			return err
+		}
+		if rate == nil {
+			return ErrTaxRateNotFound
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package billing_service
+
+import (
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+)
+
+// pricer turns catalogue items into invoice lines, looking each item and tax
+// rate up once per invoice.
+type pricer struct {
+	catalogue      repositories.CatalogueRepository
+	defaultTaxRate model.BasisPoints
+	items          map[int]*model.CatalogueItem
+	rates          map[int]model.BasisPoints
+}
+
+func newPricer(catalogue repositories.CatalogueRepository, defaultTaxRate model.BasisPoints) *pricer {
+	return &pricer{
+		catalogue:      catalogue,
+		defaultTaxRate: defaultTaxRate,
+		items:          make(map[int]*model.CatalogueItem),
+		rates:          make(map[int]model.BasisPoints),
+	}
+}
+
+// line prices quantity units of the item. Tax is rounded per line. The item
+// name is used when description is empty.
+func (p *pricer) line(itemID, quantity int, description string) (*model.InvoiceLine, error) {
+	item, err := p.item(itemID)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := p.rate(item)
+	if err != nil {
+		return nil, err
+	}
+	if description == "" {
+		description = item.Name
+	}
+
+	net := item.UnitPrice * model.Money(quantity)
+	tax := rate.Of(net)
+	return &model.InvoiceLine{
+		CatalogueItemID: &item.ID,
+		Description:     description,
+		Quantity:        quantity,
+		UnitPrice:       item.UnitPrice,
+		TaxRate:         rate,
+		TaxAmount:       tax,
+		LineTotal:       net + tax,
+	}, nil
+}
+
+func (p *pricer) item(id int) (*model.CatalogueItem, error) {
+	if item, ok := p.items[id]; ok {
+		return item, nil
+	}
+	item, err := p.catalogue.GetCatalogueItemByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrCatalogueItemNotFound
+	}
+	p.items[id] = item
+	return item, nil
+}
+
+func (p *pricer) rate(item *model.CatalogueItem) (model.BasisPoints, error) {
+	if item.TaxRateID == nil {
+		return p.defaultTaxRate, nil
+	}
+	if rate, ok := p.rates[*item.TaxRateID]; ok {
+		return rate, nil
+	}
+	rate, err := p.catalogue.GetTaxRateByID(*item.TaxRateID)
+	if err != nil {
+		return 0, err
+	}
+	if rate == nil {
+		return 0, ErrTaxRateNotFound
+	}
+	p.rates[rate.ID] = rate.Rate
+	return rate.Rate, nil
+}
@@ -0,0 +1,112 @@
+package clinic_service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+// clinicCode is the short handle of a clinic, e.g. "north-branch".
+var clinicCode = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+var (
+	ErrClinicNotFound    = errors.New("clinic not found")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrMemberNotFound    = errors.New("user does not work at this clinic")
+	ErrClinicCodeTaken   = errors.New("clinic code is already in use")
+	ErrInvalidClinicData = errors.New("invalid clinic")
+)
+
+type clinicService struct {
+	clinics repositories.ClinicRepository
+}
+
+func NewClinicService(clinics repositories.ClinicRepository) *clinicService {
+	return &clinicService{clinics: clinics}
+}
+
+// CreateClinic also makes the creating admin a member, so they can switch to
+// the new clinic and add its staff.
+func (s *clinicService) CreateClinic(actorID uuid.UUID, request dto.CreateClinicRequest) (*dto.ClinicResponse, error) {
+	request.Name = strings.TrimSpace(request.Name)
+	request.Code = strings.ToLower(strings.TrimSpace(request.Code))
+	if request.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidClinicData)
+	}
+	if !clinicCode.MatchString(request.Code) {
+		return nil, fmt.Errorf("%w: code must be up to 32 lowercase letters, digits or hyphens", ErrInvalidClinicData)
+	}
+	existing, err := s.clinics.GetClinicByCode(request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrClinicCodeTaken
+	}
+
+	clinic := request.ToModel()
+	clinic.ID = uuid.New()
+	created, err := s.clinics.CreateClinic(clinic)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.clinics.AddMember(actorID.String(), created.ID.String(), false); err != nil {
+		return nil, err
+	}
+	response := dto.ToClinicResponse(*created)
+	return &response, nil
+}
+
+func (s *clinicService) ListClinics() ([]dto.ClinicResponse, error) {
+	clinics, err := s.clinics.GetAllClinics()
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToClinicResponses(clinics), nil
+}
+
+// AddMember lets a user work at the clinic, or changes whether it is their
+// default clinic if they already do.
+func (s *clinicService) AddMember(clinicID uuid.UUID, request dto.AddClinicMemberRequest) error {
+	if request.UserID == uuid.Nil {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidClinicData)
+	}
+	if err := s.requireClinic(clinicID); err != nil {
+		return err
+	}
+	added, err := s.clinics.AddMember(request.UserID.String(), clinicID.String(), request.IsDefault)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *clinicService) RemoveMember(clinicID, userID uuid.UUID) error {
+	removed, err := s.clinics.RemoveMember(userID.String(), clinicID.String())
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+func (s *clinicService) requireClinic(clinicID uuid.UUID) error {
+	clinic, err := s.clinics.GetClinicByID(clinicID.String())
+	if err != nil {
+		return err
+	}
+	if clinic == nil {
+		return ErrClinicNotFound
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package consent_service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+// scopes lists every consent scope in the order a patient's status is shown.
+var scopes = []string{model.ConsentScopeTreatment, model.ConsentScopeDataSharing, model.ConsentScopeSMSReminders}
+
+var (
+	ErrPatientNotFound     = errors.New("patient not found")
+	ErrConsentTextNotFound = errors.New("consent text not found")
+	ErrConsentNotFound     = errors.New("patient has not consented")
+	ErrInvalidConsent      = errors.New("invalid consent")
+)
+
+type consentService struct {
+	consents repositories.ConsentRepository
+	patients repositories.PatientRepository
+}
+
+func NewConsentService(consents repositories.ConsentRepository, patients repositories.PatientRepository) *consentService {
+	return &consentService{
+		consents: consents,
+		patients: patients,
+	}
+}
+
+func validScope(scope string) error {
+	for _, s := range scopes {
+		if scope == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: scope must be one of %s", ErrInvalidConsent, strings.Join(scopes, ", "))
+}
+
+// PublishConsentText adds new wording for a scope as its next version.
+// Patients who consented to an earlier version keep that consent.
+func (s *consentService) PublishConsentText(actorID uuid.UUID, request dto.ConsentTextRequest) (*dto.ConsentTextResponse, error) {
+	if err := validScope(request.Scope); err != nil {
+		return nil, err
+	}
+	request.Text = strings.TrimSpace(request.Text)
+	if request.Text == "" {
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidConsent)
+	}
+	text, err := s.consents.CreateConsentText(model.ConsentText{Scope: request.Scope, Text: request.Text, CreatedBy: actorID})
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToConsentTextResponse(*text)
+	return &response, nil
+}
+
+func (s *consentService) ListConsentTexts(scope string) ([]dto.ConsentTextResponse, error) {
+	if scope != "" {
+		if err := validScope(scope); err != nil {
+			return nil, err
+		}
+	}
+	texts, err := s.consents.GetConsentTexts(scope)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToConsentTextResponses(texts), nil
+}
+
+// GetPatientConsents returns the patient's current consent for every scope
+// along with the full history of grants and withdrawals.
+func (s *consentService) GetPatientConsents(patientID uuid.UUID) (*dto.PatientConsentsResponse, error) {
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	history, err := s.consents.GetConsentsByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.PatientConsentsResponse{
+		PatientID: patientID,
+		History:   dto.ToPatientConsentResponses(history),
+	}
+	for _, scope := range scopes {
+		status := dto.ConsentStatusResponse{Scope: scope}
+		// History is newest first, so the first grant still held is current.
+		for _, consent := range history {
+			if consent.Scope == scope && consent.WithdrawnAt == nil {
+				current := dto.ToPatientConsentResponse(consent)
+				status.Granted = true
+				status.Consent = &current
+				break
+			}
+		}
+		latest, err := s.consents.GetLatestConsentText(scope)
+		if err != nil {
+			return nil, err
+		}
+		if latest != nil {
+			status.LatestVersion = latest.Version
+		}
+		response.Status = append(response.Status, status)
+	}
+	return response, nil
+}
+
+// GrantConsent records the patient consenting to the requested version of
+// the scope's text, the latest one if no version is given.
+func (s *consentService) GrantConsent(actorID, patientID uuid.UUID, request dto.GrantConsentRequest) (*dto.PatientConsentResponse, error) {
+	if err := validScope(request.Scope); err != nil {
+		return nil, err
+	}
+	if request.Version < 0 {
+		return nil, fmt.Errorf("%w: version must be positive", ErrInvalidConsent)
+	}
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+
+	var text *model.ConsentText
+	var err error
+	if request.Version == 0 {
+		text, err = s.consents.GetLatestConsentText(request.Scope)
+	} else {
+		text, err = s.consents.GetConsentText(request.Scope, request.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if text == nil {
+		return nil, fmt.Errorf("%w: no %s consent text has been published", ErrConsentTextNotFound, request.Scope)
+	}
+
+	consent, err := s.consents.GrantConsent(patientID.String(), text.ID, actorID.String())
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToPatientConsentResponse(*consent)
+	return &response, nil
+}
+
+func (s *consentService) WithdrawConsent(actorID, patientID uuid.UUID, scope string) (*dto.PatientConsentResponse, error) {
+	if err := validScope(scope); err != nil {
+		return nil, err
+	}
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	consent, err := s.consents.WithdrawConsent(patientID.String(), scope, actorID.String())
+	if err != nil {
+		return nil, err
+	}
+	if consent == nil {
+		return nil, fmt.Errorf("%w to %s", ErrConsentNotFound, scope)
+	}
+	response := dto.ToPatientConsentResponse(*consent)
+	return &response, nil
+}
+
+func (s *consentService) requirePatient(patientID uuid.UUID) error {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return err
+	}
+	if patient == nil {
+		return ErrPatientNotFound
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package document_service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/pdf"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEncounterNotFound = errors.New("encounter not found")
+	ErrInvoiceNotFound   = errors.New("invoice not found")
+	ErrPatientNotFound   = errors.New("patient not found")
+	ErrDoctorNotFound    = errors.New("doctor not found")
+	ErrNoPrescriptions   = errors.New("encounter has no prescriptions")
+)
+
+type documentService struct {
+	encounters    repositories.EncounterRepository
+	patients      repositories.PatientRepository
+	users         repositories.UserRepository
+	diagnoses     repositories.DiagnosisRepository
+	vitals        repositories.VitalsRepository
+	prescriptions repositories.PrescriptionRepository
+	invoices      repositories.InvoiceRepository
+	clinic        config.ClinicConfig
+}
+
+func NewDocumentService(encounters repositories.EncounterRepository, patients repositories.PatientRepository,
+	users repositories.UserRepository, diagnoses repositories.DiagnosisRepository, vitals repositories.VitalsRepository,
+	prescriptions repositories.PrescriptionRepository, invoices repositories.InvoiceRepository,
+	clinic config.ClinicConfig) *documentService {
+	return &documentService{
+		encounters:    encounters,
+		patients:      patients,
+		users:         users,
+		diagnoses:     diagnoses,
+		vitals:        vitals,
+		prescriptions: prescriptions,
+		invoices:      invoices,
+		clinic:        clinic,
+	}
+}
+
+// VisitSummaryPDF renders the diagnoses, vitals, prescriptions and discharge
+// notes of an encounter.
+func (s *documentService) VisitSummaryPDF(encounterID uuid.UUID) (*dto.DocumentFile, error) {
+	data, err := s.loadVisit(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	if data.Diagnoses, err = s.diagnoses.GetDiagnosesByEncounterID(encounterID.String()); err != nil {
+		return nil, err
+	}
+	if data.Vitals, err = s.vitals.GetVitalsByEncounterID(encounterID.String()); err != nil {
+		return nil, err
+	}
+	if data.Prescriptions, err = s.prescriptions.GetPrescriptionsByEncounterID(encounterID.String()); err != nil {
+		return nil, err
+	}
+	return s.render("Visit summary", data.Encounter.CheckedInAt,
+		fmt.Sprintf("visit-summary-%s.pdf", encounterID), data.Letterhead, func(flow *pdf.Flow) {
+			visitSummaryTemplate(flow, *data)
+		})
+}
+
+// PrescriptionPDF renders the prescriptions written during an encounter.
+func (s *documentService) PrescriptionPDF(encounterID uuid.UUID) (*dto.DocumentFile, error) {
+	data, err := s.loadVisit(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	if data.Prescriptions, err = s.prescriptions.GetPrescriptionsByEncounterID(encounterID.String()); err != nil {
+		return nil, err
+	}
+	if len(data.Prescriptions) == 0 {
+		return nil, ErrNoPrescriptions
+	}
+	return s.render("Prescription", data.Encounter.CheckedInAt,
+		fmt.Sprintf("prescription-%s.pdf", encounterID), data.Letterhead, func(flow *pdf.Flow) {
+			prescriptionTemplate(flow, *data)
+		})
+}
+
+// InvoicePDF renders an invoice with its lines and the payments received.
+func (s *documentService) InvoicePDF(invoiceID uuid.UUID) (*dto.DocumentFile, error) {
+	invoice, err := s.invoices.GetInvoiceByID(invoiceID.String())
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, ErrInvoiceNotFound
+	}
+	patient, err := s.getPatient(invoice.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.invoices.GetPaymentsByInvoiceID(invoiceID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	data := invoiceData{
+		Letterhead: letterhead{Clinic: s.clinic},
+		Patient:    *patient,
+		Invoice:    *invoice,
+		Payments:   payments,
+	}
+	if invoice.EncounterID.Valid {
+		encounter, err := s.encounters.GetEncounterByID(invoice.EncounterID.UUID.String())
+		if err != nil {
+			return nil, err
+		}
+		if encounter != nil {
+			if data.Letterhead.Doctor, err = s.getDoctor(encounter.DoctorID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.render("Invoice "+invoice.InvoiceNumber, invoice.IssuedAt,
+		fmt.Sprintf("invoice-%s.pdf", invoice.InvoiceNumber), data.Letterhead, func(flow *pdf.Flow) {
+			invoiceTemplate(flow, data)
+		})
+}
+
+// loadVisit loads the encounter with its patient and doctor.
+func (s *documentService) loadVisit(encounterID uuid.UUID) (*visitData, error) {
+	encounter, err := s.encounters.GetEncounterByID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if encounter == nil {
+		return nil, ErrEncounterNotFound
+	}
+	patient, err := s.getPatient(encounter.PatientID)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := s.getDoctor(encounter.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	return &visitData{
+		Letterhead: letterhead{Clinic: s.clinic, Doctor: doctor},
+		Patient:    *patient,
+		Encounter:  *encounter,
+	}, nil
+}
+
+func (s *documentService) getPatient(patientID uuid.UUID) (*model.Patient, error) {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+	return patient, nil
+}
+
+func (s *documentService) getDoctor(doctorID uuid.UUID) (*model.User, error) {
+	doctor, err := s.users.GetUserByID(doctorID.String())
+	if err != nil {
+		return nil, err
+	}
+	if doctor == nil {
+		return nil, ErrDoctorNotFound
+	}
+	return doctor, nil
+}
+
+func (s *documentService) render(title string, created time.Time, filename string, head letterhead,
+	body func(flow *pdf.Flow)) (*dto.DocumentFile, error) {
+	doc := pdf.NewDocument(title, created)
+	flow := pdf.NewFlow(doc, pageMargin)
+	letterheadTemplate(flow, head)
+	flow.Space(8)
+	flow.Centered(pdf.Bold, 14, title)
+	flow.Space(6)
+	body(flow)
+
+	var out bytes.Buffer
+	if _, err := doc.WriteTo(&out); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", filename, err)
+	}
+	return &dto.DocumentFile{
+		Filename:    filename,
+		ContentType: "application/pdf",
+		Content:     out.Bytes(),
+	}, nil
+}
@@ -0,0 +1,234 @@
+package document_service
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var (
+	patientID   = uuid.MustParse("6f1c2f4e-0a51-4c0e-9a57-3f1f6d3f0b01")
+	doctorID    = uuid.MustParse("0b7d8c5a-2e7e-4c8e-8f3b-8a4e5d6c7b02")
+	encounterID = uuid.MustParse("a3e4f5d6-7b8c-4d9e-8f0a-1b2c3d4e5f03")
+	invoiceID   = uuid.MustParse("c9d8e7f6-a5b4-4c3d-9e2f-1a0b9c8d7e04")
+	checkedIn   = time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC)
+	discharged  = time.Date(2024, time.March, 5, 10, 15, 0, 0, time.UTC)
+)
+
+// The fakes embed the repository interfaces and only implement what the
+// document service reads; anything else panics.
+type fakeEncounters struct {
+	repositories.EncounterRepository
+}
+
+func (fakeEncounters) GetEncounterByID(id string) (*model.Encounter, error) {
+	if id != encounterID.String() {
+		return nil, nil
+	}
+	return &model.Encounter{
+		ID:             encounterID,
+		PatientID:      patientID,
+		DoctorID:       doctorID,
+		ChiefComplaint: "Fever and sore throat for three days",
+		Status:         model.EncounterStatusDischarged,
+		CheckedInAt:    checkedIn,
+		DischargedAt:   &discharged,
+		DischargeNotes: "Rest, plenty of fluids. Return if the fever lasts beyond five days.",
+	}, nil
+}
+
+type fakePatients struct {
+	repositories.PatientRepository
+}
+
+func (fakePatients) GetPatientByID(id string) (*model.Patient, error) {
+	if id != patientID.String() {
+		return nil, nil
+	}
+	return &model.Patient{ID: patientID, Name: "Asha Verma", Age: 34, Gender: "female", PhoneNumber: "+919800000001"}, nil
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+}
+
+func (fakeUsers) GetUserByID(id string) (*model.User, error) {
+	if id != doctorID.String() {
+		return nil, nil
+	}
+	return &model.User{ID: doctorID, Name: "Rohan Mehta", Role: model.RoleDoctor, PhoneNumber: "+919800000002"}, nil
+}
+
+type fakeDiagnoses struct {
+	repositories.DiagnosisRepository
+}
+
+func (fakeDiagnoses) GetDiagnosesByEncounterID(string) ([]model.Diagnosis, error) {
+	return []model.Diagnosis{
+		{ID: 1, PatientID: patientID, DoctorID: doctorID, Description: "Acute pharyngitis"},
+	}, nil
+}
+
+type fakeVitals struct {
+	repositories.VitalsRepository
+}
+
+func (fakeVitals) GetVitalsByEncounterID(string) ([]model.Vitals, error) {
+	temperature, pulse, systolic, diastolic, saturation := 38.4, 96, 118, 76, 98
+	return []model.Vitals{{
+		ID: 1, EncounterID: encounterID, PatientID: patientID, TemperatureC: &temperature, PulseBPM: &pulse,
+		SystolicBP: &systolic, DiastolicBP: &diastolic, OxygenSaturation: &saturation,
+		RecordedAt: checkedIn.Add(10 * time.Minute),
+	}}, nil
+}
+
+type fakePrescriptions struct {
+	repositories.PrescriptionRepository
+}
+
+func (fakePrescriptions) GetPrescriptionsByEncounterID(string) ([]model.Prescription, error) {
+	return []model.Prescription{
+		{ID: 1, EncounterID: encounterID, PatientID: patientID, DoctorID: doctorID, Medication: "Paracetamol 500mg",
+			Dosage: "1 tablet", Frequency: "Every 6 hours", DurationDays: 3, Instructions: "After food, only if the fever is above 38 C"},
+		{ID: 2, EncounterID: encounterID, PatientID: patientID, DoctorID: doctorID, Medication: "Saline gargle",
+			Dosage: "1 glass", Frequency: "Twice a day", Instructions: "Warm"},
+	}, nil
+}
+
+type fakeInvoices struct {
+	repositories.InvoiceRepository
+}
+
+func (fakeInvoices) GetInvoiceByID(id string) (*model.Invoice, error) {
+	if id != invoiceID.String() {
+		return nil, nil
+	}
+	return &model.Invoice{
+		ID:            invoiceID,
+		InvoiceNumber: "INV-2024-000042",
+		PatientID:     patientID,
+		EncounterID:   uuid.NullUUID{UUID: encounterID, Valid: true},
+		Status:        model.InvoiceStatusPartiallyPaid,
+		Currency:      "INR",
+		Subtotal:      65000,
+		TaxTotal:      2700,
+		Total:         67700,
+		AmountPaid:    50000,
+		IssuedBy:      doctorID,
+		IssuedAt:      discharged,
+		Lines: []model.InvoiceLine{
+			{ID: 1, InvoiceID: invoiceID, Description: "Consultation", Quantity: 1, UnitPrice: 50000, LineTotal: 50000},
+			{ID: 2, InvoiceID: invoiceID, Description: "Paracetamol 500mg", Quantity: 12, UnitPrice: 1250,
+				TaxRate: 1800, TaxAmount: 2700, LineTotal: 17700},
+		},
+	}, nil
+}
+
+func (fakeInvoices) GetPaymentsByInvoiceID(string) ([]model.Payment, error) {
+	return []model.Payment{
+		{ID: 1, InvoiceID: invoiceID, Amount: 60000, Refunded: 10000, Method: model.PaymentMethodCash,
+			Reference: "Counter 1", ReceivedBy: doctorID, ReceivedAt: discharged.Add(5 * time.Minute)},
+	}, nil
+}
+
+func newTestService() *documentService {
+	return NewDocumentService(fakeEncounters{}, fakePatients{}, fakeUsers{}, fakeDiagnoses{}, fakeVitals{},
+		fakePrescriptions{}, fakeInvoices{}, config.ClinicConfig{
+			Name:    "Sunrise Family Clinic",
+			Address: "12 MG Road, Bengaluru 560001",
+			Phone:   "+918000000000",
+			Email:   "front-desk@sunrise.example",
+		})
+}
+
+func TestDocumentsMatchGoldenFiles(t *testing.T) {
+	service := newTestService()
+	tests := []struct {
+		golden   string
+		filename string
+		render   func() (*dto.DocumentFile, error)
+	}{
+		{"visit_summary.pdf.golden", "visit-summary-" + encounterID.String() + ".pdf", func() (*dto.DocumentFile, error) {
+			return service.VisitSummaryPDF(encounterID)
+		}},
+		{"prescription.pdf.golden", "prescription-" + encounterID.String() + ".pdf", func() (*dto.DocumentFile, error) {
+			return service.PrescriptionPDF(encounterID)
+		}},
+		{"invoice.pdf.golden", "invoice-INV-2024-000042.pdf", func() (*dto.DocumentFile, error) {
+			return service.InvoicePDF(invoiceID)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			file, err := test.render()
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			if file.Filename != test.filename {
+				t.Errorf("filename = %q, want %q", file.Filename, test.filename)
+			}
+			if file.ContentType != "application/pdf" {
+				t.Errorf("content type = %q, want application/pdf", file.ContentType)
+			}
+
+			path := filepath.Join("testdata", test.golden)
+			if *update {
+				if err := os.MkdirAll("testdata", 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, file.Content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(file.Content, want) {
+				t.Errorf("%s differs from the rendered document; if the change is intended, run go test -update", path)
+			}
+		})
+	}
+}
+
+func TestDocumentsAreDeterministic(t *testing.T) {
+	service := newTestService()
+	first, err := service.VisitSummaryPDF(encounterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := service.VisitSummaryPDF(encounterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Content, second.Content) {
+		t.Error("rendering the same visit twice gave different bytes")
+	}
+}
+
+func TestPrescriptionPDFWithoutPrescriptions(t *testing.T) {
+	service := newTestService()
+	service.prescriptions = noPrescriptions{}
+	if _, err := service.PrescriptionPDF(encounterID); err != ErrNoPrescriptions {
+		t.Errorf("err = %v, want %v", err, ErrNoPrescriptions)
+	}
+}
+
+type noPrescriptions struct {
+	repositories.PrescriptionRepository
+}
+
+func (noPrescriptions) GetPrescriptionsByEncounterID(string) ([]model.Prescription, error) {
+	return nil, nil
+}
@@ -0,0 +1,222 @@
+package document_service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/pdf"
+)
+
+// The templates below only depend on their data, never on the clock or the
+// database, so the same record always renders to the same bytes and can be
+// compared against golden files.
+
+const (
+	pageMargin = 50
+	textSize   = 10
+	dateFormat = "02 Jan 2006"
+	timeFormat = "02 Jan 2006 15:04"
+)
+
+type letterhead struct {
+	Clinic config.ClinicConfig
+	Doctor *model.User
+}
+
+type visitData struct {
+	Letterhead    letterhead
+	Patient       model.Patient
+	Encounter     model.Encounter
+	Diagnoses     []model.Diagnosis
+	Vitals        []model.Vitals
+	Prescriptions []model.Prescription
+}
+
+type invoiceData struct {
+	Letterhead letterhead
+	Patient    model.Patient
+	Invoice    model.Invoice
+	Payments   []model.Payment
+}
+
+var (
+	prescriptionColumns = []pdf.Column{{Width: 0.3}, {Width: 0.18}, {Width: 0.18}, {Width: 0.1, Align: pdf.Right},
+		{Width: 0.24}}
+	invoiceColumns = []pdf.Column{{Width: 0.4}, {Width: 0.1, Align: pdf.Right}, {Width: 0.15, Align: pdf.Right},
+		{Width: 0.15, Align: pdf.Right}, {Width: 0.2, Align: pdf.Right}}
+	totalColumns   = []pdf.Column{{Width: 0.8, Align: pdf.Right}, {Width: 0.2, Align: pdf.Right}}
+	paymentColumns = []pdf.Column{{Width: 0.3}, {Width: 0.2}, {Width: 0.3}, {Width: 0.2, Align: pdf.Right}}
+)
+
+func letterheadTemplate(flow *pdf.Flow, head letterhead) {
+	flow.Paragraph(pdf.Bold, 18, head.Clinic.Name)
+	if head.Clinic.Address != "" {
+		flow.Paragraph(pdf.Regular, textSize, head.Clinic.Address)
+	}
+	if contact := joinNonEmpty(" | ", head.Clinic.Phone, head.Clinic.Email); contact != "" {
+		flow.Paragraph(pdf.Regular, textSize, contact)
+	}
+	if head.Doctor != nil {
+		flow.Paragraph(pdf.Bold, 12, "Dr. "+head.Doctor.Name)
+		if head.Doctor.PhoneNumber != "" {
+			flow.Paragraph(pdf.Regular, textSize, "Phone: "+head.Doctor.PhoneNumber)
+		}
+	}
+	flow.Rule()
+}
+
+func patientTemplate(flow *pdf.Flow, patient model.Patient) {
+	flow.Field(textSize, "Patient:", patient.Name)
+	flow.Field(textSize, "Age / Gender:", fmt.Sprintf("%d / %s", patient.Age, patient.Gender))
+	if patient.PhoneNumber != "" {
+		flow.Field(textSize, "Phone:", patient.PhoneNumber)
+	}
+}
+
+func visitSummaryTemplate(flow *pdf.Flow, data visitData) {
+	patientTemplate(flow, data.Patient)
+	flow.Field(textSize, "Visit date:", data.Encounter.CheckedInAt.Format(timeFormat))
+	if data.Encounter.DischargedAt != nil {
+		flow.Field(textSize, "Discharged:", data.Encounter.DischargedAt.Format(timeFormat))
+	}
+	if data.Encounter.ChiefComplaint != "" {
+		flow.Field(textSize, "Chief complaint:", data.Encounter.ChiefComplaint)
+	}
+
+	section(flow, "Vitals")
+	if len(data.Vitals) == 0 {
+		flow.Paragraph(pdf.Regular, textSize, "None recorded.")
+	}
+	for _, vitals := range data.Vitals {
+		flow.Paragraph(pdf.Regular, textSize, vitals.RecordedAt.Format(timeFormat)+": "+formatVitals(vitals))
+	}
+
+	section(flow, "Diagnoses")
+	if len(data.Diagnoses) == 0 {
+		flow.Paragraph(pdf.Regular, textSize, "None recorded.")
+	}
+	for _, diagnosis := range data.Diagnoses {
+		flow.Paragraph(pdf.Regular, textSize, "- "+diagnosis.Description)
+	}
+
+	section(flow, "Prescriptions")
+	if len(data.Prescriptions) == 0 {
+		flow.Paragraph(pdf.Regular, textSize, "None.")
+	} else {
+		prescriptionTable(flow, data.Prescriptions)
+	}
+
+	if data.Encounter.DischargeNotes != "" {
+		section(flow, "Discharge notes")
+		flow.Paragraph(pdf.Regular, textSize, data.Encounter.DischargeNotes)
+	}
+	signatureTemplate(flow, data.Letterhead)
+}
+
+func prescriptionTemplate(flow *pdf.Flow, data visitData) {
+	patientTemplate(flow, data.Patient)
+	flow.Field(textSize, "Date:", data.Encounter.CheckedInAt.Format(dateFormat))
+	section(flow, "Rx")
+	prescriptionTable(flow, data.Prescriptions)
+	signatureTemplate(flow, data.Letterhead)
+}
+
+func invoiceTemplate(flow *pdf.Flow, data invoiceData) {
+	invoice := data.Invoice
+	flow.Field(textSize, "Invoice number:", invoice.InvoiceNumber)
+	flow.Field(textSize, "Date:", invoice.IssuedAt.Format(dateFormat))
+	patientTemplate(flow, data.Patient)
+	flow.Space(8)
+
+	flow.Row(pdf.Bold, textSize, invoiceColumns, []string{"Item", "Qty", "Unit price", "Tax", "Amount"})
+	flow.Rule()
+	for _, line := range invoice.Lines {
+		flow.Row(pdf.Regular, textSize, invoiceColumns, []string{line.Description, strconv.Itoa(line.Quantity),
+			line.UnitPrice.String(), line.TaxAmount.String(), line.LineTotal.String()})
+	}
+	flow.Rule()
+	flow.Row(pdf.Regular, textSize, totalColumns, []string{"Subtotal", invoice.Subtotal.String()})
+	flow.Row(pdf.Regular, textSize, totalColumns, []string{"Tax", invoice.TaxTotal.String()})
+	flow.Row(pdf.Bold, textSize, totalColumns, []string{"Total (" + invoice.Currency + ")", invoice.Total.String()})
+	flow.Row(pdf.Regular, textSize, totalColumns, []string{"Paid", invoice.AmountPaid.String()})
+	flow.Row(pdf.Bold, textSize, totalColumns, []string{"Balance due", invoice.Outstanding().String()})
+
+	if len(data.Payments) > 0 {
+		section(flow, "Payments")
+		for _, payment := range data.Payments {
+			amount := payment.Amount.String()
+			if payment.Refunded > 0 {
+				amount += " (refunded " + payment.Refunded.String() + ")"
+			}
+			flow.Row(pdf.Regular, textSize, paymentColumns, []string{payment.ReceivedAt.Format(dateFormat),
+				payment.Method, payment.Reference, amount})
+		}
+	}
+}
+
+func prescriptionTable(flow *pdf.Flow, prescriptions []model.Prescription) {
+	flow.Row(pdf.Bold, textSize, prescriptionColumns, []string{"Medication", "Dosage", "Frequency", "Days", "Instructions"})
+	flow.Rule()
+	for _, prescription := range prescriptions {
+		days := ""
+		if prescription.DurationDays > 0 {
+			days = strconv.Itoa(prescription.DurationDays)
+		}
+		flow.Row(pdf.Regular, textSize, prescriptionColumns, []string{prescription.Medication, prescription.Dosage,
+			prescription.Frequency, days, prescription.Instructions})
+	}
+}
+
+func signatureTemplate(flow *pdf.Flow, head letterhead) {
+	if head.Doctor == nil {
+		return
+	}
+	flow.Space(40)
+	flow.Paragraph(pdf.Regular, textSize, "______________________________")
+	flow.Paragraph(pdf.Bold, textSize, "Dr. "+head.Doctor.Name)
+}
+
+func section(flow *pdf.Flow, title string) {
+	flow.Space(8)
+	flow.Paragraph(pdf.Bold, 12, title)
+}
+
+// formatVitals lists the measured values, skipping the ones not taken.
+func formatVitals(vitals model.Vitals) string {
+	var parts []string
+	if vitals.TemperatureC != nil {
+		parts = append(parts, fmt.Sprintf("Temp %.1f C", *vitals.TemperatureC))
+	}
+	if vitals.PulseBPM != nil {
+		parts = append(parts, fmt.Sprintf("Pulse %d bpm", *vitals.PulseBPM))
+	}
+	if vitals.SystolicBP != nil && vitals.DiastolicBP != nil {
+		parts = append(parts, fmt.Sprintf("BP %d/%d mmHg", *vitals.SystolicBP, *vitals.DiastolicBP))
+	}
+	if vitals.RespiratoryRate != nil {
+		parts = append(parts, fmt.Sprintf("RR %d/min", *vitals.RespiratoryRate))
+	}
+	if vitals.OxygenSaturation != nil {
+		parts = append(parts, fmt.Sprintf("SpO2 %d%%", *vitals.OxygenSaturation))
+	}
+	if vitals.WeightKg != nil {
+		parts = append(parts, fmt.Sprintf("Weight %.1f kg", *vitals.WeightKg))
+	}
+	if vitals.HeightCm != nil {
+		parts = append(parts, fmt.Sprintf("Height %.1f cm", *vitals.HeightCm))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, sep)
+}
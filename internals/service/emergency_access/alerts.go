@@ -0,0 +1,49 @@
+package emergency_access_service
+
+import (
+	"sync"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+)
+
+// alertBuffer is how many alerts a slow subscriber may fall behind by before
+// it misses some. Missed alerts are still in the review queue.
+const alertBuffer = 16
+
+// alerts fans break-the-glass events out to every subscribed admin.
+type alerts struct {
+	mu          sync.Mutex
+	subscribers map[chan dto.EmergencyAccessResponse]struct{}
+}
+
+func newAlerts() *alerts {
+	return &alerts{subscribers: map[chan dto.EmergencyAccessResponse]struct{}{}}
+}
+
+func (a *alerts) subscribe() (<-chan dto.EmergencyAccessResponse, func()) {
+	ch := make(chan dto.EmergencyAccessResponse, alertBuffer)
+	a.mu.Lock()
+	a.subscribers[ch] = struct{}{}
+	a.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			a.mu.Lock()
+			delete(a.subscribers, ch)
+			a.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (a *alerts) publish(alert dto.EmergencyAccessResponse) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for ch := range a.subscribers {
+		select {
+		case ch <- alert:
+		default:
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package emergency_access_service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPatientNotFound = errors.New("patient not found")
+	ErrAccessNotFound  = errors.New("emergency access not found")
+	ErrAlreadyReviewed = errors.New("emergency access was already reviewed")
+	ErrAlreadyTreating = errors.New("you are already treating this patient")
+	ErrInvalidReason   = errors.New("invalid emergency access reason")
+)
+
+type emergencyAccessService struct {
+	accesses        repositories.EmergencyAccessRepository
+	patients        repositories.PatientRepository
+	encounters      repositories.EncounterRepository
+	duration        time.Duration
+	minReasonLength int
+	alerts          *alerts
+}
+
+func NewEmergencyAccessService(accesses repositories.EmergencyAccessRepository, patients repositories.PatientRepository,
+	encounters repositories.EncounterRepository, accessConfig config.EmergencyAccessConfig) *emergencyAccessService {
+	return &emergencyAccessService{
+		accesses:        accesses,
+		patients:        patients,
+		encounters:      encounters,
+		duration:        accessConfig.Duration,
+		minReasonLength: accessConfig.MinReasonLength,
+		alerts:          newAlerts(),
+	}
+}
+
+// BreakGlass gives a doctor access to the records of a patient they are not
+// treating for the configured duration, and alerts the clinic's admins.
+func (s *emergencyAccessService) BreakGlass(doctorID, patientID uuid.UUID, request dto.BreakGlassRequest) (*dto.EmergencyAccessResponse, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if len([]rune(reason)) < s.minReasonLength {
+		return nil, fmt.Errorf("%w: reason must be at least %d characters", ErrInvalidReason, s.minReasonLength)
+	}
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+	treating, err := s.encounters.IsTreatingDoctor(doctorID.String(), patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if treating {
+		return nil, ErrAlreadyTreating
+	}
+
+	now := time.Now()
+	access, err := s.accesses.CreateAccess(model.EmergencyAccess{
+		DoctorID:  doctorID,
+		PatientID: patientID,
+		Reason:    reason,
+		GrantedAt: now,
+		ExpiresAt: now.Add(s.duration),
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToEmergencyAccessResponse(*access, now)
+	log.Printf("emergency access %d: doctor %s opened patient %s until %s: %s",
+		access.ID, doctorID, patientID, access.ExpiresAt.Format(time.RFC3339), reason)
+	s.alerts.publish(response)
+	return &response, nil
+}
+
+// AuthorizePatient reports whether the user may see the patient's records.
+// Only doctors are restricted, to the patients they treat; a doctor who
+// broke the glass is let in until the access expires, and every request is
+// logged against the access.
+func (s *emergencyAccessService) AuthorizePatient(userID uuid.UUID, role string, patientID uuid.UUID, action string) (bool, error) {
+	if role != model.RoleDoctor {
+		return true, nil
+	}
+	treating, err := s.encounters.IsTreatingDoctor(userID.String(), patientID.String())
+	if err != nil {
+		return false, err
+	}
+	if treating {
+		return true, nil
+	}
+	access, err := s.accesses.GetActiveAccess(userID.String(), patientID.String(), time.Now())
+	if err != nil {
+		return false, err
+	}
+	if access == nil {
+		return false, nil
+	}
+	if err := s.accesses.LogAccess(access.ID, action); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AuthorizeEncounter is AuthorizePatient for the patient of the encounter.
+// The doctor the encounter is assigned to always has access to it.
+func (s *emergencyAccessService) AuthorizeEncounter(userID uuid.UUID, role string, encounterID uuid.UUID, action string) (bool, error) {
+	if role != model.RoleDoctor {
+		return true, nil
+	}
+	encounter, err := s.encounters.GetEncounterByID(encounterID.String())
+	if err != nil {
+		return false, err
+	}
+	// A missing encounter is left for the handler to report.
+	if encounter == nil || encounter.DoctorID == userID {
+		return true, nil
+	}
+	return s.AuthorizePatient(userID, role, encounter.PatientID, action)
+}
+
+// ListEmergencyAccesses is the admins' review queue, oldest first. With
+// pendingOnly unset it includes accesses already reviewed.
+func (s *emergencyAccessService) ListEmergencyAccesses(pendingOnly bool) ([]dto.EmergencyAccessResponse, error) {
+	accesses, err := s.accesses.GetAccesses(pendingOnly)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToEmergencyAccessResponses(accesses, time.Now()), nil
+}
+
+func (s *emergencyAccessService) GetEmergencyAccess(id int64) (*dto.EmergencyAccessDetailResponse, error) {
+	access, err := s.accesses.GetAccessByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if access == nil {
+		return nil, ErrAccessNotFound
+	}
+	entries, err := s.accesses.GetAccessLog(id)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToEmergencyAccessDetailResponse(*access, entries, time.Now())
+	return &response, nil
+}
+
+// ReviewEmergencyAccess takes an access off the review queue. Reviewing does
+// not end an access that is still active.
+func (s *emergencyAccessService) ReviewEmergencyAccess(adminID uuid.UUID, id int64, request dto.ReviewEmergencyAccessRequest) (*dto.EmergencyAccessResponse, error) {
+	access, err := s.accesses.GetAccessByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if access == nil {
+		return nil, ErrAccessNotFound
+	}
+	if access.ReviewedAt != nil {
+		return nil, ErrAlreadyReviewed
+	}
+	reviewed, err := s.accesses.ReviewAccess(id, adminID.String(), strings.TrimSpace(request.Note))
+	if err != nil {
+		return nil, err
+	}
+	if reviewed == nil {
+		// Another admin reviewed it in the meantime.
+		return nil, ErrAlreadyReviewed
+	}
+	response := dto.ToEmergencyAccessResponse(*reviewed, time.Now())
+	return &response, nil
+}
+
+// SubscribeAlerts streams every emergency access as it is granted.
+func (s *emergencyAccessService) SubscribeAlerts() (<-chan dto.EmergencyAccessResponse, func()) {
+	return s.alerts.subscribe()
+}
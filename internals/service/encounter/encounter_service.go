@@ -0,0 +1,264 @@
+package encounter_service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEncounterNotFound = errors.New("encounter not found")
+	ErrEncounterClosed   = errors.New("encounter is already closed")
+	ErrPatientNotFound   = errors.New("patient not found")
+	ErrDoctorNotFound    = errors.New("doctor not found")
+	ErrNotAttending      = errors.New("only the attending doctor can do this")
+	ErrInvalidTransition = errors.New("invalid encounter status transition")
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrConsentRequired   = errors.New("patient has not consented to treatment")
+	ErrSelfAssigned      = errors.New("a doctor cannot open an encounter with themselves; ask reception or use emergency access")
+)
+
+type encounterService struct {
+	encounters    repositories.EncounterRepository
+	patients      repositories.PatientRepository
+	users         repositories.UserRepository
+	diagnoses     repositories.DiagnosisRepository
+	vitals        repositories.VitalsRepository
+	prescriptions repositories.PrescriptionRepository
+	consents      repositories.ConsentRepository
+}
+
+func NewEncounterService(encounters repositories.EncounterRepository, patients repositories.PatientRepository,
+	users repositories.UserRepository, diagnoses repositories.DiagnosisRepository, vitals repositories.VitalsRepository,
+	prescriptions repositories.PrescriptionRepository, consents repositories.ConsentRepository) *encounterService {
+	return &encounterService{
+		encounters:    encounters,
+		patients:      patients,
+		users:         users,
+		diagnoses:     diagnoses,
+		vitals:        vitals,
+		prescriptions: prescriptions,
+		consents:      consents,
+	}
+}
+
+// OpenEncounter checks a patient in for a visit with the given doctor. The
+// patient must have consented to treatment. A doctor cannot check a patient in
+// with themselves: the encounter would make them a treating doctor and grant
+// them the whole record without going through emergency access.
+func (s *encounterService) OpenEncounter(actorID uuid.UUID, request dto.OpenEncounterRequest) (*dto.EncounterResponse, error) {
+	if actorID == request.DoctorID {
+		return nil, ErrSelfAssigned
+	}
+	patient, err := s.patients.GetPatientByID(request.PatientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+	if err := s.requireTreatmentConsent(patient.ID); err != nil {
+		return nil, err
+	}
+	doctor, err := s.users.GetUserByID(request.DoctorID.String())
+	if err != nil {
+		return nil, err
+	}
+	if doctor == nil || doctor.Role != model.RoleDoctor {
+		return nil, ErrDoctorNotFound
+	}
+
+	encounter, err := s.encounters.CreateEncounter(model.Encounter{
+		ID:             uuid.New(),
+		PatientID:      patient.ID,
+		DoctorID:       doctor.ID,
+		ChiefComplaint: request.ChiefComplaint,
+		Status:         model.EncounterStatusCheckedIn,
+		CheckedInAt:    time.Now(),
+		CreatedBy:      actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToEncounterResponse(*encounter)
+	return &response, nil
+}
+
+// StartConsultation marks the moment the attending doctor sees the patient.
+// Consent is checked again since it may have been withdrawn since check-in.
+func (s *encounterService) StartConsultation(doctorID, encounterID uuid.UUID) (*dto.EncounterResponse, error) {
+	encounter, err := s.getOpenEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.DoctorID != doctorID {
+		return nil, ErrNotAttending
+	}
+	if encounter.Status != model.EncounterStatusCheckedIn {
+		return nil, fmt.Errorf("%w: cannot start a consultation for an encounter that is %s", ErrInvalidTransition, encounter.Status)
+	}
+	if err := s.requireTreatmentConsent(encounter.PatientID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	encounter.Status = model.EncounterStatusInConsultation
+	encounter.ConsultationStartedAt = &now
+	return s.update(*encounter)
+}
+
+// CloseEncounter discharges the patient and ends the visit.
+func (s *encounterService) CloseEncounter(encounterID uuid.UUID, request dto.CloseEncounterRequest) (*dto.EncounterResponse, error) {
+	encounter, err := s.getOpenEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	encounter.Status = model.EncounterStatusDischarged
+	encounter.DischargedAt = &now
+	encounter.DischargeNotes = request.DischargeNotes
+	return s.update(*encounter)
+}
+
+// CancelEncounter ends a visit that never reached the consultation.
+func (s *encounterService) CancelEncounter(encounterID uuid.UUID) (*dto.EncounterResponse, error) {
+	encounter, err := s.getOpenEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.Status != model.EncounterStatusCheckedIn {
+		return nil, fmt.Errorf("%w: an encounter in consultation must be closed, not cancelled", ErrInvalidTransition)
+	}
+	encounter.Status = model.EncounterStatusCancelled
+	return s.update(*encounter)
+}
+
+func (s *encounterService) GetEncounter(encounterID uuid.UUID) (*dto.EncounterDetailResponse, error) {
+	encounter, err := s.encounters.GetEncounterByID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if encounter == nil {
+		return nil, ErrEncounterNotFound
+	}
+	diagnoses, err := s.diagnoses.GetDiagnosesByEncounterID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	vitals, err := s.vitals.GetVitalsByEncounterID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	prescriptions, err := s.prescriptions.GetPrescriptionsByEncounterID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	detail := dto.ToEncounterDetailResponse(*encounter, diagnoses, vitals, prescriptions)
+	return &detail, nil
+}
+
+// ListOpenEncountersForDay returns the doctor's open encounters checked in on the day of the given time.
+func (s *encounterService) ListOpenEncountersForDay(doctorID uuid.UUID, day time.Time) ([]dto.EncounterResponse, error) {
+	startOfDay := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	encounters, err := s.encounters.GetOpenEncountersByDoctorID(doctorID.String(), startOfDay, startOfDay.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToEncounterResponses(encounters), nil
+}
+
+// RecordDiagnosis records a diagnosis made by the attending doctor.
+func (s *encounterService) RecordDiagnosis(doctorID, encounterID uuid.UUID, request dto.EncounterDiagnosisRequest) (*dto.DiagnosisResponse, error) {
+	if request.Description == "" {
+		return nil, fmt.Errorf("%w: diagnosis description is required", ErrInvalidInput)
+	}
+	encounter, err := s.getOpenEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.DoctorID != doctorID {
+		return nil, ErrNotAttending
+	}
+	diagnosis, err := s.diagnoses.CreateDiagnosis(model.Diagnosis{
+		PatientID:   encounter.PatientID,
+		DoctorID:    doctorID,
+		EncounterID: uuid.NullUUID{UUID: encounter.ID, Valid: true},
+		Description: request.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToDiagnosisResponse(*diagnosis)
+	return &response, nil
+}
+
+func (s *encounterService) RecordVitals(actorID, encounterID uuid.UUID, request dto.VitalsRequest) (*dto.VitalsResponse, error) {
+	encounter, err := s.getOpenEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	vitals, err := s.vitals.CreateVitals(request.ToModel(*encounter, actorID))
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToVitalsResponse(*vitals)
+	return &response, nil
+}
+
+// AddPrescription adds a prescription written by the attending doctor.
+func (s *encounterService) AddPrescription(doctorID, encounterID uuid.UUID, request dto.PrescriptionRequest) (*dto.PrescriptionResponse, error) {
+	if request.Medication == "" || request.Dosage == "" || request.Frequency == "" {
+		return nil, fmt.Errorf("%w: medication, dosage and frequency are required", ErrInvalidInput)
+	}
+	encounter, err := s.getOpenEncounter(encounterID)
+	if err != nil {
+		return nil, err
+	}
+	if encounter.DoctorID != doctorID {
+		return nil, ErrNotAttending
+	}
+	prescription, err := s.prescriptions.CreatePrescription(request.ToModel(*encounter, doctorID))
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToPrescriptionResponse(*prescription)
+	return &response, nil
+}
+
+func (s *encounterService) getOpenEncounter(encounterID uuid.UUID) (*model.Encounter, error) {
+	encounter, err := s.encounters.GetEncounterByID(encounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if encounter == nil {
+		return nil, ErrEncounterNotFound
+	}
+	if !encounter.IsOpen() {
+		return nil, ErrEncounterClosed
+	}
+	return encounter, nil
+}
+
+func (s *encounterService) requireTreatmentConsent(patientID uuid.UUID) error {
+	consent, err := s.consents.GetActiveConsent(patientID.String(), model.ConsentScopeTreatment)
+	if err != nil {
+		return err
+	}
+	if consent == nil {
+		return fmt.Errorf("%w: patient %s", ErrConsentRequired, patientID)
+	}
+	return nil
+}
+
+func (s *encounterService) update(encounter model.Encounter) (*dto.EncounterResponse, error) {
+	updated, err := s.encounters.UpdateEncounter(encounter)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToEncounterResponse(*updated)
+	return &response, nil
+}
@@ -0,0 +1,436 @@
+package fhir_service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/fhir"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound          = errors.New("resource not found")
+	ErrUnsupportedSearch = errors.New("unsupported search")
+	ErrConsentRequired   = errors.New("patient has not consented to sharing their data")
+	ErrNotRecorder       = errors.New("conditions can only be recorded in the caller's own name")
+	ErrNotAttending      = errors.New("only the attending doctor can do this")
+	ErrEncounterClosed   = errors.New("encounter is already closed")
+)
+
+type fhirService struct {
+	patients   repositories.PatientRepository
+	users      repositories.UserRepository
+	diagnoses  repositories.DiagnosisRepository
+	conditions repositories.ChronicConditionRepository
+	encounters repositories.EncounterRepository
+	consents   repositories.ConsentRepository
+	mapper     fhir.Mapper
+}
+
+func NewFHIRService(patients repositories.PatientRepository, users repositories.UserRepository,
+	diagnoses repositories.DiagnosisRepository, conditions repositories.ChronicConditionRepository,
+	encounters repositories.EncounterRepository, consents repositories.ConsentRepository,
+	fhirConfig config.FHIRConfig) *fhirService {
+	return &fhirService{
+		patients:   patients,
+		users:      users,
+		diagnoses:  diagnoses,
+		conditions: conditions,
+		encounters: encounters,
+		consents:   consents,
+		mapper:     fhir.NewMapper(fhirConfig.IdentifierSystem, fhirConfig.BaseURL),
+	}
+}
+
+func (s *fhirService) GetPatient(id uuid.UUID) (*fhir.Patient, error) {
+	patient, err := s.patients.GetPatientByID(id.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, fmt.Errorf("%w: Patient/%s", ErrNotFound, id)
+	}
+	resource := s.mapper.Patient(*patient)
+	return &resource, nil
+}
+
+// SearchPatients finds patients by identifier ("[system|]value"), phone or
+// name. Exactly one parameter must be given.
+func (s *fhirService) SearchPatients(identifier, phone, name string) (*fhir.Bundle, error) {
+	var patients []model.Patient
+	switch {
+	case identifier != "" && phone == "" && name == "":
+		id, err := s.parseIdentifier(identifier)
+		if err != nil {
+			return nil, err
+		}
+		patient, err := s.patients.GetPatientByID(id.String())
+		if err != nil {
+			return nil, err
+		}
+		if patient != nil {
+			patients = append(patients, *patient)
+		}
+	case phone != "" && identifier == "" && name == "":
+		found, err := s.patients.GetPatientsByContact(phone)
+		if err != nil {
+			return nil, err
+		}
+		patients = found
+	case name != "" && identifier == "" && phone == "":
+		found, err := s.patients.GetAllPatientsByName(name)
+		if err != nil {
+			return nil, err
+		}
+		patients = found
+	default:
+		return nil, fmt.Errorf("%w: search by exactly one of identifier, phone or name", ErrUnsupportedSearch)
+	}
+
+	bundle := newSearchBundle()
+	for _, patient := range patients {
+		s.addEntry(bundle, "Patient", patient.ID.String(), s.mapper.Patient(patient), "match")
+	}
+	return bundle, nil
+}
+
+// CreatePatient registers a patient sent by another system. Phone numbers
+// may be shared within a family, so a matching phone is not a conflict;
+// duplicates are left to the duplicate finder.
+func (s *fhirService) CreatePatient(resource fhir.Patient) (*fhir.Patient, error) {
+	patient, err := s.mapper.ToPatient(resource, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	patient.ID = uuid.New()
+	if err := s.patients.CreatePatient(patient); err != nil {
+		return nil, err
+	}
+	created := s.mapper.Patient(patient)
+	return &created, nil
+}
+
+func (s *fhirService) UpdatePatient(id uuid.UUID, resource fhir.Patient) (*fhir.Patient, error) {
+	patient, err := s.mapper.ToPatient(resource, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if patient.ID != uuid.Nil && patient.ID != id {
+		return nil, fmt.Errorf("%w: resource id does not match the URL", fhir.ErrInvalidResource)
+	}
+	existing, err := s.patients.GetPatientByID(id.String())
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("%w: Patient/%s", ErrNotFound, id)
+	}
+	patient.ID = id
+	updated, err := s.patients.UpdatePatient(patient)
+	if err != nil {
+		return nil, err
+	}
+	result := s.mapper.Patient(*updated)
+	return &result, nil
+}
+
+// GetPractitioner returns a doctor. Other staff are not exposed.
+func (s *fhirService) GetPractitioner(id uuid.UUID) (*fhir.Practitioner, error) {
+	user, err := s.users.GetUserByID(id.String())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Role != model.RoleDoctor {
+		return nil, fmt.Errorf("%w: Practitioner/%s", ErrNotFound, id)
+	}
+	resource := s.mapper.Practitioner(*user)
+	return &resource, nil
+}
+
+// SearchPractitioners finds doctors by identifier or by a case-insensitive
+// substring of their name.
+func (s *fhirService) SearchPractitioners(identifier, name string) (*fhir.Bundle, error) {
+	bundle := newSearchBundle()
+	switch {
+	case identifier != "" && name == "":
+		id, err := s.parseIdentifier(identifier)
+		if err != nil {
+			return nil, err
+		}
+		practitioner, err := s.GetPractitioner(id)
+		if errors.Is(err, ErrNotFound) {
+			return bundle, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.addEntry(bundle, "Practitioner", practitioner.ID, *practitioner, "match")
+	case name != "" && identifier == "":
+		doctors, err := s.users.GetAllUsersByRole(model.RoleDoctor)
+		if err != nil {
+			return nil, err
+		}
+		for _, doctor := range doctors {
+			if strings.Contains(strings.ToLower(doctor.Name), strings.ToLower(name)) {
+				s.addEntry(bundle, "Practitioner", doctor.ID.String(), s.mapper.Practitioner(doctor), "match")
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: search by exactly one of identifier or name", ErrUnsupportedSearch)
+	}
+	return bundle, nil
+}
+
+func (s *fhirService) GetCondition(id string) (*fhir.Condition, error) {
+	problem, number, err := fhir.ParseConditionID(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Condition/%s", ErrNotFound, id)
+	}
+	var resource fhir.Condition
+	if problem {
+		condition, err := s.conditions.GetChronicConditionByID(strconv.Itoa(number))
+		if err != nil {
+			return nil, err
+		}
+		if condition == nil {
+			return nil, fmt.Errorf("%w: Condition/%s", ErrNotFound, id)
+		}
+		resource = s.mapper.ProblemCondition(*condition)
+	} else {
+		diagnosis, err := s.diagnoses.GetDiagnosisByID(strconv.Itoa(number))
+		if err != nil {
+			return nil, err
+		}
+		if diagnosis == nil {
+			return nil, fmt.Errorf("%w: Condition/%s", ErrNotFound, id)
+		}
+		resource = s.mapper.DiagnosisCondition(*diagnosis)
+	}
+	return &resource, nil
+}
+
+// ConditionPatient returns the id of the patient a condition belongs to, or
+// ErrNotFound if there is no such condition.
+func (s *fhirService) ConditionPatient(id string) (uuid.UUID, error) {
+	problem, number, err := fhir.ParseConditionID(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: Condition/%s", ErrNotFound, id)
+	}
+	if problem {
+		condition, err := s.conditions.GetChronicConditionByID(strconv.Itoa(number))
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if condition == nil {
+			return uuid.Nil, fmt.Errorf("%w: Condition/%s", ErrNotFound, id)
+		}
+		return condition.PatientID, nil
+	}
+	diagnosis, err := s.diagnoses.GetDiagnosisByID(strconv.Itoa(number))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if diagnosis == nil {
+		return uuid.Nil, fmt.Errorf("%w: Condition/%s", ErrNotFound, id)
+	}
+	return diagnosis.PatientID, nil
+}
+
+// SearchConditions returns the patient's diagnoses and problem list.
+func (s *fhirService) SearchConditions(patientID uuid.UUID) (*fhir.Bundle, error) {
+	bundle := newSearchBundle()
+	conditions, err := s.patientConditions(patientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, condition := range conditions {
+		s.addEntry(bundle, "Condition", condition.ID, condition, "match")
+	}
+	return bundle, nil
+}
+
+// CreateCondition records an incoming Condition as a diagnosis by the calling
+// doctor. As with diagnoses recorded natively, the condition must name the
+// caller as recorder and belong to an open encounter they are attending.
+func (s *fhirService) CreateCondition(doctorID uuid.UUID, resource fhir.Condition) (*fhir.Condition, error) {
+	diagnosis, err := s.mapper.ToDiagnosis(resource)
+	if err != nil {
+		return nil, err
+	}
+	if diagnosis.DoctorID != doctorID {
+		return nil, fmt.Errorf("%w: recorder is Practitioner/%s", ErrNotRecorder, diagnosis.DoctorID)
+	}
+	if !diagnosis.EncounterID.Valid {
+		return nil, fmt.Errorf("%w: condition encounter is required", fhir.ErrInvalidResource)
+	}
+	encounter, err := s.encounters.GetEncounterByID(diagnosis.EncounterID.UUID.String())
+	if err != nil {
+		return nil, err
+	}
+	if encounter == nil || encounter.PatientID != diagnosis.PatientID {
+		return nil, fmt.Errorf("%w: Encounter/%s", ErrNotFound, diagnosis.EncounterID.UUID)
+	}
+	if !encounter.IsOpen() {
+		return nil, fmt.Errorf("%w: Encounter/%s", ErrEncounterClosed, encounter.ID)
+	}
+	if encounter.DoctorID != doctorID {
+		return nil, fmt.Errorf("%w: Encounter/%s", ErrNotAttending, encounter.ID)
+	}
+	created, err := s.diagnoses.CreateDiagnosis(diagnosis)
+	if err != nil {
+		return nil, err
+	}
+	result := s.mapper.DiagnosisCondition(*created)
+	return &result, nil
+}
+
+func (s *fhirService) GetEncounter(id uuid.UUID) (*fhir.Encounter, error) {
+	encounter, err := s.encounters.GetEncounterByID(id.String())
+	if err != nil {
+		return nil, err
+	}
+	if encounter == nil {
+		return nil, fmt.Errorf("%w: Encounter/%s", ErrNotFound, id)
+	}
+	resource := s.mapper.Encounter(*encounter)
+	return &resource, nil
+}
+
+func (s *fhirService) SearchEncounters(patientID uuid.UUID) (*fhir.Bundle, error) {
+	encounters, err := s.encounters.GetEncountersByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	bundle := newSearchBundle()
+	for _, encounter := range encounters {
+		s.addEntry(bundle, "Encounter", encounter.ID.String(), s.mapper.Encounter(encounter), "match")
+	}
+	return bundle, nil
+}
+
+// ExportPatient implements Patient/$everything: the patient, their
+// encounters and conditions, and the practitioners those refer to. The
+// record leaves the clinic, so the patient must have consented to sharing.
+func (s *fhirService) ExportPatient(id uuid.UUID) (*fhir.Bundle, error) {
+	patient, err := s.GetPatient(id)
+	if err != nil {
+		return nil, err
+	}
+	consent, err := s.consents.GetActiveConsent(id.String(), model.ConsentScopeDataSharing)
+	if err != nil {
+		return nil, err
+	}
+	if consent == nil {
+		return nil, fmt.Errorf("%w: Patient/%s", ErrConsentRequired, id)
+	}
+	encounters, err := s.encounters.GetEncountersByPatientID(id.String())
+	if err != nil {
+		return nil, err
+	}
+	conditions, err := s.patientConditions(id)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := newSearchBundle()
+	s.addEntry(bundle, "Patient", patient.ID, *patient, "match")
+
+	practitioners := make(map[uuid.UUID]bool)
+	addPractitioner := func(doctorID uuid.UUID) error {
+		if doctorID == uuid.Nil || practitioners[doctorID] {
+			return nil
+		}
+		practitioners[doctorID] = true
+		practitioner, err := s.GetPractitioner(doctorID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.addEntry(bundle, "Practitioner", practitioner.ID, *practitioner, "include")
+		return nil
+	}
+
+	for _, encounter := range encounters {
+		if err := addPractitioner(encounter.DoctorID); err != nil {
+			return nil, err
+		}
+		s.addEntry(bundle, "Encounter", encounter.ID.String(), s.mapper.Encounter(encounter), "include")
+	}
+	for _, condition := range conditions {
+		if condition.Recorder != nil {
+			doctorID, _ := fhir.ParseReference(*condition.Recorder, "Practitioner")
+			if err := addPractitioner(doctorID); err != nil {
+				return nil, err
+			}
+		}
+		s.addEntry(bundle, "Condition", condition.ID, condition, "include")
+	}
+	return bundle, nil
+}
+
+func (s *fhirService) patientConditions(patientID uuid.UUID) ([]fhir.Condition, error) {
+	diagnoses, err := s.diagnoses.GetDiagnosisByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	problems, err := s.conditions.GetChronicConditionsByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	conditions := make([]fhir.Condition, 0, len(diagnoses)+len(problems))
+	for _, problem := range problems {
+		conditions = append(conditions, s.mapper.ProblemCondition(problem))
+	}
+	for _, diagnosis := range diagnoses {
+		conditions = append(conditions, s.mapper.DiagnosisCondition(diagnosis))
+	}
+	return conditions, nil
+}
+
+// parseIdentifier accepts "value" or "system|value" where system, if given,
+// must be this system's identifier system.
+func (s *fhirService) parseIdentifier(identifier string) (uuid.UUID, error) {
+	system, value, found := strings.Cut(identifier, "|")
+	if !found {
+		value, system = system, ""
+	}
+	if system != "" && system != s.mapper.IdentifierSystem {
+		return uuid.Nil, fmt.Errorf("%w: unknown identifier system %q", ErrUnsupportedSearch, system)
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: invalid identifier %q", ErrUnsupportedSearch, value)
+	}
+	return id, nil
+}
+
+// addEntry appends a resource to a search bundle. Only matches count towards
+// the total; included resources do not.
+func (s *fhirService) addEntry(bundle *fhir.Bundle, resourceType, id string, resource any, mode string) {
+	bundle.Entry = append(bundle.Entry, fhir.BundleEntry{
+		FullURL:  s.mapper.FullURL(resourceType, id),
+		Resource: resource,
+		Search:   &fhir.BundleEntrySearch{Mode: mode},
+	})
+	if mode == "match" {
+		*bundle.Total++
+	}
+}
+
+func newSearchBundle() *fhir.Bundle {
+	total := 0
+	return &fhir.Bundle{
+		ResourceType: "Bundle",
+		Type:         "searchset",
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Total:        &total,
+	}
+}
@@ -0,0 +1,267 @@
+package fhir_service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/fhir"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	patientID   = uuid.MustParse("6f1c2f4e-0a51-4c0e-9a57-3f1f6d3f0b01")
+	doctorID    = uuid.MustParse("0b7d8c5a-2e7e-4c8e-8f3b-8a4e5d6c7b02")
+	encounterID = uuid.MustParse("a3e4f5d6-7b8c-4d9e-8f0a-1b2c3d4e5f03")
+	checkedIn   = time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC)
+
+	otherDoctorID     = uuid.MustParse("5e6f7a8b-9c0d-4e1f-8a2b-3c4d5e6f7a05")
+	closedEncounterID = uuid.MustParse("d4e5f6a7-b8c9-4dae-8f01-2a3b4c5d6e06")
+)
+
+const baseURL = "https://pms.example/fhir"
+
+// The fakes embed the repository interfaces and only implement what the
+// tests exercise; anything else panics.
+type fakePatients struct {
+	repositories.PatientRepository
+}
+
+func (fakePatients) GetPatientByID(id string) (*model.Patient, error) {
+	if id != patientID.String() {
+		return nil, nil
+	}
+	return &model.Patient{ID: patientID, Name: "Asha Verma", Age: 34, Gender: "female", PhoneNumber: "+919800000001"}, nil
+}
+
+type fakeUsers struct {
+	repositories.UserRepository
+}
+
+func (fakeUsers) GetUserByID(id string) (*model.User, error) {
+	if id != doctorID.String() {
+		return nil, nil
+	}
+	return &model.User{ID: doctorID, Name: "Rohan Mehta", Role: model.RoleDoctor}, nil
+}
+
+type fakeDiagnoses struct {
+	repositories.DiagnosisRepository
+}
+
+func (fakeDiagnoses) GetDiagnosisByPatientID(string) ([]model.Diagnosis, error) {
+	return []model.Diagnosis{{ID: 7, PatientID: patientID, DoctorID: doctorID,
+		EncounterID: uuid.NullUUID{UUID: encounterID, Valid: true}, Description: "Acute pharyngitis", CreatedAt: checkedIn}}, nil
+}
+
+type fakeConditions struct {
+	repositories.ChronicConditionRepository
+}
+
+func (fakeConditions) GetChronicConditionsByPatientID(string) ([]model.ChronicCondition, error) {
+	return []model.ChronicCondition{{ID: 3, PatientID: patientID, Name: "Type 2 diabetes", Status: "active",
+		RecordedBy: doctorID, CreatedAt: checkedIn}}, nil
+}
+
+type fakeEncounters struct {
+	repositories.EncounterRepository
+}
+
+func (fakeEncounters) GetEncountersByPatientID(string) ([]model.Encounter, error) {
+	return []model.Encounter{{ID: encounterID, PatientID: patientID, DoctorID: doctorID,
+		Status: model.EncounterStatusInConsultation, CheckedInAt: checkedIn}}, nil
+}
+
+type fakeConsents struct {
+	repositories.ConsentRepository
+	granted bool
+}
+
+func (c fakeConsents) GetActiveConsent(patientID, scope string) (*model.PatientConsent, error) {
+	if !c.granted || scope != model.ConsentScopeDataSharing {
+		return nil, nil
+	}
+	return &model.PatientConsent{ID: 1, PatientID: uuid.MustParse(patientID), Scope: scope}, nil
+}
+
+func newTestService(consented bool) *fhirService {
+	return NewFHIRService(fakePatients{}, fakeUsers{}, fakeDiagnoses{}, fakeConditions{}, fakeEncounters{},
+		fakeConsents{granted: consented}, config.FHIRConfig{IdentifierSystem: "urn:example:pms", BaseURL: baseURL})
+}
+
+// bundleJSON mirrors the wire format of a Bundle with the resources left as
+// raw JSON, as a receiving system would read it.
+type bundleJSON struct {
+	ResourceType string `json:"resourceType"`
+	Type         string `json:"type"`
+	Timestamp    string `json:"timestamp"`
+	Total        *int   `json:"total"`
+	Entry        []struct {
+		FullURL  string          `json:"fullUrl"`
+		Resource json.RawMessage `json:"resource"`
+		Search   struct {
+			Mode string `json:"mode"`
+		} `json:"search"`
+	} `json:"entry"`
+}
+
+func TestExportPatientBundle(t *testing.T) {
+	bundle, err := newTestService(true).ExportPatient(patientID)
+	if err != nil {
+		t.Fatalf("ExportPatient: %v", err)
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded bundleJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.ResourceType != "Bundle" || decoded.Type != "searchset" {
+		t.Errorf("bundle is a %s %s, want a Bundle searchset", decoded.ResourceType, decoded.Type)
+	}
+	if _, err := time.Parse(time.RFC3339, decoded.Timestamp); err != nil {
+		t.Errorf("timestamp %q: %v", decoded.Timestamp, err)
+	}
+	if decoded.Total == nil || *decoded.Total != 1 {
+		t.Errorf("total = %v, want 1: only the patient is a match", decoded.Total)
+	}
+
+	want := []struct {
+		resourceType string
+		id           string
+		mode         string
+	}{
+		{"Patient", patientID.String(), "match"},
+		{"Practitioner", doctorID.String(), "include"},
+		{"Encounter", encounterID.String(), "include"},
+		{"Condition", "problem-3", "include"},
+		{"Condition", "diagnosis-7", "include"},
+	}
+	if len(decoded.Entry) != len(want) {
+		t.Fatalf("bundle has %d entries, want %d: %s", len(decoded.Entry), len(want), data)
+	}
+	for i, entry := range decoded.Entry {
+		var resource struct {
+			ResourceType string `json:"resourceType"`
+			ID           string `json:"id"`
+		}
+		if err := json.Unmarshal(entry.Resource, &resource); err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		if resource.ResourceType != want[i].resourceType || resource.ID != want[i].id {
+			t.Errorf("entry %d is %s/%s, want %s/%s", i, resource.ResourceType, resource.ID, want[i].resourceType, want[i].id)
+		}
+		if fullURL := baseURL + "/" + want[i].resourceType + "/" + want[i].id; entry.FullURL != fullURL {
+			t.Errorf("entry %d fullUrl = %q, want %q", i, entry.FullURL, fullURL)
+		}
+		if entry.Search.Mode != want[i].mode {
+			t.Errorf("entry %d search mode = %q, want %q", i, entry.Search.Mode, want[i].mode)
+		}
+	}
+
+	// The entries decode back into the resources they were built from.
+	var patient fhir.Patient
+	if err := json.Unmarshal(decoded.Entry[0].Resource, &patient); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fhir.NewMapper("urn:example:pms", baseURL).ToPatient(patient, checkedIn); err != nil {
+		t.Errorf("exported patient does not map back: %v", err)
+	}
+	var encounter fhir.Encounter
+	if err := json.Unmarshal(decoded.Entry[2].Resource, &encounter); err != nil {
+		t.Fatal(err)
+	}
+	if encounter.Subject == nil || encounter.Subject.Reference != "Patient/"+patientID.String() {
+		t.Errorf("encounter subject = %+v, want Patient/%s", encounter.Subject, patientID)
+	}
+	var condition fhir.Condition
+	if err := json.Unmarshal(decoded.Entry[4].Resource, &condition); err != nil {
+		t.Fatal(err)
+	}
+	if condition.Encounter == nil || condition.Encounter.Reference != "Encounter/"+encounterID.String() {
+		t.Errorf("condition encounter = %+v, want Encounter/%s", condition.Encounter, encounterID)
+	}
+}
+
+func TestExportPatientErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		consented bool
+		patientID uuid.UUID
+		want      error
+	}{
+		{"without data sharing consent", false, patientID, ErrConsentRequired},
+		{"unknown patient", true, uuid.MustParse("00000000-0000-4000-8000-000000000000"), ErrNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := newTestService(test.consented).ExportPatient(test.patientID); !errors.Is(err, test.want) {
+				t.Errorf("err = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func (fakeEncounters) GetEncounterByID(id string) (*model.Encounter, error) {
+	switch id {
+	case encounterID.String():
+		return &model.Encounter{ID: encounterID, PatientID: patientID, DoctorID: doctorID,
+			Status: model.EncounterStatusInConsultation, CheckedInAt: checkedIn}, nil
+	case closedEncounterID.String():
+		return &model.Encounter{ID: closedEncounterID, PatientID: patientID, DoctorID: doctorID,
+			Status: model.EncounterStatusDischarged, CheckedInAt: checkedIn}, nil
+	}
+	return nil, nil
+}
+
+func (fakeDiagnoses) CreateDiagnosis(diagnosis model.Diagnosis) (*model.Diagnosis, error) {
+	diagnosis.ID = 8
+	diagnosis.CreatedAt = checkedIn
+	return &diagnosis, nil
+}
+
+func TestCreateCondition(t *testing.T) {
+	condition := func(recorder, encounter uuid.UUID) fhir.Condition {
+		resource := fhir.Condition{
+			ResourceType: "Condition",
+			Code:         &fhir.CodeableConcept{Text: "Acute sinusitis"},
+			Subject:      fhir.Reference{Reference: "Patient/" + patientID.String()},
+			Recorder:     &fhir.Reference{Reference: "Practitioner/" + recorder.String()},
+		}
+		if encounter != uuid.Nil {
+			resource.Encounter = &fhir.Reference{Reference: "Encounter/" + encounter.String()}
+		}
+		return resource
+	}
+	tests := []struct {
+		name     string
+		caller   uuid.UUID
+		resource fhir.Condition
+		want     error
+	}{
+		{"recorded by the attending doctor", doctorID, condition(doctorID, encounterID), nil},
+		{"in another doctor's name", otherDoctorID, condition(doctorID, encounterID), ErrNotRecorder},
+		{"by a doctor not attending", otherDoctorID, condition(otherDoctorID, encounterID), ErrNotAttending},
+		{"without an encounter", doctorID, condition(doctorID, uuid.Nil), fhir.ErrInvalidResource},
+		{"in a closed encounter", doctorID, condition(doctorID, closedEncounterID), ErrEncounterClosed},
+		{"in an unknown encounter", doctorID, condition(doctorID, patientID), ErrNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			created, err := newTestService(true).CreateCondition(test.caller, test.resource)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("err = %v, want %v", err, test.want)
+			}
+			if test.want == nil && (created.ID != "diagnosis-8" || created.Recorder == nil ||
+				created.Recorder.Reference != "Practitioner/"+doctorID.String()) {
+				t.Errorf("created = %+v", created)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package hl7_service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/hl7"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrUnsupportedMessage = errors.New("unsupported HL7 message")
+	ErrInvalidPatient     = errors.New("invalid patient data")
+)
+
+type hl7Service struct {
+	patients    repositories.PatientRepository
+	identifiers repositories.PatientIdentifierRepository
+	deadLetters repositories.HL7DeadLetterRepository
+	sender      hl7.Sender
+}
+
+func NewHL7Service(patients repositories.PatientRepository, identifiers repositories.PatientIdentifierRepository,
+	deadLetters repositories.HL7DeadLetterRepository, hl7Config config.HL7Config) *hl7Service {
+	return &hl7Service{
+		patients:    patients,
+		identifiers: identifiers,
+		deadLetters: deadLetters,
+		sender:      hl7.Sender{Application: hl7Config.Application, Facility: hl7Config.Facility},
+	}
+}
+
+// HandleMessage processes one inbound message and returns its ACK. Messages
+// that fail for any reason are kept as dead letters. Unparseable and
+// unsupported messages are rejected (AR); messages that parse but cannot be
+// applied get an application error (AE) so the sender may retry.
+func (s *hl7Service) HandleMessage(raw []byte, remoteAddr string) []byte {
+	now := time.Now()
+	message, result, err := s.process(raw)
+	if err != nil {
+		if _, deadLetterErr := s.deadLetters.CreateDeadLetter(model.HL7DeadLetter{
+			RemoteAddr: remoteAddr,
+			Message:    string(raw),
+			Error:      err.Error(),
+		}); deadLetterErr != nil {
+			log.Printf("hl7: failed to keep dead letter from %s: %v", remoteAddr, deadLetterErr)
+		}
+		code := hl7.AckError
+		if errors.Is(err, hl7.ErrMalformed) || errors.Is(err, ErrUnsupportedMessage) {
+			code = hl7.AckReject
+		}
+		return hl7.Ack(message, code, err.Error(), s.sender, now)
+	}
+	return hl7.Ack(message, hl7.AckAccept, result, s.sender, now)
+}
+
+func (s *hl7Service) ListDeadLetters() ([]dto.HL7DeadLetterResponse, error) {
+	letters, err := s.deadLetters.GetUnresolvedDeadLetters()
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToHL7DeadLetterResponses(letters), nil
+}
+
+// RetryDeadLetter processes a dead letter again, e.g. after the conflicting
+// patient was fixed, and resolves it if it now succeeds.
+func (s *hl7Service) RetryDeadLetter(id int64) error {
+	letter, err := s.deadLetters.GetDeadLetterByID(id)
+	if err != nil {
+		return err
+	}
+	if letter == nil || letter.ResolvedAt != nil {
+		return ErrDeadLetterNotFound
+	}
+	if _, _, err := s.process([]byte(letter.Message)); err != nil {
+		return err
+	}
+	return s.deadLetters.ResolveDeadLetter(id)
+}
+
+// DismissDeadLetter resolves a dead letter without processing it.
+func (s *hl7Service) DismissDeadLetter(id int64) error {
+	letter, err := s.deadLetters.GetDeadLetterByID(id)
+	if err != nil {
+		return err
+	}
+	if letter == nil || letter.ResolvedAt != nil {
+		return ErrDeadLetterNotFound
+	}
+	return s.deadLetters.ResolveDeadLetter(id)
+}
+
+// process applies an ADT^A04 (register) or ADT^A08 (update) message. Both
+// create the patient if no match is found, since partners do not always send
+// the A04 first. It returns a short description of what was done.
+func (s *hl7Service) process(raw []byte) (*hl7.Message, string, error) {
+	message, err := hl7.Parse(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	code, event := message.Type()
+	if code != "ADT" || (event != "A04" && event != "A08") {
+		return message, "", fmt.Errorf("%w: %s^%s, expected ADT^A04 or ADT^A08", ErrUnsupportedMessage, code, event)
+	}
+	if _, ok := message.Segment("PID"); !ok {
+		return message, "", fmt.Errorf("%w: missing PID segment", hl7.ErrMalformed)
+	}
+
+	incoming, identifiers, err := s.mapPID(message, time.Now())
+	if err != nil {
+		return message, "", err
+	}
+	existing, err := s.match(identifiers, incoming.Name, incoming.PhoneNumber)
+	if err != nil {
+		return message, "", err
+	}
+
+	var patientID uuid.UUID
+	var result string
+	if existing == nil {
+		if incoming.PhoneNumber == "" {
+			return message, "", fmt.Errorf("%w: a phone number is required to register a new patient", ErrInvalidPatient)
+		}
+		incoming.ID = uuid.New()
+		if incoming.Gender == "" {
+			incoming.Gender = "other"
+		}
+		if err := s.patients.CreatePatient(incoming); err != nil {
+			return message, "", err
+		}
+		patientID, result = incoming.ID, "patient created"
+	} else {
+		merged := mergePatient(*existing, incoming)
+		if _, err := s.patients.UpdatePatient(merged); err != nil {
+			return message, "", err
+		}
+		patientID, result = existing.ID, "patient updated"
+	}
+
+	for _, identifier := range identifiers {
+		identifier.PatientID = patientID
+		if err := s.identifiers.LinkPatientIdentifier(identifier); err != nil {
+			return message, "", err
+		}
+	}
+	return message, result, nil
+}
+
+// match finds the patient by any of the external identifiers, then by phone.
+// Phones can be shared within a family, so a phone match also needs the same
+// name; more than one such patient is ambiguous and left for a person to sort out.
+func (s *hl7Service) match(identifiers []model.PatientIdentifier, name, phone string) (*model.Patient, error) {
+	for _, identifier := range identifiers {
+		linked, err := s.identifiers.GetPatientIdentifier(identifier.System, identifier.Value)
+		if err != nil {
+			return nil, err
+		}
+		if linked == nil {
+			continue
+		}
+		patient, err := s.patients.GetPatientByID(linked.PatientID.String())
+		if err != nil {
+			return nil, err
+		}
+		if patient != nil {
+			return patient, nil
+		}
+	}
+	if phone == "" {
+		return nil, nil
+	}
+	candidates, err := s.patients.GetPatientsByContact(phone)
+	if err != nil {
+		return nil, err
+	}
+	var matched []model.Patient
+	for _, candidate := range candidates {
+		if strings.EqualFold(strings.TrimSpace(candidate.Name), strings.TrimSpace(name)) {
+			matched = append(matched, candidate)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &matched[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %d patients named %q share phone %s", ErrInvalidPatient, len(matched), name, phone)
+	}
+}
+
+// mapPID reads the patient and their external identifiers from the PID
+// segment. Identifiers without an assigning authority are attributed to the
+// sending facility.
+func (s *hl7Service) mapPID(message *hl7.Message, now time.Time) (model.Patient, []model.PatientIdentifier, error) {
+	defaultSystem := message.Value("MSH", 4, 1)
+	if defaultSystem == "" {
+		defaultSystem = message.Value("MSH", 3, 1)
+	}
+	var identifiers []model.PatientIdentifier
+	for _, repetition := range message.Repetitions(message.Field("PID", 3)) {
+		value := message.Component(repetition, 1)
+		if value == "" {
+			continue
+		}
+		system := message.Component(repetition, 4)
+		if system == "" {
+			system = defaultSystem
+		}
+		identifiers = append(identifiers, model.PatientIdentifier{System: system, Value: value})
+	}
+
+	family := message.Value("PID", 5, 1)
+	given := message.Value("PID", 5, 2)
+	middle := message.Value("PID", 5, 3)
+	patient := model.Patient{
+		Name:        strings.Join(strings.Fields(given+" "+middle+" "+family), " "),
+		Gender:      mapGender(message.Value("PID", 8, 1)),
+		PhoneNumber: phoneNumber(message),
+	}
+	if patient.Name == "" {
+		return model.Patient{}, nil, fmt.Errorf("%w: PID-5 patient name is required", ErrInvalidPatient)
+	}
+	if dob := message.Value("PID", 7, 1); dob != "" {
+		birthDate, err := parseDate(dob)
+		if err != nil {
+			return model.Patient{}, nil, fmt.Errorf("%w: PID-7: %v", ErrInvalidPatient, err)
+		}
+		patient.Age = yearsBetween(birthDate, now)
+	}
+	return patient, identifiers, nil
+}
+
+// mergePatient applies the fields present in the message to the stored patient.
+func mergePatient(existing, incoming model.Patient) model.Patient {
+	existing.Name = incoming.Name
+	if incoming.Gender != "" {
+		existing.Gender = incoming.Gender
+	}
+	if incoming.Age > 0 {
+		existing.Age = incoming.Age
+	}
+	if incoming.PhoneNumber != "" {
+		existing.PhoneNumber = incoming.PhoneNumber
+	}
+	return existing
+}
+
+// phoneNumber reads the first PID-13 number from XTN-1, XTN-12 or the area
+// code and local number components, in that order.
+func phoneNumber(message *hl7.Message) string {
+	for _, repetition := range message.Repetitions(message.Field("PID", 13)) {
+		if number := strings.TrimSpace(message.Component(repetition, 1)); number != "" {
+			return number
+		}
+		if number := strings.TrimSpace(message.Component(repetition, 12)); number != "" {
+			return number
+		}
+		local := message.Component(repetition, 7)
+		if local != "" {
+			return message.Component(repetition, 6) + local
+		}
+	}
+	return ""
+}
+
+// mapGender maps PID-8. An empty sex maps to "" so updates keep the stored value.
+func mapGender(sex string) string {
+	switch sex {
+	case "":
+		return ""
+	case "M":
+		return "male"
+	case "F":
+		return "female"
+	default:
+		return "other"
+	}
+}
+
+// parseDate reads the date part of an HL7 DTM value (YYYY[MM[DD[...]]]).
+func parseDate(value string) (time.Time, error) {
+	layouts := map[int]string{4: "2006", 6: "200601", 8: "20060102"}
+	length := min(len(value), 8)
+	layout, ok := layouts[length]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse(layout, value[:length])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
+
+func yearsBetween(from, to time.Time) int {
+	years := to.Year() - from.Year()
+	if to.Month() < from.Month() || (to.Month() == from.Month() && to.Day() < from.Day()) {
+		years--
+	}
+	return max(years, 0)
+}
@@ -1,8 +1,181 @@
 package service
 
-import "github.com/aaryansinhaa/patient-management-system/internals/model"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/fhir"
+	"github.com/google/uuid"
+)
 
 type AuthService interface {
-	Register(user *model.User) error
-	Login(username, password string) (*model.User, string, error)
+	Register(request dto.RegisterUserRequest) (*dto.UserResponse, error)
+	Login(request dto.LoginRequest) (*dto.LoginResponse, error)
+	VerifyMFA(request dto.MFAVerifyRequest) (*dto.LoginResponse, error)
+	UnlockAccount(adminID uuid.UUID, username string) error
+	DeactivateUser(adminID, userID uuid.UUID) (*dto.UserResponse, error)
+	BeginTOTPEnrollment(userID uuid.UUID) (*dto.TOTPEnrollmentResponse, error)
+	ConfirmTOTPEnrollment(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error)
+	RegenerateRecoveryCodes(userID uuid.UUID, code string) (*dto.RecoveryCodesResponse, error)
+	DisableTOTP(userID uuid.UUID, code string) error
+	SetMFAPolicy(adminID uuid.UUID, request dto.MFAPolicyRequest) error
+	ChangePassword(userID uuid.UUID, request dto.ChangePasswordRequest) error
+	IssuePasswordReset(adminID uuid.UUID, userID uuid.UUID) (*dto.PasswordResetTokenResponse, error)
+	ResetPassword(request dto.ResetPasswordRequest) error
+	SwitchClinic(userID uuid.UUID, request dto.SwitchClinicRequest) (*dto.LoginResponse, error)
+	ListMyClinics(userID uuid.UUID) ([]dto.ClinicMembershipResponse, error)
+}
+
+type PatientService interface {
+	GetPatientSummary(patientID uuid.UUID) (*dto.PatientSummaryResponse, error)
+	AddAllergy(patientID, recordedBy uuid.UUID, request dto.AllergyRequest) (*dto.AllergyResponse, error)
+	AddChronicCondition(patientID, recordedBy uuid.UUID, request dto.ChronicConditionRequest) (*dto.ChronicConditionResponse, error)
+	AddFamilyHistory(patientID, recordedBy uuid.UUID, request dto.FamilyHistoryRequest) (*dto.FamilyHistoryResponse, error)
+	FindPatientsByContact(contact string) ([]dto.PatientResponse, error)
+	ListContacts(patientID uuid.UUID) ([]dto.PatientContactResponse, error)
+	AddContact(patientID uuid.UUID, request dto.PatientContactRequest) (*dto.PatientContactResponse, error)
+	UpdateContact(patientID uuid.UUID, contactID int, request dto.PatientContactRequest) (*dto.PatientContactResponse, error)
+	RemoveContact(patientID uuid.UUID, contactID int) error
+}
+
+type EncounterService interface {
+	OpenEncounter(actorID uuid.UUID, request dto.OpenEncounterRequest) (*dto.EncounterResponse, error)
+	StartConsultation(doctorID, encounterID uuid.UUID) (*dto.EncounterResponse, error)
+	CloseEncounter(encounterID uuid.UUID, request dto.CloseEncounterRequest) (*dto.EncounterResponse, error)
+	CancelEncounter(encounterID uuid.UUID) (*dto.EncounterResponse, error)
+	GetEncounter(encounterID uuid.UUID) (*dto.EncounterDetailResponse, error)
+	ListOpenEncountersForDay(doctorID uuid.UUID, day time.Time) ([]dto.EncounterResponse, error)
+	RecordDiagnosis(doctorID, encounterID uuid.UUID, request dto.EncounterDiagnosisRequest) (*dto.DiagnosisResponse, error)
+	RecordVitals(actorID, encounterID uuid.UUID, request dto.VitalsRequest) (*dto.VitalsResponse, error)
+	AddPrescription(doctorID, encounterID uuid.UUID, request dto.PrescriptionRequest) (*dto.PrescriptionResponse, error)
+}
+
+type QueueService interface {
+	Enqueue(request dto.EnqueueRequest) (*dto.QueueEntryResponse, error)
+	GetQueue(doctorID uuid.UUID) (*dto.QueueResponse, error)
+	CallNext(doctorID uuid.UUID) (*dto.QueueEntryResponse, error)
+	LeaveQueue(entryID int64) error
+	Subscribe(doctorID uuid.UUID) (<-chan dto.QueueResponse, func())
+}
+
+type BillingService interface {
+	CreateTaxRate(request dto.TaxRateRequest) (*dto.TaxRateResponse, error)
+	ListTaxRates() ([]dto.TaxRateResponse, error)
+	CreateCatalogueItem(request dto.CatalogueItemRequest) (*dto.CatalogueItemResponse, error)
+	UpdateCatalogueItem(id int, request dto.CatalogueItemRequest) (*dto.CatalogueItemResponse, error)
+	ListCatalogue(includeInactive bool) ([]dto.CatalogueItemResponse, error)
+	AddEncounterCharge(actorID, encounterID uuid.UUID, request dto.EncounterChargeRequest) (*dto.EncounterChargeResponse, error)
+	GenerateInvoice(actorID, encounterID uuid.UUID) (*dto.InvoiceResponse, error)
+	GetInvoice(invoiceID uuid.UUID) (*dto.InvoiceResponse, error)
+	ListPatientInvoices(patientID uuid.UUID) ([]dto.InvoiceResponse, error)
+	GetPatientBalance(patientID uuid.UUID) (*dto.PatientBalanceResponse, error)
+	RecordPayment(actorID, invoiceID uuid.UUID, request dto.PaymentRequest) (*dto.InvoiceResponse, error)
+	RefundPayment(actorID uuid.UUID, paymentID int, request dto.RefundRequest) (*dto.InvoiceResponse, error)
+}
+
+type DocumentService interface {
+	VisitSummaryPDF(encounterID uuid.UUID) (*dto.DocumentFile, error)
+	PrescriptionPDF(encounterID uuid.UUID) (*dto.DocumentFile, error)
+	InvoicePDF(invoiceID uuid.UUID) (*dto.DocumentFile, error)
+}
+
+type FHIRService interface {
+	GetPatient(id uuid.UUID) (*fhir.Patient, error)
+	SearchPatients(identifier, phone, name string) (*fhir.Bundle, error)
+	CreatePatient(resource fhir.Patient) (*fhir.Patient, error)
+	UpdatePatient(id uuid.UUID, resource fhir.Patient) (*fhir.Patient, error)
+	ExportPatient(id uuid.UUID) (*fhir.Bundle, error)
+	GetPractitioner(id uuid.UUID) (*fhir.Practitioner, error)
+	SearchPractitioners(identifier, name string) (*fhir.Bundle, error)
+	GetCondition(id string) (*fhir.Condition, error)
+	ConditionPatient(id string) (uuid.UUID, error)
+	SearchConditions(patientID uuid.UUID) (*fhir.Bundle, error)
+	CreateCondition(doctorID uuid.UUID, resource fhir.Condition) (*fhir.Condition, error)
+	GetEncounter(id uuid.UUID) (*fhir.Encounter, error)
+	SearchEncounters(patientID uuid.UUID) (*fhir.Bundle, error)
+}
+
+type HL7Service interface {
+	HandleMessage(raw []byte, remoteAddr string) []byte
+	ListDeadLetters() ([]dto.HL7DeadLetterResponse, error)
+	RetryDeadLetter(id int64) error
+	DismissDeadLetter(id int64) error
+}
+
+type PatientCSVService interface {
+	ImportPatients(r io.Reader) (*dto.PatientImportReport, error)
+	ExportPatients(w io.Writer) error
+}
+
+type PatientMergeService interface {
+	FindDuplicates() ([]dto.DuplicateCandidateResponse, error)
+	FindDuplicatesOf(patientID uuid.UUID) ([]dto.DuplicateCandidateResponse, error)
+	MergePatients(actorID, survivorID uuid.UUID, request dto.MergePatientsRequest) (*dto.PatientMergeResponse, error)
+	UndoMerge(actorID uuid.UUID, mergeID int64) (*dto.PatientMergeResponse, error)
+	ListMerges(patientID uuid.UUID) ([]dto.PatientMergeResponse, error)
+}
+
+type ClinicService interface {
+	CreateClinic(actorID uuid.UUID, request dto.CreateClinicRequest) (*dto.ClinicResponse, error)
+	ListClinics() ([]dto.ClinicResponse, error)
+	AddMember(clinicID uuid.UUID, request dto.AddClinicMemberRequest) error
+	RemoveMember(clinicID, userID uuid.UUID) error
+}
+
+type ConsentService interface {
+	PublishConsentText(actorID uuid.UUID, request dto.ConsentTextRequest) (*dto.ConsentTextResponse, error)
+	ListConsentTexts(scope string) ([]dto.ConsentTextResponse, error)
+	GetPatientConsents(patientID uuid.UUID) (*dto.PatientConsentsResponse, error)
+	GrantConsent(actorID, patientID uuid.UUID, request dto.GrantConsentRequest) (*dto.PatientConsentResponse, error)
+	WithdrawConsent(actorID, patientID uuid.UUID, scope string) (*dto.PatientConsentResponse, error)
+}
+
+type EmergencyAccessService interface {
+	BreakGlass(doctorID, patientID uuid.UUID, request dto.BreakGlassRequest) (*dto.EmergencyAccessResponse, error)
+	AuthorizePatient(userID uuid.UUID, role string, patientID uuid.UUID, action string) (bool, error)
+	AuthorizeEncounter(userID uuid.UUID, role string, encounterID uuid.UUID, action string) (bool, error)
+	ListEmergencyAccesses(pendingOnly bool) ([]dto.EmergencyAccessResponse, error)
+	GetEmergencyAccess(id int64) (*dto.EmergencyAccessDetailResponse, error)
+	ReviewEmergencyAccess(adminID uuid.UUID, id int64, request dto.ReviewEmergencyAccessRequest) (*dto.EmergencyAccessResponse, error)
+	SubscribeAlerts() (<-chan dto.EmergencyAccessResponse, func())
+}
+
+type NotificationService interface {
+	SendAppointmentReminder(patientID uuid.UUID, request dto.AppointmentReminderRequest) (*dto.NotificationResponse, error)
+	SendResultsReady(patientID uuid.UUID, request dto.ResultsReadyRequest) (*dto.NotificationResponse, error)
+	ListPatientNotifications(patientID uuid.UUID) ([]dto.NotificationResponse, error)
+	RetryNotification(id int64) (*dto.NotificationResponse, error)
+}
+
+type WebhookService interface {
+	CreateSubscription(adminID uuid.UUID, request dto.WebhookSubscriptionRequest) (*dto.WebhookSubscriptionResponse, error)
+	ListSubscriptions() ([]dto.WebhookSubscriptionResponse, error)
+	GetSubscription(id int64) (*dto.WebhookSubscriptionResponse, error)
+	UpdateSubscription(id int64, request dto.WebhookSubscriptionRequest) (*dto.WebhookSubscriptionResponse, error)
+	DeleteSubscription(id int64) error
+	ListDeliveries(subscriptionID int64, status string) ([]dto.WebhookDeliveryResponse, error)
+	GetDelivery(id int64) (*dto.WebhookDeliveryDetailResponse, error)
+	ReplayDelivery(id int64) (*dto.WebhookDeliveryResponse, error)
+}
+
+type LabService interface {
+	CreateLabTest(request dto.LabTestRequest) (*dto.LabTestResponse, error)
+	UpdateLabTest(id int, request dto.LabTestRequest) (*dto.LabTestResponse, error)
+	ListLabTests(includeInactive bool) ([]dto.LabTestResponse, error)
+	OrderLabTest(doctorID, patientID uuid.UUID, request dto.LabOrderRequest) (*dto.LabOrderResponse, error)
+	ListPatientLabOrders(patientID uuid.UUID) ([]dto.LabOrderResponse, error)
+	GetLabOrder(patientID uuid.UUID, orderID int64) (*dto.LabOrderResponse, error)
+	RecordLabResult(userID, patientID uuid.UUID, orderID int64, request dto.LabResultRequest) (*dto.LabOrderResponse, error)
+	CancelLabOrder(patientID uuid.UUID, orderID int64) (*dto.LabOrderResponse, error)
+	PendingWorklist(doctorID uuid.UUID, all bool) ([]dto.LabOrderResponse, error)
+}
+
+type AttachmentService interface {
+	UploadAttachment(ctx context.Context, userID, patientID uuid.UUID, upload dto.AttachmentUpload, content io.Reader) (*dto.AttachmentResponse, error)
+	ListPatientAttachments(patientID uuid.UUID, includeDeleted bool) ([]dto.AttachmentResponse, error)
+	GetAttachment(patientID uuid.UUID, id int64) (*dto.AttachmentResponse, error)
+	OpenAttachment(ctx context.Context, patientID uuid.UUID, id int64) (*dto.AttachmentResponse, io.ReadCloser, error)
+	DeleteAttachment(userID, patientID uuid.UUID, id int64) (*dto.AttachmentResponse, error)
 }
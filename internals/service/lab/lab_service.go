@@ -0,0 +1,336 @@
+package lab_service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/notification"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPatientNotFound   = errors.New("patient not found")
+	ErrDiagnosisNotFound = errors.New("diagnosis not found")
+	ErrLabTestNotFound   = errors.New("lab test not found")
+	ErrLabTestExists     = errors.New("lab test code already in use")
+	ErrLabOrderNotFound  = errors.New("lab order not found")
+	ErrOrderNotPending   = errors.New("lab order is not waiting for a result")
+	ErrInvalidLabTest    = errors.New("invalid lab test")
+	ErrInvalidLabOrder   = errors.New("invalid lab order")
+	ErrInvalidLabResult  = errors.New("invalid lab result")
+	ErrConsentRequired   = errors.New("patient has not consented to sharing their data")
+)
+
+type labService struct {
+	labs      repositories.LabRepository
+	patients  repositories.PatientRepository
+	diagnoses repositories.DiagnosisRepository
+	consents  repositories.ConsentRepository
+	notifier  *notification.Notifier
+}
+
+func NewLabService(labs repositories.LabRepository, patients repositories.PatientRepository, diagnoses repositories.DiagnosisRepository,
+	consents repositories.ConsentRepository, notifier *notification.Notifier) *labService {
+	return &labService{
+		labs:      labs,
+		patients:  patients,
+		diagnoses: diagnoses,
+		consents:  consents,
+		notifier:  notifier,
+	}
+}
+
+func (s *labService) CreateLabTest(request dto.LabTestRequest) (*dto.LabTestResponse, error) {
+	test, err := labTestFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.labs.GetLabTestByCode(test.Code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: %s", ErrLabTestExists, test.Code)
+	}
+	created, err := s.labs.CreateLabTest(*test)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToLabTestResponse(*created)
+	return &response, nil
+}
+
+// UpdateLabTest replaces the test and its reference ranges. Results already
+// recorded keep the flag they were given.
+func (s *labService) UpdateLabTest(id int, request dto.LabTestRequest) (*dto.LabTestResponse, error) {
+	test, err := labTestFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.labs.GetLabTestByCode(test.Code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != id {
+		return nil, fmt.Errorf("%w: %s", ErrLabTestExists, test.Code)
+	}
+	test.ID = id
+	updated, err := s.labs.UpdateLabTest(*test)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrLabTestNotFound
+	}
+	response := dto.ToLabTestResponse(*updated)
+	return &response, nil
+}
+
+func (s *labService) ListLabTests(includeInactive bool) ([]dto.LabTestResponse, error) {
+	tests, err := s.labs.GetAllLabTests(includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToLabTestResponses(tests), nil
+}
+
+// OrderLabTest orders an active catalogue test for the patient. A diagnosis
+// the order is for must be one of the patient's. Samples and the order's
+// details go to the lab, so the patient must have consented to sharing.
+func (s *labService) OrderLabTest(doctorID, patientID uuid.UUID, request dto.LabOrderRequest) (*dto.LabOrderResponse, error) {
+	code := strings.ToUpper(strings.TrimSpace(request.TestCode))
+	if code == "" {
+		return nil, fmt.Errorf("%w: test_code is required", ErrInvalidLabOrder)
+	}
+	if _, err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	consent, err := s.consents.GetActiveConsent(patientID.String(), model.ConsentScopeDataSharing)
+	if err != nil {
+		return nil, err
+	}
+	if consent == nil {
+		return nil, fmt.Errorf("%w: patient %s", ErrConsentRequired, patientID)
+	}
+	test, err := s.labs.GetLabTestByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil || !test.Active {
+		return nil, fmt.Errorf("%w: %s", ErrLabTestNotFound, code)
+	}
+	if request.DiagnosisID != nil {
+		diagnosis, err := s.diagnoses.GetDiagnosisByID(strconv.Itoa(*request.DiagnosisID))
+		if err != nil {
+			return nil, err
+		}
+		if diagnosis == nil || diagnosis.PatientID != patientID {
+			return nil, ErrDiagnosisNotFound
+		}
+	}
+
+	order, err := s.labs.CreateLabOrder(model.LabOrder{
+		PatientID:   patientID,
+		DiagnosisID: request.DiagnosisID,
+		TestID:      test.ID,
+		OrderedBy:   doctorID,
+		Notes:       strings.TrimSpace(request.Notes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToLabOrderResponse(*order)
+	return &response, nil
+}
+
+func (s *labService) ListPatientLabOrders(patientID uuid.UUID) ([]dto.LabOrderResponse, error) {
+	if _, err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	orders, err := s.labs.GetLabOrdersByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToLabOrderResponses(orders), nil
+}
+
+func (s *labService) GetLabOrder(patientID uuid.UUID, orderID int64) (*dto.LabOrderResponse, error) {
+	order, err := s.patientOrder(patientID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToLabOrderResponse(*order)
+	return &response, nil
+}
+
+// RecordLabResult stores the result of a pending order, flagged against the
+// reference range for the patient's gender and age, and tells the patient
+// their results are ready.
+func (s *labService) RecordLabResult(userID, patientID uuid.UUID, orderID int64, request dto.LabResultRequest) (*dto.LabOrderResponse, error) {
+	if request.Value == nil {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidLabResult)
+	}
+	if math.IsNaN(*request.Value) || math.IsInf(*request.Value, 0) {
+		return nil, fmt.Errorf("%w: value must be a finite number", ErrInvalidLabResult)
+	}
+	order, err := s.patientOrder(patientID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != model.LabOrderOrdered {
+		return nil, fmt.Errorf("%w: it is %s", ErrOrderNotPending, order.Status)
+	}
+	patient, err := s.requirePatient(patientID)
+	if err != nil {
+		return nil, err
+	}
+	test, err := s.labs.GetLabTestByID(order.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test == nil {
+		return nil, ErrLabTestNotFound
+	}
+
+	r := referenceRange(test.Ranges, patient.Gender, patient.Age)
+	result := model.LabResult{
+		OrderID:    order.ID,
+		Value:      *request.Value,
+		Unit:       test.Unit,
+		Flag:       flag(*request.Value, r),
+		Comment:    strings.TrimSpace(request.Comment),
+		RecordedBy: userID,
+	}
+	if r != nil {
+		result.ReferenceLow = r.Low
+		result.ReferenceHigh = r.High
+	}
+	resulted, err := s.labs.RecordLabResult(result)
+	if err != nil {
+		return nil, err
+	}
+	if resulted == nil {
+		return nil, ErrOrderNotPending
+	}
+	s.notifyResultsReady(patientID, test.Name)
+	response := dto.ToLabOrderResponse(*resulted)
+	return &response, nil
+}
+
+// notifyResultsReady queues a results-ready message by text, or by email for
+// patients who cannot be texted. The result is stored by then, so a patient
+// who cannot be reached at all is only logged; staff can still notify them
+// by hand.
+func (s *labService) notifyResultsReady(patientID uuid.UUID, testName string) {
+	var err error
+	for _, channel := range []string{model.NotificationChannelSMS, model.NotificationChannelEmail} {
+		_, err = s.notifier.Notify(patientID, channel, model.NotificationResultsReady,
+			notification.Data{TestName: testName}, time.Now())
+		if err == nil || !(errors.Is(err, notification.ErrNoSMSConsent) || errors.Is(err, notification.ErrNoRecipient)) {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("lab: results ready for patient %s not queued: %v", patientID, err)
+	}
+}
+
+func (s *labService) CancelLabOrder(patientID uuid.UUID, orderID int64) (*dto.LabOrderResponse, error) {
+	order, err := s.patientOrder(patientID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != model.LabOrderOrdered {
+		return nil, fmt.Errorf("%w: it is %s", ErrOrderNotPending, order.Status)
+	}
+	cancelled, err := s.labs.CancelLabOrder(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cancelled == nil {
+		return nil, ErrOrderNotPending
+	}
+	response := dto.ToLabOrderResponse(*cancelled)
+	return &response, nil
+}
+
+// PendingWorklist returns the orders still waiting for a result, oldest
+// first: the doctor's own, or everyone's if all is set.
+func (s *labService) PendingWorklist(doctorID uuid.UUID, all bool) ([]dto.LabOrderResponse, error) {
+	orderedBy := doctorID.String()
+	if all {
+		orderedBy = ""
+	}
+	orders, err := s.labs.GetPendingLabOrders(orderedBy)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToLabOrderResponses(orders), nil
+}
+
+func (s *labService) requirePatient(patientID uuid.UUID) (*model.Patient, error) {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+	return patient, nil
+}
+
+// patientOrder loads an order, treating one for another patient as missing.
+func (s *labService) patientOrder(patientID uuid.UUID, orderID int64) (*model.LabOrder, error) {
+	order, err := s.labs.GetLabOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.PatientID != patientID {
+		return nil, ErrLabOrderNotFound
+	}
+	return order, nil
+}
+
+func labTestFromRequest(request dto.LabTestRequest) (*model.LabTest, error) {
+	test := &model.LabTest{
+		Code:   strings.ToUpper(strings.TrimSpace(request.Code)),
+		Name:   strings.TrimSpace(request.Name),
+		Unit:   strings.TrimSpace(request.Unit),
+		Active: request.Active == nil || *request.Active,
+	}
+	if test.Code == "" || test.Name == "" {
+		return nil, fmt.Errorf("%w: code and name are required", ErrInvalidLabTest)
+	}
+	for i, r := range request.Ranges {
+		switch r.Gender {
+		case "", "male", "female", "other":
+		default:
+			return nil, fmt.Errorf("%w: range %d: gender must be male, female or other", ErrInvalidLabTest, i+1)
+		}
+		if r.MinAge < 0 || (r.MaxAge != nil && *r.MaxAge < r.MinAge) {
+			return nil, fmt.Errorf("%w: range %d: invalid age band", ErrInvalidLabTest, i+1)
+		}
+		if r.Low == nil && r.High == nil {
+			return nil, fmt.Errorf("%w: range %d: low or high is required", ErrInvalidLabTest, i+1)
+		}
+		if r.Low != nil && r.High != nil && *r.Low > *r.High {
+			return nil, fmt.Errorf("%w: range %d: low is above high", ErrInvalidLabTest, i+1)
+		}
+		test.Ranges = append(test.Ranges, model.LabReferenceRange{
+			Gender: r.Gender,
+			MinAge: r.MinAge,
+			MaxAge: r.MaxAge,
+			Low:    r.Low,
+			High:   r.High,
+		})
+	}
+	return test, nil
+}
@@ -0,0 +1,47 @@
+package lab_service
+
+import "github.com/aaryansinhaa/patient-management-system/internals/model"
+
+// referenceRange picks the range that applies to a patient. A range for the
+// patient's gender beats one for any gender, and a narrower age band beats a
+// wider one. It returns nil if no range covers the patient.
+func referenceRange(ranges []model.LabReferenceRange, gender string, age int) *model.LabReferenceRange {
+	var best *model.LabReferenceRange
+	for i := range ranges {
+		r := &ranges[i]
+		if r.Gender != "" && r.Gender != gender {
+			continue
+		}
+		if age < r.MinAge || (r.MaxAge != nil && age > *r.MaxAge) {
+			continue
+		}
+		if best == nil || better(r, best) {
+			best = r
+		}
+	}
+	return best
+}
+
+func better(r, than *model.LabReferenceRange) bool {
+	if (r.Gender != "") != (than.Gender != "") {
+		return r.Gender != ""
+	}
+	if than.MaxAge == nil {
+		return r.MaxAge != nil || r.MinAge > than.MinAge
+	}
+	return r.MaxAge != nil && *r.MaxAge-r.MinAge < *than.MaxAge-than.MinAge
+}
+
+// flag judges value against r, either end of which may be open.
+func flag(value float64, r *model.LabReferenceRange) string {
+	switch {
+	case r == nil:
+		return model.LabFlagNoRange
+	case r.Low != nil && value < *r.Low:
+		return model.LabFlagLow
+	case r.High != nil && value > *r.High:
+		return model.LabFlagHigh
+	default:
+		return model.LabFlagNormal
+	}
+}
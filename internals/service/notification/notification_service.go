@@ -0,0 +1,115 @@
+package notification_service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/notification"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("failed notification not found")
+	ErrDoctorNotFound       = errors.New("doctor not found")
+	ErrInvalidNotification  = errors.New("invalid notification")
+	// The notifier's errors already say why a patient cannot be notified.
+	ErrPatientNotFound = notification.ErrPatientNotFound
+)
+
+type notificationService struct {
+	notifier      *notification.Notifier
+	notifications repositories.NotificationRepository
+	patients      repositories.PatientRepository
+	users         repositories.UserRepository
+	reminderLead  time.Duration
+}
+
+func NewNotificationService(notifier *notification.Notifier, notifications repositories.NotificationRepository,
+	patients repositories.PatientRepository, users repositories.UserRepository,
+	notificationConfig config.NotificationConfig) *notificationService {
+	return &notificationService{
+		notifier:      notifier,
+		notifications: notifications,
+		patients:      patients,
+		users:         users,
+		reminderLead:  notificationConfig.ReminderLead,
+	}
+}
+
+// SendAppointmentReminder queues a reminder to go out the configured lead
+// time before the appointment, or straight away if that time has passed.
+func (s *notificationService) SendAppointmentReminder(patientID uuid.UUID, request dto.AppointmentReminderRequest) (*dto.NotificationResponse, error) {
+	now := time.Now()
+	if !request.AppointmentAt.After(now) {
+		return nil, fmt.Errorf("%w: appointment_at must be in the future", ErrInvalidNotification)
+	}
+	data := notification.Data{AppointmentAt: request.AppointmentAt}
+	if request.DoctorID != uuid.Nil {
+		doctor, err := s.users.GetUserByID(request.DoctorID.String())
+		if err != nil {
+			return nil, err
+		}
+		if doctor == nil || doctor.Role != model.RoleDoctor {
+			return nil, ErrDoctorNotFound
+		}
+		data.DoctorName = doctor.Name
+	}
+	sendAt := request.AppointmentAt.Add(-s.reminderLead)
+	if sendAt.Before(now) {
+		sendAt = now
+	}
+	return s.notify(patientID, request.Channel, model.NotificationAppointmentReminder, data, sendAt)
+}
+
+func (s *notificationService) SendResultsReady(patientID uuid.UUID, request dto.ResultsReadyRequest) (*dto.NotificationResponse, error) {
+	data := notification.Data{TestName: strings.TrimSpace(request.TestName)}
+	return s.notify(patientID, request.Channel, model.NotificationResultsReady, data, time.Now())
+}
+
+func (s *notificationService) notify(patientID uuid.UUID, channel, template string, data notification.Data,
+	sendAt time.Time) (*dto.NotificationResponse, error) {
+	if channel == "" {
+		channel = model.NotificationChannelSMS
+	}
+	queued, err := s.notifier.Notify(patientID, channel, template, data, sendAt)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToNotificationResponse(*queued)
+	return &response, nil
+}
+
+func (s *notificationService) ListPatientNotifications(patientID uuid.UUID) ([]dto.NotificationResponse, error) {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+	notifications, err := s.notifications.GetNotificationsByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToNotificationResponses(notifications), nil
+}
+
+// RetryNotification queues a failed notification again, e.g. once a missing
+// provider has been configured.
+func (s *notificationService) RetryNotification(id int64) (*dto.NotificationResponse, error) {
+	retried, err := s.notifications.RetryNotification(id)
+	if err != nil {
+		return nil, err
+	}
+	if retried == nil {
+		return nil, ErrNotificationNotFound
+	}
+	response := dto.ToNotificationResponse(*retried)
+	return &response, nil
+}
@@ -0,0 +1,267 @@
+package patient_service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+// recentDiagnosesLimit is how many diagnoses the patient summary includes.
+const recentDiagnosesLimit = 10
+
+var (
+	ErrPatientNotFound = errors.New("patient not found")
+	ErrContactNotFound = errors.New("patient contact not found")
+	ErrInvalidContact  = errors.New("invalid patient contact")
+)
+
+type patientService struct {
+	patients      repositories.PatientRepository
+	diagnoses     repositories.DiagnosisRepository
+	allergies     repositories.AllergyRepository
+	conditions    repositories.ChronicConditionRepository
+	familyHistory repositories.FamilyHistoryRepository
+	contacts      repositories.PatientContactRepository
+}
+
+func NewPatientService(patients repositories.PatientRepository, diagnoses repositories.DiagnosisRepository,
+	allergies repositories.AllergyRepository, conditions repositories.ChronicConditionRepository,
+	familyHistory repositories.FamilyHistoryRepository, contacts repositories.PatientContactRepository) *patientService {
+	return &patientService{
+		patients:      patients,
+		diagnoses:     diagnoses,
+		allergies:     allergies,
+		conditions:    conditions,
+		familyHistory: familyHistory,
+		contacts:      contacts,
+	}
+}
+
+// GetPatientSummary loads the patient with their allergies, problem list,
+// family history, contacts and most recent diagnoses in one call.
+func (s *patientService) GetPatientSummary(patientID uuid.UUID) (*dto.PatientSummaryResponse, error) {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+
+	if patient.Allergies, err = s.allergies.GetAllergiesByPatientID(patientID.String()); err != nil {
+		return nil, err
+	}
+	if patient.ChronicConditions, err = s.conditions.GetChronicConditionsByPatientID(patientID.String()); err != nil {
+		return nil, err
+	}
+	if patient.FamilyHistory, err = s.familyHistory.GetFamilyHistoryByPatientID(patientID.String()); err != nil {
+		return nil, err
+	}
+	if patient.Contacts, err = s.contacts.GetContactsByPatientID(patientID.String()); err != nil {
+		return nil, err
+	}
+	diagnoses, err := s.diagnoses.GetRecentDiagnosesByPatientID(patientID.String(), recentDiagnosesLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := dto.ToPatientSummaryResponse(*patient, diagnoses)
+	return &summary, nil
+}
+
+func (s *patientService) AddAllergy(patientID, recordedBy uuid.UUID, request dto.AllergyRequest) (*dto.AllergyResponse, error) {
+	switch request.Severity {
+	case model.AllergySeverityMild, model.AllergySeverityModerate, model.AllergySeveritySevere, model.AllergySeverityLifeThreatening:
+	default:
+		return nil, fmt.Errorf("invalid allergy severity: %q", request.Severity)
+	}
+	if request.Substance == "" {
+		return nil, errors.New("allergy substance is required")
+	}
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	allergy, err := s.allergies.CreateAllergy(request.ToModel(patientID, recordedBy))
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToAllergyResponse(*allergy)
+	return &response, nil
+}
+
+func (s *patientService) AddChronicCondition(patientID, recordedBy uuid.UUID, request dto.ChronicConditionRequest) (*dto.ChronicConditionResponse, error) {
+	if request.Status == "" {
+		request.Status = model.ConditionStatusActive
+	}
+	switch request.Status {
+	case model.ConditionStatusActive, model.ConditionStatusInactive, model.ConditionStatusResolved:
+	default:
+		return nil, fmt.Errorf("invalid condition status: %q", request.Status)
+	}
+	if request.Name == "" {
+		return nil, errors.New("condition name is required")
+	}
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	condition, err := s.conditions.CreateChronicCondition(request.ToModel(patientID, recordedBy))
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToChronicConditionResponse(*condition)
+	return &response, nil
+}
+
+func (s *patientService) AddFamilyHistory(patientID, recordedBy uuid.UUID, request dto.FamilyHistoryRequest) (*dto.FamilyHistoryResponse, error) {
+	if request.Relation == "" || request.Condition == "" {
+		return nil, errors.New("family history relation and condition are required")
+	}
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	entry, err := s.familyHistory.CreateFamilyHistory(request.ToModel(patientID, recordedBy))
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToFamilyHistoryResponse(*entry)
+	return &response, nil
+}
+
+// FindPatientsByContact looks patients up by a phone number or email address,
+// matching their own phone as well as any of their contacts.
+func (s *patientService) FindPatientsByContact(contact string) ([]dto.PatientResponse, error) {
+	contact = strings.TrimSpace(contact)
+	if contact == "" {
+		return nil, fmt.Errorf("%w: a phone number or email address is required", ErrInvalidContact)
+	}
+	patients, err := s.patients.GetPatientsByContact(contact)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToPatientResponses(patients), nil
+}
+
+func (s *patientService) ListContacts(patientID uuid.UUID) ([]dto.PatientContactResponse, error) {
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	contacts, err := s.contacts.GetContactsByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToPatientContactResponses(contacts), nil
+}
+
+func (s *patientService) AddContact(patientID uuid.UUID, request dto.PatientContactRequest) (*dto.PatientContactResponse, error) {
+	contact := request.ToModel(patientID)
+	if err := validateContact(&contact); err != nil {
+		return nil, err
+	}
+	if err := s.requirePatient(patientID); err != nil {
+		return nil, err
+	}
+	created, err := s.contacts.CreateContact(contact)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToPatientContactResponse(*created)
+	return &response, nil
+}
+
+func (s *patientService) UpdateContact(patientID uuid.UUID, contactID int, request dto.PatientContactRequest) (*dto.PatientContactResponse, error) {
+	contact := request.ToModel(patientID)
+	contact.ID = contactID
+	if err := validateContact(&contact); err != nil {
+		return nil, err
+	}
+	if _, err := s.requireContact(patientID, contactID); err != nil {
+		return nil, err
+	}
+	updated, err := s.contacts.UpdateContact(contact)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrContactNotFound
+	}
+	response := dto.ToPatientContactResponse(*updated)
+	return &response, nil
+}
+
+func (s *patientService) RemoveContact(patientID uuid.UUID, contactID int) error {
+	if _, err := s.requireContact(patientID, contactID); err != nil {
+		return err
+	}
+	deleted, err := s.contacts.DeleteContact(contactID)
+	if err != nil {
+		return err
+	}
+	if deleted == nil {
+		return ErrContactNotFound
+	}
+	return nil
+}
+
+// validateContact trims the contact and checks it can actually be used to
+// reach someone.
+func validateContact(contact *model.PatientContact) error {
+	contact.Name = strings.TrimSpace(contact.Name)
+	contact.PhoneNumber = strings.TrimSpace(contact.PhoneNumber)
+	contact.Email = strings.TrimSpace(contact.Email)
+	contact.Address = strings.TrimSpace(contact.Address)
+	if contact.Relation == "" {
+		contact.Relation = model.ContactRelationSelf
+	}
+
+	switch contact.Relation {
+	case model.ContactRelationSelf:
+		if contact.IsGuardian {
+			return fmt.Errorf("%w: a patient cannot be their own guardian", ErrInvalidContact)
+		}
+	case model.ContactRelationParent, model.ContactRelationGuardian, model.ContactRelationSpouse,
+		model.ContactRelationChild, model.ContactRelationSibling, model.ContactRelationCaregiver, model.ContactRelationOther:
+		if contact.Name == "" {
+			return fmt.Errorf("%w: name is required for a %s", ErrInvalidContact, contact.Relation)
+		}
+	default:
+		return fmt.Errorf("%w: unknown relation %q", ErrInvalidContact, contact.Relation)
+	}
+	if contact.PhoneNumber == "" && contact.Email == "" && contact.Address == "" {
+		return fmt.Errorf("%w: a phone number, email or address is required", ErrInvalidContact)
+	}
+	if contact.Email != "" {
+		if _, err := mail.ParseAddress(contact.Email); err != nil {
+			return fmt.Errorf("%w: invalid email address %q", ErrInvalidContact, contact.Email)
+		}
+	}
+	return nil
+}
+
+// requireContact loads a contact and checks it belongs to the patient.
+func (s *patientService) requireContact(patientID uuid.UUID, contactID int) (*model.PatientContact, error) {
+	contact, err := s.contacts.GetContactByID(contactID)
+	if err != nil {
+		return nil, err
+	}
+	if contact == nil || contact.PatientID != patientID {
+		return nil, ErrContactNotFound
+	}
+	return contact, nil
+}
+
+func (s *patientService) requirePatient(patientID uuid.UUID) error {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return err
+	}
+	if patient == nil {
+		return ErrPatientNotFound
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package patient_csv_service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+// maxAge rejects ages that are almost certainly typos in the source sheet.
+const maxAge = 150
+
+var ErrInvalidCSV = errors.New("invalid CSV file")
+
+// exportColumns is the header of exported files. Imports accept the same
+// columns in any order; id is ignored since imported patients get new ids.
+var exportColumns = []string{"id", "name", "age", "gender", "phone_number"}
+
+type patientCSVService struct {
+	patients  repositories.PatientRepository
+	batchSize int
+}
+
+func NewPatientCSVService(patients repositories.PatientRepository, importConfig config.PatientImportConfig) *patientCSVService {
+	batchSize := importConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &patientCSVService{
+		patients:  patients,
+		batchSize: batchSize,
+	}
+}
+
+// pendingRow is a valid row waiting for its batch to be inserted.
+type pendingRow struct {
+	line    int
+	patient model.Patient
+}
+
+// ImportPatients reads patients from a CSV file with a header row, validating
+// each row and inserting valid ones in batches. Rows whose phone number is
+// already registered, or appears earlier in the file, are reported as
+// duplicates. Row problems never stop the import; only an unreadable header,
+// a read error or a failing database lookup do.
+func (s *patientCSVService) ImportPatients(r io.Reader) (*dto.PatientImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	columns, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.PatientImportReport{Errors: []dto.PatientImportRowError{}}
+	seen := make(map[string]int)
+	pending := make([]pendingRow, 0, s.batchSize)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Rows++
+			reportFailure(report, parseErr.StartLine, "", parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		report.Rows++
+		line, _ := reader.FieldPos(0)
+
+		patient, err := columns.patient(record)
+		if err != nil {
+			reportFailure(report, line, patient.PhoneNumber, err.Error())
+			continue
+		}
+		if first, ok := seen[patient.PhoneNumber]; ok {
+			reportDuplicate(report, line, patient.PhoneNumber, fmt.Sprintf("phone_number already used on line %d", first))
+			continue
+		}
+		seen[patient.PhoneNumber] = line
+
+		pending = append(pending, pendingRow{line: line, patient: patient})
+		if len(pending) == s.batchSize {
+			if err := s.insert(pending, report); err != nil {
+				return nil, err
+			}
+			pending = pending[:0]
+		}
+	}
+	if len(pending) > 0 {
+		if err := s.insert(pending, report); err != nil {
+			return nil, err
+		}
+	}
+	// Duplicates of registered patients are only found when a batch is
+	// flushed, after later rows may already have been reported.
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+	return report, nil
+}
+
+// insert stores one batch, skipping rows whose phone number is already
+// registered. When the insert itself fails every row of the batch is reported
+// and the import carries on with the next batch.
+func (s *patientCSVService) insert(batch []pendingRow, report *dto.PatientImportReport) error {
+	phoneNumbers := make([]string, 0, len(batch))
+	for _, row := range batch {
+		phoneNumbers = append(phoneNumbers, row.patient.PhoneNumber)
+	}
+	existing, err := s.patients.GetExistingPhoneNumbers(phoneNumbers)
+	if err != nil {
+		return err
+	}
+	registered := make(map[string]bool, len(existing))
+	for _, phoneNumber := range existing {
+		registered[phoneNumber] = true
+	}
+
+	patients := make([]model.Patient, 0, len(batch))
+	rows := make([]pendingRow, 0, len(batch))
+	for _, row := range batch {
+		if registered[row.patient.PhoneNumber] {
+			reportDuplicate(report, row.line, row.patient.PhoneNumber, "phone_number is already registered")
+			continue
+		}
+		patients = append(patients, row.patient)
+		rows = append(rows, row)
+	}
+	if len(patients) == 0 {
+		return nil
+	}
+	if err := s.patients.CreatePatients(patients); err != nil {
+		for _, row := range rows {
+			reportFailure(report, row.line, row.patient.PhoneNumber, err.Error())
+		}
+		return nil
+	}
+	report.Imported += len(patients)
+	return nil
+}
+
+// ExportPatients writes every patient to w as CSV, streaming them from the
+// database as they are read.
+func (s *patientCSVService) ExportPatients(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportColumns); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	record := make([]string, len(exportColumns))
+	err := s.patients.EachPatient(func(patient model.Patient) error {
+		record[0] = patient.ID.String()
+		record[1] = patient.Name
+		record[2] = strconv.Itoa(patient.Age)
+		record[3] = patient.Gender
+		record[4] = patient.PhoneNumber
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+func reportFailure(report *dto.PatientImportReport, line int, phoneNumber, message string) {
+	report.Failed++
+	report.Errors = append(report.Errors, dto.PatientImportRowError{Row: line, PhoneNumber: phoneNumber, Error: message})
+}
+
+func reportDuplicate(report *dto.PatientImportReport, line int, phoneNumber, message string) {
+	report.Duplicates++
+	report.Errors = append(report.Errors, dto.PatientImportRowError{Row: line, PhoneNumber: phoneNumber, Error: message})
+}
+
+// columnIndex holds the position of each known column in the file, -1 when
+// the column is absent.
+type columnIndex struct {
+	name, age, gender, phoneNumber int
+}
+
+func parseHeader(header []string) (columnIndex, error) {
+	columns := columnIndex{name: -1, age: -1, gender: -1, phoneNumber: -1}
+	for i, column := range header {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "name":
+			columns.name = i
+		case "age":
+			columns.age = i
+		case "gender":
+			columns.gender = i
+		case "phone_number":
+			columns.phoneNumber = i
+		}
+	}
+	var missing []string
+	if columns.name < 0 {
+		missing = append(missing, "name")
+	}
+	if columns.age < 0 {
+		missing = append(missing, "age")
+	}
+	if columns.phoneNumber < 0 {
+		missing = append(missing, "phone_number")
+	}
+	if len(missing) > 0 {
+		return columns, fmt.Errorf("%w: missing column(s) %s", ErrInvalidCSV, strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// patient validates one record. The returned patient carries whatever phone
+// number was read even on error, so the report can show it.
+func (c columnIndex) patient(record []string) (model.Patient, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	patient := model.Patient{
+		Name:        field(c.name),
+		PhoneNumber: field(c.phoneNumber),
+		Gender:      strings.ToLower(field(c.gender)),
+	}
+	if patient.PhoneNumber == "" {
+		return patient, errors.New("phone_number is required")
+	}
+	if patient.Name == "" {
+		return patient, errors.New("name is required")
+	}
+	age, err := strconv.Atoi(field(c.age))
+	if err != nil || age < 0 || age > maxAge {
+		return patient, fmt.Errorf("age must be a whole number between 0 and %d", maxAge)
+	}
+	patient.Age = age
+	switch patient.Gender {
+	case "":
+		patient.Gender = "other"
+	case "male", "female", "other":
+	default:
+		return patient, fmt.Errorf("gender must be male, female or other, got %q", patient.Gender)
+	}
+	patient.ID = uuid.New()
+	return patient, nil
+}
@@ -0,0 +1,143 @@
+package patient_merge_service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPatientNotFound = errors.New("patient not found")
+	ErrMergeNotFound   = errors.New("patient merge not found")
+	ErrSamePatient     = errors.New("a patient cannot be merged into itself")
+	ErrInvalidInput    = errors.New("invalid input")
+	// ErrMergeConflict is the repository error, which already explains which
+	// record changed since the merge.
+	ErrMergeConflict = repositories.ErrMergeConflict
+)
+
+type patientMergeService struct {
+	patients  repositories.PatientRepository
+	merges    repositories.PatientMergeRepository
+	threshold float64
+	limit     int
+}
+
+func NewPatientMergeService(patients repositories.PatientRepository, merges repositories.PatientMergeRepository,
+	mergeConfig config.PatientMergeConfig) *patientMergeService {
+	return &patientMergeService{
+		patients:  patients,
+		merges:    merges,
+		threshold: mergeConfig.DuplicateThreshold,
+		limit:     mergeConfig.MaxCandidates,
+	}
+}
+
+// FindDuplicates scans all patients for likely duplicate pairs. To keep the
+// scan from comparing every pair, only patients whose ages are within
+// similarAgeYears of each other are compared; FindDuplicatesOf has no such
+// restriction.
+func (s *patientMergeService) FindDuplicates() ([]dto.DuplicateCandidateResponse, error) {
+	patients, err := s.patients.GetAllPatients()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(patients, func(i, j int) bool { return patients[i].Age < patients[j].Age })
+
+	var candidates []model.DuplicateCandidate
+	for i := range patients {
+		for j := i + 1; j < len(patients) && patients[j].Age-patients[i].Age <= similarAgeYears; j++ {
+			candidates = s.appendCandidate(candidates, patients[i], patients[j])
+		}
+	}
+	return dto.ToDuplicateCandidateResponses(s.best(candidates)), nil
+}
+
+// FindDuplicatesOf compares one patient against every other patient.
+func (s *patientMergeService) FindDuplicatesOf(patientID uuid.UUID) ([]dto.DuplicateCandidateResponse, error) {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	if patient == nil {
+		return nil, ErrPatientNotFound
+	}
+	patients, err := s.patients.GetAllPatients()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []model.DuplicateCandidate
+	for _, other := range patients {
+		if other.ID != patient.ID {
+			candidates = s.appendCandidate(candidates, *patient, other)
+		}
+	}
+	return dto.ToDuplicateCandidateResponses(s.best(candidates)), nil
+}
+
+func (s *patientMergeService) appendCandidate(candidates []model.DuplicateCandidate, a, b model.Patient) []model.DuplicateCandidate {
+	total, reasons := score(a, b)
+	if total < s.threshold {
+		return candidates
+	}
+	return append(candidates, model.DuplicateCandidate{Patient: a, Duplicate: b, Score: total, Reasons: reasons})
+}
+
+// best orders candidates by score and keeps the configured maximum.
+func (s *patientMergeService) best(candidates []model.DuplicateCandidate) []model.DuplicateCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if s.limit > 0 && len(candidates) > s.limit {
+		candidates = candidates[:s.limit]
+	}
+	return candidates
+}
+
+// MergePatients folds the patient in the request into survivorID. The
+// survivor keeps its own demographics and gains every record of the merged
+// patient, which is then removed.
+func (s *patientMergeService) MergePatients(actorID, survivorID uuid.UUID, request dto.MergePatientsRequest) (*dto.PatientMergeResponse, error) {
+	if request.MergedPatientID == uuid.Nil {
+		return nil, fmt.Errorf("%w: merged_patient_id is required", ErrInvalidInput)
+	}
+	if request.MergedPatientID == survivorID {
+		return nil, ErrSamePatient
+	}
+	merge, err := s.merges.MergePatients(survivorID.String(), request.MergedPatientID.String(), actorID.String())
+	if err != nil {
+		return nil, err
+	}
+	if merge == nil {
+		return nil, ErrPatientNotFound
+	}
+	response := dto.ToPatientMergeResponse(*merge)
+	return &response, nil
+}
+
+// UndoMerge restores the merged patient with the records that were moved
+// from it. Records added to the survivor since the merge stay where they are.
+func (s *patientMergeService) UndoMerge(actorID uuid.UUID, mergeID int64) (*dto.PatientMergeResponse, error) {
+	merge, err := s.merges.UndoMerge(mergeID, actorID.String())
+	if err != nil {
+		return nil, err
+	}
+	if merge == nil {
+		return nil, ErrMergeNotFound
+	}
+	response := dto.ToPatientMergeResponse(*merge)
+	return &response, nil
+}
+
+func (s *patientMergeService) ListMerges(patientID uuid.UUID) ([]dto.PatientMergeResponse, error) {
+	merges, err := s.merges.GetMergesByPatientID(patientID.String())
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToPatientMergeResponses(merges), nil
+}
@@ -0,0 +1,130 @@
+package patient_merge_service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+)
+
+// minNameSimilarity is how alike two names must be before the other fields
+// are considered at all; without it every same-age pair would score.
+const minNameSimilarity = 0.6
+
+// Score weights. A pair matching on every field scores 1.
+const (
+	nameWeight         = 0.5
+	sameAgeWeight      = 0.2
+	similarAgeWeight   = 0.1
+	genderWeight       = 0.1
+	samePhoneWeight    = 0.2
+	similarPhoneWeight = 0.15
+)
+
+// similarAgeYears is the largest age difference still counted as similar,
+// allowing for ages recorded a birthday apart.
+const similarAgeYears = 2
+
+// score rates how likely a and b are the same person, from 0 to 1, and
+// explains what matched.
+func score(a, b model.Patient) (float64, []string) {
+	nameSimilarity := similarity(normalizeName(a.Name), normalizeName(b.Name))
+	if nameSimilarity < minNameSimilarity {
+		return 0, nil
+	}
+
+	total := nameWeight * nameSimilarity
+	var reasons []string
+	if nameSimilarity == 1 {
+		reasons = append(reasons, "same name")
+	} else {
+		reasons = append(reasons, "similar name")
+	}
+
+	switch ageDifference := abs(a.Age - b.Age); {
+	case ageDifference == 0:
+		total += sameAgeWeight
+		reasons = append(reasons, "same age")
+	case ageDifference <= similarAgeYears:
+		total += similarAgeWeight
+		reasons = append(reasons, "similar age")
+	}
+
+	if a.Gender == b.Gender {
+		total += genderWeight
+		reasons = append(reasons, "same gender")
+	}
+
+	// A shared phone alone means little since families share one, which is
+	// why the name must already be similar. Families registered before phones
+	// could be shared often have the number plus a suffix, a prefix match.
+	phoneA, phoneB := digits(a.PhoneNumber), digits(b.PhoneNumber)
+	switch {
+	case phoneA == "" || phoneB == "":
+	case phoneA == phoneB:
+		total += samePhoneWeight
+		reasons = append(reasons, "same phone number")
+	case strings.HasPrefix(phoneA, phoneB), strings.HasPrefix(phoneB, phoneA),
+		levenshtein([]rune(phoneA), []rune(phoneB)) <= 2:
+		total += similarPhoneWeight
+		reasons = append(reasons, "similar phone number")
+	}
+	return total, reasons
+}
+
+// normalizeName lowercases the name, drops punctuation and sorts the words,
+// so "Doe, John" and "john doe" compare equal.
+func normalizeName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// similarity is one minus the edit distance relative to the longer string.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package queue_service
+
+import (
+	"sync"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/google/uuid"
+)
+
+// broker fans queue snapshots out to the subscribers of each doctor's queue.
+// Every event carries the whole queue, so a slow subscriber can safely miss
+// one and catch up on the next.
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan dto.QueueResponse]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{subscribers: map[uuid.UUID]map[chan dto.QueueResponse]struct{}{}}
+}
+
+func (b *broker) subscribe(doctorID uuid.UUID) (<-chan dto.QueueResponse, func()) {
+	ch := make(chan dto.QueueResponse, 1)
+	b.mu.Lock()
+	if b.subscribers[doctorID] == nil {
+		b.subscribers[doctorID] = map[chan dto.QueueResponse]struct{}{}
+	}
+	b.subscribers[doctorID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[doctorID], ch)
+			if len(b.subscribers[doctorID]) == 0 {
+				delete(b.subscribers, doctorID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *broker) publish(snapshot dto.QueueResponse) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[snapshot.DoctorID] {
+		// Replace a snapshot the subscriber has not read yet with the newer one.
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- snapshot:
+		default:
+		}
+	}
+}
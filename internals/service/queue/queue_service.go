@@ -0,0 +1,187 @@
+package queue_service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEncounterNotFound   = errors.New("encounter not found")
+	ErrEncounterNotWaiting = errors.New("only checked-in encounters can join the queue")
+	ErrQueueEntryNotFound  = errors.New("queue entry not found")
+	ErrQueueEmpty          = errors.New("no patients waiting")
+	ErrInvalidPriority     = errors.New("invalid queue priority")
+)
+
+type queueService struct {
+	queue      repositories.QueueRepository
+	encounters repositories.EncounterRepository
+	patients   repositories.PatientRepository
+	broker     *broker
+	config     config.QueueConfig
+}
+
+func NewQueueService(queue repositories.QueueRepository, encounters repositories.EncounterRepository,
+	patients repositories.PatientRepository, queueConfig config.QueueConfig) *queueService {
+	return &queueService{
+		queue:      queue,
+		encounters: encounters,
+		patients:   patients,
+		broker:     newBroker(),
+		config:     queueConfig,
+	}
+}
+
+// Enqueue adds a checked-in encounter to its doctor's queue and hands out a token.
+func (s *queueService) Enqueue(request dto.EnqueueRequest) (*dto.QueueEntryResponse, error) {
+	encounter, err := s.encounters.GetEncounterByID(request.EncounterID.String())
+	if err != nil {
+		return nil, err
+	}
+	if encounter == nil {
+		return nil, ErrEncounterNotFound
+	}
+	if encounter.Status != model.EncounterStatusCheckedIn {
+		return nil, ErrEncounterNotWaiting
+	}
+
+	priority := request.Priority
+	switch priority {
+	case model.QueuePriorityEmergency, model.QueuePriorityElderly, model.QueuePriorityNormal:
+	case "":
+		priority, err = s.defaultPriority(encounter.PatientID)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPriority, priority)
+	}
+
+	entry, err := s.queue.CreateQueueEntry(model.QueueEntry{
+		EncounterID: encounter.ID,
+		DoctorID:    encounter.DoctorID,
+		PatientID:   encounter.PatientID,
+		Priority:    priority,
+	})
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.notify(encounter.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+	return findEntry(snapshot, entry.ID, *entry), nil
+}
+
+// GetQueue returns the doctor's current queue with estimated waits.
+func (s *queueService) GetQueue(doctorID uuid.UUID) (*dto.QueueResponse, error) {
+	return s.snapshot(doctorID)
+}
+
+// CallNext finishes the doctor's current patient and calls the next one.
+func (s *queueService) CallNext(doctorID uuid.UUID) (*dto.QueueEntryResponse, error) {
+	next, err := s.queue.CallNextQueueEntry(doctorID.String())
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.notify(doctorID)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, ErrQueueEmpty
+	}
+	return findEntry(snapshot, next.ID, *next), nil
+}
+
+// LeaveQueue removes a patient who left before being seen.
+func (s *queueService) LeaveQueue(entryID int64) error {
+	entry, err := s.queue.GetQueueEntryByID(entryID)
+	if err != nil {
+		return err
+	}
+	if entry == nil || (entry.Status != model.QueueStatusWaiting && entry.Status != model.QueueStatusCalled) {
+		return ErrQueueEntryNotFound
+	}
+	if _, err := s.queue.UpdateQueueEntryStatus(entryID, model.QueueStatusLeft); err != nil {
+		return err
+	}
+	_, err = s.notify(entry.DoctorID)
+	return err
+}
+
+// Subscribe streams a snapshot of the doctor's queue every time it changes.
+// The returned function must be called to stop the subscription.
+func (s *queueService) Subscribe(doctorID uuid.UUID) (<-chan dto.QueueResponse, func()) {
+	return s.broker.subscribe(doctorID)
+}
+
+func (s *queueService) defaultPriority(patientID uuid.UUID) (string, error) {
+	patient, err := s.patients.GetPatientByID(patientID.String())
+	if err != nil {
+		return "", err
+	}
+	if patient != nil && s.config.ElderlyAge > 0 && patient.Age >= s.config.ElderlyAge {
+		return model.QueuePriorityElderly, nil
+	}
+	return model.QueuePriorityNormal, nil
+}
+
+func (s *queueService) notify(doctorID uuid.UUID) (*dto.QueueResponse, error) {
+	snapshot, err := s.snapshot(doctorID)
+	if err != nil {
+		return nil, err
+	}
+	s.broker.publish(*snapshot)
+	return snapshot, nil
+}
+
+// snapshot builds the queue and estimates each waiting patient's wait as the
+// number of patients ahead of them, including the one being seen, times the
+// doctor's average consultation time.
+func (s *queueService) snapshot(doctorID uuid.UUID) (*dto.QueueResponse, error) {
+	entries, err := s.queue.GetActiveQueueByDoctorID(doctorID.String())
+	if err != nil {
+		return nil, err
+	}
+	average, samples, err := s.encounters.GetAverageConsultationDuration(doctorID.String(), time.Now().Add(-s.config.HistoryWindow))
+	if err != nil {
+		return nil, err
+	}
+	if samples == 0 {
+		average = s.config.DefaultConsultation
+	}
+
+	response := &dto.QueueResponse{
+		DoctorID:                   doctorID,
+		AverageConsultationSeconds: int(average.Seconds()),
+		Entries:                    make([]dto.QueueEntryResponse, 0, len(entries)),
+	}
+	ahead := 0
+	for _, entry := range entries {
+		wait := time.Duration(0)
+		if entry.Status == model.QueueStatusWaiting {
+			wait = time.Duration(ahead) * average
+		}
+		ahead++
+		response.Entries = append(response.Entries, dto.ToQueueEntryResponse(entry, wait))
+	}
+	return response, nil
+}
+
+func findEntry(snapshot *dto.QueueResponse, id int64, fallback model.QueueEntry) *dto.QueueEntryResponse {
+	for _, entry := range snapshot.Entries {
+		if entry.ID == id {
+			return &entry
+		}
+	}
+	response := dto.ToQueueEntryResponse(fallback, 0)
+	return &response
+}
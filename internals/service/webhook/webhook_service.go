@@ -0,0 +1,220 @@
+package webhook_service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/dto"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrDeliveryPending      = errors.New("webhook delivery is still pending")
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+)
+
+// minSecretLength keeps admins from choosing secrets short enough to guess.
+const minSecretLength = 16
+
+type webhookService struct {
+	webhooks repositories.WebhookRepository
+}
+
+func NewWebhookService(webhooks repositories.WebhookRepository) *webhookService {
+	return &webhookService{webhooks: webhooks}
+}
+
+// CreateSubscription subscribes a partner URL to the clinic's events. When
+// the secret is generated, the response is the only place it is shown.
+func (s *webhookService) CreateSubscription(adminID uuid.UUID, request dto.WebhookSubscriptionRequest) (*dto.WebhookSubscriptionResponse, error) {
+	subscription, err := s.validate(request)
+	if err != nil {
+		return nil, err
+	}
+	generated := subscription.Secret == ""
+	if generated {
+		if subscription.Secret, err = generateSecret(); err != nil {
+			return nil, err
+		}
+	}
+	subscription.CreatedBy = adminID
+	created, err := s.webhooks.CreateSubscription(subscription)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToWebhookSubscriptionResponse(*created)
+	if generated {
+		response.Secret = created.Secret
+	}
+	return &response, nil
+}
+
+func (s *webhookService) ListSubscriptions() ([]dto.WebhookSubscriptionResponse, error) {
+	subscriptions, err := s.webhooks.GetSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToWebhookSubscriptionResponses(subscriptions), nil
+}
+
+func (s *webhookService) GetSubscription(id int64) (*dto.WebhookSubscriptionResponse, error) {
+	subscription, err := s.webhooks.GetSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	response := dto.ToWebhookSubscriptionResponse(*subscription)
+	return &response, nil
+}
+
+// UpdateSubscription replaces a subscription. Deliveries already queued go
+// to the new URL, signed with the new secret.
+func (s *webhookService) UpdateSubscription(id int64, request dto.WebhookSubscriptionRequest) (*dto.WebhookSubscriptionResponse, error) {
+	subscription, err := s.validate(request)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.webhooks.GetSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	subscription.ID = id
+	if subscription.Secret == "" {
+		subscription.Secret = existing.Secret
+	}
+	updated, err := s.webhooks.UpdateSubscription(subscription)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	response := dto.ToWebhookSubscriptionResponse(*updated)
+	return &response, nil
+}
+
+func (s *webhookService) DeleteSubscription(id int64) error {
+	deleted, err := s.webhooks.DeleteSubscription(id)
+	if err != nil {
+		return err
+	}
+	if deleted == nil {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// ListDeliveries is the delivery log of a subscription, optionally only the
+// deliveries with status.
+func (s *webhookService) ListDeliveries(subscriptionID int64, status string) ([]dto.WebhookDeliveryResponse, error) {
+	switch status {
+	case "", model.WebhookDeliveryPending, model.WebhookDeliveryDelivered, model.WebhookDeliveryFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown delivery status %q", ErrInvalidSubscription, status)
+	}
+	subscription, err := s.webhooks.GetSubscriptionByID(subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	deliveries, err := s.webhooks.GetDeliveriesBySubscriptionID(subscriptionID, status)
+	if err != nil {
+		return nil, err
+	}
+	return dto.ToWebhookDeliveryResponses(deliveries), nil
+}
+
+func (s *webhookService) GetDelivery(id int64) (*dto.WebhookDeliveryDetailResponse, error) {
+	delivery, err := s.webhooks.GetDeliveryByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+	attempts, err := s.webhooks.GetAttemptsByDeliveryID(id)
+	if err != nil {
+		return nil, err
+	}
+	response := dto.ToWebhookDeliveryDetailResponse(*delivery, attempts)
+	return &response, nil
+}
+
+// ReplayDelivery sends a delivered or failed delivery again, e.g. after the
+// receiver lost it or was fixed.
+func (s *webhookService) ReplayDelivery(id int64) (*dto.WebhookDeliveryResponse, error) {
+	delivery, err := s.webhooks.GetDeliveryByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, ErrDeliveryNotFound
+	}
+	replayed, err := s.webhooks.ReplayDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	if replayed == nil {
+		return nil, ErrDeliveryPending
+	}
+	response := dto.ToWebhookDeliveryResponse(*replayed)
+	return &response, nil
+}
+
+func (s *webhookService) validate(request dto.WebhookSubscriptionRequest) (model.WebhookSubscription, error) {
+	target, err := url.Parse(strings.TrimSpace(request.URL))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return model.WebhookSubscription{}, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	if len(request.EventTypes) == 0 {
+		return model.WebhookSubscription{}, fmt.Errorf("%w: event_types is required", ErrInvalidSubscription)
+	}
+	var eventTypes []string
+	for _, eventType := range request.EventTypes {
+		if !slices.Contains(model.DomainEventTypes, eventType) {
+			return model.WebhookSubscription{}, fmt.Errorf("%w: unknown event type %q, expected one of %s",
+				ErrInvalidSubscription, eventType, strings.Join(model.DomainEventTypes, ", "))
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	if request.Secret != "" && len(request.Secret) < minSecretLength {
+		return model.WebhookSubscription{}, fmt.Errorf("%w: secret must be at least %d characters",
+			ErrInvalidSubscription, minSecretLength)
+	}
+	active := true
+	if request.Active != nil {
+		active = *request.Active
+	}
+	return model.WebhookSubscription{
+		URL:        target.String(),
+		EventTypes: eventTypes,
+		Secret:     request.Secret,
+		Active:     active,
+	}, nil
+}
+
+func generateSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
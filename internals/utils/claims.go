@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Claims is the payload of every token we issue. The user ID travels in the
+// registered "sub" claim. Purpose is empty for access tokens and set for
+// restricted tokens such as MFA challenges and enrolment-only tokens. Clinic is the clinic an access
+// token works in.
+type Claims struct {
+	Name    string `json:"name,omitempty"`
+	Role    string `json:"role,omitempty"`
+	Clinic  string `json:"clinic_id,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+func (c *Claims) ClinicID() (uuid.UUID, error) {
+	return uuid.Parse(c.Clinic)
+}
@@ -0,0 +1,327 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
+	"github.com/aaryansinhaa/patient-management-system/internals/model"
+	"github.com/aaryansinhaa/patient-management-system/internals/repositories"
+)
+
+// keySize is the size of master and data keys, for AES-256.
+const keySize = 32
+
+// dataKeyAAD binds wrapped data keys to their purpose, so a wrapped key can
+// never be opened as a field value or the other way round.
+const dataKeyAAD = "data-key"
+
+var ErrDecryption = errors.New("failed to decrypt field")
+
+// Keyring implements envelope encryption of sensitive columns. Values are
+// encrypted with AES-GCM under a data key; data keys are stored in the
+// database wrapped by a master key that only ever lives in the key file.
+type Keyring struct {
+	masterKeys     map[string][]byte
+	activeMasterID string
+	blindIndexKey  []byte
+	keys           repositories.DataKeyRepository
+	refresh        time.Duration
+
+	mu          sync.Mutex
+	dataKeys    map[int]*DataKey
+	active      *DataKey
+	refreshedAt time.Time
+}
+
+// DataKey is an unwrapped data key, ready to encrypt and decrypt fields.
+type DataKey struct {
+	ID   int
+	aead cipher.AEAD
+}
+
+// LoadKeyring reads the master keys from the key file and makes sure there is
+// an active data key, creating the first one on a fresh database.
+func LoadKeyring(cfg config.EncryptionConfig, keys repositories.DataKeyRepository) (*Keyring, error) {
+	if cfg.KeyFile == "" {
+		return nil, errors.New("encryption key file is required")
+	}
+	masterKeys, err := readMasterKeys(cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := masterKeys[cfg.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("active master key %q is not in the key file", cfg.ActiveKeyID)
+	}
+	blindIndexMaster, ok := masterKeys[cfg.BlindIndexKeyID]
+	if !ok {
+		return nil, fmt.Errorf("blind index master key %q is not in the key file", cfg.BlindIndexKeyID)
+	}
+	mac := hmac.New(sha256.New, blindIndexMaster)
+	mac.Write([]byte("blind-index"))
+
+	keyring := &Keyring{
+		masterKeys:     masterKeys,
+		activeMasterID: cfg.ActiveKeyID,
+		blindIndexKey:  mac.Sum(nil),
+		keys:           keys,
+		refresh:        cfg.ActiveKeyRefresh,
+		dataKeys:       map[int]*DataKey{},
+	}
+	if _, err := keyring.ActiveKey(); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// readMasterKeys parses the key file: one "id:base64 key" per line, with blank
+// lines and lines starting with # ignored.
+func readMasterKeys(path string) (map[string][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	keys := map[string][]byte{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		id, encoded, found := strings.Cut(text, ":")
+		if !found || id == "" {
+			return nil, fmt.Errorf("%s:%d: expected id:key", path, line)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("%s:%d: key must be %d base64 encoded bytes", path, line, keySize)
+		}
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("%s:%d: duplicate key id %q", path, line, id)
+		}
+		keys[id] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func (k *Keyring) wrap(masterKeyID string, dataKey []byte) ([]byte, error) {
+	aead, err := newAEAD(k.masterKeys[masterKeyID])
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, dataKey, []byte(dataKeyAAD))
+}
+
+func (k *Keyring) unwrap(masterKeyID string, wrappedKey []byte) ([]byte, error) {
+	masterKey, ok := k.masterKeys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %q is not in the key file", masterKeyID)
+	}
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := open(aead, wrappedKey, []byte(dataKeyAAD))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with master key %q: %w", masterKeyID, err)
+	}
+	return dataKey, nil
+}
+
+// ActiveKey returns the data key new values are encrypted with. It is looked
+// up again every refresh interval, so a key rotated by another process is
+// picked up.
+func (k *Keyring) ActiveKey() (*DataKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.active != nil && time.Since(k.refreshedAt) < k.refresh {
+		return k.active, nil
+	}
+
+	stored, err := k.keys.GetActiveDataKey()
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		if stored, err = k.createActiveKey(); err != nil {
+			return nil, err
+		}
+	}
+	active, err := k.dataKey(stored.ID, stored.MasterKeyID, stored.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	k.active = active
+	k.refreshedAt = time.Now()
+	return active, nil
+}
+
+// createActiveKey generates a data key, wraps it with the active master key
+// and stores it as the active one.
+func (k *Keyring) createActiveKey() (*model.DataKey, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := k.wrap(k.activeMasterID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return k.keys.CreateActiveDataKey(k.activeMasterID, wrapped)
+}
+
+// dataKey unwraps a stored data key, caching the result. k.mu must be held.
+func (k *Keyring) dataKey(id int, masterKeyID string, wrappedKey []byte) (*DataKey, error) {
+	if key, ok := k.dataKeys[id]; ok {
+		return key, nil
+	}
+	raw, err := k.unwrap(masterKeyID, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	key := &DataKey{ID: id, aead: aead}
+	k.dataKeys[id] = key
+	return key, nil
+}
+
+func (k *Keyring) keyByID(id int) (*DataKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.dataKeys[id]; ok {
+		return key, nil
+	}
+	stored, err := k.keys.GetDataKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("data key %d does not exist", id)
+	}
+	return k.dataKey(stored.ID, stored.MasterKeyID, stored.WrappedKey)
+}
+
+// Seal encrypts value for column field, e.g. "patients.name". The field is
+// authenticated with the value, so a ciphertext copied into another column
+// does not decrypt.
+func (d *DataKey) Seal(field, value string) (string, error) {
+	sealed, err := seal(d.aead, []byte(value), []byte(field))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value of column field written with data key keyID. Rows
+// written before encryption was enabled have no key and are returned as is.
+func (k *Keyring) Open(keyID sql.NullInt32, field, value string) (string, error) {
+	if !keyID.Valid {
+		return value, nil
+	}
+	key, err := k.keyByID(int(keyID.Int32))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("%w %s: %v", ErrDecryption, field, err)
+	}
+	plaintext, err := open(key.aead, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("%w %s: %v", ErrDecryption, field, err)
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex is a keyed hash of value that allows exact-match lookups on an
+// encrypted column without decrypting it. field keeps equal values of
+// different columns from having equal indexes.
+func (k *Keyring) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, k.blindIndexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Rotate creates a new data key wrapped with the active master key and makes
+// it the active one, then wraps every older data key with the active master
+// key too, so retired master keys can be removed from the key file once
+// rotation has finished. Rows encrypted with older data keys stay readable
+// until they are re-encrypted.
+func (k *Keyring) Rotate() (*DataKey, error) {
+	if _, err := k.createActiveKey(); err != nil {
+		return nil, err
+	}
+
+	stored, err := k.keys.GetAllDataKeys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range stored {
+		if key.MasterKeyID == k.activeMasterID {
+			continue
+		}
+		raw, err := k.unwrap(key.MasterKeyID, key.WrappedKey)
+		if err != nil {
+			return nil, err
+		}
+		rewrapped, err := k.wrap(k.activeMasterID, raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := k.keys.RewrapDataKey(key.ID, k.activeMasterID, rewrapped); err != nil {
+			return nil, err
+		}
+	}
+
+	k.mu.Lock()
+	k.active = nil
+	k.mu.Unlock()
+	return k.ActiveKey()
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// JSONWebKey is the public part of a signing key in RFC 7517 form.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	Modulus  string `json:"n,omitempty"`
+	Exponent string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys that currently verify tokens, including retired
+// keys still within their grace period.
+func (j *JWTManager) JWKS() JSONWebKeySet {
+	now := time.Now()
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range j.keys {
+		if !j.verifiable(key, now) {
+			continue
+		}
+		jwk := JSONWebKey{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch public := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Modulus = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(a, b int) bool { return set.Keys[a].KeyID < set.Keys[b].KeyID })
+	return set
+}
@@ -1,31 +1,296 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
+	"github.com/aaryansinhaa/patient-management-system/internals/config"
 	"github.com/aaryansinhaa/patient-management-system/internals/model"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// mfaChallengePurpose marks tokens that only prove the password step of a
+// two-step login and cannot be used as access tokens.
+const mfaChallengePurpose = "mfa_challenge"
+
+// mfaEnrollmentPurpose marks tokens handed to users whose role requires a
+// second factor they have not set up yet. They only work for enrolment.
+const mfaEnrollmentPurpose = "mfa_enrollment"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// SigningKey is a key pair identified by kid. PrivateKey is nil for keys that
+// are only kept to verify tokens signed before a rotation.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	RetiredAt  *time.Time
+}
+
 type JWTManager struct {
-	SecretKey     string
+	keys          map[string]*SigningKey
+	activeKeyID   string
+	issuer        string
+	audience      string
+	GracePeriod   time.Duration
 	TokenDuration time.Duration
 }
 
-func NewJWTManager(secret string, duration time.Duration) *JWTManager {
-	return &JWTManager{
-		SecretKey:     secret,
-		TokenDuration: duration,
+// LoadJWTManager reads every configured key from its PEM files. The active key
+// must have a private key; the others only need a public one.
+func LoadJWTManager(cfg config.JWTConfig) (*JWTManager, error) {
+	manager := &JWTManager{
+		keys:          map[string]*SigningKey{},
+		activeKeyID:   cfg.ActiveKeyID,
+		issuer:        cfg.Issuer,
+		audience:      cfg.Audience,
+		GracePeriod:   cfg.GracePeriod,
+		TokenDuration: cfg.TokenDuration,
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt issuer and audience are required")
 	}
+	for _, keyConfig := range cfg.Keys {
+		key, err := loadSigningKey(keyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt key %q: %w", keyConfig.ID, err)
+		}
+		if _, exists := manager.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		manager.keys[key.ID] = key
+	}
+
+	active, ok := manager.keys[cfg.ActiveKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q is not configured", cfg.ActiveKeyID)
+	}
+	if active.PrivateKey == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private key", cfg.ActiveKeyID)
+	}
+	if active.RetiredAt != nil {
+		return nil, fmt.Errorf("active jwt key %q is retired", cfg.ActiveKeyID)
+	}
+	return manager, nil
 }
 
-func (j *JWTManager) Generate(user *model.User) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": user.ID.String(),
-		"role":    user.Role,
-		"exp":     time.Now().Add(j.TokenDuration).Unix(),
+func loadSigningKey(cfg config.JWTKeyConfig) (*SigningKey, error) {
+	if cfg.ID == "" {
+		return nil, errors.New("key id is required")
+	}
+	key := &SigningKey{ID: cfg.ID}
+	switch cfg.Algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		key.Method = jwt.SigningMethodRS256
+	case jwt.SigningMethodEdDSA.Alg():
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.SecretKey))
+	if cfg.PrivateKeyPath != "" {
+		block, err := readPEM(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			// RSA keys are often still stored in the older PKCS #1 format.
+			if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes); rsaErr == nil {
+				parsed, err = rsaKey, nil
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("private key cannot sign")
+		}
+		key.PrivateKey = signer
+		key.PublicKey = signer.Public()
+	}
+
+	if cfg.PublicKeyPath != "" {
+		block, err := readPEM(cfg.PublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		key.PublicKey = parsed
+	}
+	if key.PublicKey == nil {
+		return nil, errors.New("either a private or a public key path is required")
+	}
+
+	switch key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.Method != jwt.SigningMethodRS256 {
+			return nil, errors.New("RSA key configured for a non-RSA algorithm")
+		}
+	case ed25519.PublicKey:
+		if key.Method != jwt.SigningMethodEdDSA {
+			return nil, errors.New("Ed25519 key configured for a non-EdDSA algorithm")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key.PublicKey)
+	}
+
+	if cfg.RetiredAt != "" {
+		retiredAt, err := time.Parse(time.RFC3339, cfg.RetiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retired_at: %w", err)
+		}
+		key.RetiredAt = &retiredAt
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
+
+// verifiable reports whether tokens signed with key are still accepted at now.
+func (j *JWTManager) verifiable(key *SigningKey, now time.Time) bool {
+	return key.RetiredAt == nil || now.Before(key.RetiredAt.Add(j.GracePeriod))
+}
+
+func (j *JWTManager) sign(claims jwt.Claims) (string, error) {
+	key := j.keys[j.activeKeyID]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// keyFunc resolves the verification key from the kid header and makes sure
+// the token was signed with the algorithm that key is configured for.
+func (j *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.keys[kid]
+	if !ok || !j.verifiable(key, time.Now()) {
+		return nil, fmt.Errorf("unknown or expired signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.PublicKey, nil
+}
+
+// newClaims fills in the registered claims shared by every token we issue.
+func (j *JWTManager) newClaims(user *model.User, duration time.Duration) *Claims {
+	now := time.Now()
+	return &Claims{
+		Name: user.Name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   user.ID.String(),
+			Issuer:    j.issuer,
+			Audience:  jwt.ClaimStrings{j.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+}
+
+// parse verifies the signature and every registered claim we set.
+func (j *JWTManager) parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, j.keyFunc,
+		jwt.WithIssuer(j.issuer),
+		jwt.WithAudience(j.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" || claims.ID == "" || claims.NotBefore == nil {
+		return nil, errors.New("token is missing required claims")
+	}
+	return claims, nil
+}
+
+// Generate issues an access token for user working at clinicID.
+func (j *JWTManager) Generate(user *model.User, clinicID uuid.UUID) (string, error) {
+	claims := j.newClaims(user, j.TokenDuration)
+	claims.Role = user.Role
+	claims.Clinic = clinicID.String()
+	return j.sign(claims)
+}
+
+// Verify validates an access token and returns its claims.
+func (j *JWTManager) Verify(tokenString string) (*Claims, error) {
+	claims, err := j.parse(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("%w: not an access token", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// GenerateMFAEnrollment issues a token for clinicID that is only good for
+// setting up two-factor authentication.
+func (j *JWTManager) GenerateMFAEnrollment(user *model.User, clinicID uuid.UUID) (string, error) {
+	claims := j.newClaims(user, j.TokenDuration)
+	claims.Role = user.Role
+	claims.Clinic = clinicID.String()
+	claims.Purpose = mfaEnrollmentPurpose
+	return j.sign(claims)
+}
+
+// VerifyEnrollment validates a token for the enrolment routes, which accept
+// access tokens as well as enrolment tokens.
+func (j *JWTManager) VerifyEnrollment(tokenString string) (*Claims, error) {
+	claims, err := j.parse(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Purpose != "" && claims.Purpose != mfaEnrollmentPurpose {
+		return nil, fmt.Errorf("%w: not an access token", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// GenerateMFAChallenge issues a short-lived token handed out after a correct
+// password when the user still has to present a second factor.
+func (j *JWTManager) GenerateMFAChallenge(user *model.User, duration time.Duration) (string, error) {
+	claims := j.newClaims(user, duration)
+	claims.Purpose = mfaChallengePurpose
+	return j.sign(claims)
+}
+
+// VerifyMFAChallenge validates a challenge token and returns the user it was issued for.
+func (j *JWTManager) VerifyMFAChallenge(tokenString string) (uuid.UUID, error) {
+	claims, err := j.parse(tokenString)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Purpose != mfaChallengePurpose {
+		return uuid.Nil, fmt.Errorf("%w: not an mfa challenge", ErrInvalidToken)
+	}
+	return claims.UserID()
 }
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// maxJSONBodyBytes bounds the size of JSON request bodies.
+const maxJSONBodyBytes = 1 << 20
+
+// DecodeJSON reads a JSON request body into v, rejecting unknown fields and
+// trailing data.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if decoder.More() {
+		return errors.New("invalid request body: unexpected data after JSON object")
+	}
+	return nil
+}
+
+// PathUUID parses the named path wildcard as a UUID.
+func PathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return id, nil
+}
+
+// ClientIP returns the address of the connecting peer without the port.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters as recommended by RFC 6238 and understood by common
+// authenticator apps.
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160-bit secret encoded as unpadded base32.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(secret), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI that authenticator apps read from a QR code.
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPStep returns the RFC 6238 time step counter for t.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// GenerateTOTPCode computes the code for the given time step.
+func GenerateTOTPCode(secret string, step int64) (string, error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %w", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%modulo), nil
+}
+
+// ValidateTOTPCode checks code against the steps within skew periods of t and
+// returns the matching step, so callers can refuse to accept it twice.
+func ValidateTOTPCode(secret, code string, t time.Time, skew int) (int64, bool, error) {
+	current := TOTPStep(t)
+	for offset := -skew; offset <= skew; offset++ {
+		step := current + int64(offset)
+		expected, err := GenerateTOTPCode(secret, step)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// GenerateRecoveryCodes returns n random one-time codes formatted as xxxxx-xxxxx.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		encoded := strings.ToLower(base32NoPadding.EncodeToString(raw))[:10]
+		codes = append(codes, encoded[:5]+"-"+encoded[5:])
+	}
+	return codes, nil
+}
+
+// HashToken returns the hex SHA-256 of a high-entropy secret such as a recovery
+// code. Unlike passwords these do not need a slow hash, and a deterministic one
+// lets them be looked up directly.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}